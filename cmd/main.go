@@ -2,8 +2,8 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,13 +11,27 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/geo"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/health"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/middleware"
+	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
+	"github.com/sharmaprinceji/delivery-management-system/internal/metrics"
+	"github.com/sharmaprinceji/delivery-management-system/internal/notify"
 	"github.com/sharmaprinceji/delivery-management-system/internal/router"
 
 	"github.com/sharmaprinceji/delivery-management-system/internal/router/agentRoute"
+	"github.com/sharmaprinceji/delivery-management-system/internal/router/customerRoute"
+	"github.com/sharmaprinceji/delivery-management-system/internal/router/healthRoute"
+	"github.com/sharmaprinceji/delivery-management-system/internal/router/jobRoute"
 	"github.com/sharmaprinceji/delivery-management-system/internal/router/orderRoute"
 
 	_ "github.com/sharmaprinceji/delivery-management-system/docs"
 	httpSwagger "github.com/swaggo/http-swagger"
+
+	// job timezones must resolve even where the host has no zoneinfo
+	_ "time/tzdata"
 )
    // delivery-management-system-h5nh.onrender.com  // localhost:5002
 
@@ -29,16 +43,75 @@ import (
 // @host delivery-management-system-h5nh.onrender.com
 // @BasePath /
 func main() {
-	//cfg := config.MustLoad()
+	cfg := config.MustLoad()
+	logger.Setup(cfg.LogLevel)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
+	}
+
+	// cancelled on SIGINT/SIGTERM; stops the scheduler and any allocation it is running
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	route, storage, podFiles, sched := router.SetupRouter(ctx)
 
-	route, storage := router.SetupRouter()
+	// allocations submitted over the API; runs still going at shutdown are cancelled
+	allocations := jobs.NewRunner(storage, cfg.Variables.Delivery, cfg.Allocation)
+	allocations.Start(ctx)
+
+	// new orders inserted into current routes as they come in, when streaming is enabled
+	stream := jobs.NewStreamer(storage, cfg.Variables.Delivery, cfg.Allocation.Streaming)
+	stream.Start(ctx)
+
+	// addresses sent instead of coordinates are looked up offline
+	gazetteer, err := geo.LoadGazetteer(cfg.Geocoding.GazetteerPath)
+	if err != nil {
+		logger.Fatal("failed to load gazetteer", slog.String("error", err.Error()))
+	}
+	pincodes, localities := gazetteer.Len()
+	slog.Info("gazetteer loaded", slog.Int("pincodes", pincodes), slog.Int("localities", localities))
+
+
+	// customer notifications, sent from the outbox in the background
+	notifyLoc, err := time.LoadLocation(cfg.Notifications.Timezone)
+	if err != nil {
+		logger.Fatal("invalid notifications.timezone", slog.String("error", err.Error()))
+	}
+	templates, err := notify.LoadTemplates(cfg.Notifications.TemplatesDir, notifyLoc)
+	if err != nil {
+		logger.Fatal("failed to load notification templates", slog.String("error", err.Error()))
+	}
+	var notifiers []notify.Notifier
+	if cfg.Notifications.SMTP.Host != "" {
+		notifiers = append(notifiers, notify.NewSMTP(cfg.Notifications.SMTP))
+	}
+	if cfg.Notifications.SMS.URL != "" {
+		notifiers = append(notifiers, notify.NewSMSGateway(cfg.Notifications.SMS))
+	}
+	notifications := notify.NewDispatcher(storage, templates, cfg.Notifications, notifiers...)
+	notifications.Start(ctx)
 
 	// Enable CORS
+	route.Use(middleware.RequestID)
+	route.Use(middleware.AccessLog)
+	route.Use(middleware.Timeout(cfg.HTTPServer.RequestTimeout))
 	route.Use(mux.CORSMethodMiddleware(route))
 	route.Use(corsMiddleware)
+	route.Use(metrics.Middleware)
+	route.Use(middleware.Recover)
+
+	agentRoute.RegisterAgentRoutes(route, storage, sched, cfg.Variables.Delivery)
+	orderroute.RegisterOrderRoutes(route, storage, allocations, stream, cfg.Zones, gazetteer, podFiles, cfg.POD, cfg.Attempts, cfg.Variables.Delivery)
+	customerRoute.RegisterCustomerRoutes(route, storage, gazetteer)
+	healthRoute.RegisterHealthRoutes(route, storage, sched)
+	jobRoute.RegisterJobRoutes(route, sched)
 
-	agentRoute.RegisterAgentRoutes(route, storage)
-	orderroute.RegisterOrderRoutes(route, storage)
+	// Prometheus metrics
+	metrics.Register(metrics.WarehouseCollector{Load: storage.GetWarehouseLoad})
+	route.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	route.NotFoundHandler = middleware.RequestID(middleware.AccessLog(http.NotFoundHandler()))
 
 	// Swagger route
 	route.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
@@ -48,31 +121,44 @@ func main() {
 		port = "5002"
 	}
 
+	// request contexts derive from baseCtx so that requests still running after the
+	// shutdown timeout are cancelled together with their queries
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	server := http.Server{
-		Addr:    ":" + port,
-		Handler: route,
+		Addr:        ":" + port,
+		Handler:     route,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
 
 	slog.Info("Starting server...", slog.String("address",  ":" + port))
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
-
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			fmt.Printf("Error starting server: %v\n", err)
+			logger.Fatal("error starting server", slog.String("error", err.Error()))
 		}
 	}()
 
-	<-done
+	<-ctx.Done()
+	stop()
 	slog.Info("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// fail readiness first so the load balancer drains traffic
+	health.SetDraining()
+	time.Sleep(cfg.HTTPServer.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		slog.Error("failed to shutting down server", slog.String("error", err.Error()))
+		cancelRequests()
 	}
+	sched.Wait()
+	allocations.Wait()
+	stream.Wait()
+	notifications.Wait()
 
 	slog.Info("Server stopped gracefully")
 }
@@ -97,7 +183,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Set headers required for preflight and CORS
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Location")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 
 		// Handle preflight requests
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/sharmaprinceji/delivery-management-system/db"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/migrate"
+)
+
+const migrateUsage = `usage: main migrate <command>
+
+commands:
+  up            apply all pending migrations
+  down [n]      roll back the last n migrations (default 1)
+  to <version>  migrate up or down to the given version
+  status        list migrations and whether they are applied
+`
+
+// runMigrate implements the "migrate" subcommand and returns the process exit code.
+func runMigrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	st, err := db.Open(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open database: %v\n", err)
+		return 1
+	}
+
+	migratable, ok := st.(interface {
+		Migrator() (*migrate.Migrator, error)
+	})
+	if !ok {
+		fmt.Fprintf(os.Stderr, "driver %q does not support migrations\n", cfg.Driver)
+		return 1
+	}
+
+	m, err := migratable.Migrator()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load migrations: %v\n", err)
+		return 1
+	}
+
+	// Ctrl-C rolls back the migration in progress instead of leaving it half applied
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// databases created before migrations were tracked are baselined as on startup
+	if legacy, ok := st.(interface {
+		BaselineLegacy(context.Context, *migrate.Migrator) error
+	}); ok && args[0] != "status" {
+		if err := legacy.BaselineLegacy(ctx, m); err != nil {
+			fmt.Fprintf(os.Stderr, "baseline legacy schema: %v\n", err)
+			return 1
+		}
+	}
+
+	switch args[0] {
+	case "up":
+		err = m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintf(os.Stderr, "invalid step count %q\n", args[1])
+				return 2
+			}
+		}
+		err = m.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			fmt.Fprint(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return 2
+		}
+		err = m.To(ctx, version)
+	case "status":
+		statuses, serr := m.Status(ctx)
+		if serr != nil {
+			err = serr
+			break
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			at := "pending"
+			if s.AppliedAt != nil {
+				at = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		tw.Flush()
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
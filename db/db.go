@@ -1,19 +1,35 @@
 package db
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 
 	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
 	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/postgres"
 	"github.com/sharmaprinceji/delivery-management-system/internal/storage/sqlite"
 )
 
 func Mydb(cfg *config.Config) (storage.Storage, error) {
-	st, err := sqlite.New(cfg) 
+	st, err := Open(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		logger.Fatal("failed to initialize database", slog.String("driver", cfg.Driver), slog.String("error", err.Error()))
 	}
-	//log.Printf("DB Path: %s\n", cfg.StoragePath)
-	return st,nil
+	return st, nil
 }
 
+// Open returns the storage backend selected by the driver config key.
+func Open(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.Driver {
+	case "", "sqlite":
+		return sqlite.New(cfg)
+	case "postgres":
+		return postgres.New(cfg)
+	case "memory":
+		return memory.New(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
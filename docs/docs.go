@@ -49,7 +49,7 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
@@ -57,7 +57,7 @@ const docTemplate = `{
         },
         "/api/agent/checkin": {
             "post": {
-                "description": "Allows an agent to check in to a warehouse",
+                "description": "Allows an agent to check in to a warehouse. The vehicle's weight, volume and parcel limits are optional; a limit left out or 0 is not enforced",
                 "consumes": [
                     "application/json"
                 ],
@@ -92,13 +92,13 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
@@ -134,45 +134,185 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/agent/{agent_id}/release": {
+            "post": {
+                "description": "Takes back all orders the agent has not delivered, keeping the closed assignments with the reason, so the next allocation run can hand them to others. With check_out the agent is also checked out. The body is optional",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Agent"
+                ],
+                "summary": "Release every open order of an agent",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Agent ID",
+                        "name": "agent_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason and check-out",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/types.ReleaseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ReleaseResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
         "/api/allocate": {
+            "post": {
+                "description": "Queues an allocation run on the worker pool and returns at once. Poll the URL in the Location header for progress",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Start an allocation run",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/types.AllocationRun"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the run"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/allocate/{job_id}": {
             "get": {
-                "description": "Runs the allocation algorithm to assign orders to agents",
+                "description": "Returns the progress of a run (orders processed, assigned, deferred) and, once finished, its outcome. Only recent runs accepted by this instance are known",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
                     "Orders"
                 ],
-                "summary": "Trigger manual allocation of orders",
+                "summary": "Get an allocation run",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Run ID",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "Allocation successful",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/types.AllocationRun"
                         }
                     },
-                    "500": {
-                        "description": "Allocation failed",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/allocate/{job_id}/cancel": {
+            "post": {
+                "description": "Cancels a queued run, or stops a running one before its next order. Assignments already made are kept",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Cancel an allocation run",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Run ID",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.AllocationRun"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
@@ -180,7 +320,7 @@ const docTemplate = `{
         },
         "/api/assignments": {
             "get": {
-                "description": "Returns paginated list of assignments with formatted date",
+                "description": "Returns paginated list of assignments with times in RFC 3339, in the timezone of the order's warehouse",
                 "consumes": [
                     "application/json"
                 ],
@@ -216,15 +356,15 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/order": {
+        "/api/customers": {
             "post": {
-                "description": "Creates a new customer order and stores it in the database",
+                "description": "Saves a customer with their contact details and delivery addresses. Addresses without lat and lng are geocoded from the local gazetteer; one that cannot be is refused. The phone number identifies the customer and may be saved only once",
                 "consumes": [
                     "application/json"
                 ],
@@ -232,17 +372,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Customers"
                 ],
-                "summary": "Create a new order",
+                "summary": "Create a customer",
                 "parameters": [
                     {
-                        "description": "Order details",
-                        "name": "order",
+                        "description": "Customer details",
+                        "name": "customer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.OrderRequest"
+                            "$ref": "#/definitions/types.CustomerRequest"
                         }
                     }
                 ],
@@ -250,30 +390,80 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
-                            }
+                            "$ref": "#/definitions/types.Customer"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/orders/bulk": {
+        "/api/customers/{customer_id}": {
+            "get": {
+                "description": "Returns a customer with their saved addresses, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Customers"
+                ],
+                "summary": "Get a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.Customer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/customers/{customer_id}/addresses": {
             "post": {
-                "description": "Accepts a list of customer orders and stores them in the database",
+                "description": "Without lat and lng the address is geocoded from the local gazetteer; one that cannot be is refused",
                 "consumes": [
                     "application/json"
                 ],
@@ -281,17 +471,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Customers"
                 ],
-                "summary": "Create multiple orders in bulk",
+                "summary": "Save another address for a customer",
                 "parameters": [
                     {
-                        "description": "List of order requests",
-                        "name": "orders",
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Address",
+                        "name": "address",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.BulkOrderRequest"
+                            "$ref": "#/definitions/types.CustomerAddressRequest"
                         }
                     }
                 ],
@@ -299,30 +496,33 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
-                            }
+                            "$ref": "#/definitions/types.CustomerAddress"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/system-summary": {
-            "get": {
-                "description": "Returns a system-wide summary including total, assigned, and deferred orders, along with agent utilization",
+        "/api/customers/{customer_id}/notifications": {
+            "put": {
+                "description": "With opt_out true the customer gets no more emails or text messages about their orders; messages already waiting to be sent still go out",
                 "consumes": [
                     "application/json"
                 ],
@@ -330,192 +530,2459 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Summary"
+                    "Customers"
                 ],
-                "summary": "Get system summary with paginated agent utilization",
+                "summary": "Turn a customer's notifications off or on",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Page number (default is 1)",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Preferences",
+                        "name": "preferences",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.NotificationPreferences"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.SystemSummary"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/warehouse": {
-            "post": {
-                "description": "Accepts warehouse details and stores them in the system",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/customers/{customer_id}/orders": {
+            "get": {
+                "description": "Returns a page of the orders placed for a customer, newest first, each with its status (pending, assigned or delivered). Orders given only a free-text customer name are not linked to any customer",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Warehouse"
+                    "Customers"
                 ],
-                "summary": "Create a new warehouse",
+                "summary": "List a customer's orders",
                 "parameters": [
                     {
-                        "description": "Warehouse Details",
-                        "name": "warehouse",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/types.WarehouseRequest"
-                        }
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default is 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Items per page (default is 10)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "Orders of the customer",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "response.Response": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                },
-                "status": {
+        },
+        "/api/jobs": {
+            "get": {
+                "description": "Returns every scheduled job with its schedule, timezone and last/next run",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "List scheduled jobs",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.JobState"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/jobs/{name}/run": {
+            "post": {
+                "description": "Starts the named job in the background; its outcome appears in GET /api/jobs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Run a job now",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/order": {
+            "post": {
+                "description": "Creates a new customer order and stores it in the database. An address may be given instead of lat and lng: it is geocoded from the local gazetteer, and when it cannot be the order is queued for review (202) rather than rejected. Without warehouse_id the order goes to the warehouse whose service zone contains it. An order outside every zone of its warehouse is rejected, or taken and flagged out_of_zone when zones.out_of_zone is flag. With allocation streaming enabled the order is inserted into an agent's current route shortly after.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Create a new order",
+                "parameters": [
+                    {
+                        "description": "Order details",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.OrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/bulk": {
+            "post": {
+                "description": "Accepts a list of customer orders and stores them in the database, all or none. Addresses, warehouses and service zones are handled per order as for a single order; orders whose address cannot be geocoded are queued for review and counted in address_review. With allocation streaming enabled they are inserted into agents' current routes shortly after.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Create multiple orders in bulk",
+                "parameters": [
+                    {
+                        "description": "List of order requests",
+                        "name": "orders",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.BulkOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/late": {
+            "get": {
+                "description": "Lists undelivered orders whose delivery window has closed (late) or whose planned ETA falls after it (at_risk), earliest window first, with times in RFC 3339 in the timezone of the order's warehouse",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "List late and at-risk orders",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.SLAOrder"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/returns": {
+            "get": {
+                "description": "Lists orders whose delivery attempts are exhausted and that have not been received back yet, longest on the way first. Without warehouse_id every warehouse is listed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "List orders on their way back to the warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "warehouse_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.Order"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/reviews": {
+            "get": {
+                "description": "Lists orders whose address could not be geocoded, oldest first, with the request as it was sent",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "List orders waiting for address review",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.AddressReview"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/reviews/{review_id}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Discard an order waiting for address review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "review_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/reviews/{review_id}/resolve": {
+            "post": {
+                "description": "Creates the queued order at the given coordinates and takes it off the review queue. Warehouse, service zone and distance checks apply as for a new order",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Give the coordinates of an order waiting for review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "review_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Delivery coordinates",
+                        "name": "location",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.Location"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/{order_id}/attempts": {
+            "get": {
+                "description": "Lists the failed attempts recorded for the order, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "List an order's failed delivery attempts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.DeliveryAttempt"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/{order_id}/fail": {
+            "post": {
+                "description": "Closes the order's open assignment and records why the delivery failed. The order goes back to the next allocation run with its priority raised by attempts.priority_bump, until it has failed attempts.max_attempts times; then its agent returns it to the warehouse (outcome returning). A note is required when the reason is other",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Record a failed delivery attempt",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason for the failure",
+                        "name": "attempt",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.FailedAttemptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/{order_id}/notifications": {
+            "get": {
+                "description": "Lists the emails and text messages written to the order's customer, oldest first, with whether each was sent, is waiting for another attempt, failed or was skipped for want of an adapter",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "List the notifications about an order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.Notification"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/{order_id}/pod": {
+            "get": {
+                "description": "Returns what was captured when the order was delivered, with links to its photo and signature",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get the proof of delivery of an order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.DeliveryProof"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Completes the delivery of an order with the distance and time actually travelled, keeping a proof of it for disputes: the GPS fix taken at the door, the OTP the customer was given when the order was assigned, an optional photo and signature (JPEG, PNG or WebP) and a note. With pod.otp_optional a photo or signature may stand in for the OTP. Wrong OTPs are counted; after pod.max_otp_attempts the order must be reassigned, which issues a new one. The GPS fix is refused when farther than pod.max_distance_m from the order",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Deliver an order with proof",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Latitude of the GPS fix",
+                        "name": "lat",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude of the GPS fix",
+                        "name": "lng",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Accuracy of the GPS fix in metres",
+                        "name": "accuracy_m",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OTP given by the customer",
+                        "name": "otp",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Note, e.g. left with the security guard",
+                        "name": "note",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Distance actually travelled",
+                        "name": "actual_km",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Time actually taken",
+                        "name": "actual_minutes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Photo of the handover",
+                        "name": "photo",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Customer's signature",
+                        "name": "signature",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/types.DeliveryProof"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/{order_id}/pod/{artefact}": {
+            "get": {
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/webp"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Download the photo or signature of a proof of delivery",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "photo or signature",
+                        "name": "artefact",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/{order_id}/reassign": {
+            "post": {
+                "description": "Closes the order's open assignment with the reason and assigns the order to another checked-in agent, planning the leg and ETA from the end of the agent's route",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Move an order to another agent",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New agent and reason",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.ReassignRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/{order_id}/return": {
+            "post": {
+                "description": "Marks an order that is being returned as back at its warehouse. Orders not being returned are refused with 409",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Receive a returned order at the warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orders/{order_id}/unassign": {
+            "post": {
+                "description": "Closes the order's open assignment, keeping it in the history with the reason, and makes the order eligible for the next allocation run. The body is optional",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Take an order back from its agent",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/types.UnassignRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/system-summary": {
+            "get": {
+                "description": "Returns a system-wide summary including total, assigned, and deferred orders, along with agent utilization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Summary"
+                ],
+                "summary": "Get system summary with paginated agent utilization",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number (default is 1)",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.SystemSummary"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/warehouse": {
+            "post": {
+                "description": "Accepts warehouse details and stores them in the system. The calendar is optional: UTC, open 00:00-23:59 and no holidays by default. Allocation for the warehouse is scheduled daily at its local opening time",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouse"
+                ],
+                "summary": "Create a new warehouse",
+                "parameters": [
+                    {
+                        "description": "Warehouse Details",
+                        "name": "warehouse",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.WarehouseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/warehouse/{warehouse_id}/calendar": {
+            "put": {
+                "description": "Replaces the calendar; empty fields fall back to the defaults. The warehouse's allocation job is rescheduled at the new opening time",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouse"
+                ],
+                "summary": "Set a warehouse's timezone, hours and holidays",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "warehouse_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Timezone, hours and holidays",
+                        "name": "calendar",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.WarehouseCalendar"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.WarehouseCalendar"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/warehouses": {
+            "get": {
+                "description": "Returns every warehouse with its timezone, operating hours and holidays",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouse"
+                ],
+                "summary": "List warehouses",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.Warehouse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/warehouses/{warehouse_id}/clusters": {
+            "get": {
+                "description": "Groups the warehouse's pending orders into compact clusters sized to one agent's daily budget, with their centroids, most urgent first. Allocation assigns whole clusters when variables.delivery.cluster_orders is set",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouse"
+                ],
+                "summary": "Show a warehouse's delivery zones",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "warehouse_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.WarehouseClusters"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/warehouses/{warehouse_id}/zones": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouse"
+                ],
+                "summary": "List a warehouse's service zones",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "warehouse_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.ServiceZone"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Reads GeoJSON (a Polygon, MultiPolygon, Feature or FeatureCollection) and stores one zone per polygon, named after the feature's \"name\" property. Coordinates are [lng, lat]. Once a warehouse has zones its orders must lie in one of them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouse"
+                ],
+                "summary": "Upload service zones for a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "warehouse_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "GeoJSON",
+                        "name": "zones",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.ServiceZone"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/zones/check": {
+            "get": {
+                "description": "Lists the zones containing the point and the warehouse an order there without warehouse_id would go to",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouse"
+                ],
+                "summary": "Test a point against every service zone",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Latitude",
+                        "name": "lat",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude",
+                        "name": "lng",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ZoneCheck"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/zones/{zone_id}": {
+            "delete": {
+                "tags": [
+                    "Warehouse"
+                ],
+                "summary": "Delete a service zone",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Zone ID",
+                        "name": "zone_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Returns 200 while the process is running",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks the database connection, the job scheduler and the schema version",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "description": "Returns the git commit, build time and schema version of the running binary",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Build information",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "response.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "lat"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "lat is required"
+                },
+                "param": {
+                    "type": "string"
+                },
+                "tag": {
+                    "type": "string",
+                    "example": "required"
+                }
+            }
+        },
+        "response.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "VALIDATION_FAILED"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "request body failed validation"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/order"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Bad Request"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "urn:dms:error:VALIDATION_FAILED"
+                }
+            }
+        },
+        "types.Address": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Bengaluru"
+                },
+                "line": {
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "80 Feet Road, Koramangala 4th Block"
+                },
+                "pincode": {
+                    "type": "string",
+                    "maxLength": 10,
+                    "example": "560034"
+                }
+            }
+        },
+        "types.AddressReview": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "address not found"
+                },
+                "request": {
+                    "$ref": "#/definitions/types.OrderRequest"
+                }
+            }
+        },
+        "types.AgentCheckInRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "warehouse_id"
+            ],
+            "properties": {
+                "max_parcels": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 10
+                },
+                "max_volume_l": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 40
+                },
+                "max_weight_kg": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 15
+                },
+                "name": {
+                    "type": "string"
+                },
+                "vehicle": {
+                    "type": "string",
+                    "example": "bike"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.AgentSummary": {
+            "type": "object",
+            "properties": {
+                "actual_km": {
+                    "type": "number"
+                },
+                "actual_minutes": {
+                    "type": "number"
+                },
+                "agent_id": {
+                    "type": "integer"
+                },
+                "delivered_orders": {
+                    "type": "integer"
+                },
+                "planned_km": {
+                    "type": "number"
+                },
+                "planned_minutes": {
+                    "type": "number"
+                },
+                "profit": {
+                    "type": "number"
+                },
+                "total_km": {
+                    "type": "number"
+                },
+                "total_minutes": {
+                    "type": "number"
+                },
+                "total_orders": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.AllocationRun": {
+            "type": "object",
+            "properties": {
+                "assigned": {
+                    "type": "integer"
+                },
+                "at_risk": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "conflicts": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deferred": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f9c1a7e5b2d4c60"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "running"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "unfit": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "types.BulkOrderRequest": {
+            "type": "object",
+            "required": [
+                "orders"
+            ],
+            "properties": {
+                "orders": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/types.OrderRequest"
+                    }
+                }
+            }
+        },
+        "types.Customer": {
+            "type": "object",
+            "properties": {
+                "addresses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.CustomerAddress"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "notifications_opt_out": {
+                    "type": "boolean"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.CustomerAddress": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Bengaluru"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "home"
+                },
+                "line": {
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "80 Feet Road, Koramangala 4th Block"
+                },
+                "location": {
+                    "$ref": "#/definitions/types.Location"
+                },
+                "pincode": {
+                    "type": "string",
+                    "maxLength": 10,
+                    "example": "560034"
+                },
+                "verified": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "types.CustomerAddressRequest": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Bengaluru"
+                },
+                "label": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "home"
+                },
+                "lat": {
+                    "type": "number",
+                    "example": 12.9352
+                },
+                "line": {
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "80 Feet Road, Koramangala 4th Block"
+                },
+                "lng": {
+                    "type": "number",
+                    "example": 77.6245
+                },
+                "pincode": {
+                    "type": "string",
+                    "maxLength": 10,
+                    "example": "560034"
+                }
+            }
+        },
+        "types.CustomerRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "phone"
+            ],
+            "properties": {
+                "addresses": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "$ref": "#/definitions/types.CustomerAddressRequest"
+                    }
+                },
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Asha Rao"
+                },
+                "notifications_opt_out": {
+                    "description": "NotificationsOptOut stops messages by email and SMS about the customer's orders.",
+                    "type": "boolean"
+                },
+                "phone": {
+                    "type": "string",
+                    "maxLength": 20,
+                    "minLength": 7,
+                    "example": "+919876543210"
+                }
+            }
+        },
+        "types.DeliveryAttempt": {
+            "type": "object",
+            "properties": {
+                "agent_id": {
+                    "type": "integer"
+                },
+                "assignment_id": {
+                    "type": "integer"
+                },
+                "attempt": {
+                    "type": "integer"
+                },
+                "attempted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "customer_unavailable"
+                }
+            }
+        },
+        "types.DeliveryProof": {
+            "type": "object",
+            "properties": {
+                "accuracy_m": {
+                    "type": "number"
+                },
+                "agent_id": {
+                    "type": "integer"
+                },
+                "assignment_id": {
+                    "type": "integer"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "distance_m": {
+                    "type": "number"
+                },
+                "location": {
+                    "$ref": "#/definitions/types.Location"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "otp_verified": {
+                    "type": "boolean"
+                },
+                "photo": {
+                    "$ref": "#/definitions/types.ProofArtefact"
+                },
+                "signature": {
+                    "$ref": "#/definitions/types.ProofArtefact"
+                }
+            }
+        },
+        "types.FailedAttemptRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "no answer at the door or on the phone"
+                },
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "customer_unavailable",
+                        "address_not_found",
+                        "refused",
+                        "access_restricted",
+                        "other"
+                    ],
+                    "example": "customer_unavailable"
+                }
+            }
+        },
+        "types.JobState": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "last_duration_ms": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_result": {
+                    "type": "string"
+                },
+                "last_run_at": {
+                    "type": "string"
+                },
+                "last_status": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "next_run_at": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.Location": {
+            "type": "object",
+            "properties": {
+                "lat": {
+                    "type": "number",
+                    "example": 12.9716
+                },
+                "lng": {
+                    "type": "number",
+                    "example": 77.5946
+                }
+            }
+        },
+        "types.Notification": {
+            "type": "object",
+            "properties": {
+                "agent_name": {
+                    "type": "string"
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "channel": {
+                    "type": "string",
+                    "example": "sms"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "customer_name": {
+                    "type": "string"
+                },
+                "eta": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "assigned"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "recipient": {
+                    "type": "string",
+                    "example": "+919876543210"
+                },
+                "sent_at": {
                     "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "sent"
                 }
             }
         },
-        "types.AgentCheckInRequest": {
+        "types.NotificationPreferences": {
             "type": "object",
             "required": [
-                "name",
-                "warehouse_id"
+                "opt_out"
             ],
             "properties": {
-                "name": {
-                    "type": "string"
-                },
-                "warehouse_id": {
-                    "type": "integer"
+                "opt_out": {
+                    "type": "boolean",
+                    "example": true
                 }
             }
         },
-        "types.AgentSummary": {
+        "types.Order": {
             "type": "object",
+            "required": [
+                "customer",
+                "warehouse_id"
+            ],
             "properties": {
+                "address_id": {
+                    "type": "integer"
+                },
                 "agent_id": {
                     "type": "integer"
                 },
-                "profit": {
-                    "type": "number"
+                "assigned": {
+                    "type": "boolean"
                 },
-                "total_km": {
+                "customer": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "description": "CustomerID and AddressID are set for orders placed for a saved customer and address.",
+                    "type": "integer"
+                },
+                "failed_attempts": {
+                    "description": "FailedAttempts counts the deliveries that failed. Once too many have, the order goes\nback to its warehouse: ReturnStartedAt is set, and ReturnedAt when it is back.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lat": {
                     "type": "number"
                 },
-                "total_minutes": {
+                "lng": {
                     "type": "number"
                 },
-                "total_orders": {
+                "out_of_zone": {
+                    "description": "OutOfZone marks an order taken although no service zone of its warehouse contains it.",
+                    "type": "boolean"
+                },
+                "parcels": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                },
+                "priority": {
+                    "description": "Priority ranks orders with the same deadline, higher first.",
+                    "type": "integer"
+                },
+                "return_started_at": {
+                    "type": "string"
+                },
+                "returned_at": {
+                    "type": "string"
+                },
+                "volume_l": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 6
+                },
+                "warehouse_id": {
                     "type": "integer"
+                },
+                "weight_kg": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 2.5
+                },
+                "window_end": {
+                    "type": "string"
+                },
+                "window_start": {
+                    "type": "string"
                 }
             }
         },
-        "types.BulkOrderRequest": {
+        "types.OrderCluster": {
             "type": "object",
             "properties": {
-                "orders": {
+                "centroid": {
+                    "$ref": "#/definitions/types.Location"
+                },
+                "estimated_km": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order_ids": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/types.OrderRequest"
+                        "type": "integer"
                     }
+                },
+                "parcels": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                },
+                "volume_l": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 6
+                },
+                "weight_kg": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 2.5
                 }
             }
         },
-        "types.Location": {
+        "types.OrderRequest": {
             "type": "object",
-            "required": [
-                "lat",
-                "lng"
-            ],
             "properties": {
+                "address": {
+                    "$ref": "#/definitions/types.Address"
+                },
+                "address_id": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "customer": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer",
+                    "minimum": 0
+                },
                 "lat": {
-                    "type": "number"
+                    "type": "number",
+                    "example": 12.9721
                 },
                 "lng": {
-                    "type": "number"
+                    "type": "number",
+                    "example": 77.594
+                },
+                "parcels": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                },
+                "priority": {
+                    "type": "integer",
+                    "maximum": 9,
+                    "minimum": 0
+                },
+                "volume_l": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 6
+                },
+                "warehouse_id": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "weight_kg": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 2.5
+                },
+                "window_end": {
+                    "type": "string",
+                    "example": "2024-05-01T12:00:00+05:30"
+                },
+                "window_start": {
+                    "type": "string",
+                    "example": "2024-05-01T10:00:00+05:30"
                 }
             }
         },
-        "types.OrderRequest": {
+        "types.PaginatedAgentSummary": {
+            "type": "object",
+            "properties": {
+                "current_page": {
+                    "type": "integer"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.AgentSummary"
+                    }
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.ProofArtefact": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string",
+                    "example": "image/jpeg"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 48213
+                },
+                "url": {
+                    "type": "string",
+                    "example": "/api/orders/7/pod/photo"
+                }
+            }
+        },
+        "types.ReassignRequest": {
             "type": "object",
             "required": [
-                "customer",
-                "lat",
-                "lng",
-                "warehouse_id"
+                "agent_id"
             ],
             "properties": {
+                "agent_id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "closer agent available"
+                }
+            }
+        },
+        "types.ReleaseRequest": {
+            "type": "object",
+            "properties": {
+                "check_out": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "sick"
+                }
+            }
+        },
+        "types.ReleaseResult": {
+            "type": "object",
+            "properties": {
+                "agent_id": {
+                    "type": "integer"
+                },
+                "checked_out": {
+                    "type": "boolean"
+                },
+                "released": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "types.SLAOrder": {
+            "type": "object",
+            "properties": {
+                "agent_id": {
+                    "type": "integer"
+                },
                 "customer": {
                     "type": "string"
                 },
-                "lat": {
-                    "type": "number"
+                "eta": {
+                    "type": "string"
                 },
-                "lng": {
+                "minutes_late": {
                     "type": "number"
                 },
+                "order_id": {
+                    "type": "integer"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "at_risk"
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Asia/Kolkata"
+                },
                 "warehouse_id": {
                     "type": "integer"
+                },
+                "window_end": {
+                    "type": "string"
+                },
+                "window_start": {
+                    "type": "string"
                 }
             }
         },
-        "types.PaginatedAgentSummary": {
+        "types.ServiceZone": {
             "type": "object",
             "properties": {
-                "current_page": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "data": {
+                "name": {
+                    "type": "string"
+                },
+                "polygon": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/types.AgentSummary"
+                        "type": "number"
                     }
                 },
-                "total_pages": {
+                "warehouse_id": {
                     "type": "integer"
                 }
             }
@@ -532,11 +2999,118 @@ const docTemplate = `{
                 "deferred_orders": {
                     "type": "integer"
                 },
+                "failed_attempts": {
+                    "type": "integer"
+                },
+                "requeued_orders": {
+                    "type": "integer"
+                },
+                "returned_orders": {
+                    "type": "integer"
+                },
+                "returning_orders": {
+                    "type": "integer"
+                },
                 "total_orders": {
                     "type": "integer"
                 }
             }
         },
+        "types.UnassignRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "customer rescheduled"
+                }
+            }
+        },
+        "types.Warehouse": {
+            "type": "object",
+            "required": [
+                "location",
+                "name"
+            ],
+            "properties": {
+                "closes_at": {
+                    "type": "string",
+                    "example": "20:00"
+                },
+                "holidays": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "2024-12-25"
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "location": {
+                    "$ref": "#/definitions/types.Location"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "opens_at": {
+                    "type": "string",
+                    "example": "08:00"
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Asia/Kolkata"
+                }
+            }
+        },
+        "types.WarehouseCalendar": {
+            "type": "object",
+            "properties": {
+                "closes_at": {
+                    "type": "string",
+                    "example": "20:00"
+                },
+                "holidays": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "2024-12-25"
+                    ]
+                },
+                "opens_at": {
+                    "type": "string",
+                    "example": "08:00"
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Asia/Kolkata"
+                }
+            }
+        },
+        "types.WarehouseClusters": {
+            "type": "object",
+            "properties": {
+                "budget_km": {
+                    "type": "number"
+                },
+                "clusters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.OrderCluster"
+                    }
+                },
+                "location": {
+                    "$ref": "#/definitions/types.Location"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "types.WarehouseRequest": {
             "type": "object",
             "required": [
@@ -544,11 +3118,55 @@ const docTemplate = `{
                 "name"
             ],
             "properties": {
+                "closes_at": {
+                    "type": "string",
+                    "example": "20:00"
+                },
+                "holidays": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "2024-12-25"
+                    ]
+                },
                 "location": {
                     "$ref": "#/definitions/types.Location"
                 },
                 "name": {
                     "type": "string"
+                },
+                "opens_at": {
+                    "type": "string",
+                    "example": "08:00"
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Asia/Kolkata"
+                }
+            }
+        },
+        "types.ZoneCheck": {
+            "type": "object",
+            "properties": {
+                "lat": {
+                    "type": "number"
+                },
+                "lng": {
+                    "type": "number"
+                },
+                "served": {
+                    "type": "boolean"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                },
+                "zones": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ServiceZone"
+                    }
                 }
             }
         }
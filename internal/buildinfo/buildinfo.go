@@ -0,0 +1,13 @@
+// Package buildinfo holds values injected at build time, e.g.
+//
+//	go build -ldflags "-X github.com/sharmaprinceji/delivery-management-system/internal/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/sharmaprinceji/delivery-management-system/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+var (
+	Commit    = "dev"
+	BuildTime = "unknown"
+	// SchemaVersion is the schema version the binary was built against; empty means
+	// the version compiled into the storage layer is reported instead.
+	SchemaVersion = ""
+)
@@ -1,21 +1,199 @@
 package config
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
 )
 
 type HTTPServer struct {
 	Addr string `yaml:"address" env-required:"true"`
+	// DrainDelay is how long readiness reports failure before the server stops accepting requests.
+	DrainDelay time.Duration `yaml:"drain_delay" env-default:"5s"`
+	// RequestTimeout bounds each request's context, and with it every query it runs. Zero disables it.
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT" env-default:"30s"`
+	// ShutdownTimeout is how long in-flight requests may finish before their contexts are cancelled.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"5s"`
+}
+
+// Delivery holds the business rules used by allocation and the summaries.
+type Delivery struct {
+	MaxDailyDistance float64 `yaml:"max_daily_distance" env-default:"100"`
+	MaxDailyTime     float64 `yaml:"max_daily_time" env-default:"600"`
+	PerKmTime        float64 `yaml:"per_km_time" env-default:"5"`
+	MinEarnings      float64 `yaml:"min_earnings" env-default:"500"`
+	BaseRate         float64 `yaml:"base_rate" env-default:"20"`
+	Tier1Orders      int     `yaml:"tier1_orders" env-default:"25"`
+	Tier2Orders      int     `yaml:"tier2_orders" env-default:"50"`
+	Tier1Rate        float64 `yaml:"tier1_rate" env-default:"35"`
+	Tier2Rate        float64 `yaml:"tier2_rate" env-default:"42"`
+	// ClusterOrders groups pending orders into zones sized to an agent's daily budget and
+	// assigns whole zones before falling back to order by order.
+	ClusterOrders bool `yaml:"cluster_orders" env:"ALLOCATION_CLUSTERING" env-default:"false"`
+}
+
+// Job configures one scheduled job. Schedule is a five-field cron expression or a
+// descriptor such as @daily; empty fields fall back to the job's defaults.
+type Job struct {
+	Schedule string        `yaml:"schedule"`
+	Timezone string        `yaml:"timezone"`
+	Jitter   time.Duration `yaml:"jitter"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Disabled bool          `yaml:"disabled"`
+}
+
+// Jobs configures the scheduler. OverdueAfter and RetainFor tune the escalation and
+// retention jobs. Allocation runs per warehouse at its local opening time. Its schedule
+// and timezone, when set, apply to the warehouses open all day, which otherwise run at
+// midnight in their own timezone.
+type Jobs struct {
+	Allocation        Job           `yaml:"allocation"`
+	OverdueEscalation Job           `yaml:"overdue_escalation"`
+	DailyReport       Job           `yaml:"daily_report"`
+	RetentionCleanup  Job           `yaml:"retention_cleanup"`
+	OverdueAfter      time.Duration `yaml:"overdue_after" env-default:"4h"`
+	RetainFor         time.Duration `yaml:"retain_for" env-default:"2160h"`
+}
+
+// Allocation sizes the pool that runs allocations submitted over the API.
+type Allocation struct {
+	Workers int `yaml:"workers" env-default:"1"`
+	// QueueSize bounds the runs waiting for a worker; further submissions are refused.
+	QueueSize int `yaml:"queue_size" env-default:"16"`
+	// Timeout bounds one run.
+	Timeout time.Duration `yaml:"timeout" env-default:"15m"`
+	// KeepFinished is how many finished runs stay queryable.
+	KeepFinished int       `yaml:"keep_finished" env-default:"100"`
+	Streaming    Streaming `yaml:"streaming"`
+}
+
+// Streaming configures allocating new orders as they are created, by inserting them into
+// the agents' current routes. Off by default.
+type Streaming struct {
+	Enabled bool `yaml:"enabled" env:"ALLOCATION_STREAMING" env-default:"false"`
+	// Debounce is how long orders created after the first one are gathered into its batch.
+	Debounce time.Duration `yaml:"debounce" env-default:"2s"`
+	// MaxBatch starts a batch early once this many orders are waiting.
+	MaxBatch int `yaml:"max_batch" env-default:"1000"`
+}
+
+// Out-of-zone policies: what order intake does with an order that no service zone of its
+// warehouse contains.
+const (
+	OutOfZoneReject = "reject"
+	OutOfZoneFlag   = "flag"
+)
+
+// Zones configures service zones and the plausibility check on order locations.
+// Warehouses without zones deliver anywhere.
+type Zones struct {
+	OutOfZone string `yaml:"out_of_zone" env:"OUT_OF_ZONE" env-default:"reject"`
+	// MaxDistanceKm refuses orders farther than this from their warehouse; 0 turns it off.
+	MaxDistanceKm float64 `yaml:"max_distance_km" env:"MAX_ORDER_DISTANCE_KM" env-default:"0"`
+}
+
+// Geocoding configures turning order addresses into coordinates, offline, from a CSV of
+// pincode,locality,city,lat,lng rows. Without a gazetteer every order that comes with an
+// address and no coordinates waits for review.
+type Geocoding struct {
+	GazetteerPath string `yaml:"gazetteer_path" env:"GAZETTEER_PATH"`
+}
+
+// POD configures proof of delivery. Photos and signatures are kept as files under Dir.
+// With OTPOptional, a photo or signature stands in for an OTP the customer cannot give.
+type POD struct {
+	Dir         string `yaml:"dir" env:"POD_DIR" env-default:"./data/pod"`
+	OTPOptional bool   `yaml:"otp_optional" env:"POD_OTP_OPTIONAL" env-default:"false"`
+	// MaxOTPAttempts locks the OTP of an assignment after this many wrong tries.
+	MaxOTPAttempts int `yaml:"max_otp_attempts" env-default:"5"`
+	// MaxDistanceM refuses a proof whose GPS fix is farther than this from the order; 0
+	// only records the distance.
+	MaxDistanceM float64 `yaml:"max_distance_m" env:"POD_MAX_DISTANCE_M" env-default:"0"`
+	// MaxUploadMB bounds the whole multipart request.
+	MaxUploadMB int64 `yaml:"max_upload_mb" env-default:"10"`
+}
+
+// Attempts configures failed delivery attempts. A failed order goes back to allocation
+// with its priority raised by PriorityBump until it has failed MaxAttempts times, after
+// which its agent returns it to the warehouse.
+type Attempts struct {
+	MaxAttempts  int `yaml:"max_attempts" env:"MAX_DELIVERY_ATTEMPTS" env-default:"3"`
+	PriorityBump int `yaml:"priority_bump" env-default:"10"`
+}
+
+// Notifications configures the messages customers get when their orders are assigned,
+// delivered or could not be delivered. Every instance sends what is due in the outbox;
+// messages for a channel without an adapter configured are skipped.
+type Notifications struct {
+	// TemplatesDir may hold <event>.tmpl files replacing the built-in templates.
+	TemplatesDir string `yaml:"templates_dir" env:"NOTIFY_TEMPLATES_DIR"`
+	// Timezone is the one ETAs are written in.
+	Timezone     string        `yaml:"timezone" env:"NOTIFY_TIMEZONE" env-default:"UTC"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"10s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"50"`
+	// MaxAttempts gives up on a message after this many failed sends, waiting RetryBackoff
+	// after the first and twice as long after each one since, up to an hour.
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"5"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env-default:"30s"`
+	// SendTimeout bounds one send; a message stays claimed for twice as long.
+	SendTimeout time.Duration `yaml:"send_timeout" env-default:"15s"`
+	SMTP        SMTP          `yaml:"smtp"`
+	SMS         SMS           `yaml:"sms"`
+}
+
+// SMTP configures sending email through an SMTP server, off when Host is empty. STARTTLS
+// is used when the server offers it; without a username no authentication is attempted.
+type SMTP struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string `yaml:"from" env:"SMTP_FROM" env-default:"deliveries@localhost"`
+}
+
+// SMS configures sending text messages through an HTTP gateway, off when URL is empty.
+// Messages are posted as JSON {"to", "from", "message"} with Token as a bearer token.
+type SMS struct {
+	URL    string `yaml:"url" env:"SMS_GATEWAY_URL"`
+	Token  string `yaml:"token" env:"SMS_GATEWAY_TOKEN"`
+	Sender string `yaml:"sender" env:"SMS_SENDER"`
+}
+
+type Variables struct {
+	Delivery Delivery `yaml:"delivery"`
 }
 
 type Config struct {
-	Env         string     `yaml:"env" env-required:"true"`
-	StoragePath string     `yaml:"storage_path" env-required:"true"`
-	HTTPServer  HTTPServer `yaml:"http_server"`
+	Env           string        `yaml:"env" env-required:"true"`
+	LogLevel      string        `yaml:"log_level" env:"LOG_LEVEL" env-default:"info"`
+	Driver        string        `yaml:"driver" env:"DB_DRIVER" env-default:"sqlite"`
+	StoragePath   string        `yaml:"storage_path" env-required:"true"`
+	DatabaseURL   string        `yaml:"database_url" env:"DATABASE_URL"`
+	HTTPServer    HTTPServer    `yaml:"http_server"`
+	Jobs          Jobs          `yaml:"jobs"`
+	Allocation    Allocation    `yaml:"allocation"`
+	Zones         Zones         `yaml:"zones"`
+	Geocoding     Geocoding     `yaml:"geocoding"`
+	POD           POD           `yaml:"pod"`
+	Attempts      Attempts      `yaml:"attempts"`
+	Notifications Notifications `yaml:"notifications"`
+	Variables     Variables     `yaml:"variables"`
+}
+
+// Profit returns the payout for an agent who delivered the given number of orders.
+func (d Delivery) Profit(orders int) float64 {
+	switch {
+	case orders >= d.Tier2Orders:
+		return float64(orders) * d.Tier2Rate
+	case orders >= d.Tier1Orders:
+		return float64(orders) * d.Tier1Rate
+	default:
+		return float64(orders) * d.BaseRate
+	}
 }
 
 var (
@@ -29,12 +207,19 @@ func MustLoad() *Config {
 		const configPath = "config/local.yaml"
 
 		if _, err := os.Stat(configPath); os.IsNotExist(err) {
-			log.Fatalf("Configuration file does not exist: %s", configPath)
+			logger.Fatal("configuration file does not exist", slog.String("path", configPath))
 		}
 
 		var c Config
 		if err := cleanenv.ReadConfig(configPath, &c); err != nil {
-			log.Fatalf("Failed to read config: %v", err)
+			logger.Fatal("failed to read config", slog.String("error", err.Error()))
+		}
+		if p := c.Zones.OutOfZone; p != OutOfZoneReject && p != OutOfZoneFlag {
+			logger.Fatal("invalid zones.out_of_zone, want reject or flag", slog.String("out_of_zone", p))
+		}
+
+		if c.Attempts.MaxAttempts < 1 {
+			logger.Fatal("invalid attempts.max_attempts, want at least 1", slog.Int("max_attempts", c.Attempts.MaxAttempts))
 		}
 
 		cfg = &c
@@ -0,0 +1,176 @@
+package geo
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// ErrUnresolved is returned by a Geocoder that cannot place an address.
+var ErrUnresolved = errors.New("address not found")
+
+// Geocoder turns an address into coordinates.
+type Geocoder interface {
+	// Geocode returns ErrUnresolved when it does not know the address.
+	Geocode(ctx context.Context, a types.Address) (types.Location, error)
+}
+
+// Gazetteer geocodes offline from a table of locality centroids. A locality named in the
+// address line wins, as long as it lies in the address's pincode or city; otherwise the
+// pincode's centroid is used. A city alone is too coarse to deliver to and is not resolved.
+type Gazetteer struct {
+	pincodes map[string]types.Location
+	// localities of each pincode and city, keyed "pin:560034" and "city:bengaluru"
+	localities map[string][]locality
+	places     int // locality rows read
+}
+
+type locality struct {
+	name string // folded, see fold
+	at   types.Location
+}
+
+// LoadGazetteer reads the CSV file at path, see ReadGazetteer. An empty path gives a
+// gazetteer that resolves nothing.
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	if path == "" {
+		return newGazetteer(), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open gazetteer: %w", err)
+	}
+	defer f.Close()
+
+	g, err := ReadGazetteer(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+// ReadGazetteer reads a CSV with the header pincode,locality,city,lat,lng, one row per
+// locality. Either pincode or locality may be empty. A pincode listed on several rows
+// is placed at the mean of their coordinates.
+func ReadGazetteer(r io.Reader) (*Gazetteer, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 5
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read gazetteer header: %w", err)
+	}
+	for i, want := range []string{"pincode", "locality", "city", "lat", "lng"} {
+		// spreadsheets often save a byte order mark first
+		if strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))) != want {
+			return nil, fmt.Errorf("gazetteer column %d is %q, want %q", i+1, header[i], want)
+		}
+	}
+
+	g := newGazetteer()
+	sums := make(map[string]struct {
+		lat, lng float64
+		n        int
+	})
+
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		pin := strings.ReplaceAll(strings.TrimSpace(rec[0]), " ", "")
+		name, city := fold(rec[1]), fold(rec[2])
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(rec[3]), 64)
+		lng, errLng := strconv.ParseFloat(strings.TrimSpace(rec[4]), 64)
+		if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return nil, fmt.Errorf("line %d: invalid coordinates %q, %q", line, rec[3], rec[4])
+		}
+		if pin == "" && name == "" {
+			return nil, fmt.Errorf("line %d: needs a pincode or a locality", line)
+		}
+		at := types.Location{Lat: lat, Lng: lng}.Normalized()
+
+		if pin != "" {
+			s := sums[pin]
+			s.lat, s.lng, s.n = s.lat+lat, s.lng+lng, s.n+1
+			sums[pin] = s
+		}
+		if name != "" {
+			l := locality{name: name, at: at}
+			g.places++
+			if pin != "" {
+				g.localities["pin:"+pin] = append(g.localities["pin:"+pin], l)
+			}
+			if city != "" {
+				g.localities["city:"+city] = append(g.localities["city:"+city], l)
+			}
+		}
+	}
+
+	for pin, s := range sums {
+		n := float64(s.n)
+		g.pincodes[pin] = types.Location{Lat: s.lat / n, Lng: s.lng / n}.Normalized()
+	}
+	return g, nil
+}
+
+func newGazetteer() *Gazetteer {
+	return &Gazetteer{
+		pincodes:   make(map[string]types.Location),
+		localities: make(map[string][]locality),
+	}
+}
+
+// Len is the number of pincodes and localities known.
+func (g *Gazetteer) Len() (pincodes, localities int) {
+	return len(g.pincodes), g.places
+}
+
+func (g *Gazetteer) Geocode(ctx context.Context, a types.Address) (types.Location, error) {
+	if err := ctx.Err(); err != nil {
+		return types.Location{}, err
+	}
+
+	a = a.Normalized()
+	line := " " + fold(a.Line) + " "
+
+	// the longest name is the most specific: "hsr layout sector 2" over "hsr layout"
+	var best *locality
+	for _, key := range []string{"pin:" + a.Pincode, "city:" + fold(a.City)} {
+		for i, l := range g.localities[key] {
+			if strings.Contains(line, " "+l.name+" ") && (best == nil || len(l.name) > len(best.name)) {
+				best = &g.localities[key][i]
+			}
+		}
+	}
+	if best != nil {
+		return best.at, nil
+	}
+
+	if at, ok := g.pincodes[a.Pincode]; ok {
+		return at, nil
+	}
+	return types.Location{}, ErrUnresolved
+}
+
+// fold lower-cases s and turns every run of punctuation and space into one space, so
+// names match whatever the merchant put between words.
+func fold(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
@@ -0,0 +1,137 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// gazetteer is saved by a spreadsheet, with a byte order mark and the columns spaced out.
+const gazetteer = "\ufeffPincode, Locality, City, Lat, Lng\n" +
+	"560034, Koramangala 4th Block, Bengaluru, 12.9340, 77.6290\n" +
+	"560034, Koramangala 5th Block, Bengaluru, 12.9350, 77.6190\n" +
+	"560102, HSR Layout, Bengaluru, 12.9120, 77.6440\n" +
+	"560102, HSR Layout Sector 2, Bengaluru, 12.9100, 77.6300\n" +
+	"560011, Jayanagar, Bengaluru, 12.9250, 77.5938\n" +
+	", Indiranagar, Bengaluru, 12.9780, 77.6400\n" +
+	"400001, , Mumbai, 18.9380, 72.8350\n"
+
+func TestGeocode(t *testing.T) {
+	g, err := ReadGazetteer(strings.NewReader(gazetteer))
+	if err != nil {
+		t.Fatalf("ReadGazetteer: %v", err)
+	}
+	if pins, places := g.Len(); pins != 4 || places != 6 {
+		t.Errorf("Len = %d pincodes, %d localities; want 4, 6", pins, places)
+	}
+
+	tests := []struct {
+		name string
+		addr types.Address
+		want types.Location
+	}{
+		{"locality in the pincode",
+			types.Address{Line: "42, Koramangala 4th Block", Pincode: "560034"}, types.Location{Lat: 12.934, Lng: 77.629}},
+		{"locality spelt loosely",
+			types.Address{Line: "12/3 KORAMANGALA-5th   block, near Sony signal", Pincode: "560034"}, types.Location{Lat: 12.935, Lng: 77.619}},
+		{"longest locality wins",
+			types.Address{Line: "HSR Layout Sector 2, 27th Main", Pincode: "560102"}, types.Location{Lat: 12.91, Lng: 77.63}},
+		{"locality in the city",
+			types.Address{Line: "100 Feet Road, Indiranagar", City: " bengaluru "}, types.Location{Lat: 12.978, Lng: 77.64}},
+		{"pincode alone is its localities' mean",
+			types.Address{Line: "Flat 4B, Lake View Apartments", Pincode: "560 034"}, types.Location{Lat: 12.9345, Lng: 77.624}},
+		{"pincode without localities",
+			types.Address{Line: "Fort", Pincode: "400001", City: "Mumbai"}, types.Location{Lat: 18.938, Lng: 72.835}},
+		// Jayanagar lies in another pincode and the address names no city
+		{"locality outside the pincode",
+			types.Address{Line: "Jayanagar Main Road", Pincode: "560034"}, types.Location{Lat: 12.9345, Lng: 77.624}},
+		{"locality as part of a longer word",
+			types.Address{Line: "Jayanagaram Street", Pincode: "560034"}, types.Location{Lat: 12.9345, Lng: 77.624}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.Geocode(context.Background(), tt.addr)
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Geocode = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	unresolved := []struct {
+		name string
+		addr types.Address
+	}{
+		{"city alone", types.Address{Line: "Some Street", City: "Bengaluru"}},
+		{"unknown pincode", types.Address{Line: "Some Street", Pincode: "110001"}},
+		{"unknown locality and city", types.Address{Line: "Koramangala 4th Block", City: "Chennai"}},
+		{"nothing", types.Address{}},
+	}
+	for _, tt := range unresolved {
+		if got, err := g.Geocode(context.Background(), tt.addr); !errors.Is(err, ErrUnresolved) {
+			t.Errorf("%s: Geocode = %+v, %v; want ErrUnresolved", tt.name, got, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.Geocode(ctx, types.Address{Pincode: "560034"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Geocode with a cancelled context: %v", err)
+	}
+}
+
+func TestReadGazetteerErrors(t *testing.T) {
+	const header = "pincode,locality,city,lat,lng\n"
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", "", "read gazetteer header"},
+		{"columns out of order", "pincode,city,locality,lat,lng\n", `column 2 is "city"`},
+		{"missing column", header + "560034,Koramangala,Bengaluru,12.93\n", "wrong number of fields"},
+		{"bad latitude", header + "560034,Koramangala,Bengaluru,north,77.62\n", `line 2: invalid coordinates "north"`},
+		{"out of range", header + "560034,Koramangala,Bengaluru,97.62,12.93\n", "line 2: invalid coordinates"},
+		{"no pincode or locality", header + "560034,Koramangala,Bengaluru,12.93,77.62\n,,Bengaluru,12.97,77.59\n",
+			"line 3: needs a pincode or a locality"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadGazetteer(strings.NewReader(tt.src))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadGazetteer: %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadGazetteer(t *testing.T) {
+	// without a file nothing resolves and every address goes to review
+	g, err := LoadGazetteer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Geocode(context.Background(), types.Address{Pincode: "560034"}); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("empty gazetteer: %v, want ErrUnresolved", err)
+	}
+
+	g, err = LoadGazetteer("../../config/gazetteer.sample.csv")
+	if err != nil {
+		t.Fatalf("LoadGazetteer: %v", err)
+	}
+	if pins, places := g.Len(); pins == 0 || places == 0 {
+		t.Errorf("sample gazetteer has %d pincodes and %d localities", pins, places)
+	}
+	if _, err := g.Geocode(context.Background(), types.Address{Line: "MG Road", Pincode: "560001"}); err != nil {
+		t.Errorf("Geocode from the sample: %v", err)
+	}
+
+	if _, err := LoadGazetteer("testdata/missing.csv"); err == nil {
+		t.Error("LoadGazetteer of a missing file succeeded")
+	}
+}
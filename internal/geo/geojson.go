@@ -0,0 +1,95 @@
+// Package geo reads service zones from GeoJSON, decides which warehouse serves a point and
+// geocodes addresses offline.
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// Shape is a polygon read from GeoJSON, named after its feature's "name" property.
+type Shape struct {
+	Name    string
+	Polygon types.Polygon
+}
+
+// object is any GeoJSON object; only the members of the types we accept are used.
+type object struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *object         `json:"geometry"`
+	Features    []object        `json:"features"`
+	Properties  struct {
+		Name string `json:"name"`
+	} `json:"properties"`
+}
+
+// ParseGeoJSON reads the polygons of a Polygon, MultiPolygon, Feature or FeatureCollection.
+// A MultiPolygon gives one shape per polygon, all with the feature's name.
+func ParseGeoJSON(data []byte) ([]Shape, error) {
+	var o object
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+	shapes, err := o.shapes("")
+	if err != nil {
+		return nil, err
+	}
+	if len(shapes) == 0 {
+		return nil, errors.New("GeoJSON has no polygons")
+	}
+	return shapes, nil
+}
+
+func (o object) shapes(name string) ([]Shape, error) {
+	switch o.Type {
+	case "FeatureCollection":
+		var out []Shape
+		for i, f := range o.Features {
+			if f.Type != "Feature" {
+				return nil, fmt.Errorf("features[%d]: type %q, want Feature", i, f.Type)
+			}
+			s, err := f.shapes("")
+			if err != nil {
+				return nil, fmt.Errorf("features[%d]: %w", i, err)
+			}
+			out = append(out, s...)
+		}
+		return out, nil
+
+	case "Feature":
+		if o.Geometry == nil {
+			return nil, errors.New("feature has no geometry")
+		}
+		return o.Geometry.shapes(o.Properties.Name)
+
+	case "Polygon":
+		var p types.Polygon
+		if err := json.Unmarshal(o.Coordinates, &p); err != nil {
+			return nil, fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		return []Shape{{Name: name, Polygon: p}}, nil
+
+	case "MultiPolygon":
+		var ps []types.Polygon
+		if err := json.Unmarshal(o.Coordinates, &ps); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+		out := make([]Shape, 0, len(ps))
+		for i, p := range ps {
+			if err := p.Validate(); err != nil {
+				return nil, fmt.Errorf("polygon %d: %w", i, err)
+			}
+			out = append(out, Shape{Name: name, Polygon: p})
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unsupported GeoJSON type %q, want Polygon, MultiPolygon, Feature or FeatureCollection", o.Type)
+}
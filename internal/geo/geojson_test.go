@@ -0,0 +1,105 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+)
+
+const square = `[[[77.5, 12.9], [77.7, 12.9], [77.7, 13.1], [77.5, 13.1], [77.5, 12.9]]]`
+
+func TestParseGeoJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		shapes []string // names of the shapes read
+		rings  []int    // rings in each shape
+	}{
+		{
+			name:   "polygon",
+			src:    `{"type": "Polygon", "coordinates": ` + square + `}`,
+			shapes: []string{""},
+			rings:  []int{1},
+		},
+		{
+			name: "polygon with a hole",
+			src: `{"type": "Polygon", "coordinates": [
+				[[77.5, 12.9], [77.7, 12.9], [77.7, 13.1], [77.5, 13.1], [77.5, 12.9]],
+				[[77.55, 12.95], [77.65, 12.95], [77.65, 13.05], [77.55, 12.95]]]}`,
+			shapes: []string{""},
+			rings:  []int{2},
+		},
+		{
+			name: "feature",
+			src: `{"type": "Feature", "properties": {"name": "Indiranagar", "colour": "red"},
+				"geometry": {"type": "Polygon", "coordinates": ` + square + `}}`,
+			shapes: []string{"Indiranagar"},
+			rings:  []int{1},
+		},
+		{
+			name: "multipolygon feature",
+			src: `{"type": "Feature", "properties": {"name": "Islands"}, "geometry": {"type": "MultiPolygon", "coordinates": [` +
+				square + `, [[[78, 12], [78.1, 12], [78.1, 12.1], [78, 12]]]]}}`,
+			shapes: []string{"Islands", "Islands"},
+			rings:  []int{1, 1},
+		},
+		{
+			name: "feature collection",
+			src: `{"type": "FeatureCollection", "features": [
+				{"type": "Feature", "properties": {"name": "North"}, "geometry": {"type": "Polygon", "coordinates": ` + square + `}},
+				{"type": "Feature", "properties": {}, "geometry": {"type": "Polygon", "coordinates": ` + square + `}}]}`,
+			shapes: []string{"North", ""},
+			rings:  []int{1, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shapes, err := ParseGeoJSON([]byte(tt.src))
+			if err != nil {
+				t.Fatalf("ParseGeoJSON: %v", err)
+			}
+			if len(shapes) != len(tt.shapes) {
+				t.Fatalf("got %d shapes, want %d", len(shapes), len(tt.shapes))
+			}
+			for i, s := range shapes {
+				if s.Name != tt.shapes[i] || len(s.Polygon) != tt.rings[i] {
+					t.Errorf("shape %d = %q with %d rings, want %q with %d", i, s.Name, len(s.Polygon), tt.shapes[i], tt.rings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseGeoJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"not json", `{"type": `, "invalid GeoJSON"},
+		{"point", `{"type": "Point", "coordinates": [77.6, 12.9]}`, `unsupported GeoJSON type "Point"`},
+		{"empty collection", `{"type": "FeatureCollection", "features": []}`, "no polygons"},
+		{"feature without geometry", `{"type": "Feature", "properties": {}}`, "no geometry"},
+		{"geometry in a collection", `{"type": "FeatureCollection", "features": [{"type": "Polygon", "coordinates": ` + square + `}]}`,
+			`features[0]: type "Polygon", want Feature`},
+		{"bad feature in a collection", `{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": ` + square + `}},
+			{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[77.5, 12.9], [77.7, 12.9]]}}]}`,
+			`features[1]: unsupported GeoJSON type "LineString"`},
+		{"coordinates of a point", `{"type": "Polygon", "coordinates": [77.6, 12.9]}`, "invalid polygon coordinates"},
+		{"open ring", `{"type": "Polygon", "coordinates": [[[77.5, 12.9], [77.7, 12.9], [77.7, 13.1], [77.5, 13.1]]]}`,
+			"does not end where it starts"},
+		{"short ring", `{"type": "Polygon", "coordinates": [[[77.5, 12.9], [77.7, 12.9], [77.5, 12.9]]]}`, "at least 4"},
+		{"lat and lng swapped", `{"type": "Polygon", "coordinates": [[[12.9, 77.5], [12.9, 97.7], [13.1, 77.7], [12.9, 77.5]]]}`,
+			"outside [lng, lat] bounds"},
+		{"bad polygon in a multipolygon", `{"type": "MultiPolygon", "coordinates": [` + square + `, [[[78, 12], [78.1, 12], [78, 12]]]]}`,
+			"polygon 1: ring 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGeoJSON([]byte(tt.src))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseGeoJSON: %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package geo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// Index answers which warehouses deliver to a point. A warehouse without service zones
+// delivers anywhere, as before zones existed.
+type Index struct {
+	zones []types.ServiceZone
+	hubs  map[int64]types.Location
+	zoned map[int64]bool
+}
+
+func NewIndex(zones []types.ServiceZone, warehouses []types.Warehouse) *Index {
+	ix := &Index{
+		zones: zones,
+		hubs:  make(map[int64]types.Location, len(warehouses)),
+		zoned: make(map[int64]bool),
+	}
+	for _, w := range warehouses {
+		ix.hubs[w.ID] = w.Location
+	}
+	for _, z := range zones {
+		ix.zoned[z.WarehouseID] = true
+	}
+	return ix
+}
+
+// Load builds an Index of every warehouse and zone in s.
+func Load(ctx context.Context, s storage.Storage) (*Index, error) {
+	zones, err := s.GetServiceZones(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("load service zones: %w", err)
+	}
+	warehouses, err := s.GetWarehouses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load warehouses: %w", err)
+	}
+	return NewIndex(zones, warehouses), nil
+}
+
+// Matching returns the zones containing the point, in the order they were created.
+func (ix *Index) Matching(lat, lng float64) []types.ServiceZone {
+	out := []types.ServiceZone{}
+	for _, z := range ix.zones {
+		if z.Contains(lat, lng) {
+			out = append(out, z)
+		}
+	}
+	return out
+}
+
+// Serves reports whether the warehouse delivers to the point.
+func (ix *Index) Serves(warehouseID int64, lat, lng float64) bool {
+	if !ix.zoned[warehouseID] {
+		return true
+	}
+	for _, z := range ix.zones {
+		if z.WarehouseID == warehouseID && z.Contains(lat, lng) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hub returns the location of a warehouse.
+func (ix *Index) Hub(warehouseID int64) (types.Location, bool) {
+	hub, ok := ix.hubs[warehouseID]
+	return hub, ok
+}
+
+// Route returns the warehouse whose zone contains the point; when zones of several do,
+// the one nearest to the point. It reports false when no zone contains the point.
+func (ix *Index) Route(lat, lng float64) (int64, bool) {
+	var best int64
+	bestKm := 0.0
+	for _, z := range ix.Matching(lat, lng) {
+		hub, ok := ix.hubs[z.WarehouseID]
+		if !ok {
+			continue
+		}
+		km := jobs.Distance(lat, lng, hub.Lat, hub.Lng)
+		if best == 0 || km < bestKm {
+			best, bestKm = z.WarehouseID, km
+		}
+	}
+	return best, best != 0
+}
@@ -0,0 +1,173 @@
+package geo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// zones are read from GeoJSON as they are uploaded:
+//   - central, warehouse 1: a square with a park cut out of it
+//   - east, warehouse 2: the square east of central, sharing its edge at lng 77.7, and an
+//     island further south, as one MultiPolygon
+//   - corner, warehouse 3: a small square overlapping central's south-west corner
+//
+// Warehouse 4 has no zones.
+const zonesJSON = `{"type": "FeatureCollection", "features": [
+	{"type": "Feature", "properties": {"name": "central"}, "geometry": {"type": "Polygon", "coordinates": [
+		[[77.5, 12.9], [77.7, 12.9], [77.7, 13.1], [77.5, 13.1], [77.5, 12.9]],
+		[[77.58, 12.98], [77.62, 12.98], [77.62, 13.02], [77.58, 13.02], [77.58, 12.98]]]}},
+	{"type": "Feature", "properties": {"name": "east"}, "geometry": {"type": "MultiPolygon", "coordinates": [
+		[[[77.7, 12.9], [77.9, 12.9], [77.9, 13.1], [77.7, 13.1], [77.7, 12.9]]],
+		[[[77.5, 12.5], [77.6, 12.5], [77.6, 12.6], [77.5, 12.6], [77.5, 12.5]]]]}},
+	{"type": "Feature", "properties": {"name": "corner"}, "geometry": {"type": "Polygon", "coordinates": [
+		[[77.45, 12.85], [77.55, 12.85], [77.55, 12.95], [77.45, 12.95], [77.45, 12.85]]]}}]}`
+
+var owners = map[string]int64{"central": 1, "east": 2, "corner": 3}
+
+var warehouses = []types.Warehouse{
+	{ID: 1, Name: "Central", Location: types.Location{Lat: 13.0, Lng: 77.55}},
+	{ID: 2, Name: "East", Location: types.Location{Lat: 13.0, Lng: 77.8}},
+	{ID: 3, Name: "Corner", Location: types.Location{Lat: 12.9, Lng: 77.5}},
+	{ID: 4, Name: "Anywhere", Location: types.Location{Lat: 12.0, Lng: 77.0}},
+}
+
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+	shapes, err := ParseGeoJSON([]byte(zonesJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones := make([]types.ServiceZone, len(shapes))
+	for i, s := range shapes {
+		zones[i] = types.ServiceZone{ID: int64(i + 1), WarehouseID: owners[s.Name], Name: s.Name, Polygon: s.Polygon}
+	}
+	return NewIndex(zones, warehouses)
+}
+
+func TestMatchingAndRoute(t *testing.T) {
+	ix := testIndex(t)
+	tests := []struct {
+		name     string
+		lat, lng float64
+		zones    []string
+		route    int64
+	}{
+		{"inside central", 12.95, 77.65, []string{"central"}, 1},
+		{"in the park", 13.0, 77.6, []string{}, 0},
+		{"between the park and the edge", 13.0, 77.57, []string{"central"}, 1},
+		{"inside east", 13.0, 77.8, []string{"east"}, 2},
+		{"on the island", 12.55, 77.55, []string{"east"}, 2},
+		{"between the parts of east", 12.7, 77.55, []string{}, 0},
+		// the corner is nearer warehouse 3 than warehouse 1
+		{"where central and corner overlap", 12.92, 77.52, []string{"central", "corner"}, 3},
+		{"outside every zone", 14.0, 77.0, []string{}, 0},
+
+		// a ring holds the points on its west and south edges but not those on its east
+		// and north ones, so a point on the edge between two zones is in exactly one
+		{"on the edge between central and east", 13.0, 77.7, []string{"east"}, 2},
+		{"on central's west edge", 13.0, 77.5, []string{"central"}, 1},
+		{"on central's north edge", 13.1, 77.6, []string{}, 0},
+		{"on the park's west edge", 13.0, 77.58, []string{}, 0},
+		{"on the park's east edge", 13.0, 77.62, []string{"central"}, 1},
+		{"on the corner central and east share", 12.9, 77.7, []string{"east"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{}
+			for _, z := range ix.Matching(tt.lat, tt.lng) {
+				names = append(names, z.Name)
+			}
+			if !reflect.DeepEqual(names, tt.zones) {
+				t.Errorf("Matching(%g, %g) = %v, want %v", tt.lat, tt.lng, names, tt.zones)
+			}
+			id, ok := ix.Route(tt.lat, tt.lng)
+			if id != tt.route || ok != (tt.route != 0) {
+				t.Errorf("Route(%g, %g) = %d, %v; want %d", tt.lat, tt.lng, id, ok, tt.route)
+			}
+		})
+	}
+}
+
+func TestServes(t *testing.T) {
+	ix := testIndex(t)
+	tests := []struct {
+		warehouse int64
+		lat, lng  float64
+		want      bool
+	}{
+		{1, 12.95, 77.65, true},
+		{1, 13.0, 77.6, false}, // the park
+		{1, 13.0, 77.8, false},
+		{2, 12.55, 77.55, true}, // the island
+		{2, 12.95, 77.65, false},
+		{3, 12.92, 77.52, true},
+		// a warehouse without zones delivers anywhere
+		{4, 13.0, 77.6, true},
+		{4, 40.7, -74.0, true},
+	}
+	for _, tt := range tests {
+		if got := ix.Serves(tt.warehouse, tt.lat, tt.lng); got != tt.want {
+			t.Errorf("Serves(%d, %g, %g) = %v, want %v", tt.warehouse, tt.lat, tt.lng, got, tt.want)
+		}
+	}
+}
+
+func TestRouteSkipsUnknownWarehouse(t *testing.T) {
+	shapes, err := ParseGeoJSON([]byte(zonesJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// central's warehouse is gone; the overlapping corner still routes
+	ix := NewIndex([]types.ServiceZone{
+		{WarehouseID: 9, Name: "central", Polygon: shapes[0].Polygon},
+		{WarehouseID: 3, Name: "corner", Polygon: shapes[3].Polygon},
+	}, warehouses)
+	if id, ok := ix.Route(12.95, 77.65); ok {
+		t.Errorf("Route to a zone without a warehouse = %d", id)
+	}
+	if id, ok := ix.Route(12.92, 77.52); !ok || id != 3 {
+		t.Errorf("Route = %d, %v; want 3", id, ok)
+	}
+	if _, ok := ix.Hub(9); ok {
+		t.Error("Hub of an unknown warehouse found")
+	}
+	if hub, ok := ix.Hub(3); !ok || hub != warehouses[2].Location {
+		t.Errorf("Hub(3) = %v, %v", hub, ok)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New(&config.Config{})
+	zoned, err := s.CreateWarehouse(ctx, types.Warehouse{Name: "Central", Location: types.Location{Lat: 13.0, Lng: 77.55}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	open, err := s.CreateWarehouse(ctx, types.Warehouse{Name: "Anywhere", Location: types.Location{Lat: 12.0, Lng: 77.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shapes, err := ParseGeoJSON([]byte(zonesJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateServiceZones(ctx, zoned, []types.ServiceZone{{Name: "central", Polygon: shapes[0].Polygon}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ix, err := Load(ctx, s)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if id, ok := ix.Route(12.95, 77.65); !ok || id != zoned {
+		t.Errorf("Route = %d, %v; want %d", id, ok, zoned)
+	}
+	if ix.Serves(zoned, 13.0, 77.6) || !ix.Serves(open, 13.0, 77.6) {
+		t.Error("Serves does not follow the stored zones")
+	}
+}
@@ -1,61 +1,82 @@
 package agent
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	// "strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/geo"
+	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
+	"github.com/sharmaprinceji/delivery-management-system/internal/schedular"
 	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
 	"github.com/sharmaprinceji/delivery-management-system/internal/types"
 	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/validation"
 )
 
 //var validate = validator.New()
 
 // CreateWareHouse godoc
 // @Summary Create a new warehouse
-// @Description Accepts warehouse details and stores them in the system
+// @Description Accepts warehouse details and stores them in the system. The calendar is optional: UTC, open 00:00-23:59 and no holidays by default. Allocation for the warehouse is scheduled daily at its local opening time
 // @Tags Warehouse
 // @Accept json
 // @Produce json
 // @Param warehouse body types.WarehouseRequest true "Warehouse Details"
 // @Success 201 {object} map[string]int64
-// @Failure 400 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 500 {object} response.Problem
 // @Router /api/warehouse [post]
-func CreateWareHouse(storage storage.Storage) http.HandlerFunc {
+func CreateWareHouse(storage storage.Storage, sched *schedular.Scheduler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req types.WarehouseRequest // ✅ Correct type for Swagger match
 
 		err := json.NewDecoder(r.Body).Decode(&req)
 		if err != nil {
-			response.WriteJSON(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("failed to decode request body: %v", err)))
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("failed to decode request body: %v", err)))
 			return
 		}
+		req.Location = req.Location.Normalized()
 
 		// Validate the struct 
-		if err := validator.New().Struct(req); err != nil {
+		if err := validation.Struct(req); err != nil {
 			validateErrs := err.(validator.ValidationErrors)
-			response.WriteJSON(w, http.StatusBadRequest, response.ValidationError(validateErrs))
+			response.WriteProblem(w, r, response.ValidationError(validateErrs))
 			return
 		}
 
-		id, err := storage.CreateWarehouse(req.Name, req.Location)
+		if err := checkHours(req.WarehouseCalendar); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeValidationFailed, err))
+			return
+		}
+
+		wh := types.Warehouse{Name: req.Name, Location: req.Location, WarehouseCalendar: req.WarehouseCalendar.WithDefaults()}
+		id, err := storage.CreateWarehouse(r.Context(), wh)
 		if err != nil {
-			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("failed to create warehouse: %v", err)))
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to create warehouse: %w", err), ""))
 			return
 		}
+		wh.ID = id
 
-		slog.Info("warehouse created successfully", slog.Int64("id", id))
+		log := logger.FromContext(r.Context())
+		if err := sched.ScheduleWarehouse(wh); err != nil {
+			log.Error("failed to schedule warehouse allocation", slog.Int64("id", id), slog.String("error", err.Error()))
+		}
+
+		log.Info("warehouse created successfully", slog.Int64("id", id))
 		response.WriteJSON(w, http.StatusCreated, map[string]int64{
 			"warehouse created successfully with Id": id,
 		})
@@ -63,44 +84,381 @@ func CreateWareHouse(storage storage.Storage) http.HandlerFunc {
 }
 
 
+// ListWarehouses godoc
+// @Summary List warehouses
+// @Description Returns every warehouse with its timezone, operating hours and holidays
+// @Tags Warehouse
+// @Produce json
+// @Success 200 {array} types.Warehouse
+// @Failure 500 {object} response.Problem
+// @Router /api/warehouses [get]
+func ListWarehouses(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		warehouses, err := storage.GetWarehouses(r.Context())
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to load warehouses: %w", err), ""))
+			return
+		}
+		if warehouses == nil {
+			warehouses = []types.Warehouse{}
+		}
+
+		response.WriteJSON(w, http.StatusOK, warehouses)
+	}
+}
+
+// GetWarehouseClusters godoc
+// @Summary Show a warehouse's delivery zones
+// @Description Groups the warehouse's pending orders into compact clusters sized to one agent's daily budget, with their centroids, most urgent first. Allocation assigns whole clusters when variables.delivery.cluster_orders is set
+// @Tags Warehouse
+// @Produce json
+// @Param warehouse_id path int true "Warehouse ID"
+// @Success 200 {object} types.WarehouseClusters
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/warehouses/{warehouse_id}/clusters [get]
+func GetWarehouseClusters(storage storage.Storage, limits config.Delivery) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["warehouse_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid warehouse ID")))
+			return
+		}
+
+		warehouses, err := storage.GetWarehouses(r.Context())
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to load warehouses: %w", err), ""))
+			return
+		}
+		i := slices.IndexFunc(warehouses, func(wh types.Warehouse) bool { return wh.ID == id })
+		if i < 0 {
+			response.WriteProblem(w, r, response.NewProblem(http.StatusNotFound, response.CodeWarehouseNotFound, fmt.Sprintf("warehouse %d not found", id)))
+			return
+		}
+
+		orders, err := storage.GetUnassignedOrders(r.Context())
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to load orders: %w", err), ""))
+			return
+		}
+		orders = slices.DeleteFunc(orders, func(o types.Order) bool { return o.WarehouseID != id })
+
+		response.WriteJSON(w, http.StatusOK, types.WarehouseClusters{
+			WarehouseID: id,
+			Location:    warehouses[i].Location,
+			BudgetKm:    jobs.BudgetKm(limits),
+			Clusters:    jobs.Clusters(orders, warehouses[i].Location, limits),
+		})
+	}
+}
+
+// CreateServiceZones godoc
+// @Summary Upload service zones for a warehouse
+// @Description Reads GeoJSON (a Polygon, MultiPolygon, Feature or FeatureCollection) and stores one zone per polygon, named after the feature's "name" property. Coordinates are [lng, lat]. Once a warehouse has zones its orders must lie in one of them
+// @Tags Warehouse
+// @Accept json
+// @Produce json
+// @Param warehouse_id path int true "Warehouse ID"
+// @Param zones body object true "GeoJSON"
+// @Success 201 {array} types.ServiceZone
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/warehouses/{warehouse_id}/zones [post]
+func CreateServiceZones(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["warehouse_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid warehouse ID")))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		shapes, err := geo.ParseGeoJSON(body)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidGeoJSON, err))
+			return
+		}
+
+		zones := make([]types.ServiceZone, 0, len(shapes))
+		for i, sh := range shapes {
+			name := sh.Name
+			if name == "" {
+				name = fmt.Sprintf("zone %d", i+1)
+			}
+			zones = append(zones, types.ServiceZone{WarehouseID: id, Name: name, Polygon: sh.Polygon})
+		}
+
+		ids, err := storage.CreateServiceZones(r.Context(), id, zones)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeWarehouseNotFound))
+			return
+		}
+		logger.FromContext(r.Context()).Info("service zones created", slog.Int64("warehouse_id", id), slog.Any("zone_ids", ids))
+
+		// read them back for the stored creation times
+		stored, err := storage.GetServiceZones(r.Context(), id)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to load service zones: %w", err), ""))
+			return
+		}
+		created := slices.DeleteFunc(stored, func(z types.ServiceZone) bool { return !slices.Contains(ids, z.ID) })
+
+		response.WriteJSON(w, http.StatusCreated, created)
+	}
+}
+
+// ListServiceZones godoc
+// @Summary List a warehouse's service zones
+// @Tags Warehouse
+// @Produce json
+// @Param warehouse_id path int true "Warehouse ID"
+// @Success 200 {array} types.ServiceZone
+// @Failure 400 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/warehouses/{warehouse_id}/zones [get]
+func ListServiceZones(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["warehouse_id"], 10, 64)
+		if err != nil || id <= 0 {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid warehouse ID")))
+			return
+		}
+
+		zones, err := storage.GetServiceZones(r.Context(), id)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to load service zones: %w", err), ""))
+			return
+		}
+		if zones == nil {
+			zones = []types.ServiceZone{}
+		}
+
+		response.WriteJSON(w, http.StatusOK, zones)
+	}
+}
+
+// DeleteServiceZone godoc
+// @Summary Delete a service zone
+// @Tags Warehouse
+// @Param zone_id path int true "Zone ID"
+// @Success 204
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/zones/{zone_id} [delete]
+func DeleteServiceZone(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["zone_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid zone ID")))
+			return
+		}
+
+		if err := storage.DeleteServiceZone(r.Context(), id); err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeZoneNotFound))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("service zone deleted", slog.Int64("zone_id", id))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CheckServiceZones godoc
+// @Summary Test a point against every service zone
+// @Description Lists the zones containing the point and the warehouse an order there without warehouse_id would go to
+// @Tags Warehouse
+// @Produce json
+// @Param lat query number true "Latitude"
+// @Param lng query number true "Longitude"
+// @Success 200 {object} types.ZoneCheck
+// @Failure 400 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/zones/check [get]
+func CheckServiceZones(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lat, errLat := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+		lng, errLng := strconv.ParseFloat(r.URL.Query().Get("lng"), 64)
+		if errLat != nil || errLng != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("lat and lng must be numbers")))
+			return
+		}
+		point := types.Location{Lat: lat, Lng: lng}.Normalized()
+		if err := validation.Struct(point); err != nil {
+			response.WriteProblem(w, r, response.ValidationError(err.(validator.ValidationErrors)))
+			return
+		}
+		lat, lng = point.Lat, point.Lng
+
+		ix, err := geo.Load(r.Context(), storage)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+
+		check := types.ZoneCheck{Lat: lat, Lng: lng, Zones: ix.Matching(lat, lng)}
+		check.WarehouseID, check.Served = ix.Route(lat, lng)
+		for i := range check.Zones {
+			check.Zones[i].Polygon = nil
+		}
+
+		response.WriteJSON(w, http.StatusOK, check)
+	}
+}
+
+// SetWarehouseCalendar godoc
+// @Summary Set a warehouse's timezone, hours and holidays
+// @Description Replaces the calendar; empty fields fall back to the defaults. The warehouse's allocation job is rescheduled at the new opening time
+// @Tags Warehouse
+// @Accept json
+// @Produce json
+// @Param warehouse_id path int true "Warehouse ID"
+// @Param calendar body types.WarehouseCalendar true "Timezone, hours and holidays"
+// @Success 200 {object} types.WarehouseCalendar
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/warehouse/{warehouse_id}/calendar [put]
+func SetWarehouseCalendar(storage storage.Storage, sched *schedular.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["warehouse_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid warehouse ID")))
+			return
+		}
+
+		var req types.WarehouseCalendar
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+
+		if err := validation.Struct(req); err != nil {
+			validateErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validateErrs))
+			return
+		}
+		if err := checkHours(req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeValidationFailed, err))
+			return
+		}
+
+		cal := req.WithDefaults()
+		if err := storage.SetWarehouseCalendar(r.Context(), id, cal); err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeWarehouseNotFound))
+			return
+		}
+
+		log := logger.FromContext(r.Context())
+		if err := sched.ScheduleWarehouse(types.Warehouse{ID: id, WarehouseCalendar: cal}); err != nil {
+			log.Error("failed to schedule warehouse allocation", slog.Int64("id", id), slog.String("error", err.Error()))
+		}
+
+		log.Info("warehouse calendar updated", slog.Int64("id", id), slog.String("timezone", cal.Timezone))
+		response.WriteJSON(w, http.StatusOK, cal)
+	}
+}
+
+// checkHours rejects a calendar that closes before it opens.
+func checkHours(cal types.WarehouseCalendar) error {
+	cal = cal.WithDefaults()
+	if cal.OpensAt >= cal.ClosesAt {
+		return fmt.Errorf("opens_at %s must be before closes_at %s", cal.OpensAt, cal.ClosesAt)
+	}
+	return nil
+}
+
+
 // CheckedInAgents godoc
 // @Summary Check-in an agent
-// @Description Allows an agent to check in to a warehouse
+// @Description Allows an agent to check in to a warehouse. The vehicle's weight, volume and parcel limits are optional; a limit left out or 0 is not enforced
 // @Tags Agent
 // @Accept json
 // @Produce json
 // @Param agent body types.AgentCheckInRequest true "Agent Check-In Info"
 // @Success 201 {object} map[string]int64
-// @Failure 400 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 500 {object} response.Problem
 // @Router /api/agent/checkin [post]
 func CheckedInAgents(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req types.AgentCheckInRequest
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			response.WriteJSON(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid request: %v", err)))
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
 			return
 		}
 
-		if err := validator.New().Struct(req); err != nil {
+		if err := validation.Struct(req); err != nil {
 			validateErrs := err.(validator.ValidationErrors)
-			response.WriteJSON(w, http.StatusBadRequest, response.ValidationError(validateErrs))
+			response.WriteProblem(w, r, response.ValidationError(validateErrs))
 			return
 		}
 
-		id, err := storage.CheckInAgents(req.Name, req.WarehouseID)
+		id, err := storage.CheckInAgents(r.Context(), types.Agent{Name: req.Name, WarehouseID: req.WarehouseID, Capacity: req.Capacity})
 		if err != nil {
-			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("check-in failed: %v", err)))
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("check-in failed: %w", err), ""))
 			return
 		}
 
-		slog.Info("Agent checked in successfully :", slog.Int64("id", id))
+		logger.FromContext(r.Context()).Info("agent checked in successfully", slog.Int64("id", id))
 		response.WriteJSON(w, http.StatusCreated, map[string]int64{"Agent checked successfully with Id": id})
 	}
 }
 
 
+// ReleaseAgentOrders godoc
+// @Summary Release every open order of an agent
+// @Description Takes back all orders the agent has not delivered, keeping the closed assignments with the reason, so the next allocation run can hand them to others. With check_out the agent is also checked out. The body is optional
+// @Tags Agent
+// @Accept json
+// @Produce json
+// @Param agent_id path int true "Agent ID"
+// @Param request body types.ReleaseRequest false "Reason and check-out"
+// @Success 200 {object} types.ReleaseResult
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/agent/{agent_id}/release [post]
+func ReleaseAgentOrders(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		agentID, err := strconv.ParseInt(mux.Vars(r)["agent_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid agent ID")))
+			return
+		}
+
+		var req types.ReleaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		if err := validation.Struct(req); err != nil {
+			validateErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validateErrs))
+			return
+		}
+
+		released, err := storage.ReleaseAgentOrders(r.Context(), agentID, req.Reason, req.CheckOut)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeAgentNotFound))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("agent orders released",
+			slog.Int64("agent_id", agentID), slog.Int("orders", len(released)),
+			slog.Bool("checked_out", req.CheckOut), slog.String("reason", req.Reason))
+		response.WriteJSON(w, http.StatusOK, types.ReleaseResult{AgentID: agentID, Released: released, CheckedOut: req.CheckOut})
+	}
+}
+
+
 // In handler/agent.go
 // GetAgentDetails godoc
 // @Summary Get Agent Details
@@ -109,9 +467,9 @@ func CheckedInAgents(storage storage.Storage) http.HandlerFunc {
 // @Produce json
 // @Param agent_id path int true "Agent ID"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} response.Response
-// @Failure 404 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
 // @Router /api/agent/{agent_id} [get]
 func GetAgentDetails(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -119,17 +477,13 @@ func GetAgentDetails(storage storage.Storage) http.HandlerFunc {
 		agentIDStr := vars["agent_id"]
 		agentID, err := strconv.ParseInt(agentIDStr, 10, 64)
 		if err != nil {
-			response.WriteJSON(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid agent ID")))
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid agent ID")))
 			return
 		}
 
-		agentData, err := storage.GetAgentDetails(agentID)
+		agentData, err := storage.GetAgentDetails(r.Context(), agentID)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				response.WriteJSON(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("agent not found")))
-				return
-			}
-			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(err))
+			response.WriteProblem(w, r, response.FromError(err, response.CodeAgentNotFound))
 			return
 		}
 
@@ -140,14 +494,14 @@ func GetAgentDetails(storage storage.Storage) http.HandlerFunc {
 
 // GetAssignments godoc
 // @Summary Get paginated assignments
-// @Description Returns paginated list of assignments with formatted date
+// @Description Returns paginated list of assignments with times in RFC 3339, in the timezone of the order's warehouse
 // @Tags Assignments
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number"
 // @Param limit query int false "Items per page"
 // @Success 200 {object} map[string]interface{} "List of assignments"
-// @Failure 500 {object} response.Response
+// @Failure 500 {object} response.Problem
 // @Router /api/assignments [get]
 func GetAssignments(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -168,20 +522,52 @@ func GetAssignments(storage storage.Storage) http.HandlerFunc {
 
 		offset := (page - 1) * limit
 
-		assignments, total, err := storage.GetPaginatedAssignments(limit, offset)
+		assignments, total, err := storage.GetPaginatedAssignments(r.Context(), limit, offset)
 		if err != nil {
-			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("failed to fetch assignments: %v", err)))
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to fetch assignments: %w", err), ""))
 			return
 		}
 
+		warehouses, err := storage.GetWarehouses(r.Context())
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to fetch warehouses: %w", err), ""))
+			return
+		}
+		zones := make(map[int64]*time.Location, len(warehouses))
+		for _, wh := range warehouses {
+			zones[wh.ID] = wh.Zone()
+		}
+
 		var formatted []types.AssignmentResponse
 		for _, a := range assignments {
-			formatted = append(formatted, types.AssignmentResponse{
-				ID:         a.ID,
-				AgentID:    a.AgentID,
-				OrderID:    a.OrderID,
-				AssignedAt: a.AssignedAt.Format("02/01/2006 03:04 PM"),
-			})
+			zone, ok := zones[a.WarehouseID]
+			if !ok {
+				zone = time.UTC
+			}
+			item := types.AssignmentResponse{
+				ID:             a.ID,
+				AgentID:        a.AgentID,
+				OrderID:        a.OrderID,
+				WarehouseID:    a.WarehouseID,
+				Timezone:       zone.String(),
+				AssignedAt:     a.AssignedAt.In(zone).Format(time.RFC3339),
+				PlannedKm:      a.PlannedKm,
+				PlannedMinutes: a.PlannedMinutes,
+				ActualKm:       a.ActualKm,
+				ActualMinutes:  a.ActualMinutes,
+				RouteSeq:       a.RouteSeq,
+			}
+			if a.ETA != nil {
+				item.ETA = a.ETA.In(zone).Format(time.RFC3339)
+			}
+			if a.DeliveredAt != nil {
+				item.DeliveredAt = a.DeliveredAt.In(zone).Format(time.RFC3339)
+			}
+			if a.UnassignedAt != nil {
+				item.UnassignedAt = a.UnassignedAt.In(zone).Format(time.RFC3339)
+				item.UnassignReason = a.UnassignReason
+			}
+			formatted = append(formatted, item)
 		}
 
 		totalPages := int(math.Ceil(float64(total) / float64(limit)))
@@ -0,0 +1,120 @@
+package agent_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/agent"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+)
+
+// server routes the agent endpoints to an in-memory store.
+func server() (http.Handler, storage.Storage) {
+	store := memory.New(&config.Config{})
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/agent/checkin", agent.CheckedInAgents(store)).Methods("POST")
+	r.HandleFunc("/api/agent/{agent_id}", agent.GetAgentDetails(store)).Methods("GET")
+	r.HandleFunc("/api/assignments", agent.GetAssignments(store)).Methods("GET")
+	return r, store
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func wantProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	var p response.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if rec.Code != status || p.Code != code {
+		t.Errorf("got %d %s, want %d %s", rec.Code, p.Code, status, code)
+	}
+}
+
+func TestCheckIn(t *testing.T) {
+	h, store := server()
+	ctx := context.Background()
+	wh, err := store.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(t, h, "POST", "/api/agent/checkin", `{"name": "Ravi Sharma", "warehouse_id": 1, "vehicle": "bike", "max_parcels": 10}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	agents, err := store.GetCheckedInAgents(ctx)
+	if err != nil || len(agents) != 1 || agents[0].WarehouseID != wh || agents[0].Vehicle != "bike" || agents[0].MaxParcels != 10 {
+		t.Errorf("checked in agents = %+v, %v", agents, err)
+	}
+
+	wantProblem(t, do(t, h, "POST", "/api/agent/checkin", `{"name": "Asha"}`), http.StatusBadRequest, response.CodeValidationFailed)
+	wantProblem(t, do(t, h, "POST", "/api/agent/checkin", `{"name": "Asha", "warehouse_id": 1, "max_parcels": -1}`),
+		http.StatusBadRequest, response.CodeValidationFailed)
+	wantProblem(t, do(t, h, "POST", "/api/agent/checkin", `not json`), http.StatusBadRequest, response.CodeInvalidRequest)
+
+	wantProblem(t, do(t, h, "GET", "/api/agent/x", ""), http.StatusBadRequest, response.CodeInvalidID)
+	wantProblem(t, do(t, h, "GET", "/api/agent/99", ""), http.StatusNotFound, response.CodeAgentNotFound)
+}
+
+func TestAssignmentsInWarehouseTime(t *testing.T) {
+	h, store := server()
+	ctx := context.Background()
+	wh, err := store.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59},
+		WarehouseCalendar: types.WarehouseCalendar{Timezone: "Asia/Kolkata"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ravi, err := store.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		id, err := store.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AssignOrderToAgent(ctx, id, ravi, 1.5, 7.5, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := do(t, h, "GET", "/api/assignments?page=2&limit=2", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var got struct {
+		CurrentPage int                        `json:"current_page"`
+		TotalPages  int                        `json:"total_pages"`
+		TotalItems  int                        `json:"total_items"`
+		Data        []types.AssignmentResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentPage != 2 || got.TotalPages != 2 || got.TotalItems != 3 || len(got.Data) != 1 {
+		t.Fatalf("page = %+v, want the last of 3 assignments on page 2 of 2", got)
+	}
+	a := got.Data[0]
+	if a.Timezone != "Asia/Kolkata" || !strings.HasSuffix(a.AssignedAt, "+05:30") || !strings.HasSuffix(a.ETA, "+05:30") {
+		t.Errorf("assignment = %+v, want times in Asia/Kolkata", a)
+	}
+}
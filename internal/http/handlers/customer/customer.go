@@ -0,0 +1,287 @@
+package customer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/geo"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/validation"
+)
+
+// CreateCustomer godoc
+// @Summary Create a customer
+// @Description Saves a customer with their contact details and delivery addresses. Addresses without lat and lng are geocoded from the local gazetteer; one that cannot be is refused. The phone number identifies the customer and may be saved only once
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param customer body types.CustomerRequest true "Customer details"
+// @Success 201 {object} types.Customer
+// @Failure 400 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/customers [post]
+func CreateCustomer(storage storage.Storage, geocoder geo.Geocoder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.CustomerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		req = req.Normalized()
+
+		if err := validation.Struct(req); err != nil {
+			validationErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+
+		c := types.Customer{Name: req.Name, Phone: req.Phone, Email: req.Email, NotificationsOptOut: req.NotificationsOptOut}
+		var unknown []response.FieldError
+		for i, a := range req.Addresses {
+			saved, fe, err := locate(r.Context(), geocoder, a, fmt.Sprintf("addresses[%d].", i))
+			if err != nil {
+				response.WriteProblem(w, r, response.FromError(err, ""))
+				return
+			}
+			if fe != nil {
+				unknown = append(unknown, *fe)
+				continue
+			}
+			c.Addresses = append(c.Addresses, saved)
+		}
+		if len(unknown) > 0 {
+			response.WriteProblem(w, r, response.FieldErrors(unknown...))
+			return
+		}
+
+		id, err := storage.CreateCustomer(r.Context(), c)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to create customer: %w", err), ""))
+			return
+		}
+		created, err := storage.GetCustomer(r.Context(), id)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("customer created", slog.Int64("customer_id", id), slog.Int("addresses", len(c.Addresses)))
+		w.Header().Set("Location", fmt.Sprintf("/api/customers/%d", id))
+		response.WriteJSON(w, http.StatusCreated, created)
+	}
+}
+
+// GetCustomer godoc
+// @Summary Get a customer
+// @Description Returns a customer with their saved addresses, oldest first
+// @Tags Customers
+// @Produce json
+// @Param customer_id path int true "Customer ID"
+// @Success 200 {object} types.Customer
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/customers/{customer_id} [get]
+func GetCustomer(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := strconv.ParseInt(mux.Vars(r)["customer_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid customer ID")))
+			return
+		}
+
+		c, err := storage.GetCustomer(r.Context(), customerID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeCustomerNotFound))
+			return
+		}
+		response.WriteJSON(w, http.StatusOK, c)
+	}
+}
+
+// AddCustomerAddress godoc
+// @Summary Save another address for a customer
+// @Description Without lat and lng the address is geocoded from the local gazetteer; one that cannot be is refused
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param customer_id path int true "Customer ID"
+// @Param address body types.CustomerAddressRequest true "Address"
+// @Success 201 {object} types.CustomerAddress
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/customers/{customer_id}/addresses [post]
+func AddCustomerAddress(storage storage.Storage, geocoder geo.Geocoder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := strconv.ParseInt(mux.Vars(r)["customer_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid customer ID")))
+			return
+		}
+
+		var req types.CustomerAddressRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		req = req.Normalized()
+
+		if err := validation.Struct(req); err != nil {
+			validationErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+
+		a, fe, err := locate(r.Context(), geocoder, req, "")
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+		if fe != nil {
+			response.WriteProblem(w, r, response.FieldErrors(*fe))
+			return
+		}
+
+		id, err := storage.AddCustomerAddress(r.Context(), customerID, a)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeCustomerNotFound))
+			return
+		}
+		saved, err := storage.GetCustomerAddress(r.Context(), id)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("customer address saved", slog.Int64("customer_id", customerID), slog.Int64("address_id", id))
+		response.WriteJSON(w, http.StatusCreated, saved)
+	}
+}
+
+// GetCustomerOrders godoc
+// @Summary List a customer's orders
+// @Description Returns a page of the orders placed for a customer, newest first, each with its status (pending, assigned or delivered). Orders given only a free-text customer name are not linked to any customer
+// @Tags Customers
+// @Produce json
+// @Param customer_id path int true "Customer ID"
+// @Param page query int false "Page number (default is 1)"
+// @Param limit query int false "Items per page (default is 10)"
+// @Success 200 {object} map[string]interface{} "Orders of the customer"
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/customers/{customer_id}/orders [get]
+func GetCustomerOrders(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := strconv.ParseInt(mux.Vars(r)["customer_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid customer ID")))
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 {
+			limit = 10
+		}
+
+		orders, total, err := storage.GetCustomerOrders(r.Context(), customerID, limit, (page-1)*limit)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeCustomerNotFound))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, map[string]any{
+			"current_page": page,
+			"total_pages":  int(math.Ceil(float64(total) / float64(limit))),
+			"total_items":  total,
+			"data":         orders,
+		})
+	}
+}
+
+// SetNotifications godoc
+// @Summary Turn a customer's notifications off or on
+// @Description With opt_out true the customer gets no more emails or text messages about their orders; messages already waiting to be sent still go out
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param customer_id path int true "Customer ID"
+// @Param preferences body types.NotificationPreferences true "Preferences"
+// @Success 200 {object} map[string]any
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/customers/{customer_id}/notifications [put]
+func SetNotifications(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := strconv.ParseInt(mux.Vars(r)["customer_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid customer ID")))
+			return
+		}
+
+		var req types.NotificationPreferences
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		if err := validation.Struct(req); err != nil {
+			validationErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+
+		if err := storage.SetNotificationsOptOut(r.Context(), customerID, *req.OptOut); err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeCustomerNotFound))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("customer notifications updated",
+			slog.Int64("customer_id", customerID), slog.Bool("opt_out", *req.OptOut))
+		response.WriteJSON(w, http.StatusOK, map[string]any{
+			"Notifications updated for customer with id": customerID,
+			"notifications_opt_out":                      *req.OptOut,
+		})
+	}
+}
+
+// locate turns an address request into a saved address, geocoding it when it has no
+// coordinates. An address the geocoder does not know is reported as a field error, under
+// prefix, e.g. "addresses[1].".
+func locate(ctx context.Context, geocoder geo.Geocoder, req types.CustomerAddressRequest, prefix string) (types.CustomerAddress, *response.FieldError, error) {
+	a := types.CustomerAddress{Label: req.Label, Address: req.Address, Location: types.Location{Lat: req.Lat, Lng: req.Lng}}
+	if req.Lat != 0 || req.Lng != 0 {
+		return a, nil, nil
+	}
+
+	at, err := geocoder.Geocode(ctx, req.Address)
+	if errors.Is(err, geo.ErrUnresolved) {
+		return a, &response.FieldError{
+			Field:   prefix + "lat",
+			Tag:     "geocode",
+			Message: fmt.Sprintf("%saddress not found, give lat and lng", prefix),
+		}, nil
+	}
+	if err != nil {
+		return a, nil, err
+	}
+	a.Location = at
+	return a, nil, nil
+}
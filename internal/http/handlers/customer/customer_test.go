@@ -0,0 +1,63 @@
+package customer_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/customer"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// The OTP is the customer's to hand over at the door; the unauthenticated orders endpoint
+// must not give it to anyone who knows the customer id.
+func TestCustomerOrdersHideOTP(t *testing.T) {
+	store := memory.New(&config.Config{})
+	ctx := context.Background()
+	wh, err := store.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent, err := store.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := store.CreateCustomer(ctx, types.Customer{Name: "Meera", Phone: "+919876543210"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.CreateOrder(ctx, types.Order{Customer: "Meera", Lat: 12.98, Lng: 77.60, WarehouseID: wh, CustomerID: cust})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AssignOrderToAgent(ctx, id, agent, 1.5, 7.5, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/customers/{customer_id}/orders", customer.GetCustomerOrders(store)).Methods("GET")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/customers/1/orders", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var got struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Data) != 1 || got.Data[0]["status"] != types.OrderAssigned {
+		t.Fatalf("orders = %v, want the assigned order", got.Data)
+	}
+	if otp, ok := got.Data[0]["otp"]; ok {
+		t.Errorf("order has otp %v, want none", otp)
+	}
+}
@@ -0,0 +1,108 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/buildinfo"
+	"github.com/sharmaprinceji/delivery-management-system/internal/schedular"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+)
+
+var draining atomic.Bool
+
+// SetDraining makes readiness fail so the load balancer stops routing traffic before shutdown.
+func SetDraining() {
+	draining.Store(true)
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Returns 200 while the process is running
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func Liveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJSON(w, http.StatusOK, map[string]string{"status": response.StatusOk})
+	}
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Checks the database connection, the job scheduler and the schema version
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /readyz [get]
+func Readiness(storage storage.Storage, sched *schedular.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checks := map[string]string{
+			"database":  "ok",
+			"scheduler": "ok",
+			"schema":    "ok",
+		}
+		ready := true
+
+		if draining.Load() {
+			checks["shutdown"] = "draining"
+			ready = false
+		}
+
+		if err := storage.Ping(r.Context()); err != nil {
+			checks["database"] = err.Error()
+			ready = false
+		}
+
+		if !sched.Running() {
+			checks["scheduler"] = "not running"
+			ready = false
+		}
+
+		current, expected, err := storage.SchemaVersion(r.Context())
+		switch {
+		case err != nil:
+			checks["schema"] = err.Error()
+			ready = false
+		case current != expected:
+			checks["schema"] = fmt.Sprintf("at version %d, expected %d", current, expected)
+			ready = false
+		}
+
+		status := http.StatusOK
+		checks["status"] = response.StatusOk
+		if !ready {
+			status = http.StatusServiceUnavailable
+			checks["status"] = response.StatusError
+		}
+		response.WriteJSON(w, status, checks)
+	}
+}
+
+// Version godoc
+// @Summary Build information
+// @Description Returns the git commit, build time and schema version of the running binary
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /version [get]
+func Version(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema := buildinfo.SchemaVersion
+		if schema == "" {
+			_, expected, _ := storage.SchemaVersion(r.Context())
+			schema = strconv.Itoa(expected)
+		}
+
+		response.WriteJSON(w, http.StatusOK, map[string]string{
+			"commit":         buildinfo.Commit,
+			"build_time":     buildinfo.BuildTime,
+			"schema_version": schema,
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/buildinfo"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/health"
+	"github.com/sharmaprinceji/delivery-management-system/internal/schedular"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+)
+
+// store is an in-memory store whose database and schema checks report what it is told.
+type store struct {
+	storage.Storage
+	pingErr         error
+	current, latest int
+	schemaErr       error
+}
+
+func (s *store) Ping(ctx context.Context) error { return s.pingErr }
+
+func (s *store) SchemaVersion(ctx context.Context) (int, int, error) {
+	return s.current, s.latest, s.schemaErr
+}
+
+func newStore() *store {
+	return &store{Storage: memory.New(&config.Config{}), current: 5, latest: 5}
+}
+
+// started returns a running scheduler, stopped with the test.
+func started(t *testing.T, s storage.Storage) *schedular.Scheduler {
+	t.Helper()
+	sch := schedular.New(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	sch.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		sch.Wait()
+	})
+	return sch
+}
+
+func get(t *testing.T, h http.HandlerFunc, path string) (int, map[string]string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode %s: %v", path, err)
+	}
+	return rec.Code, body
+}
+
+func TestLiveness(t *testing.T) {
+	code, body := get(t, health.Liveness(), "/healthz")
+	if code != http.StatusOK || body["status"] != "OK" {
+		t.Errorf("GET /healthz = %d %v", code, body)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	ready := map[string]string{"database": "ok", "scheduler": "ok", "schema": "ok", "status": "OK"}
+	with := func(changes map[string]string) map[string]string {
+		m := map[string]string{}
+		for k, v := range ready {
+			m[k] = v
+		}
+		for k, v := range changes {
+			m[k] = v
+		}
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		store   func(*store)
+		stopped bool
+		want    map[string]string
+	}{
+		{"ready", func(*store) {}, false, ready},
+		{"database down", func(s *store) { s.pingErr = errors.New("connection refused") }, false,
+			with(map[string]string{"database": "connection refused", "status": "Error"})},
+		{"scheduler stopped", func(*store) {}, true,
+			with(map[string]string{"scheduler": "not running", "status": "Error"})},
+		{"schema behind", func(s *store) { s.current = 3 }, false,
+			with(map[string]string{"schema": "at version 3, expected 5", "status": "Error"})},
+		{"schema unreadable", func(s *store) { s.schemaErr = errors.New("no such table") }, false,
+			with(map[string]string{"schema": "no such table", "status": "Error"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore()
+			tt.store(s)
+			sch := schedular.New(s)
+			if !tt.stopped {
+				sch = started(t, s)
+			}
+
+			code, body := get(t, health.Readiness(s, sch), "/readyz")
+			want := http.StatusOK
+			if tt.want["status"] != "OK" {
+				want = http.StatusServiceUnavailable
+			}
+			if code != want || !reflect.DeepEqual(body, tt.want) {
+				t.Errorf("GET /readyz = %d %v, want %d %v", code, body, want, tt.want)
+			}
+		})
+	}
+
+	// draining cannot be undone, so it goes last
+	s := newStore()
+	health.SetDraining()
+	code, body := get(t, health.Readiness(s, started(t, s)), "/readyz")
+	if want := with(map[string]string{"shutdown": "draining", "status": "Error"}); code != http.StatusServiceUnavailable || !reflect.DeepEqual(body, want) {
+		t.Errorf("GET /readyz while draining = %d %v, want 503 %v", code, body, want)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	s := newStore()
+	code, body := get(t, health.Version(s), "/version")
+	want := map[string]string{"commit": buildinfo.Commit, "build_time": buildinfo.BuildTime, "schema_version": "5"}
+	if code != http.StatusOK || !reflect.DeepEqual(body, want) {
+		t.Errorf("GET /version = %d %v, want %v", code, body, want)
+	}
+
+	// a version stamped at build time wins
+	buildinfo.SchemaVersion = "7"
+	t.Cleanup(func() { buildinfo.SchemaVersion = "" })
+	if _, body := get(t, health.Version(s), "/version"); body["schema_version"] != "7" {
+		t.Errorf("schema_version = %q, want the stamped 7", body["schema_version"])
+	}
+}
@@ -0,0 +1,65 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
+	"github.com/sharmaprinceji/delivery-management-system/internal/schedular"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+)
+
+// ListJobs godoc
+// @Summary List scheduled jobs
+// @Description Returns every scheduled job with its schedule, timezone and last/next run
+// @Tags Jobs
+// @Produce json
+// @Success 200 {array} types.JobState
+// @Failure 500 {object} response.Problem
+// @Router /api/jobs [get]
+func ListJobs(sched *schedular.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		states, err := sched.States(r.Context())
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to load jobs: %w", err), ""))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, states)
+	}
+}
+
+// RunJob godoc
+// @Summary Run a job now
+// @Description Starts the named job in the background; its outcome appears in GET /api/jobs
+// @Tags Jobs
+// @Produce json
+// @Param name path string true "Job name"
+// @Success 202 {object} map[string]string
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Router /api/jobs/{name}/run [post]
+func RunJob(sched *schedular.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+
+		err := sched.Trigger(name)
+		switch {
+		case errors.Is(err, schedular.ErrUnknownJob):
+			response.WriteProblem(w, r, response.NewProblem(http.StatusNotFound, response.CodeJobNotFound, err.Error()))
+			return
+		case errors.Is(err, schedular.ErrJobRunning):
+			response.WriteProblem(w, r, response.NewProblem(http.StatusConflict, response.CodeJobRunning, err.Error()))
+			return
+		case err != nil:
+			response.WriteProblem(w, r, response.Internal(err))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("job triggered", slog.String("job", name))
+		response.WriteJSON(w, http.StatusAccepted, map[string]string{"job": name, "status": "started"})
+	}
+}
@@ -0,0 +1,169 @@
+package order
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/validation"
+)
+
+// FailDelivery godoc
+// @Summary Record a failed delivery attempt
+// @Description Closes the order's open assignment and records why the delivery failed. The order goes back to the next allocation run with its priority raised by attempts.priority_bump, until it has failed attempts.max_attempts times; then its agent returns it to the warehouse (outcome returning). A note is required when the reason is other
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Param attempt body types.FailedAttemptRequest true "Reason for the failure"
+// @Success 200 {object} map[string]any
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/{order_id}/fail [post]
+func FailDelivery(storage storage.Storage, attempts config.Attempts) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := strconv.ParseInt(mux.Vars(r)["order_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid order ID")))
+			return
+		}
+
+		var req types.FailedAttemptRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+
+		if err := validation.Struct(req); err != nil {
+			validationErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+
+		attempt := types.DeliveryAttempt{Reason: req.Reason, Note: req.Note}
+		o, err := storage.FailDelivery(r.Context(), orderID, attempt, attempts.MaxAttempts, attempts.PriorityBump)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeNoOpenAssignment))
+			return
+		}
+
+		outcome := "requeued"
+		if o.ReturnStartedAt != nil {
+			outcome = "returning"
+		}
+		logger.FromContext(r.Context()).Info("delivery failed",
+			slog.Int64("order_id", orderID),
+			slog.String("reason", req.Reason),
+			slog.Int("failed_attempts", o.FailedAttempts),
+			slog.String("outcome", outcome),
+		)
+		response.WriteJSON(w, http.StatusOK, map[string]any{
+			"Delivery attempt failed for order with id": orderID,
+			"failed_attempts": o.FailedAttempts,
+			"attempts_left":   max(attempts.MaxAttempts-o.FailedAttempts, 0),
+			"outcome":         outcome,
+			"priority":        o.Priority,
+		})
+	}
+}
+
+// GetDeliveryAttempts godoc
+// @Summary List an order's failed delivery attempts
+// @Description Lists the failed attempts recorded for the order, oldest first
+// @Tags Orders
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Success 200 {array} types.DeliveryAttempt
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/{order_id}/attempts [get]
+func GetDeliveryAttempts(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := strconv.ParseInt(mux.Vars(r)["order_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid order ID")))
+			return
+		}
+
+		attempts, err := storage.GetDeliveryAttempts(r.Context(), orderID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeOrderNotFound))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, attempts)
+	}
+}
+
+// GetReturningOrders godoc
+// @Summary List orders on their way back to the warehouse
+// @Description Lists orders whose delivery attempts are exhausted and that have not been received back yet, longest on the way first. Without warehouse_id every warehouse is listed
+// @Tags Orders
+// @Produce json
+// @Param warehouse_id query int false "Warehouse ID"
+// @Success 200 {array} types.Order
+// @Failure 400 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/returns [get]
+func GetReturningOrders(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var warehouseID int64
+		if s := r.URL.Query().Get("warehouse_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || id < 1 {
+				response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid warehouse ID")))
+				return
+			}
+			warehouseID = id
+		}
+
+		orders, err := storage.GetReturningOrders(r.Context(), warehouseID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, orders)
+	}
+}
+
+// CompleteReturn godoc
+// @Summary Receive a returned order at the warehouse
+// @Description Marks an order that is being returned as back at its warehouse. Orders not being returned are refused with 409
+// @Tags Orders
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/{order_id}/return [post]
+func CompleteReturn(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := strconv.ParseInt(mux.Vars(r)["order_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid order ID")))
+			return
+		}
+
+		if err := storage.CompleteReturn(r.Context(), orderID); err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeOrderNotFound))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("order returned to warehouse", slog.Int64("order_id", orderID))
+		response.WriteJSON(w, http.StatusOK, map[string]int64{"Order returned to warehouse with id": orderID})
+	}
+}
@@ -0,0 +1,40 @@
+package order
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+)
+
+// GetOrderNotifications godoc
+// @Summary List the notifications about an order
+// @Description Lists the emails and text messages written to the order's customer, oldest first, with whether each was sent, is waiting for another attempt, failed or was skipped for want of an adapter
+// @Tags Orders
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Success 200 {array} types.Notification
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/{order_id}/notifications [get]
+func GetOrderNotifications(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := strconv.ParseInt(mux.Vars(r)["order_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid order ID")))
+			return
+		}
+
+		notifications, err := storage.GetOrderNotifications(r.Context(), orderID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeOrderNotFound))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, notifications)
+	}
+}
@@ -1,144 +1,682 @@
 package order
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"strconv"
+	"time"
 
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/geo"
 	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
 	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
 	"github.com/sharmaprinceji/delivery-management-system/internal/types"
 	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/validation"
 )
 
 // CreateOrder godoc
 // @Summary Create a new order
-// @Description Creates a new customer order and stores it in the database
+// @Description Creates a new customer order and stores it in the database. An address may be given instead of lat and lng: it is geocoded from the local gazetteer, and when it cannot be the order is queued for review (202) rather than rejected. Without warehouse_id the order goes to the warehouse whose service zone contains it. An order outside every zone of its warehouse is rejected, or taken and flagged out_of_zone when zones.out_of_zone is flag. With allocation streaming enabled the order is inserted into an agent's current route shortly after.
 // @Tags Orders
 // @Accept json
 // @Produce json
 // @Param order body types.OrderRequest true "Order details"
-// @Success 201 {object} map[string]int64
-// @Failure 400 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Success 201 {object} map[string]any
+// @Success 202 {object} map[string]int64
+// @Failure 400 {object} response.Problem
+// @Failure 500 {object} response.Problem
 // @Router /api/order [post]
-func CreateOrder(storage storage.Storage) http.HandlerFunc {
+func CreateOrder(storage storage.Storage, stream *jobs.Streamer, zones config.Zones, geocoder geo.Geocoder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req types.OrderRequest 
+		var req types.OrderRequest
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			response.WriteJSON(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid request: %v", err)))
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
 			return
 		}
+		req = req.Normalized()
 
-	
-		if err := validator.New().Struct(req); err != nil {
+		if err := validation.Struct(req); err != nil {
 			validationErrs := err.(validator.ValidationErrors)
-			response.WriteJSON(w, http.StatusBadRequest, response.ValidationError(validationErrs))
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+		if err := checkWindow(req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeValidationFailed, err))
+			return
+		}
+		req, p, ok := forCustomer(r.Context(), storage, req, "")
+		if !ok {
+			response.WriteProblem(w, r, p)
+			return
+		}
+
+		req, err := geocode(r.Context(), geocoder, req)
+		if errors.Is(err, geo.ErrUnresolved) {
+			ids, err := storage.CreateAddressReviews(r.Context(), []types.AddressReview{{Request: req, Reason: err.Error()}})
+			if err != nil {
+				response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to queue order for review: %w", err), ""))
+				return
+			}
+			logger.FromContext(r.Context()).Info("order queued for address review", slog.Int64("review_id", ids[0]))
+			response.WriteJSON(w, http.StatusAccepted, map[string]int64{"Order queued for address review with id": ids[0]})
+			return
+		}
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
 			return
 		}
 
-		order := types.Order{
-			Customer:    req.Customer,
-			Lat:         req.Lat,
-			Lng:         req.Lng,
-			WarehouseID: req.WarehouseID,
-			Assigned:    false,
+		ix, err := geo.Load(r.Context(), storage)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+		warehouseID, outOfZone, err := place(ix, zones, req)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeOutOfZone, err))
+			return
+		}
+		if fe, ok := checkDistance(ix, zones, warehouseID, req, ""); !ok {
+			response.WriteProblem(w, r, response.FieldErrors(fe))
+			return
 		}
 
-		id, err := storage.CreateOrder(order)
+		id, err := storage.CreateOrder(r.Context(), newOrder(req, warehouseID, outOfZone))
 		if err != nil {
-			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("failed to create order: %v", err)))
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to create order: %w", err), ""))
 			return
 		}
+		stream.Enqueue(id)
+		if outOfZone {
+			logger.FromContext(r.Context()).Warn("order taken outside service zones",
+				slog.Int64("order_id", id), slog.Int64("warehouse_id", warehouseID))
+		}
 
-	
-		response.WriteJSON(w, http.StatusCreated, map[string]int64{
+		response.WriteJSON(w, http.StatusCreated, map[string]any{
 			"Order has been created successfully with id": id,
+			"warehouse_id": warehouseID,
+			"out_of_zone":  outOfZone,
 		})
 	}
 }
 
-
-
 // CreateBulkOrders godoc
 // @Summary Create multiple orders in bulk
-// @Description Accepts a list of customer orders and stores them in the database
+// @Description Accepts a list of customer orders and stores them in the database, all or none. Addresses, warehouses and service zones are handled per order as for a single order; orders whose address cannot be geocoded are queued for review and counted in address_review. With allocation streaming enabled they are inserted into agents' current routes shortly after.
 // @Tags Orders
 // @Accept json
 // @Produce json
 // @Param orders body types.BulkOrderRequest true "List of order requests"
 // @Success 201 {object} map[string]int
-// @Failure 400 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 500 {object} response.Problem
 // @Router /api/orders/bulk [post]
-func CreateBulkOrders(storage storage.Storage) http.HandlerFunc {
+func CreateBulkOrders(storage storage.Storage, stream *jobs.Streamer, zones config.Zones, geocoder geo.Geocoder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req types.BulkOrderRequest
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			response.WriteJSON(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid request body: %v", err)))
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request body: %v", err)))
+			return
+		}
+		for i := range req.Orders {
+			req.Orders[i] = req.Orders[i].Normalized()
+		}
+
+		if err := validation.Struct(req); err != nil {
+			validationErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+
+		ix, err := geo.Load(r.Context(), storage)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+
+		var orders []types.Order
+		var reviews []types.AddressReview
+		var invalid []response.FieldError
+		flagged := 0
+		for i, o := range req.Orders {
+			if err := checkWindow(o); err != nil {
+				response.WriteProblem(w, r, response.BadRequest(response.CodeValidationFailed, fmt.Errorf("orders[%d]: %w", i, err)))
+				return
+			}
+			o, p, ok := forCustomer(r.Context(), storage, o, fmt.Sprintf("orders[%d].", i))
+			if !ok {
+				if len(p.Errors) == 0 {
+					response.WriteProblem(w, r, p)
+					return
+				}
+				invalid = append(invalid, p.Errors...)
+				continue
+			}
+			o, err := geocode(r.Context(), geocoder, o)
+			if errors.Is(err, geo.ErrUnresolved) {
+				reviews = append(reviews, types.AddressReview{Request: o, Reason: err.Error()})
+				continue
+			}
+			if err != nil {
+				response.WriteProblem(w, r, response.FromError(err, ""))
+				return
+			}
+			warehouseID, outOfZone, err := place(ix, zones, o)
+			if err != nil {
+				response.WriteProblem(w, r, response.BadRequest(response.CodeOutOfZone, fmt.Errorf("orders[%d]: %w", i, err)))
+				return
+			}
+			if fe, ok := checkDistance(ix, zones, warehouseID, o, fmt.Sprintf("orders[%d].", i)); !ok {
+				invalid = append(invalid, fe)
+			}
+			if outOfZone {
+				flagged++
+			}
+			orders = append(orders, newOrder(o, warehouseID, outOfZone))
+		}
+		if len(invalid) > 0 {
+			response.WriteProblem(w, r, response.FieldErrors(invalid...))
 			return
 		}
 
-		validate := validator.New()
-		for i, order := range req.Orders {
-			if err := validate.Struct(order); err != nil {
-				response.WriteJSON(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("validation failed at index %d: %v", i, err)))
+		var ids []int64
+		if len(orders) > 0 {
+			ids, err = storage.CreateBulkOrders(r.Context(), orders)
+			if err != nil {
+				response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to insert orders: %w", err), ""))
+				return
+			}
+			stream.Enqueue(ids...)
+		}
+		if len(reviews) > 0 {
+			if _, err := storage.CreateAddressReviews(r.Context(), reviews); err != nil {
+				response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to queue orders for review: %w", err), ""))
 				return
 			}
+			logger.FromContext(r.Context()).Info("orders queued for address review", slog.Int("orders", len(reviews)))
 		}
 
-	
-		var orders []types.Order
-		for _, o := range req.Orders {
-			orders = append(orders, types.Order{
-				Customer:    o.Customer,
-				Lat:         o.Lat,
-				Lng:         o.Lng,
-				WarehouseID: o.WarehouseID,
-				Assigned:    false,
-			})
+		if flagged > 0 {
+			logger.FromContext(r.Context()).Warn("orders taken outside service zones", slog.Int("orders", flagged))
+		}
+
+		response.WriteJSON(w, http.StatusCreated, map[string]int{"Ordered inserted": len(ids), "out_of_zone": flagged, "address_review": len(reviews)})
+	}
+}
+
+// geocode fills in the coordinates of an order that came with an address instead. It
+// returns geo.ErrUnresolved, with req unchanged, when the address is unknown.
+func geocode(ctx context.Context, geocoder geo.Geocoder, req types.OrderRequest) (types.OrderRequest, error) {
+	if req.Address == nil || req.Lat != 0 || req.Lng != 0 {
+		return req, nil
+	}
+	at, err := geocoder.Geocode(ctx, *req.Address)
+	if err != nil {
+		return req, err
+	}
+	req.Lat, req.Lng = at.Lat, at.Lng
+	return req.Normalized(), nil
+}
+
+// newOrder is the pending order for a request placed at a warehouse.
+func newOrder(req types.OrderRequest, warehouseID int64, outOfZone bool) types.Order {
+	return types.Order{
+		Customer:    req.Customer,
+		Lat:         req.Lat,
+		Lng:         req.Lng,
+		WarehouseID: warehouseID,
+		Assigned:    false,
+		Priority:    req.Priority,
+		WindowStart: req.WindowStart,
+		WindowEnd:   req.WindowEnd,
+		Load:        req.Load.WithDefaults(),
+		OutOfZone:   outOfZone,
+		CustomerID:  req.CustomerID,
+		AddressID:   req.AddressID,
+	}
+}
+
+// forCustomer fills in an order placed for a saved customer or address: the customer's
+// name when none is given and the address's coordinates. Unknown or mismatched IDs are
+// reported as field errors under prefix, e.g. "orders[2].".
+func forCustomer(ctx context.Context, store storage.Storage, req types.OrderRequest, prefix string) (types.OrderRequest, response.Problem, bool) {
+	if req.AddressID != 0 {
+		a, err := store.GetCustomerAddress(ctx, req.AddressID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return req, response.FieldErrors(response.FieldError{
+				Field:   prefix + "address_id",
+				Tag:     "exists",
+				Message: fmt.Sprintf("%saddress_id %d is not a saved address", prefix, req.AddressID),
+			}), false
+		}
+		if err != nil {
+			return req, response.FromError(err, ""), false
+		}
+		if req.CustomerID != 0 && req.CustomerID != a.CustomerID {
+			return req, response.FieldErrors(response.FieldError{
+				Field:   prefix + "address_id",
+				Tag:     "customer",
+				Message: fmt.Sprintf("%saddress_id %d belongs to another customer than %d", prefix, req.AddressID, req.CustomerID),
+			}), false
+		}
+		req.CustomerID = a.CustomerID
+		req.Lat, req.Lng, req.Address = a.Location.Lat, a.Location.Lng, nil
+	}
+
+	if req.CustomerID != 0 {
+		c, err := store.GetCustomer(ctx, req.CustomerID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return req, response.FieldErrors(response.FieldError{
+				Field:   prefix + "customer_id",
+				Tag:     "exists",
+				Message: fmt.Sprintf("%scustomer_id %d is not a saved customer", prefix, req.CustomerID),
+			}), false
+		}
+		if err != nil {
+			return req, response.FromError(err, ""), false
+		}
+		if req.Customer == "" {
+			req.Customer = c.Name
+		}
+	}
+	return req, response.Problem{}, true
+}
+
+// place picks the warehouse for an order: the one given, or the one whose service zone
+// contains the order. An order outside every zone of the given warehouse is refused, or
+// taken and reported out of zone under the flag policy.
+func place(ix *geo.Index, zones config.Zones, req types.OrderRequest) (warehouseID int64, outOfZone bool, err error) {
+	if req.WarehouseID == 0 {
+		id, ok := ix.Route(req.Lat, req.Lng)
+		if !ok {
+			return 0, false, fmt.Errorf("no service zone contains (%g, %g); give a warehouse_id", req.Lat, req.Lng)
+		}
+		return id, false, nil
+	}
+
+	if ix.Serves(req.WarehouseID, req.Lat, req.Lng) {
+		return req.WarehouseID, false, nil
+	}
+	if zones.OutOfZone == config.OutOfZoneFlag {
+		return req.WarehouseID, true, nil
+	}
+	return 0, false, fmt.Errorf("(%g, %g) is outside every service zone of warehouse %d", req.Lat, req.Lng, req.WarehouseID)
+}
+
+// checkDistance refuses an order farther from its warehouse than zones.MaxDistanceKm,
+// which usually means swapped or mistyped coordinates. prefix leads the reported field,
+// e.g. "orders[2].".
+func checkDistance(ix *geo.Index, zones config.Zones, warehouseID int64, req types.OrderRequest, prefix string) (response.FieldError, bool) {
+	hub, ok := ix.Hub(warehouseID)
+	if zones.MaxDistanceKm <= 0 || !ok {
+		return response.FieldError{}, true
+	}
+	km := jobs.Distance(hub.Lat, hub.Lng, req.Lat, req.Lng)
+	if km <= zones.MaxDistanceKm {
+		return response.FieldError{}, true
+	}
+
+	limit := strconv.FormatFloat(zones.MaxDistanceKm, 'f', -1, 64)
+	return response.FieldError{
+		Field: prefix + "lat",
+		Tag:   "max_distance_km",
+		Param: limit,
+		Message: fmt.Sprintf("%slat, %slng (%g, %g) is %.1f km from warehouse %d, more than the %s km allowed",
+			prefix, prefix, req.Lat, req.Lng, km, warehouseID, limit),
+	}, false
+}
+
+// checkWindow rejects a delivery window that ends before it starts.
+func checkWindow(req types.OrderRequest) error {
+	if req.WindowStart != nil && req.WindowEnd != nil && !req.WindowEnd.After(*req.WindowStart) {
+		return fmt.Errorf("window_end %s must be after window_start %s",
+			req.WindowEnd.Format(time.RFC3339), req.WindowStart.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// GetLateOrders godoc
+// @Summary List late and at-risk orders
+// @Description Lists undelivered orders whose delivery window has closed (late) or whose planned ETA falls after it (at_risk), earliest window first, with times in RFC 3339 in the timezone of the order's warehouse
+// @Tags Orders
+// @Produce json
+// @Success 200 {array} types.SLAOrder
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/late [get]
+func GetLateOrders(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orders, err := jobs.LateOrders(r.Context(), storage, time.Now())
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+
+		warehouses, err := storage.GetWarehouses(r.Context())
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to fetch warehouses: %w", err), ""))
+			return
+		}
+		zones := make(map[int64]*time.Location, len(warehouses))
+		for _, wh := range warehouses {
+			zones[wh.ID] = wh.Zone()
+		}
+		for i := range orders {
+			o := &orders[i]
+			zone, ok := zones[o.WarehouseID]
+			if !ok {
+				zone = time.UTC
+			}
+			o.Timezone = zone.String()
+			o.WindowEnd = o.WindowEnd.In(zone)
+			if o.WindowStart != nil {
+				t := o.WindowStart.In(zone)
+				o.WindowStart = &t
+			}
+			if o.ETA != nil {
+				t := o.ETA.In(zone)
+				o.ETA = &t
+			}
+		}
+		response.WriteJSON(w, http.StatusOK, orders)
+	}
+}
+
+// ListAddressReviews godoc
+// @Summary List orders waiting for address review
+// @Description Lists orders whose address could not be geocoded, oldest first, with the request as it was sent
+// @Tags Orders
+// @Produce json
+// @Success 200 {array} types.AddressReview
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/reviews [get]
+func ListAddressReviews(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviews, err := storage.GetAddressReviews(r.Context())
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+		if reviews == nil {
+			reviews = []types.AddressReview{}
+		}
+		response.WriteJSON(w, http.StatusOK, reviews)
+	}
+}
+
+// ResolveAddressReview godoc
+// @Summary Give the coordinates of an order waiting for review
+// @Description Creates the queued order at the given coordinates and takes it off the review queue. Warehouse, service zone and distance checks apply as for a new order
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param review_id path int true "Review ID"
+// @Param location body types.Location true "Delivery coordinates"
+// @Success 201 {object} map[string]any
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/reviews/{review_id}/resolve [post]
+func ResolveAddressReview(storage storage.Storage, stream *jobs.Streamer, zones config.Zones) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewID, err := strconv.ParseInt(mux.Vars(r)["review_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid review ID")))
+			return
+		}
+
+		var loc types.Location
+		if err := json.NewDecoder(r.Body).Decode(&loc); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		loc = loc.Normalized()
+		if err := validation.Struct(loc); err != nil {
+			validationErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+
+		rv, err := storage.GetAddressReview(r.Context(), reviewID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeReviewNotFound))
+			return
+		}
+		req := rv.Request
+		req.Lat, req.Lng = loc.Lat, loc.Lng
+
+		ix, err := geo.Load(r.Context(), storage)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+		warehouseID, outOfZone, err := place(ix, zones, req)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeOutOfZone, err))
+			return
+		}
+		if fe, ok := checkDistance(ix, zones, warehouseID, req, ""); !ok {
+			response.WriteProblem(w, r, response.FieldErrors(fe))
+			return
 		}
 
-		count, err := storage.CreateBulkOrders(orders)
+		id, err := storage.ResolveAddressReview(r.Context(), reviewID, newOrder(req, warehouseID, outOfZone))
 		if err != nil {
-			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("failed to insert orders: %v", err)))
+			response.WriteProblem(w, r, response.FromError(err, response.CodeReviewNotFound))
 			return
 		}
+		stream.Enqueue(id)
+		logger.FromContext(r.Context()).Info("address review resolved", slog.Int64("review_id", reviewID), slog.Int64("order_id", id))
 
-		response.WriteJSON(w, http.StatusCreated, map[string]int{"Ordered inserted": count})
+		response.WriteJSON(w, http.StatusCreated, map[string]any{
+			"Order has been created successfully with id": id,
+			"warehouse_id": warehouseID,
+			"out_of_zone":  outOfZone,
+		})
 	}
 }
 
+// DeleteAddressReview godoc
+// @Summary Discard an order waiting for address review
+// @Tags Orders
+// @Produce json
+// @Param review_id path int true "Review ID"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/reviews/{review_id} [delete]
+func DeleteAddressReview(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewID, err := strconv.ParseInt(mux.Vars(r)["review_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid review ID")))
+			return
+		}
 
+		if err := storage.DeleteAddressReview(r.Context(), reviewID); err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeReviewNotFound))
+			return
+		}
 
+		logger.FromContext(r.Context()).Info("address review discarded", slog.Int64("review_id", reviewID))
+		response.WriteJSON(w, http.StatusOK, map[string]int64{"Address review deleted with id": reviewID})
+	}
+}
 
-// ManualAllocation godoc
-// @Summary Trigger manual allocation of orders
-// @Description Runs the allocation algorithm to assign orders to agents
+// StartAllocation godoc
+// @Summary Start an allocation run
+// @Description Queues an allocation run on the worker pool and returns at once. Poll the URL in the Location header for progress
 // @Tags Orders
-// @Produce plain
-// @Success 200 {string} string "Allocation successful"
-// @Failure 500 {string} string "Allocation failed"
-// @Router /api/allocate [get]
-func ManualAllocation(s storage.Storage) http.HandlerFunc {
+// @Produce json
+// @Success 202 {object} types.AllocationRun
+// @Header 202 {string} Location "URL of the run"
+// @Failure 503 {object} response.Problem
+// @Router /api/allocate [post]
+func StartAllocation(runner *jobs.Runner) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		err := jobs.AllocateOrders(s)
+		run, err := runner.Submit()
+		if errors.Is(err, jobs.ErrQueueFull) {
+			w.Header().Set("Retry-After", "30")
+			response.WriteProblem(w, r, response.NewProblem(http.StatusServiceUnavailable, response.CodeAllocationQueueFull, err.Error()))
+			return
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(" Allocation failed: " + err.Error()))
+			response.WriteProblem(w, r, response.FromError(err, ""))
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Allocation successful"))
+		w.Header().Set("Location", "/api/allocate/"+run.ID)
+		response.WriteJSON(w, http.StatusAccepted, run)
 	}
 }
 
+// GetAllocation godoc
+// @Summary Get an allocation run
+// @Description Returns the progress of a run (orders processed, assigned, deferred) and, once finished, its outcome. Only recent runs accepted by this instance are known
+// @Tags Orders
+// @Produce json
+// @Param job_id path string true "Run ID"
+// @Success 200 {object} types.AllocationRun
+// @Failure 404 {object} response.Problem
+// @Router /api/allocate/{job_id} [get]
+func GetAllocation(runner *jobs.Runner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		run, err := runner.Get(mux.Vars(r)["job_id"])
+		if err != nil {
+			response.WriteProblem(w, r, response.NewProblem(http.StatusNotFound, response.CodeAllocationNotFound, err.Error()))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, run)
+	}
+}
+
+// CancelAllocation godoc
+// @Summary Cancel an allocation run
+// @Description Cancels a queued run, or stops a running one before its next order. Assignments already made are kept
+// @Tags Orders
+// @Produce json
+// @Param job_id path string true "Run ID"
+// @Success 200 {object} types.AllocationRun
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Router /api/allocate/{job_id}/cancel [post]
+func CancelAllocation(runner *jobs.Runner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		run, err := runner.Cancel(mux.Vars(r)["job_id"])
+		switch {
+		case errors.Is(err, jobs.ErrRunNotFound):
+			response.WriteProblem(w, r, response.NewProblem(http.StatusNotFound, response.CodeAllocationNotFound, err.Error()))
+			return
+		case errors.Is(err, jobs.ErrRunFinished):
+			response.WriteProblem(w, r, response.NewProblem(http.StatusConflict, response.CodeAllocationFinished, err.Error()))
+			return
+		case err != nil:
+			response.WriteProblem(w, r, response.Internal(err))
+			return
+		}
+
+		response.WriteJSON(w, http.StatusOK, run)
+	}
+}
+
+// UnassignOrder godoc
+// @Summary Take an order back from its agent
+// @Description Closes the order's open assignment, keeping it in the history with the reason, and makes the order eligible for the next allocation run. The body is optional
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Param request body types.UnassignRequest false "Reason"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/{order_id}/unassign [post]
+func UnassignOrder(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := strconv.ParseInt(mux.Vars(r)["order_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid order ID")))
+			return
+		}
+
+		var req types.UnassignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		if err := validation.Struct(req); err != nil {
+			validationErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+
+		if err := storage.UnassignOrder(r.Context(), orderID, req.Reason); err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeNoOpenAssignment))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("order unassigned", slog.Int64("order_id", orderID), slog.String("reason", req.Reason))
+		response.WriteJSON(w, http.StatusOK, map[string]int64{"Order unassigned successfully with id": orderID})
+	}
+}
+
+// ReassignOrder godoc
+// @Summary Move an order to another agent
+// @Description Closes the order's open assignment with the reason and assigns the order to another checked-in agent, planning the leg and ETA from the end of the agent's route
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Param request body types.ReassignRequest true "New agent and reason"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/{order_id}/reassign [post]
+func ReassignOrder(storage storage.Storage, limits config.Delivery) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := strconv.ParseInt(mux.Vars(r)["order_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid order ID")))
+			return
+		}
+
+		var req types.ReassignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		if err := validation.Struct(req); err != nil {
+			validationErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+
+		if err := jobs.Reassign(r.Context(), storage, limits, orderID, req.AgentID, req.Reason); err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeNoOpenAssignment))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("order reassigned",
+			slog.Int64("order_id", orderID), slog.Int64("agent_id", req.AgentID), slog.String("reason", req.Reason))
+		response.WriteJSON(w, http.StatusOK, map[string]int64{"Order reassigned successfully to agent": req.AgentID})
+	}
+}
 
 // GetAgentSummary godoc
 // @Summary Get agent summary with pagination
@@ -148,7 +686,7 @@ func ManualAllocation(s storage.Storage) http.HandlerFunc {
 // @Produce json
 // @Param page query int false "Page number (default is 1)"
 // @Success 200 {object} types.PaginatedAgentSummary
-// @Failure 500 {object} response.Response
+// @Failure 500 {object} response.Problem
 // @Router /api/agent-summary [get]
 func GetAgentSummary(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -161,10 +699,10 @@ func GetAgentSummary(storage storage.Storage) http.HandlerFunc {
 			}
 		}
 
-		limit := 10 
-		summaries, err := storage.GetAgentSummaryPaginated(page, limit)
+		limit := 10
+		summaries, err := storage.GetAgentSummaryPaginated(r.Context(), page, limit)
 		if err != nil {
-			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("failed to fetch summary: %v", err)))
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to fetch summary: %w", err), ""))
 			return
 		}
 
@@ -172,7 +710,6 @@ func GetAgentSummary(storage storage.Storage) http.HandlerFunc {
 	}
 }
 
-
 // GetSystemSummary godoc
 // @Summary Get system summary with paginated agent utilization
 // @Description Returns a system-wide summary including total, assigned, and deferred orders, along with agent utilization
@@ -181,7 +718,7 @@ func GetAgentSummary(storage storage.Storage) http.HandlerFunc {
 // @Produce json
 // @Param page query int false "Page number (default is 1)"
 // @Success 200 {object} types.SystemSummary
-// @Failure 500 {object} response.Response
+// @Failure 500 {object} response.Problem
 // @Router /api/system-summary [get]
 func GetSystemSummary(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -192,12 +729,11 @@ func GetSystemSummary(storage storage.Storage) http.HandlerFunc {
 		}
 		limit := 10
 
-		summary, err := storage.GetSystemSummaryPaginated(page, limit)
+		summary, err := storage.GetSystemSummaryPaginated(r.Context(), page, limit)
 		if err != nil {
-			response.WriteJSON(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("failed to get system summary: %v", err)))
+			response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to get system summary: %w", err), ""))
 			return
 		}
 		response.WriteJSON(w, http.StatusOK, summary)
 	}
 }
-
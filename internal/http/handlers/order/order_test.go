@@ -0,0 +1,444 @@
+package order_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/geo"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/order"
+	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/disk"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+)
+
+var limits = config.Delivery{
+	MaxDailyDistance: 100,
+	MaxDailyTime:     600,
+	PerKmTime:        5,
+	MinEarnings:      500,
+	BaseRate:         20,
+	Tier1Orders:      25,
+	Tier2Orders:      50,
+	Tier1Rate:        35,
+	Tier2Rate:        42,
+}
+
+// server routes the order endpoints to an in-memory store, geocoding from the sample
+// gazetteer. The allocation runner stops with the test.
+func server(t *testing.T) (http.Handler, storage.Storage) {
+	t.Helper()
+	store := memory.New(&config.Config{Variables: config.Variables{Delivery: limits}})
+
+	gazetteer, err := geo.LoadGazetteer("../../../../config/gazetteer.sample.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := jobs.NewRunner(store, limits, config.Allocation{})
+	runner.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		runner.Wait()
+	})
+	stream := jobs.NewStreamer(store, limits, config.Streaming{})
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/order", order.CreateOrder(store, stream, config.Zones{}, gazetteer)).Methods("POST")
+	pod := config.POD{MaxUploadMB: 1}
+	blobs, err := disk.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.HandleFunc("/api/orders/{order_id}/pod", order.SubmitProof(store, blobs, pod)).Methods("POST")
+	r.HandleFunc("/api/orders/{order_id}/unassign", order.UnassignOrder(store)).Methods("POST")
+	r.HandleFunc("/api/orders/{order_id}/reassign", order.ReassignOrder(store, limits)).Methods("POST")
+	r.HandleFunc("/api/allocate", order.StartAllocation(runner)).Methods("POST")
+	r.HandleFunc("/api/allocate/{job_id}", order.GetAllocation(runner)).Methods("GET")
+	r.HandleFunc("/api/agent-summary", order.GetAgentSummary(store)).Methods("GET")
+	r.HandleFunc("/api/orders/late", order.GetLateOrders(store)).Methods("GET")
+	r.HandleFunc("/api/orders/reviews", order.ListAddressReviews(store)).Methods("GET")
+	return r, store
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode reads the JSON body of rec into v.
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+// wantProblem checks that rec is a problem+json reply with the given status and code.
+func wantProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) response.Problem {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, status, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != response.ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, response.ProblemContentType)
+	}
+	var p response.Problem
+	decode(t, rec, &p)
+	if p.Code != code || p.Status != status {
+		t.Errorf("problem = %+v, want code %s", p, code)
+	}
+	return p
+}
+
+func seed(t *testing.T, s storage.Storage) (warehouseID, agentID int64) {
+	t.Helper()
+	ctx := context.Background()
+	warehouseID, err := s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentID, err = s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: warehouseID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return warehouseID, agentID
+}
+
+// allocate submits an allocation run and polls it until it succeeds.
+func allocate(t *testing.T, h http.Handler) types.AllocationRun {
+	t.Helper()
+	rec := do(t, h, "POST", "/api/allocate", "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	var run types.AllocationRun
+	decode(t, rec, &run)
+	if loc := rec.Header().Get("Location"); loc != "/api/allocate/"+run.ID {
+		t.Errorf("Location = %q, want /api/allocate/%s", loc, run.ID)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for run.Status != jobs.RunSucceeded {
+		if run.Status == jobs.RunFailed || run.Status == jobs.RunCanceled || time.Now().After(deadline) {
+			t.Fatalf("run = %+v, want it to succeed", run)
+		}
+		time.Sleep(10 * time.Millisecond)
+		rec = do(t, h, "GET", "/api/allocate/"+run.ID, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+		}
+		decode(t, rec, &run)
+	}
+	return run
+}
+
+func TestCreateOrder(t *testing.T) {
+	h, store := server(t)
+	seed(t, store)
+
+	rec := do(t, h, "POST", "/api/order", `{"customer": "John Doe", "lat": 12.9721, "lng": 77.594, "warehouse_id": 1, "priority": 5}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	var created map[string]any
+	decode(t, rec, &created)
+	if created["Order has been created successfully with id"] != 1.0 || created["warehouse_id"] != 1.0 {
+		t.Errorf("reply = %v", created)
+	}
+	got, err := store.GetUnassignedOrders(context.Background())
+	if err != nil || len(got) != 1 || got[0].Customer != "John Doe" || got[0].Priority != 5 {
+		t.Errorf("pending orders = %+v, %v", got, err)
+	}
+
+	p := wantProblem(t, do(t, h, "POST", "/api/order", `{"lat": 12.9721, "lng": 77.594, "warehouse_id": 1}`),
+		http.StatusBadRequest, response.CodeValidationFailed)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "customer" {
+		t.Errorf("field errors = %+v, want customer missing", p.Errors)
+	}
+	wantProblem(t, do(t, h, "POST", "/api/order", `{"customer":`), http.StatusBadRequest, response.CodeInvalidRequest)
+	wantProblem(t, do(t, h, "POST", "/api/order", `{"customer": "A", "lat": 91, "lng": 77.594, "warehouse_id": 1}`),
+		http.StatusBadRequest, response.CodeValidationFailed)
+}
+
+func TestCreateOrderByAddress(t *testing.T) {
+	h, store := server(t)
+	seed(t, store)
+
+	// a locality of the gazetteer places the order
+	rec := do(t, h, "POST", "/api/order", `{"customer": "A", "warehouse_id": 1,
+		"address": {"line": "14, M.G. Road", "city": "Bengaluru", "pincode": "560001"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	got, err := store.GetUnassignedOrders(context.Background())
+	if err != nil || len(got) != 1 || got[0].Lat != 12.9756 || got[0].Lng != 77.605 {
+		t.Errorf("pending orders = %+v, %v; want one at MG Road", got, err)
+	}
+
+	// an address it does not know waits for review instead
+	rec = do(t, h, "POST", "/api/order", `{"customer": "B", "warehouse_id": 1,
+		"address": {"line": "Somewhere", "city": "Bengaluru", "pincode": "999999"}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	var queued map[string]int64
+	decode(t, rec, &queued)
+
+	var reviews []types.AddressReview
+	rec = do(t, h, "GET", "/api/orders/reviews", "")
+	decode(t, rec, &reviews)
+	if len(reviews) != 1 || reviews[0].ID != queued["Order queued for address review with id"] ||
+		reviews[0].Reason != geo.ErrUnresolved.Error() || reviews[0].Request.Customer != "B" {
+		t.Errorf("reviews = %+v, want the order for B", reviews)
+	}
+	if got, _ := store.GetUnassignedOrders(context.Background()); len(got) != 1 {
+		t.Errorf("%d pending orders, want the review left out", len(got))
+	}
+}
+
+func TestAllocationRun(t *testing.T) {
+	h, store := server(t)
+	wh, agent := seed(t, store)
+	id, err := store.CreateOrder(context.Background(), types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := allocate(t, h)
+	if run.Assigned != 1 || run.Processed != 1 || run.Total != 1 {
+		t.Errorf("run = %+v, want the order assigned", run)
+	}
+	if a, err := store.GetOpenAssignment(context.Background(), id); err != nil || a.AgentID != agent {
+		t.Errorf("assignment = %+v, %v; want agent %d", a, err, agent)
+	}
+
+	wantProblem(t, do(t, h, "GET", "/api/allocate/unknown", ""), http.StatusNotFound, response.CodeAllocationNotFound)
+}
+
+func TestRepeatedAllocationRuns(t *testing.T) {
+	h, store := server(t)
+	ctx := context.Background()
+	wh, err := store.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bike := types.Capacity{Vehicle: "bike", MaxParcels: 1}
+	if _, err := store.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh, Capacity: bike}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := store.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh,
+			Load: types.Load{Parcels: 1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if run := allocate(t, h); run.Assigned != 1 || run.Deferred != 1 {
+		t.Errorf("first run = %+v, want 1 assigned and 1 deferred", run)
+	}
+	// the bike still carries the first parcel
+	if run := allocate(t, h); run.Assigned != 0 || run.Deferred != 1 {
+		t.Errorf("second run = %+v, want the order deferred again", run)
+	}
+	if got, err := store.GetUnassignedOrders(ctx); err != nil || len(got) != 1 {
+		t.Errorf("pending orders = %+v, %v; want one", got, err)
+	}
+}
+
+// submitProof posts fields as the multipart form of a proof of delivery.
+func submitProof(t *testing.T, h http.Handler, orderID int64, fields map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/orders/%d/pod", orderID), &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDeliveryNeedsOTP(t *testing.T) {
+	h, store := server(t)
+	ctx := context.Background()
+	wh, ravi := seed(t, store)
+	id, err := store.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AssignOrderToAgent(ctx, id, ravi, 1.5, 7.5, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.GetOpenAssignment(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// there is no way around the proof
+	if rec := do(t, h, "POST", fmt.Sprintf("/api/orders/%d/deliver", id), `{"actual_km": 2}`); rec.Code != http.StatusNotFound {
+		t.Errorf("/deliver status = %d, want 404", rec.Code)
+	}
+
+	p := wantProblem(t, submitProof(t, h, id, map[string]string{"lat": "12.98", "lng": "77.60"}), http.StatusBadRequest, response.CodeValidationFailed)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "otp" {
+		t.Errorf("field errors = %+v, want otp missing", p.Errors)
+	}
+	wrong := "0000"
+	if a.OTP == wrong {
+		wrong = "1111"
+	}
+	wantProblem(t, submitProof(t, h, id, map[string]string{"lat": "12.98", "lng": "77.60", "otp": wrong}),
+		http.StatusForbidden, response.CodeOTPMismatch)
+
+	rec := submitProof(t, h, id, map[string]string{"lat": "12.98", "lng": "77.60", "otp": a.OTP, "actual_km": "2"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	var proof types.DeliveryProof
+	decode(t, rec, &proof)
+	if !proof.OTPVerified {
+		t.Errorf("proof = %+v, want the OTP verified", proof)
+	}
+}
+
+func TestUnassignAndReassign(t *testing.T) {
+	h, store := server(t)
+	ctx := context.Background()
+	wh, ravi := seed(t, store)
+	asha, err := store.CheckInAgents(ctx, types.Agent{Name: "Asha", WarehouseID: wh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AssignOrderToAgent(ctx, id, ravi, 1.5, 7.5, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	wantProblem(t, do(t, h, "POST", "/api/orders/x/reassign", `{"agent_id": 2}`), http.StatusBadRequest, response.CodeInvalidID)
+	wantProblem(t, do(t, h, "POST", "/api/orders/1/reassign", `{"reason": "closer"}`), http.StatusBadRequest, response.CodeValidationFailed)
+	wantProblem(t, do(t, h, "POST", "/api/orders/1/reassign", `{"agent_id": 1}`), http.StatusConflict, response.CodeConflict)
+
+	if rec := do(t, h, "POST", "/api/orders/1/reassign", `{"agent_id": 2, "reason": "closer"}`); rec.Code != http.StatusOK {
+		t.Fatalf("reassign status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	// the leg is planned again, from Asha's warehouse
+	a, err := store.GetOpenAssignment(ctx, id)
+	if err != nil || a.AgentID != asha {
+		t.Fatalf("assignment after reassign = %+v, %v; want agent %d", a, err, asha)
+	}
+	if km := jobs.Distance(12.97, 77.59, 12.98, 77.60); math.Abs(a.PlannedKm-km) > 1e-6 || a.ETA == nil || a.ETA.Before(time.Now()) {
+		t.Errorf("reassigned leg = %.3f km, ETA %v; want %.3f km and an ETA", a.PlannedKm, a.ETA, km)
+	}
+
+	// the body is optional
+	if rec := do(t, h, "POST", "/api/orders/1/unassign", ""); rec.Code != http.StatusOK {
+		t.Fatalf("unassign status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	wantProblem(t, do(t, h, "POST", "/api/orders/1/unassign", ""), http.StatusNotFound, response.CodeNoOpenAssignment)
+	if got, err := store.GetUnassignedOrders(ctx); err != nil || len(got) != 1 || got[0].ID != id {
+		t.Errorf("pending orders = %+v, %v; want order %d", got, err, id)
+	}
+}
+
+func TestAgentSummaryPages(t *testing.T) {
+	h, store := server(t)
+	ctx := context.Background()
+	wh, _ := seed(t, store)
+	for i := 0; i < 11; i++ {
+		if _, err := store.CheckInAgents(ctx, types.Agent{Name: "Agent", WarehouseID: wh}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// only agents with orders are summarised
+	for agent := int64(1); agent <= 12; agent++ {
+		id, err := store.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AssignOrderToAgent(ctx, id, agent, 1.5, 7.5, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		query      string
+		page, rows int
+	}{
+		{"", 1, 10},
+		{"?page=2", 2, 2},
+		{"?page=0", 1, 10},
+		{"?page=x", 1, 10},
+	} {
+		rec := do(t, h, "GET", "/api/agent-summary"+tc.query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200: %s", tc.query, rec.Code, rec.Body)
+		}
+		var got types.PaginatedAgentSummary
+		decode(t, rec, &got)
+		if got.CurrentPage != tc.page || got.TotalPages != 2 || len(got.Data) != tc.rows {
+			t.Errorf("%s: page %d of %d with %d rows, want page %d of 2 with %d", tc.query,
+				got.CurrentPage, got.TotalPages, len(got.Data), tc.page, tc.rows)
+		}
+	}
+}
+
+func TestLateOrdersInWarehouseZone(t *testing.T) {
+	h, s := server(t)
+	ctx := context.Background()
+	wh, err := s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59},
+		WarehouseCalendar: types.WarehouseCalendar{Timezone: "Asia/Kolkata"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-3 * time.Hour).UTC().Truncate(time.Second)
+	end := start.Add(time.Hour)
+	if _, err := s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh,
+		WindowStart: &start, WindowEnd: &end}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(t, h, "GET", "/api/orders/late", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var late []map[string]any
+	decode(t, rec, &late)
+	if len(late) != 1 || late[0]["status"] != jobs.SLALate || late[0]["timezone"] != "Asia/Kolkata" {
+		t.Fatalf("late orders = %v, want the order late in Asia/Kolkata", late)
+	}
+	for field, want := range map[string]time.Time{"window_start": start, "window_end": end} {
+		got, _ := late[0][field].(string)
+		at, err := time.Parse(time.RFC3339, got)
+		if err != nil || !at.Equal(want) || !strings.HasSuffix(got, "+05:30") {
+			t.Errorf("%s = %q, want %s at +05:30", field, got, want)
+		}
+	}
+}
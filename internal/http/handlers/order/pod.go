@@ -0,0 +1,393 @@
+package order
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/validation"
+)
+
+// imageTypes are the uploads kept as proof, by sniffed content type, with the extension
+// they are stored under. Nothing that a browser would run is served back.
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// upload is a photo or signature taken from the form, not yet stored.
+type upload struct {
+	field       string
+	file        multipart.File
+	contentType string
+}
+
+// SubmitProof godoc
+// @Summary Deliver an order with proof
+// @Description Completes the delivery of an order with the distance and time actually travelled, keeping a proof of it for disputes: the GPS fix taken at the door, the OTP the customer was given when the order was assigned, an optional photo and signature (JPEG, PNG or WebP) and a note. With pod.otp_optional a photo or signature may stand in for the OTP. Wrong OTPs are counted; after pod.max_otp_attempts the order must be reassigned, which issues a new one. The GPS fix is refused when farther than pod.max_distance_m from the order
+// @Tags Orders
+// @Accept multipart/form-data
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Param lat formData number true "Latitude of the GPS fix"
+// @Param lng formData number true "Longitude of the GPS fix"
+// @Param accuracy_m formData number false "Accuracy of the GPS fix in metres"
+// @Param otp formData string false "OTP given by the customer"
+// @Param note formData string false "Note, e.g. left with the security guard"
+// @Param actual_km formData number false "Distance actually travelled"
+// @Param actual_minutes formData number false "Time actually taken"
+// @Param photo formData file false "Photo of the handover"
+// @Param signature formData file false "Customer's signature"
+// @Success 201 {object} types.DeliveryProof
+// @Failure 400 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 413 {object} response.Problem
+// @Failure 415 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/{order_id}/pod [post]
+func SubmitProof(storage storage.Storage, blobs storage.BlobStore, pod config.POD) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := strconv.ParseInt(mux.Vars(r)["order_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid order ID")))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, pod.MaxUploadMB<<20)
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				response.WriteProblem(w, r, response.NewProblem(http.StatusRequestEntityTooLarge, response.CodeUploadTooLarge,
+					fmt.Sprintf("upload is larger than %d MB", pod.MaxUploadMB)))
+				return
+			}
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		req, err := proofForm(r)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid request: %v", err)))
+			return
+		}
+		if err := validation.Struct(req); err != nil {
+			validationErrs := err.(validator.ValidationErrors)
+			response.WriteProblem(w, r, response.ValidationError(validationErrs))
+			return
+		}
+
+		var uploads []upload
+		for _, field := range []string{"photo", "signature"} {
+			u, err := formImage(r, field)
+			if err != nil {
+				response.WriteProblem(w, r, response.FromError(err, ""))
+				return
+			}
+			if u != nil {
+				defer u.file.Close()
+				uploads = append(uploads, *u)
+			}
+		}
+
+		o, err := storage.GetOrder(r.Context(), orderID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeOrderNotFound))
+			return
+		}
+		a, err := storage.GetOpenAssignment(r.Context(), orderID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeNoOpenAssignment))
+			return
+		}
+
+		verified, err := checkOTP(r.Context(), storage, pod, a, req.OTP)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeNoOpenAssignment))
+			return
+		}
+		if !verified {
+			switch {
+			case !pod.OTPOptional && a.OTP != "":
+				response.WriteProblem(w, r, response.FieldErrors(response.FieldError{
+					Field: "otp", Tag: "required", Message: "otp is required",
+				}))
+				return
+			case len(uploads) == 0:
+				response.WriteProblem(w, r, response.FieldErrors(response.FieldError{
+					Field: "photo", Tag: "required_without", Param: "otp", Message: "photo or signature is required without a verified otp",
+				}))
+				return
+			}
+		}
+
+		// metres are plenty for a doorstep
+		distanceM := math.Round(jobs.Distance(req.Lat, req.Lng, o.Lat, o.Lng) * 1000)
+		if pod.MaxDistanceM > 0 && distanceM-req.AccuracyM > pod.MaxDistanceM {
+			response.WriteProblem(w, r, response.FieldErrors(response.FieldError{
+				Field:   "lat",
+				Tag:     "max_distance",
+				Param:   strconv.FormatFloat(pod.MaxDistanceM, 'f', -1, 64),
+				Message: fmt.Sprintf("the GPS fix is %.0f m from the order, at most %g m is allowed", distanceM, pod.MaxDistanceM),
+			}))
+			return
+		}
+
+		p := types.DeliveryProof{
+			OrderID:      orderID,
+			AssignmentID: a.ID,
+			AgentID:      a.AgentID,
+			Location:     types.Location{Lat: req.Lat, Lng: req.Lng},
+			AccuracyM:    req.AccuracyM,
+			DistanceM:    distanceM,
+			OTPVerified:  verified,
+			Note:         req.Note,
+		}
+		var keys []string
+		// files of a proof that was not kept would be found by nothing
+		discard := func() {
+			for _, key := range keys {
+				if err := blobs.Delete(context.WithoutCancel(r.Context()), key); err != nil {
+					logger.FromContext(r.Context()).Warn("failed to discard proof file", slog.String("key", key), slog.String("error", err.Error()))
+				}
+			}
+		}
+		for _, u := range uploads {
+			key := fmt.Sprintf("%d/%d-%s-%d%s", orderID, a.ID, u.field, time.Now().UnixNano(), imageTypes[u.contentType])
+			size, err := blobs.Put(r.Context(), key, u.file)
+			if err != nil {
+				discard()
+				response.WriteProblem(w, r, response.FromError(fmt.Errorf("failed to store %s: %w", u.field, err), ""))
+				return
+			}
+			keys = append(keys, key)
+			artefact := &types.ProofArtefact{Key: key, ContentType: u.contentType, Size: size}
+			if u.field == "photo" {
+				p.Photo = artefact
+			} else {
+				p.Signature = artefact
+			}
+		}
+
+		if err := storage.CompleteDeliveryWithProof(r.Context(), p, req.ActualKm, req.ActualMinutes); err != nil {
+			discard()
+			response.WriteProblem(w, r, response.FromError(err, response.CodeNoOpenAssignment))
+			return
+		}
+		saved, err := storage.GetDeliveryProof(r.Context(), orderID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, ""))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("order delivered with proof",
+			slog.Int64("order_id", orderID), slog.Int64("agent_id", a.AgentID),
+			slog.Bool("otp_verified", verified), slog.Float64("distance_m", distanceM), slog.Int("files", len(keys)))
+		w.Header().Set("Location", fmt.Sprintf("/api/orders/%d/pod", orderID))
+		response.WriteJSON(w, http.StatusCreated, withURLs(saved))
+	}
+}
+
+// GetProof godoc
+// @Summary Get the proof of delivery of an order
+// @Description Returns what was captured when the order was delivered, with links to its photo and signature
+// @Tags Orders
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Success 200 {object} types.DeliveryProof
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/{order_id}/pod [get]
+func GetProof(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := strconv.ParseInt(mux.Vars(r)["order_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid order ID")))
+			return
+		}
+
+		p, err := storage.GetDeliveryProof(r.Context(), orderID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeProofNotFound))
+			return
+		}
+		response.WriteJSON(w, http.StatusOK, withURLs(p))
+	}
+}
+
+// GetProofArtefact godoc
+// @Summary Download the photo or signature of a proof of delivery
+// @Tags Orders
+// @Produce image/jpeg,image/png,image/webp
+// @Param order_id path int true "Order ID"
+// @Param artefact path string true "photo or signature"
+// @Success 200 {file} binary
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Router /api/orders/{order_id}/pod/{artefact} [get]
+func GetProofArtefact(storage storage.Storage, blobs storage.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := strconv.ParseInt(mux.Vars(r)["order_id"], 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, response.BadRequest(response.CodeInvalidID, fmt.Errorf("invalid order ID")))
+			return
+		}
+
+		p, err := storage.GetDeliveryProof(r.Context(), orderID)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeProofNotFound))
+			return
+		}
+
+		name := mux.Vars(r)["artefact"]
+		var artefact *types.ProofArtefact
+		switch name {
+		case "photo":
+			artefact = p.Photo
+		case "signature":
+			artefact = p.Signature
+		default:
+			response.WriteProblem(w, r, response.NewProblem(http.StatusNotFound, response.CodeNotFound,
+				fmt.Sprintf("unknown artefact %q, want photo or signature", name)))
+			return
+		}
+		if artefact == nil {
+			response.WriteProblem(w, r, response.NewProblem(http.StatusNotFound, response.CodeProofNotFound,
+				fmt.Sprintf("order %d was delivered without a %s", orderID, name)))
+			return
+		}
+
+		f, err := blobs.Open(r.Context(), artefact.Key)
+		if err != nil {
+			response.WriteProblem(w, r, response.FromError(err, response.CodeProofNotFound))
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Type", artefact.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(artefact.Size, 10))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="order-%d-%s%s"`, orderID, name, path.Ext(artefact.Key)))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, f); err != nil {
+			logger.FromContext(r.Context()).Warn("failed to send proof file", slog.String("key", artefact.Key), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// proofForm reads the fields of a proof from the parsed form. Numbers left out are zero.
+func proofForm(r *http.Request) (types.ProofRequest, error) {
+	req := types.ProofRequest{
+		OTP:  strings.TrimSpace(r.FormValue("otp")),
+		Note: strings.TrimSpace(r.FormValue("note")),
+	}
+	for _, f := range []struct {
+		name string
+		dst  *float64
+	}{
+		{"lat", &req.Lat}, {"lng", &req.Lng}, {"accuracy_m", &req.AccuracyM},
+		{"actual_km", &req.ActualKm}, {"actual_minutes", &req.ActualMinutes},
+	} {
+		v := strings.TrimSpace(r.FormValue(f.name))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return req, fmt.Errorf("%s: %q is not a number", f.name, v)
+		}
+		*f.dst = n
+	}
+	req.Lat, req.Lng = types.RoundCoordinate(req.Lat), types.RoundCoordinate(req.Lng)
+	return req, nil
+}
+
+// formImage returns the image uploaded as field, nil when there is none. Its type is
+// sniffed from the content; the client's claim is not trusted.
+func formImage(r *http.Request, field string) (*upload, error) {
+	f, _, err := r.FormFile(field)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, response.BadRequest(response.CodeInvalidRequest, fmt.Errorf("invalid %s: %v", field, err))
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		f.Close()
+		return nil, err
+	}
+	contentType := http.DetectContentType(head[:n])
+	if _, ok := imageTypes[contentType]; !ok {
+		f.Close()
+		return nil, response.NewProblem(http.StatusUnsupportedMediaType, response.CodeUnsupportedMedia,
+			fmt.Sprintf("%s is %s, want a JPEG, PNG or WebP image", field, contentType))
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &upload{field: field, file: f, contentType: contentType}, nil
+}
+
+// checkOTP compares the customer's code with the one issued for the assignment, counting
+// wrong ones, and reports whether it was verified. Nothing is verified without a code, or
+// for an assignment opened before codes were issued.
+func checkOTP(ctx context.Context, store storage.Storage, pod config.POD, a types.Assignment, otp string) (bool, error) {
+	if a.OTP == "" || otp == "" {
+		return false, nil
+	}
+	if pod.MaxOTPAttempts > 0 && a.OTPFailures >= pod.MaxOTPAttempts {
+		return false, response.NewProblem(http.StatusTooManyRequests, response.CodeOTPLocked,
+			fmt.Sprintf("too many wrong OTPs for order %d, reassign it to issue a new one", a.OrderID))
+	}
+	if subtle.ConstantTimeCompare([]byte(otp), []byte(a.OTP)) == 1 {
+		return true, nil
+	}
+
+	failures, err := store.RecordOTPFailure(ctx, a.ID)
+	if err != nil {
+		return false, err
+	}
+	detail := fmt.Sprintf("wrong OTP for order %d", a.OrderID)
+	if pod.MaxOTPAttempts > 0 {
+		detail += fmt.Sprintf(", %d attempts left", max(pod.MaxOTPAttempts-failures, 0))
+	}
+	return false, response.NewProblem(http.StatusForbidden, response.CodeOTPMismatch, detail)
+}
+
+// withURLs links the proof's files to GetProofArtefact.
+func withURLs(p types.DeliveryProof) types.DeliveryProof {
+	if p.Photo != nil {
+		p.Photo.URL = fmt.Sprintf("/api/orders/%d/pod/photo", p.OrderID)
+	}
+	if p.Signature != nil {
+		p.Signature.URL = fmt.Sprintf("/api/orders/%d/pod/signature", p.OrderID)
+	}
+	return p
+}
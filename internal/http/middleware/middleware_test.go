@@ -0,0 +1,182 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/middleware"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+)
+
+// logged serves req through h with a JSON logger in its context, returning the response
+// and the log lines written.
+func logged(t *testing.T, h http.Handler, req *http.Request) (*httptest.ResponseRecorder, []map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	req = req.WithContext(logger.IntoContext(req.Context(), slog.New(slog.NewJSONHandler(&buf, nil))))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		lines = append(lines, m)
+	}
+	return rec, lines
+}
+
+func TestRequestID(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+	tests := []struct {
+		name     string
+		incoming string
+		kept     bool
+	}{
+		{"none", "", false},
+		{"well-formed", "checkout-7f3a", true},
+		{"with a space", "checkout 7f3a", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = logger.RequestID(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+			if tt.incoming != "" {
+				req.Header.Set(middleware.RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(middleware.RequestIDHeader)
+			if id != seen {
+				t.Errorf("response has %q, handler saw %q", id, seen)
+			}
+			if tt.kept && id != tt.incoming {
+				t.Errorf("id = %q, want the incoming %q", id, tt.incoming)
+			}
+			if !tt.kept && !generated.MatchString(id) {
+				t.Errorf("id = %q, want a generated one", id)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(middleware.AccessLog)
+	r.HandleFunc("/api/orders/{order_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("queued"))
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/orders/7", nil)
+	req.Header.Set(middleware.RequestIDHeader, "checkout-7f3a")
+
+	_, lines := logged(t, middleware.RequestID(r), req)
+	if len(lines) != 1 {
+		t.Fatalf("got %d log lines, want 1: %v", len(lines), lines)
+	}
+	line := lines[0]
+	want := map[string]any{
+		"msg": "http request", "method": "POST", "route": "/api/orders/{order_id}", "path": "/api/orders/7",
+		"status": 202.0, "bytes": 6.0, "request_id": "checkout-7f3a",
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Errorf("%s = %v, want %v", k, line[k], v)
+		}
+	}
+	if _, ok := line["latency_ms"].(float64); !ok {
+		t.Errorf("latency_ms = %v", line["latency_ms"])
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	var err error
+	var bounded bool
+	wait := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, bounded = r.Context().Deadline()
+		select {
+		case <-r.Context().Done():
+			err = r.Context().Err()
+		case <-time.After(time.Second):
+			err = nil
+		}
+	})
+
+	start := time.Now()
+	middleware.Timeout(20*time.Millisecond)(wait).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("request context ended with %v after %v, want the deadline after 20ms", err, time.Since(start))
+	}
+
+	// without a timeout the request keeps the context it came with
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	middleware.Timeout(0)(wait).ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	if bounded || !errors.Is(err, context.Canceled) {
+		t.Errorf("unbounded request: deadline %v, ended with %v", bounded, err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	h := middleware.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			var m map[string]int
+			m["boom"]++
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec, lines := logged(t, h, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Type") != response.ProblemContentType {
+		t.Fatalf("got %d %q, want a 500 problem", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var p response.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Code != response.CodeInternal || p.Instance != "/panic" || strings.Contains(p.Detail, "nil map") {
+		t.Errorf("problem = %+v, want INTERNAL_ERROR without the panic", p)
+	}
+	if len(lines) == 0 || lines[0]["msg"] != "handler panicked" || !strings.Contains(lines[0]["panic"].(string), "nil map") ||
+		!strings.Contains(lines[0]["stack"].(string), "middleware_test.go") {
+		t.Errorf("log = %v, want the panic with its stack", lines)
+	}
+
+	if rec, _ := logged(t, h, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusNoContent {
+		t.Errorf("got %d from a handler that did not panic", rec.Code)
+	}
+
+	// aborting a response on purpose still aborts it
+	abort := middleware.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler passed on", v)
+		}
+	}()
+	abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Error("ErrAbortHandler swallowed")
+}
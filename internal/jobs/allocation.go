@@ -1,58 +1,428 @@
 package jobs
 
 import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math"
+	"os"
+	"slices"
+	"time"
 
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/metrics"
 	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
 	"github.com/sharmaprinceji/delivery-management-system/internal/types"
 )
 
-func AllocateOrders(s storage.Storage) error {
-	agents, _ := s.GetCheckedInAgents()
-	orders, _ := s.GetUnassignedOrders()
+// ErrAllocationInProgress is returned when another run, in this or another process, holds
+// the allocation lock.
+var ErrAllocationInProgress = errors.New("an allocation run is already in progress")
 
-	const MaxKm = 100.0
-	const MaxMinutes = 600
+const (
+	// lockName is the lock shared by manual and scheduled allocation runs.
+	lockName = "allocation"
+	// lockTTL is how long a lock left behind by a crashed run blocks new runs. A run renews
+	// its lock while it lasts, however long its timeout.
+	lockTTL = 2 * time.Minute
+	// lockRetry is how often a run that waits for the lock tries again.
+	lockRetry = 2 * time.Second
+)
+
+// Allocate assigns the pending orders of every open warehouse to the agents checked in
+// there, extending the routes they already have within the day's limits. It returns
+// ErrAllocationInProgress while another run holds the allocation lock.
+func Allocate(ctx context.Context, s storage.Storage, limits config.Delivery) (types.AllocationResult, error) {
+	return allocate(ctx, s, limits, 0, nil)
+}
+
+// AllocateWarehouse allocates one warehouse's pending orders to the agents checked in
+// there, whatever its hours. Unlike Allocate it waits for a run holding the lock to finish.
+func AllocateWarehouse(ctx context.Context, s storage.Storage, limits config.Delivery, warehouseID int64) (types.AllocationResult, error) {
+	return allocateWhenFree(ctx, s, limits, warehouseID, nil)
+}
+
+// allocateWhenFree retries allocate every lockRetry while another run holds the lock.
+func allocateWhenFree(ctx context.Context, s storage.Storage, limits config.Delivery, warehouseID int64, report progress) (types.AllocationResult, error) {
+	return whenFree(ctx, func() (types.AllocationResult, error) {
+		return allocate(ctx, s, limits, warehouseID, report)
+	})
+}
+
+// whenFree calls run again every lockRetry while it reports ErrAllocationInProgress.
+func whenFree(ctx context.Context, run func() (types.AllocationResult, error)) (types.AllocationResult, error) {
+	for {
+		res, err := run()
+		if !errors.Is(err, ErrAllocationInProgress) {
+			return res, err
+		}
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case <-time.After(lockRetry):
+		}
+	}
+}
+
+// progress is told about every order an allocation run has decided on. conflict is
+// the order's ID when another writer claimed it first, zero otherwise.
+type progress func(processed, total int, result types.AllocationResult, conflict int64)
+
+// allocate runs one allocation over every open warehouse, or over warehouseID alone when
+// it is non-zero.
+func allocate(ctx context.Context, s storage.Storage, limits config.Delivery, warehouseID int64, report progress) (result types.AllocationResult, err error) {
+	if report == nil {
+		report = func(int, int, types.AllocationResult, int64) {}
+	}
+	start := time.Now()
+	result.Conflicts = []int64{}
+	result.AtRisk = []int64{}
+	result.Unfit = []int64{}
+	defer func() {
+		metrics.ObserveAllocation(start, result.Assigned, result.Deferred, len(result.Conflicts), err)
+	}()
+
+	// only one run proceeds at a time; orders claimed by anything else meanwhile are
+	// reported as conflicts
+	release, err := acquire(ctx, s)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		if rerr := release(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	agents, err := s.GetCheckedInAgents(ctx)
+	if err != nil {
+		return result, fmt.Errorf("load agents: %w", err)
+	}
+	orders, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		return result, fmt.Errorf("load orders: %w", err)
+	}
+	warehouses, err := s.GetWarehouses(ctx)
+	if err != nil {
+		return result, fmt.Errorf("load warehouses: %w", err)
+	}
+
+	maxKm := limits.MaxDailyDistance
+	maxMinutes := limits.MaxDailyTime
+
+	now := time.Now()
+	stops, err := s.GetRoutes(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		return result, fmt.Errorf("load routes: %w", err)
+	}
+	hubs := make(map[int64]types.Location)
+	closed := make(map[int64]bool)
+	for _, w := range warehouses {
+		hubs[w.ID] = w.Location
+		closed[w.ID] = !w.IsOpen(now)
+	}
+	// warehouses outside their hours or on a holiday are left alone, orders and agents
+	// both, unless the run is for that warehouse
+	excluded := func(id int64) bool {
+		if warehouseID != 0 {
+			return id != warehouseID
+		}
+		return closed[id]
+	}
+	agents = slices.DeleteFunc(agents, func(a types.Agent) bool { return excluded(a.WarehouseID) })
+	orders = slices.DeleteFunc(orders, func(o types.Order) bool { return excluded(o.WarehouseID) })
+
+	// most urgent first, so that orders close to breaching get the nearest agents
+	slices.SortStableFunc(orders, byUrgency)
 
 	agentDistance := make(map[int64]float64)
+	agentMinutes := make(map[int64]float64)
+	agentPosition := make(map[int64]types.Location)
+	agentClock := make(map[int64]time.Time)
+	agentLoad := make(map[int64]types.Load)
 	agentOrders := make(map[int64][]types.Order)
 
-	for _, order := range orders {
-		bestAgentID := int64(0)
-		bestDistance := math.MaxFloat64
+	// agents carry on from the routes they already have: what they drove and still carry
+	// today counts against the limits, and new orders follow their last stop, or start at
+	// the agent's warehouse when there is none
+	for _, rt := range currentRoutes(agents, warehouses, stops, now) {
+		id := rt.agent.ID
+		agentDistance[id] = rt.km
+		agentMinutes[id] = rt.minutes
+		agentLoad[id] = rt.load
+		if n := len(rt.stops); n > 0 {
+			agentPosition[id] = rt.stops[n-1].Location
+			agentClock[id] = arrivals(rt.from, now, rt.stops, limits.PerKmTime)[n-1]
+		} else if rt.delivered {
+			agentPosition[id] = rt.from
+		}
+	}
+
+	processed := 0
+	// assign gives order to the agent of c, or records it as a conflict when another
+	// writer claimed it first.
+	assign := func(order types.Order, c stop) error {
+		processed++
+		// the assignment is planned for the leg that reaches the order
+		minutes := c.km * limits.PerKmTime
+		err := s.AssignOrderToAgent(ctx, order.ID, c.agentID, c.km, minutes, c.eta)
+		if errors.Is(err, storage.ErrConflict) {
+			result.Conflicts = append(result.Conflicts, order.ID)
+			report(processed, len(orders), result, order.ID)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("assign order %d to agent %d: %w", order.ID, c.agentID, err)
+		}
+		agentDistance[c.agentID] += c.km
+		agentMinutes[c.agentID] += minutes
+		agentPosition[c.agentID] = types.Location{Lat: order.Lat, Lng: order.Lng}
+		agentClock[c.agentID] = c.eta
+		agentLoad[c.agentID] = agentLoad[c.agentID].Add(order.Load)
+		agentOrders[c.agentID] = append(agentOrders[c.agentID], order)
+		result.Assigned++
+		if !c.onTime {
+			result.AtRisk = append(result.AtRisk, order.ID)
+		}
+		report(processed, len(orders), result, 0)
+		return nil
+	}
+
+	report(0, len(orders), result, 0)
+	pending := orders
+	if limits.ClusterOrders {
+		// whole zones first, each to the agent that delivers it with the fewest late stops
+		// and the least distance; zones no agent can take go order by order below
+		pending = nil
+		for _, cluster := range clustersByWarehouse(orders, hubs, limits) {
+			if err := ctx.Err(); err != nil {
+				return result, fmt.Errorf("allocation interrupted after %d orders: %w", result.Assigned, err)
+			}
 
+			var route []types.Order
+			var stops []stop
+			for _, agent := range agents {
+				if !agent.Fits(agentLoad[agent.ID].Add(loadOf(cluster))) {
+					continue
+				}
+				from, ok := agentPosition[agent.ID]
+				if !ok {
+					from = startOf(agent, cluster[0], hubs)
+				}
+				clock, ok := agentClock[agent.ID]
+				if !ok {
+					clock = now
+				}
+				r, st, ok := planCluster(cluster, agent.ID, from, clock,
+					maxKm-agentDistance[agent.ID], maxMinutes-agentMinutes[agent.ID], limits.PerKmTime)
+				if ok && (stops == nil || betterRoute(st, stops)) {
+					route, stops = r, st
+				}
+			}
+			if stops == nil {
+				pending = append(pending, cluster...)
+				continue
+			}
+			for j, order := range route {
+				if err := assign(order, stops[j]); err != nil {
+					return result, err
+				}
+			}
+		}
+		slices.SortStableFunc(pending, byUrgency)
+	}
+
+	for _, order := range pending {
+		// cancelling stops the run between orders; assignments already made are kept
+		if err := ctx.Err(); err != nil {
+			return result, fmt.Errorf("allocation interrupted after %d orders: %w", result.Assigned, err)
+		}
+
+		// vehicle limits are never exceeded; an order no checked-in vehicle could carry even
+		// when empty is unfit rather than deferred
+		var best stop
+		fits := false // some vehicle could carry the order if it were empty
 		for _, agent := range agents {
-			if agentDistance[agent.ID] >= MaxKm {
+			if !agent.Fits(order.Load) {
+				continue
+			}
+			fits = true
+			if !agent.Fits(agentLoad[agent.ID].Add(order.Load)) {
 				continue
 			}
 
-			d := Distance(order.Lat, order.Lng, 0, 0) 
-			if agentDistance[agent.ID]+d > MaxKm {
+			if agentDistance[agent.ID] >= maxKm {
+				continue
+			}
+
+			from, ok := agentPosition[agent.ID]
+			if !ok {
+				from = startOf(agent, order, hubs)
+			}
+
+			d := Distance(from.Lat, from.Lng, order.Lat, order.Lng)
+			if agentDistance[agent.ID]+d > maxKm {
+				continue
+			}
+			if agentMinutes[agent.ID]+d*limits.PerKmTime > maxMinutes {
 				continue
 			}
 
-			if d < bestDistance {
-				bestDistance = d
-				bestAgentID = agent.ID
+			clock, ok := agentClock[agent.ID]
+			if !ok {
+				clock = now
+			}
+			c := plan(order, agent.ID, d, clock, limits.PerKmTime)
+			if best.agentID == 0 || c.better(best) {
+				best = c
 			}
 		}
 
-		if bestAgentID != 0 {
-			s.AssignOrderToAgent(order.ID, bestAgentID)
-			agentDistance[bestAgentID] += bestDistance
-			agentOrders[bestAgentID] = append(agentOrders[bestAgentID], order)
+		if best.agentID != 0 {
+			if err := assign(order, best); err != nil {
+				return result, err
+			}
+			continue
 		}
+		if len(agents) > 0 && !fits {
+			result.Unfit = append(result.Unfit, order.ID)
+		} else {
+			result.Deferred++
+		}
+		processed++
+		report(processed, len(orders), result, 0)
 	}
 
+	log := slog.Default().With(slog.String("component", "allocation"))
 	for id, list := range agentOrders {
-		fmt.Printf("Agent %d assigned %d orders\n", id, len(list))
+		log.Info("agent assigned orders",
+			slog.Int64("agent_id", id),
+			slog.Int("orders", len(list)),
+			slog.Float64("planned_km", agentDistance[id]),
+			slog.Float64("planned_minutes", agentMinutes[id]),
+			slog.Float64("weight_kg", agentLoad[id].WeightKg),
+			slog.Float64("volume_l", agentLoad[id].VolumeL),
+			slog.Int("parcels", agentLoad[id].Parcels),
+		)
 	}
+	log.Info("allocation finished",
+		slog.Int("assigned", result.Assigned),
+		slog.Int("deferred", result.Deferred),
+		slog.Any("conflicts", result.Conflicts),
+		slog.Any("at_risk", result.AtRisk),
+		slog.Any("unfit", result.Unfit),
+	)
+
+	return result, nil
+}
 
-	return nil
+// stop is a candidate agent for an order: how far it travels and when it gets there.
+type stop struct {
+	agentID int64
+	km      float64
+	eta     time.Time
+	onTime  bool
 }
 
-//calculation function..
+// plan works out when an agent free at clock reaches order km away. An agent arriving
+// before the window opens waits for it.
+func plan(order types.Order, agentID int64, km float64, clock time.Time, perKmTime float64) stop {
+	eta := clock.Add(time.Duration(km * perKmTime * float64(time.Minute)))
+	if order.WindowStart != nil && eta.Before(*order.WindowStart) {
+		eta = *order.WindowStart
+	}
+	return stop{
+		agentID: agentID,
+		km:      km,
+		eta:     eta,
+		onTime:  order.WindowEnd == nil || !eta.After(*order.WindowEnd),
+	}
+}
+
+// better prefers an agent that makes the window, then the nearest one, or when
+// neither does, the one that is least late.
+func (c stop) better(than stop) bool {
+	if c.onTime != than.onTime {
+		return c.onTime
+	}
+	if c.onTime {
+		return c.km < than.km
+	}
+	return c.eta.Before(than.eta)
+}
+
+// byUrgency sorts the earliest window end first and orders without a window last,
+// breaking ties by higher priority.
+func byUrgency(a, b types.Order) int {
+	switch {
+	case a.WindowEnd != nil && b.WindowEnd != nil:
+		if c := a.WindowEnd.Compare(*b.WindowEnd); c != 0 {
+			return c
+		}
+	case a.WindowEnd != nil:
+		return -1
+	case b.WindowEnd != nil:
+		return 1
+	}
+	return cmp.Compare(b.Priority, a.Priority)
+}
+
+// acquire takes the allocation lock, or returns ErrAllocationInProgress while another run
+// holds it.
+func acquire(ctx context.Context, s storage.Storage) (release func() error, err error) {
+	owner := lockOwner()
+	ok, err := s.AcquireLock(ctx, lockName, owner, lockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("acquire allocation lock: %w", err)
+	}
+	if !ok {
+		return nil, ErrAllocationInProgress
+	}
+	stop := storage.KeepLock(ctx, s, lockName, owner, lockTTL)
+	return func() error {
+		stop()
+		// release even when ctx was cancelled, or the next run waits for lockTTL
+		if err := s.ReleaseLock(context.WithoutCancel(ctx), lockName, owner); err != nil {
+			return fmt.Errorf("release allocation lock: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// lockOwner returns a unique holder name for a lock taken by this process.
+func lockOwner() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// startOf returns where an agent's route begins: the agent's warehouse, falling back
+// to the order's warehouse and finally to the order itself when neither is known.
+func startOf(agent types.Agent, order types.Order, hubs map[int64]types.Location) types.Location {
+	if loc, ok := hubs[agent.WarehouseID]; ok {
+		return loc
+	}
+	if loc, ok := hubs[order.WarehouseID]; ok {
+		return loc
+	}
+	return types.Location{Lat: order.Lat, Lng: order.Lng}
+}
+
+const earthRadiusKm = 6371.0
+
+// Distance returns the great-circle distance in km between two coordinates.
 func Distance(lat1, lng1, lat2, lng2 float64) float64 {
-	return 2.0 + math.Abs(lat1-lat2)+math.Abs(lng1-lng2)
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
 }
@@ -0,0 +1,226 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+var testLimits = config.Delivery{
+	MaxDailyDistance: 100,
+	MaxDailyTime:     600,
+	PerKmTime:        5,
+}
+
+func newStore() storage.Storage {
+	return memory.New(&config.Config{Variables: config.Variables{Delivery: testLimits}})
+}
+
+func must(t *testing.T) func(int64, error) int64 {
+	return func(id int64, err error) int64 {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+}
+
+// agentOf returns the agent an order was given, or zero.
+func agentOf(t *testing.T, s storage.Storage, orderID int64) int64 {
+	t.Helper()
+	a, err := s.GetOpenAssignment(context.Background(), orderID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("GetOpenAssignment(%d): %v", orderID, err)
+	}
+	return a.AgentID
+}
+
+func TestAllocateNearestAgent(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	north := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "North", Location: types.Location{Lat: 13.05, Lng: 77.59}}))
+	south := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "South", Location: types.Location{Lat: 12.90, Lng: 77.59}}))
+	ravi := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: north}))
+	asha := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Asha", WarehouseID: south}))
+	up := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 13.06, Lng: 77.60, WarehouseID: north}))
+	down := must(t)(s.CreateOrder(ctx, types.Order{Customer: "B", Lat: 12.91, Lng: 77.60, WarehouseID: south}))
+
+	res, err := Allocate(ctx, s, testLimits)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if res.Assigned != 2 || res.Deferred != 0 || len(res.Conflicts) != 0 {
+		t.Errorf("result = %+v, want 2 assigned", res)
+	}
+	if got := agentOf(t, s, up); got != ravi {
+		t.Errorf("order %d went to agent %d, want %d", up, got, ravi)
+	}
+	if got := agentOf(t, s, down); got != asha {
+		t.Errorf("order %d went to agent %d, want %d", down, got, asha)
+	}
+
+	// the leg from the warehouse is planned
+	a, err := s.GetOpenAssignment(ctx, up)
+	if err != nil {
+		t.Fatalf("GetOpenAssignment: %v", err)
+	}
+	want := Distance(13.05, 77.59, 13.06, 77.60)
+	if a.PlannedKm < want-1e-6 || a.PlannedKm > want+1e-6 || a.PlannedMinutes < want*5-1e-6 || a.PlannedMinutes > want*5+1e-6 {
+		t.Errorf("planned %.3f km %.3f min, want %.3f km %.3f min", a.PlannedKm, a.PlannedMinutes, want, want*5)
+	}
+}
+
+func TestAllocateDistanceLimit(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}}))
+	must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+	// about 150 km away, beyond a day's driving
+	far := must(t)(s.CreateOrder(ctx, types.Order{Customer: "Mysuru", Lat: 12.30, Lng: 76.64, WarehouseID: wh}))
+
+	res, err := Allocate(ctx, s, testLimits)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if res.Assigned != 0 || res.Deferred != 1 {
+		t.Errorf("result = %+v, want the order deferred", res)
+	}
+	if got := agentOf(t, s, far); got != 0 {
+		t.Errorf("order %d went to agent %d, want none", far, got)
+	}
+}
+
+func TestAllocateVehicleCapacity(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}}))
+	bike := types.Capacity{Vehicle: "bike", MaxWeightKg: 10, MaxParcels: 1}
+	must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh, Capacity: bike}))
+	first := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh, Priority: 5,
+		Load: types.Load{WeightKg: 2, Parcels: 1}}))
+	second := must(t)(s.CreateOrder(ctx, types.Order{Customer: "B", Lat: 12.98, Lng: 77.61, WarehouseID: wh,
+		Load: types.Load{WeightKg: 2, Parcels: 1}}))
+	heavy := must(t)(s.CreateOrder(ctx, types.Order{Customer: "C", Lat: 12.99, Lng: 77.60, WarehouseID: wh,
+		Load: types.Load{WeightKg: 40, Parcels: 1}}))
+
+	res, err := Allocate(ctx, s, testLimits)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if res.Assigned != 1 || res.Deferred != 1 || len(res.Unfit) != 1 || res.Unfit[0] != heavy {
+		t.Errorf("result = %+v, want 1 assigned, 1 deferred and order %d unfit", res, heavy)
+	}
+	if agentOf(t, s, first) == 0 || agentOf(t, s, second) != 0 {
+		t.Errorf("want only the higher priority order %d assigned", first)
+	}
+}
+
+func TestAllocateClosedWarehouse(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	today := time.Now().UTC().Format("2006-01-02")
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59},
+		WarehouseCalendar: types.WarehouseCalendar{Timezone: "UTC", Holidays: []string{today}}}))
+	must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+	id := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh}))
+
+	res, err := Allocate(ctx, s, testLimits)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if res.Assigned != 0 || res.Deferred != 0 {
+		t.Errorf("result = %+v, want the closed warehouse left alone", res)
+	}
+
+	// an explicit run for the warehouse ignores its hours
+	res, err = AllocateWarehouse(ctx, s, testLimits, wh)
+	if err != nil {
+		t.Fatalf("AllocateWarehouse: %v", err)
+	}
+	if res.Assigned != 1 || agentOf(t, s, id) == 0 {
+		t.Errorf("result = %+v, want order %d assigned", res, id)
+	}
+}
+
+func TestAllocateInProgress(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	ok, err := s.AcquireLock(ctx, lockName, "elsewhere", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("AcquireLock = %v, %v", ok, err)
+	}
+	if _, err := Allocate(ctx, s, testLimits); !errors.Is(err, ErrAllocationInProgress) {
+		t.Errorf("Allocate while locked: got %v, want ErrAllocationInProgress", err)
+	}
+
+	if err := s.ReleaseLock(ctx, lockName, "elsewhere"); err != nil {
+		t.Fatalf("ReleaseLock: %v", err)
+	}
+	if _, err := Allocate(ctx, s, testLimits); err != nil {
+		t.Errorf("Allocate after release: %v", err)
+	}
+}
+
+func TestAllocateCountsCurrentRoutes(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}}))
+	bike := types.Capacity{Vehicle: "bike", MaxParcels: 1}
+	must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh, Capacity: bike}))
+	// about 40 km north of the hub
+	north := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 13.33, Lng: 77.59, WarehouseID: wh,
+		Load: types.Load{Parcels: 1}}))
+	if res, err := Allocate(ctx, s, testLimits); err != nil || res.Assigned != 1 {
+		t.Fatalf("first run = %+v, %v; want the order assigned", res, err)
+	}
+
+	// the bike still carries its one parcel
+	near := must(t)(s.CreateOrder(ctx, types.Order{Customer: "B", Lat: 12.98, Lng: 77.60, WarehouseID: wh,
+		Load: types.Load{Parcels: 1}}))
+	res, err := Allocate(ctx, s, testLimits)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if res.Assigned != 0 || agentOf(t, s, near) != 0 {
+		t.Errorf("result = %+v, want order %d deferred on a full bike", res, near)
+	}
+
+	// once delivered, the next order is driven to from the last stop: 40 km there and
+	// 70 km on to an order 30 km south of the hub is over the 100 km a day
+	if err := s.CompleteDelivery(ctx, north, 40, 200); err != nil {
+		t.Fatalf("CompleteDelivery: %v", err)
+	}
+	south := must(t)(s.CreateOrder(ctx, types.Order{Customer: "C", Lat: 12.70, Lng: 77.59, WarehouseID: wh, Priority: 5,
+		Load: types.Load{Parcels: 1}}))
+	res, err = Allocate(ctx, s, testLimits)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if agentOf(t, s, south) != 0 {
+		t.Errorf("result = %+v, want order %d deferred beyond the daily distance", res, south)
+	}
+	a, err := s.GetOpenAssignment(ctx, near)
+	if err != nil {
+		t.Fatalf("GetOpenAssignment: %v", err)
+	}
+	want := Distance(13.33, 77.59, 12.98, 77.60)
+	if a.PlannedKm < want-1e-6 || a.PlannedKm > want+1e-6 {
+		t.Errorf("planned %.3f km, want %.3f km from the last delivery", a.PlannedKm, want)
+	}
+}
@@ -0,0 +1,242 @@
+package jobs
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// maxBisectRounds bounds the k-means iterations of one split.
+const maxBisectRounds = 20
+
+// BudgetKm is how far one agent can travel in a day: the distance limit, or less when
+// the time limit runs out first.
+func BudgetKm(limits config.Delivery) float64 {
+	km := limits.MaxDailyDistance
+	if limits.PerKmTime > 0 {
+		km = min(km, limits.MaxDailyTime/limits.PerKmTime)
+	}
+	return km
+}
+
+// Clusters groups a warehouse's pending orders into delivery zones, most urgent first,
+// for showing them: each is a compact group one agent can deliver from hub within
+// BudgetKm. See clusters.
+func Clusters(orders []types.Order, hub types.Location, limits config.Delivery) []types.OrderCluster {
+	orders = slices.Clone(orders)
+	slices.SortStableFunc(orders, byUrgency)
+
+	out := make([]types.OrderCluster, 0)
+	for i, cl := range clusters(orders, hub, BudgetKm(limits)) {
+		c := types.OrderCluster{ID: i + 1, Centroid: centroid(cl), OrderIDs: make([]int64, 0, len(cl))}
+		for _, o := range cl {
+			c.OrderIDs = append(c.OrderIDs, o.ID)
+			c.Load = c.Load.Add(o.Load)
+		}
+		c.EstimatedKm = routeKm(hub, cl)
+		out = append(out, c)
+	}
+	return out
+}
+
+// clusters splits orders by bisecting k-means on their coordinates: a cluster whose
+// nearest-neighbour route from hub is longer than budgetKm is split in two, until every
+// cluster fits or holds a single order. Orders keep their relative order within a cluster
+// and clusters are sorted by their first order, so sorting orders by urgency beforehand
+// puts the most urgent cluster first.
+func clusters(orders []types.Order, hub types.Location, budgetKm float64) [][]types.Order {
+	var done [][]types.Order
+	todo := [][]types.Order{orders}
+	for len(todo) > 0 {
+		cl := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if len(cl) == 0 {
+			continue
+		}
+		if len(cl) == 1 || routeKm(hub, cl) <= budgetKm {
+			done = append(done, cl)
+			continue
+		}
+		a, b := bisect(cl)
+		todo = append(todo, b, a)
+	}
+
+	slices.SortStableFunc(done, func(a, b []types.Order) int { return byUrgency(a[0], b[0]) })
+	return done
+}
+
+// clustersByWarehouse clusters each warehouse's orders around its hub, most urgent
+// cluster first across warehouses.
+func clustersByWarehouse(orders []types.Order, hubs map[int64]types.Location, limits config.Delivery) [][]types.Order {
+	var ids []int64
+	byWarehouse := make(map[int64][]types.Order)
+	for _, o := range orders {
+		if _, ok := byWarehouse[o.WarehouseID]; !ok {
+			ids = append(ids, o.WarehouseID)
+		}
+		byWarehouse[o.WarehouseID] = append(byWarehouse[o.WarehouseID], o)
+	}
+
+	var out [][]types.Order
+	for _, id := range ids {
+		group := byWarehouse[id]
+		hub, ok := hubs[id]
+		if !ok {
+			hub = types.Location{Lat: group[0].Lat, Lng: group[0].Lng}
+		}
+		out = append(out, clusters(group, hub, BudgetKm(limits))...)
+	}
+	slices.SortStableFunc(out, func(a, b []types.Order) int { return byUrgency(a[0], b[0]) })
+	return out
+}
+
+// bisect splits orders in two by 2-means, seeded with the order farthest from the centroid
+// and the order farthest from that one. Orders at one spot are split down the middle.
+func bisect(orders []types.Order) (a, b []types.Order) {
+	c := centroid(orders)
+	p := farthest(orders, c)
+	q := farthest(orders, types.Location{Lat: p.Lat, Lng: p.Lng})
+	ca, cb := types.Location{Lat: p.Lat, Lng: p.Lng}, types.Location{Lat: q.Lat, Lng: q.Lng}
+
+	side := make([]bool, len(orders)) // true for b
+	for round := 0; round < maxBisectRounds; round++ {
+		changed := round == 0
+		for i, o := range orders {
+			toB := Distance(o.Lat, o.Lng, cb.Lat, cb.Lng) < Distance(o.Lat, o.Lng, ca.Lat, ca.Lng)
+			if toB != side[i] {
+				side[i], changed = toB, true
+			}
+		}
+		if !changed {
+			break
+		}
+		a, b = a[:0], b[:0]
+		for i, o := range orders {
+			if side[i] {
+				b = append(b, o)
+			} else {
+				a = append(a, o)
+			}
+		}
+		if len(a) == 0 || len(b) == 0 {
+			break
+		}
+		ca, cb = centroid(a), centroid(b)
+	}
+
+	if len(a) == 0 || len(b) == 0 {
+		half := len(orders) / 2
+		return slices.Clone(orders[:half]), slices.Clone(orders[half:])
+	}
+	return a, b
+}
+
+// centroid is the mean position of orders. At city scale the mean of the coordinates is
+// close enough to the geographic centre.
+func centroid(orders []types.Order) types.Location {
+	var c types.Location
+	for _, o := range orders {
+		c.Lat += o.Lat
+		c.Lng += o.Lng
+	}
+	n := float64(len(orders))
+	return types.Location{Lat: c.Lat / n, Lng: c.Lng / n}
+}
+
+func farthest(orders []types.Order, from types.Location) types.Order {
+	best, bestKm := orders[0], -1.0
+	for _, o := range orders {
+		if km := Distance(from.Lat, from.Lng, o.Lat, o.Lng); km > bestKm {
+			best, bestKm = o, km
+		}
+	}
+	return best
+}
+
+// tour visits orders nearest first from from, returning them in visiting order with the
+// length of the leg that reaches each.
+func tour(from types.Location, orders []types.Order) ([]types.Order, []float64) {
+	left := slices.Clone(orders)
+	seq := make([]types.Order, 0, len(orders))
+	legs := make([]float64, 0, len(orders))
+	for len(left) > 0 {
+		next, nextKm := 0, math.Inf(1)
+		for i, o := range left {
+			if km := Distance(from.Lat, from.Lng, o.Lat, o.Lng); km < nextKm {
+				next, nextKm = i, km
+			}
+		}
+		o := left[next]
+		seq = append(seq, o)
+		legs = append(legs, nextKm)
+		from = types.Location{Lat: o.Lat, Lng: o.Lng}
+		left = slices.Delete(left, next, next+1)
+	}
+	return seq, legs
+}
+
+func routeKm(from types.Location, orders []types.Order) float64 {
+	_, legs := tour(from, orders)
+	km := 0.0
+	for _, l := range legs {
+		km += l
+	}
+	return km
+}
+
+// planCluster routes an agent free at clock from from through every order of a cluster,
+// nearest first. It reports false when the route does not fit the km and minutes the agent
+// has left today.
+func planCluster(cluster []types.Order, agentID int64, from types.Location, clock time.Time, kmLeft, minutesLeft, perKmTime float64) ([]types.Order, []stop, bool) {
+	seq, legs := tour(from, cluster)
+	stops := make([]stop, len(seq))
+	for i, o := range seq {
+		stops[i] = plan(o, agentID, legs[i], clock, perKmTime)
+		clock = stops[i].eta
+	}
+
+	km := routeLength(stops)
+	if km > kmLeft || km*perKmTime > minutesLeft {
+		return nil, nil, false
+	}
+	return seq, stops, true
+}
+
+// betterRoute prefers the route with fewer stops missing their window, then the shorter one.
+func betterRoute(a, b []stop) bool {
+	lateA, lateB := late(a), late(b)
+	if lateA != lateB {
+		return lateA < lateB
+	}
+	return routeLength(a) < routeLength(b)
+}
+
+func late(stops []stop) int {
+	n := 0
+	for _, st := range stops {
+		if !st.onTime {
+			n++
+		}
+	}
+	return n
+}
+
+func routeLength(stops []stop) float64 {
+	km := 0.0
+	for _, st := range stops {
+		km += st.km
+	}
+	return km
+}
+
+// loadOf is what a cluster puts on a vehicle.
+func loadOf(orders []types.Order) types.Load {
+	var l types.Load
+	for _, o := range orders {
+		l = l.Add(o.Load)
+	}
+	return l
+}
@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// northAndSouth are two groups of three orders about 36 km either side of the hub: each
+// group is a 40 km route, both together over 110 km.
+func northAndSouth() []types.Order {
+	return []types.Order{
+		{ID: 1, Lat: 12.65, Lng: 77.59, Load: types.Load{WeightKg: 1, Parcels: 1}},
+		{ID: 2, Lat: 13.30, Lng: 77.59, Load: types.Load{WeightKg: 2, Parcels: 1}},
+		{ID: 3, Lat: 12.64, Lng: 77.60, Load: types.Load{WeightKg: 1, Parcels: 1}},
+		{ID: 4, Lat: 13.31, Lng: 77.60, Load: types.Load{WeightKg: 2, Parcels: 2}},
+		{ID: 5, Lat: 12.65, Lng: 77.61, Load: types.Load{WeightKg: 1, Parcels: 1}},
+		{ID: 6, Lat: 13.30, Lng: 77.61, Load: types.Load{WeightKg: 2, Parcels: 1}},
+	}
+}
+
+func idsOf(orders []types.Order) []int64 {
+	ids := []int64{}
+	for _, o := range orders {
+		ids = append(ids, o.ID)
+	}
+	return ids
+}
+
+func TestBudgetKm(t *testing.T) {
+	tests := []struct {
+		limits config.Delivery
+		want   float64
+	}{
+		{config.Delivery{MaxDailyDistance: 100, MaxDailyTime: 600, PerKmTime: 5}, 100},
+		{config.Delivery{MaxDailyDistance: 100, MaxDailyTime: 300, PerKmTime: 5}, 60},
+		{config.Delivery{MaxDailyDistance: 100, MaxDailyTime: 300}, 100},
+	}
+	for _, tt := range tests {
+		if got := BudgetKm(tt.limits); got != tt.want {
+			t.Errorf("BudgetKm(%+v) = %g, want %g", tt.limits, got, tt.want)
+		}
+	}
+}
+
+func TestClustersGroupNearbyOrders(t *testing.T) {
+	orders := northAndSouth()
+	// the first order due in the north makes its cluster the most urgent
+	due := time.Now().Add(time.Hour)
+	orders[3].WindowEnd = &due
+	limits := config.Delivery{MaxDailyDistance: 60, MaxDailyTime: 600, PerKmTime: 5}
+
+	got := Clusters(orders, hub, limits)
+	if len(got) != 2 {
+		t.Fatalf("got %d clusters, want 2: %+v", len(got), got)
+	}
+	north, south := got[0], got[1]
+	if north.ID != 1 || !reflect.DeepEqual(north.OrderIDs, []int64{4, 2, 6}) {
+		t.Errorf("first cluster = %d %v, want 1 [4 2 6]", north.ID, north.OrderIDs)
+	}
+	if south.ID != 2 || !reflect.DeepEqual(south.OrderIDs, []int64{1, 3, 5}) {
+		t.Errorf("second cluster = %d %v, want 2 [1 3 5]", south.ID, south.OrderIDs)
+	}
+
+	if north.Load != (types.Load{WeightKg: 6, Parcels: 4}) {
+		t.Errorf("north load = %+v", north.Load)
+	}
+	for _, c := range got {
+		var members []types.Order
+		for _, o := range orders {
+			if slices.Contains(c.OrderIDs, o.ID) {
+				members = append(members, o)
+			}
+		}
+		if c.EstimatedKm != routeKm(hub, members) || c.EstimatedKm > BudgetKm(limits) {
+			t.Errorf("cluster %d estimated at %.1f km, route is %.1f", c.ID, c.EstimatedKm, routeKm(hub, members))
+		}
+		if want := centroid(members); c.Centroid != want {
+			t.Errorf("cluster %d centroid = %+v, want %+v", c.ID, c.Centroid, want)
+		}
+	}
+
+	// the caller's orders are not reordered
+	if !reflect.DeepEqual(idsOf(orders), []int64{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("orders reordered to %v", idsOf(orders))
+	}
+
+	// with budget for both groups they are one cluster
+	limits.MaxDailyDistance = 200
+	if got := Clusters(orders, hub, limits); len(got) != 1 || len(got[0].OrderIDs) != 6 {
+		t.Errorf("Clusters within budget = %+v, want one cluster", got)
+	}
+	if got := Clusters(nil, hub, limits); got == nil || len(got) != 0 {
+		t.Errorf("Clusters of nothing = %#v, want empty", got)
+	}
+}
+
+func TestClustersSplitUntilSingle(t *testing.T) {
+	// an order out of reach stays a cluster of its own
+	far := []types.Order{{ID: 1, Lat: 14.5, Lng: 77.59}}
+	if got := clusters(far, hub, 10); len(got) != 1 || got[0][0].ID != 1 {
+		t.Errorf("clusters = %v, want the single order", got)
+	}
+
+	// orders at one spot beyond the budget are split down to single orders
+	var spot []types.Order
+	for i := int64(1); i <= 4; i++ {
+		spot = append(spot, types.Order{ID: i, Lat: 13.30, Lng: 77.59})
+	}
+	got := clusters(spot, hub, 10)
+	if len(got) != 4 {
+		t.Fatalf("got %d clusters, want 4", len(got))
+	}
+	for i, cl := range got {
+		if len(cl) != 1 || cl[0].ID != int64(i+1) {
+			t.Errorf("cluster %d = %v", i, idsOf(cl))
+		}
+	}
+}
+
+func TestBisect(t *testing.T) {
+	a, b := bisect(northAndSouth())
+	got := [][]int64{idsOf(a), idsOf(b)}
+	slices.SortFunc(got, func(x, y []int64) int { return int(x[0] - y[0]) })
+	if want := [][]int64{{1, 3, 5}, {2, 4, 6}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("bisect = %v, want north and south apart", got)
+	}
+}
+
+func TestClustersByWarehouse(t *testing.T) {
+	// each warehouse has orders north and south; within budget they make one cluster
+	// per warehouse, never one per spot
+	orders := northAndSouth()
+	for i := range orders {
+		orders[i].WarehouseID = int64(1 + i/3)
+	}
+	hubs := map[int64]types.Location{1: hub, 2: hub}
+	limits := config.Delivery{MaxDailyDistance: 200, MaxDailyTime: 2000, PerKmTime: 5}
+
+	got := clustersByWarehouse(orders, hubs, limits)
+	var ids [][]int64
+	for _, cl := range got {
+		ids = append(ids, idsOf(cl))
+	}
+	if want := [][]int64{{1, 2, 3}, {4, 5, 6}}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("clustersByWarehouse = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,284 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/metrics"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// InsertOrders places pending orders into the routes the agents already have, by cheapest
+// insertion: each order goes to the agent and position where it adds the least distance,
+// keeping every agent within today's km and minute budget and its vehicle capacity, and
+// never making a stop that is on time late. An order is planned for the detour it adds.
+// Orders of closed warehouses, and orders that fit nowhere, stay pending for the next
+// allocation run. It waits while another run holds the allocation lock.
+func InsertOrders(ctx context.Context, s storage.Storage, limits config.Delivery, orderIDs []int64) (types.AllocationResult, error) {
+	return whenFree(ctx, func() (types.AllocationResult, error) {
+		return insert(ctx, s, limits, orderIDs)
+	})
+}
+
+// route is an agent's plan for the day as far as insertion is concerned.
+type route struct {
+	agent     types.Agent
+	from      types.Location    // the last stop delivered today, or the agent's warehouse
+	delivered bool              // from is a stop delivered today
+	stops     []types.RouteStop // still to deliver, in order
+	km        float64           // planned today, delivered stops included
+	minutes   float64
+	load      types.Load // of the stops still to deliver
+}
+
+// insertion is a candidate place for an order: before stops[at] of the agent's route.
+type insertion struct {
+	stop
+	at int
+}
+
+func insert(ctx context.Context, s storage.Storage, limits config.Delivery, orderIDs []int64) (result types.AllocationResult, err error) {
+	start := time.Now()
+	result.Conflicts = []int64{}
+	result.AtRisk = []int64{}
+	result.Unfit = []int64{}
+	defer func() {
+		metrics.ObserveAllocation(start, result.Assigned, result.Deferred, len(result.Conflicts), err)
+	}()
+
+	release, err := acquire(ctx, s)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		if rerr := release(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	agents, err := s.GetCheckedInAgents(ctx)
+	if err != nil {
+		return result, fmt.Errorf("load agents: %w", err)
+	}
+	orders, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		return result, fmt.Errorf("load orders: %w", err)
+	}
+	warehouses, err := s.GetWarehouses(ctx)
+	if err != nil {
+		return result, fmt.Errorf("load warehouses: %w", err)
+	}
+	now := time.Now()
+	stops, err := s.GetRoutes(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		return result, fmt.Errorf("load routes: %w", err)
+	}
+
+	closed := make(map[int64]bool)
+	for _, w := range warehouses {
+		closed[w.ID] = !w.IsOpen(now)
+	}
+
+	wanted := make(map[int64]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		wanted[id] = true
+	}
+	orders = slices.DeleteFunc(orders, func(o types.Order) bool { return !wanted[o.ID] || closed[o.WarehouseID] })
+	agents = slices.DeleteFunc(agents, func(a types.Agent) bool { return closed[a.WarehouseID] })
+	slices.SortStableFunc(orders, byUrgency)
+
+	routes := currentRoutes(agents, warehouses, stops, now)
+
+	for _, order := range orders {
+		if err := ctx.Err(); err != nil {
+			return result, fmt.Errorf("insertion interrupted after %d orders: %w", result.Assigned, err)
+		}
+
+		var best insertion
+		var bestRoute *route
+		fits := false // some vehicle could carry the order if it were empty
+		for _, rt := range routes {
+			if !rt.agent.Fits(order.Load) {
+				continue
+			}
+			fits = true
+			if !rt.agent.Fits(rt.load.Add(order.Load)) {
+				continue
+			}
+			if c, ok := rt.cheapest(order, now, limits); ok && (bestRoute == nil || c.better(best.stop)) {
+				best, bestRoute = c, rt
+			}
+		}
+
+		if bestRoute == nil {
+			if len(routes) > 0 && !fits {
+				result.Unfit = append(result.Unfit, order.ID)
+			} else {
+				result.Deferred++
+			}
+			continue
+		}
+
+		var before int64
+		if best.at < len(bestRoute.stops) {
+			before = bestRoute.stops[best.at].OrderID
+		}
+		minutes := best.km * limits.PerKmTime
+		err := s.InsertOrderIntoRoute(ctx, order.ID, best.agentID, before, best.km, minutes, best.eta)
+		if errors.Is(err, storage.ErrConflict) {
+			result.Conflicts = append(result.Conflicts, order.ID)
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("insert order %d into agent %d's route: %w", order.ID, best.agentID, err)
+		}
+
+		bestRoute.stops = slices.Insert(bestRoute.stops, best.at, stopOf(order, best.agentID))
+		bestRoute.km += best.km
+		bestRoute.minutes += minutes
+		bestRoute.load = bestRoute.load.Add(order.Load)
+		result.Assigned++
+		if !best.onTime {
+			result.AtRisk = append(result.AtRisk, order.ID)
+		}
+	}
+
+	slog.Default().With(slog.String("component", "allocation")).Info("orders inserted into routes",
+		slog.Int("orders", len(orders)),
+		slog.Int("assigned", result.Assigned),
+		slog.Int("deferred", result.Deferred),
+		slog.Any("conflicts", result.Conflicts),
+		slog.Any("at_risk", result.AtRisk),
+		slog.Any("unfit", result.Unfit),
+	)
+	return result, nil
+}
+
+// currentRoutes returns the route of each agent, in the order of agents, from the stops
+// listed by GetRoutes: those still to deliver and those delivered since the start of the
+// day in the agent's warehouse.
+func currentRoutes(agents []types.Agent, warehouses []types.Warehouse, stops []types.RouteStop, now time.Time) []*route {
+	hubs := make(map[int64]types.Location)
+	dayStart := make(map[int64]time.Time)
+	for _, w := range warehouses {
+		hubs[w.ID] = w.Location
+		y, m, d := now.In(w.Zone()).Date()
+		dayStart[w.ID] = time.Date(y, m, d, 0, 0, 0, 0, w.Zone())
+	}
+
+	routes := make([]*route, 0, len(agents))
+	byAgent := make(map[int64]*route, len(agents))
+	for _, a := range agents {
+		rt := &route{agent: a, from: hubs[a.WarehouseID]}
+		routes = append(routes, rt)
+		byAgent[a.ID] = rt
+	}
+	for _, st := range stops {
+		rt, ok := byAgent[st.AgentID]
+		if !ok {
+			continue
+		}
+		if st.DeliveredAt != nil {
+			if st.AssignedAt.Before(dayStart[rt.agent.WarehouseID]) {
+				continue
+			}
+			rt.from = st.Location
+			rt.delivered = true
+		} else {
+			rt.stops = append(rt.stops, st)
+			rt.load = rt.load.Add(st.Load)
+		}
+		rt.km += st.PlannedKm
+		rt.minutes += st.PlannedMinutes
+	}
+	return routes
+}
+
+// cheapest finds the position in the route where order adds the least distance within the
+// agent's budgets, without making a stop that is on time late. Positions that get the order
+// there in its window win over those that do not.
+func (rt *route) cheapest(order types.Order, now time.Time, limits config.Delivery) (insertion, bool) {
+	current := arrivals(rt.from, now, rt.stops, limits.PerKmTime)
+	candidate := stopOf(order, rt.agent.ID)
+
+	var best insertion
+	found := false
+	for at := 0; at <= len(rt.stops); at++ {
+		prev := rt.from
+		if at > 0 {
+			prev = rt.stops[at-1].Location
+		}
+		detour := Distance(prev.Lat, prev.Lng, order.Lat, order.Lng)
+		if at < len(rt.stops) {
+			next := rt.stops[at].Location
+			detour += Distance(order.Lat, order.Lng, next.Lat, next.Lng) - Distance(prev.Lat, prev.Lng, next.Lat, next.Lng)
+		}
+		detour = max(detour, 0) // rounding when the order lies on the way
+		if rt.km+detour > limits.MaxDailyDistance || rt.minutes+detour*limits.PerKmTime > limits.MaxDailyTime {
+			continue
+		}
+
+		planned := arrivals(rt.from, now, slices.Insert(slices.Clone(rt.stops), at, candidate), limits.PerKmTime)
+		late := false
+		for i, st := range rt.stops {
+			j := i // where st ends up once the order is in
+			if i >= at {
+				j++
+			}
+			if onTime(st, current[i]) && !onTime(st, planned[j]) {
+				late = true
+				break
+			}
+		}
+		if late {
+			continue
+		}
+
+		c := insertion{
+			stop: stop{agentID: rt.agent.ID, km: detour, eta: planned[at], onTime: onTime(candidate, planned[at])},
+			at:   at,
+		}
+		if !found || c.better(best.stop) {
+			best, found = c, true
+		}
+	}
+	return best, found
+}
+
+// arrivals returns when an agent setting off from at clock reaches each stop in turn,
+// waiting at stops whose window has not opened yet.
+func arrivals(from types.Location, clock time.Time, stops []types.RouteStop, perKmTime float64) []time.Time {
+	out := make([]time.Time, len(stops))
+	for i, st := range stops {
+		km := Distance(from.Lat, from.Lng, st.Location.Lat, st.Location.Lng)
+		clock = clock.Add(time.Duration(km * perKmTime * float64(time.Minute)))
+		if st.WindowStart != nil && clock.Before(*st.WindowStart) {
+			clock = *st.WindowStart
+		}
+		out[i] = clock
+		from = st.Location
+	}
+	return out
+}
+
+func onTime(st types.RouteStop, eta time.Time) bool {
+	return st.WindowEnd == nil || !eta.After(*st.WindowEnd)
+}
+
+// stopOf returns order as a stop of the agent's route.
+func stopOf(order types.Order, agentID int64) types.RouteStop {
+	return types.RouteStop{
+		AgentID:     agentID,
+		OrderID:     order.ID,
+		Location:    types.Location{Lat: order.Lat, Lng: order.Lng},
+		WindowStart: order.WindowStart,
+		WindowEnd:   order.WindowEnd,
+		Load:        order.Load,
+	}
+}
@@ -0,0 +1,277 @@
+package jobs
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+var hub = types.Location{Lat: 12.97, Lng: 77.59}
+
+// routeOf lists the orders still on an agent's route, in route order.
+func routeOf(t *testing.T, s storage.Storage, agentID int64) []int64 {
+	t.Helper()
+	stops, err := s.GetRoutes(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetRoutes: %v", err)
+	}
+	ids := []int64{}
+	for _, st := range stops {
+		if st.AgentID == agentID && st.DeliveredAt == nil {
+			ids = append(ids, st.OrderID)
+		}
+	}
+	return ids
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+func TestInsertCheapestPosition(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: hub}))
+	ravi := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+	a := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 13.00, Lng: 77.59, WarehouseID: wh}))
+	b := must(t)(s.CreateOrder(ctx, types.Order{Customer: "B", Lat: 13.06, Lng: 77.59, WarehouseID: wh}))
+	if res, err := InsertOrders(ctx, s, testLimits, []int64{a, b}); err != nil || res.Assigned != 2 {
+		t.Fatalf("InsertOrders = %+v, %v; want 2 assigned", res, err)
+	}
+	if got := routeOf(t, s, ravi); !reflect.DeepEqual(got, []int64{a, b}) {
+		t.Fatalf("route = %v, want %v", got, []int64{a, b})
+	}
+
+	// c lies on the way from a to b and d past b
+	c := must(t)(s.CreateOrder(ctx, types.Order{Customer: "C", Lat: 13.03, Lng: 77.595, WarehouseID: wh}))
+	d := must(t)(s.CreateOrder(ctx, types.Order{Customer: "D", Lat: 13.09, Lng: 77.59, WarehouseID: wh}))
+	// orders not asked for are left alone
+	other := must(t)(s.CreateOrder(ctx, types.Order{Customer: "E", Lat: 12.98, Lng: 77.59, WarehouseID: wh}))
+	res, err := InsertOrders(ctx, s, testLimits, []int64{d, c})
+	if err != nil || res.Assigned != 2 || res.Deferred != 0 || len(res.AtRisk) != 0 {
+		t.Fatalf("InsertOrders = %+v, %v; want 2 assigned", res, err)
+	}
+	if got, want := routeOf(t, s, ravi), []int64{a, c, b, d}; !reflect.DeepEqual(got, want) {
+		t.Errorf("route = %v, want %v", got, want)
+	}
+	if agentOf(t, s, other) != 0 {
+		t.Errorf("order %d was not asked for but got assigned", other)
+	}
+
+	// each is planned for the detour it adds
+	detour := Distance(13.00, 77.59, 13.03, 77.595) + Distance(13.03, 77.595, 13.06, 77.59) - Distance(13.00, 77.59, 13.06, 77.59)
+	for id, want := range map[int64]float64{c: detour, d: Distance(13.06, 77.59, 13.09, 77.59)} {
+		as, err := s.GetOpenAssignment(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !near(as.PlannedKm, want) || !near(as.PlannedMinutes, want*testLimits.PerKmTime) {
+			t.Errorf("order %d planned %.3f km %.3f min, want %.3f km", id, as.PlannedKm, as.PlannedMinutes, want)
+		}
+	}
+}
+
+func TestInsertCheapestAgent(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: hub}))
+	ravi := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+	north := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 13.06, Lng: 77.59, WarehouseID: wh}))
+	if res, err := InsertOrders(ctx, s, testLimits, []int64{north}); err != nil || res.Assigned != 1 {
+		t.Fatalf("InsertOrders = %+v, %v", res, err)
+	}
+	asha := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Asha", WarehouseID: wh}))
+
+	// just past Ravi's stop is a km from him and ten from Asha at the hub; just south of
+	// the hub is a km from Asha and a two km detour for Ravi
+	further := must(t)(s.CreateOrder(ctx, types.Order{Customer: "B", Lat: 13.07, Lng: 77.59, WarehouseID: wh}))
+	south := must(t)(s.CreateOrder(ctx, types.Order{Customer: "C", Lat: 12.96, Lng: 77.59, WarehouseID: wh}))
+	if res, err := InsertOrders(ctx, s, testLimits, []int64{further, south}); err != nil || res.Assigned != 2 {
+		t.Fatalf("InsertOrders = %+v, %v", res, err)
+	}
+	if got := agentOf(t, s, further); got != ravi {
+		t.Errorf("order %d went to agent %d, want %d", further, got, ravi)
+	}
+	if got := agentOf(t, s, south); got != asha {
+		t.Errorf("order %d went to agent %d, want %d", south, got, asha)
+	}
+}
+
+func TestInsertCapacity(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: hub}))
+	bike := types.Capacity{Vehicle: "bike", MaxWeightKg: 10, MaxParcels: 1}
+	must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh, Capacity: bike}))
+	first := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 13.00, Lng: 77.59, WarehouseID: wh,
+		Load: types.Load{WeightKg: 2, Parcels: 1}}))
+	if res, err := InsertOrders(ctx, s, testLimits, []int64{first}); err != nil || res.Assigned != 1 {
+		t.Fatalf("InsertOrders = %+v, %v", res, err)
+	}
+
+	// the bike is full, and no vehicle carries 40 kg
+	second := must(t)(s.CreateOrder(ctx, types.Order{Customer: "B", Lat: 13.00, Lng: 77.60, WarehouseID: wh,
+		Load: types.Load{WeightKg: 2, Parcels: 1}}))
+	heavy := must(t)(s.CreateOrder(ctx, types.Order{Customer: "C", Lat: 13.00, Lng: 77.60, WarehouseID: wh,
+		Load: types.Load{WeightKg: 40, Parcels: 1}}))
+	res, err := InsertOrders(ctx, s, testLimits, []int64{second, heavy})
+	if err != nil {
+		t.Fatalf("InsertOrders: %v", err)
+	}
+	if res.Assigned != 0 || res.Deferred != 1 || !reflect.DeepEqual(res.Unfit, []int64{heavy}) {
+		t.Errorf("result = %+v, want 1 deferred and order %d unfit", res, heavy)
+	}
+
+	// a van further away takes the order the bike has no room for
+	van := types.Capacity{Vehicle: "van", MaxWeightKg: 500, MaxParcels: 50}
+	asha := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Asha", WarehouseID: wh, Capacity: van}))
+	res, err = InsertOrders(ctx, s, testLimits, []int64{second})
+	if err != nil || res.Assigned != 1 {
+		t.Fatalf("InsertOrders = %+v, %v", res, err)
+	}
+	if got := agentOf(t, s, second); got != asha {
+		t.Errorf("order %d went to agent %d, want %d", second, got, asha)
+	}
+}
+
+func TestInsertKeepsStopsOnTime(t *testing.T) {
+	for _, windowed := range []bool{false, true} {
+		ctx := context.Background()
+		s := newStore()
+
+		wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: hub}))
+		ravi := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+		// about 11 km north, reached in 56 minutes
+		first := types.Order{Customer: "A", Lat: 13.07, Lng: 77.59, WarehouseID: wh}
+		if windowed {
+			end := time.Now().Add(time.Hour)
+			first.WindowEnd = &end
+		}
+		a := must(t)(s.CreateOrder(ctx, first))
+		if res, err := InsertOrders(ctx, s, testLimits, []int64{a}); err != nil || res.Assigned != 1 || len(res.AtRisk) != 0 {
+			t.Fatalf("InsertOrders = %+v, %v", res, err)
+		}
+
+		// b is cheapest on the way to a, but the two km detour would get to a after its window
+		b := must(t)(s.CreateOrder(ctx, types.Order{Customer: "B", Lat: 13.02, Lng: 77.62, WarehouseID: wh}))
+		if res, err := InsertOrders(ctx, s, testLimits, []int64{b}); err != nil || res.Assigned != 1 {
+			t.Fatalf("InsertOrders = %+v, %v", res, err)
+		}
+		want := []int64{b, a}
+		if windowed {
+			want = []int64{a, b}
+		}
+		if got := routeOf(t, s, ravi); !reflect.DeepEqual(got, want) {
+			t.Errorf("windowed %v: route = %v, want %v", windowed, got, want)
+		}
+	}
+}
+
+func TestInsertAtRisk(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: hub}))
+	must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+	// an hour away and due in ten minutes: delivered late rather than not at all
+	end := time.Now().Add(10 * time.Minute)
+	id := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 13.08, Lng: 77.59, WarehouseID: wh, WindowEnd: &end}))
+
+	res, err := InsertOrders(ctx, s, testLimits, []int64{id})
+	if err != nil {
+		t.Fatalf("InsertOrders: %v", err)
+	}
+	if res.Assigned != 1 || !reflect.DeepEqual(res.AtRisk, []int64{id}) {
+		t.Errorf("result = %+v, want order %d assigned at risk", res, id)
+	}
+}
+
+func TestInsertSkipsClosedWarehouse(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	today := time.Now().UTC().Format("2006-01-02")
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: hub,
+		WarehouseCalendar: types.WarehouseCalendar{Timezone: "UTC", Holidays: []string{today}}}))
+	must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+	id := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh}))
+
+	res, err := InsertOrders(ctx, s, testLimits, []int64{id})
+	if err != nil {
+		t.Fatalf("InsertOrders: %v", err)
+	}
+	if res.Assigned != 0 || res.Deferred != 0 || agentOf(t, s, id) != 0 {
+		t.Errorf("result = %+v, want the closed warehouse left alone", res)
+	}
+}
+
+func TestCheapest(t *testing.T) {
+	now := time.Now()
+	later := now.Add(3 * time.Hour)
+	// the agent waits at the first stop until its window opens in three hours
+	waiting := types.RouteStop{OrderID: 1, Location: types.Location{Lat: 13.07, Lng: 77.59}, WindowStart: &later}
+	order := types.Order{ID: 2, Lat: 13.08, Lng: 77.59}
+
+	rt := &route{agent: types.Agent{ID: 7}, from: hub, stops: []types.RouteStop{waiting}}
+	c, ok := rt.cheapest(order, now, testLimits)
+	if !ok || c.at != 1 || !near(c.km, Distance(13.07, 77.59, 13.08, 77.59)) || !c.onTime || c.agentID != 7 {
+		t.Errorf("cheapest = %+v, %v; want after the first stop", c, ok)
+	}
+	if want := later.Add(time.Duration(c.km * testLimits.PerKmTime * float64(time.Minute))); !c.eta.Equal(want) {
+		t.Errorf("eta = %v, want %v", c.eta, want)
+	}
+
+	// due in two hours, it must go first though the detour is longer
+	due := now.Add(2 * time.Hour)
+	order.WindowEnd = &due
+	c, ok = rt.cheapest(order, now, testLimits)
+	if !ok || c.at != 0 || !c.onTime {
+		t.Errorf("cheapest = %+v, %v; want before the first stop", c, ok)
+	}
+
+	// with the day's km nearly used up nothing fits
+	rt.km = testLimits.MaxDailyDistance - 1
+	if c, ok := rt.cheapest(order, now, testLimits); ok {
+		t.Errorf("cheapest = %+v beyond the day's km", c)
+	}
+	rt.km, rt.minutes = 0, testLimits.MaxDailyTime-1
+	if c, ok := rt.cheapest(order, now, testLimits); ok {
+		t.Errorf("cheapest = %+v beyond the day's minutes", c)
+	}
+}
+
+func TestCurrentRoutes(t *testing.T) {
+	now := time.Now()
+	yesterday := now.Add(-25 * time.Hour)
+	wh := types.Warehouse{ID: 1, Location: hub, WarehouseCalendar: types.WarehouseCalendar{Timezone: "UTC"}}
+	agents := []types.Agent{{ID: 7, WarehouseID: 1}, {ID: 8, WarehouseID: 1}}
+	done := types.Location{Lat: 13.0, Lng: 77.6}
+	stops := []types.RouteStop{
+		{AgentID: 7, OrderID: 1, Location: types.Location{Lat: 12.9, Lng: 77.6}, PlannedKm: 9, PlannedMinutes: 45,
+			AssignedAt: yesterday, DeliveredAt: &yesterday},
+		{AgentID: 7, OrderID: 2, Location: done, PlannedKm: 4, PlannedMinutes: 20, AssignedAt: now, DeliveredAt: &now},
+		{AgentID: 7, OrderID: 3, Location: types.Location{Lat: 13.1, Lng: 77.6}, PlannedKm: 11, PlannedMinutes: 55,
+			AssignedAt: now, Load: types.Load{Parcels: 2}},
+		{AgentID: 9, OrderID: 4, AssignedAt: now},
+	}
+
+	routes := currentRoutes(agents, []types.Warehouse{wh}, stops, now)
+	if len(routes) != 2 {
+		t.Fatalf("got %d routes, want 2", len(routes))
+	}
+	// yesterday's delivery does not count; today's is where the agent sets off from
+	ravi := routes[0]
+	if ravi.from != done || !ravi.delivered || len(ravi.stops) != 1 || ravi.stops[0].OrderID != 3 ||
+		ravi.km != 15 || ravi.minutes != 75 || ravi.load.Parcels != 2 {
+		t.Errorf("route = %+v", ravi)
+	}
+	if asha := routes[1]; asha.from != hub || asha.delivered || len(asha.stops) != 0 || asha.km != 0 {
+		t.Errorf("idle agent's route = %+v", asha)
+	}
+}
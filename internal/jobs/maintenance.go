@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/metrics"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// EscalateOverdue flags assignments still undelivered after the given age. Each one is
+// logged at warn level once so that alerting can pick it up.
+func EscalateOverdue(ctx context.Context, s storage.Storage, after time.Duration) (int, error) {
+	overdue, err := s.EscalateOverdueAssignments(ctx, time.Now().Add(-after))
+	if err != nil {
+		return 0, fmt.Errorf("escalate overdue assignments: %w", err)
+	}
+
+	log := slog.Default().With(slog.String("component", "escalation"))
+	for _, a := range overdue {
+		log.Warn("order overdue",
+			slog.Int64("order_id", a.OrderID),
+			slog.Int64("agent_id", a.AgentID),
+			slog.Time("assigned_at", a.AssignedAt),
+		)
+	}
+	metrics.ObserveEscalations(len(overdue))
+
+	return len(overdue), nil
+}
+
+// DailyReport summarises the previous calendar day in loc.
+func DailyReport(ctx context.Context, s storage.Storage, loc *time.Location) (types.DailyReport, error) {
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	from := to.AddDate(0, 0, -1)
+
+	report, err := s.GetDailyReport(ctx, from, to)
+	if err != nil {
+		return report, fmt.Errorf("daily report: %w", err)
+	}
+
+	slog.Default().Info("daily report",
+		slog.String("component", "report"),
+		slog.String("day", from.Format("2006-01-02")),
+		slog.Int("orders_assigned", report.OrdersAssigned),
+		slog.Int("orders_delivered", report.OrdersDelivered),
+		slog.Int("active_agents", report.ActiveAgents),
+		slog.Float64("planned_km", report.PlannedKm),
+		slog.Float64("actual_km", report.ActualKm),
+	)
+	return report, nil
+}
+
+// PurgeDelivered deletes orders delivered longer than retainFor ago, and the photos and
+// signatures of their proofs from blobs. A file that cannot be deleted is logged and left
+// behind; the rows are gone by then.
+func PurgeDelivered(ctx context.Context, s storage.Storage, blobs storage.BlobStore, retainFor time.Duration) (int, error) {
+	n, keys, err := s.PurgeDeliveredBefore(ctx, time.Now().Add(-retainFor))
+	if err != nil {
+		return 0, fmt.Errorf("purge delivered orders: %w", err)
+	}
+
+	log := slog.Default().With(slog.String("component", "retention"))
+	deleted := 0
+	for _, key := range keys {
+		if err := blobs.Delete(ctx, key); err != nil {
+			log.Warn("failed to delete proof file", slog.String("key", key), slog.String("error", err.Error()))
+			continue
+		}
+		deleted++
+	}
+
+	log.Info("retention cleanup finished", slog.Int("purged", n), slog.Int("files_deleted", deleted))
+	return n, nil
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/disk"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+func TestPurgeDeliveredDeletesProofFiles(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+	blobs, err := disk.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}}))
+	agent := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+	id := must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: wh}))
+	if err := s.AssignOrderToAgent(ctx, id, agent, 1.5, 7.5, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.GetOpenAssignment(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := types.DeliveryProof{OrderID: id, AssignmentID: a.ID, AgentID: agent, OTPVerified: true}
+	for _, key := range []string{"1/1-photo.jpg", "1/1-signature.png"} {
+		size, err := blobs.Put(ctx, key, strings.NewReader("image"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := &types.ProofArtefact{Key: key, ContentType: "image/jpeg", Size: size}
+		if p.Photo == nil {
+			p.Photo = f
+		} else {
+			p.Signature = f
+		}
+	}
+	if err := s.CompleteDeliveryWithProof(ctx, p, 1.5, 7.5); err != nil {
+		t.Fatal(err)
+	}
+
+	// a negative retention purges what was delivered just now
+	n, err := PurgeDelivered(ctx, s, blobs, -time.Minute)
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeDelivered = %d, %v; want 1", n, err)
+	}
+	for _, key := range []string{p.Photo.Key, p.Signature.Key} {
+		if _, err := blobs.Open(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("file %s after purge: got %v, want ErrNotFound", key, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// Reassign moves an order to agentID at the end of the agent's current route. The new
+// assignment is planned like an allocation would plan it: the leg from the agent's last
+// stop, or its warehouse, and the ETA of getting there after the stops still to deliver.
+// The daily limits are not checked; moving an order is the operator's call.
+func Reassign(ctx context.Context, s storage.Storage, limits config.Delivery, orderID, agentID int64, reason string) error {
+	order, err := s.GetOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	agents, err := s.GetCheckedInAgents(ctx)
+	if err != nil {
+		return fmt.Errorf("load agents: %w", err)
+	}
+	var agent *types.Agent
+	for i := range agents {
+		if agents[i].ID == agentID {
+			agent = &agents[i]
+		}
+	}
+	if agent == nil {
+		return fmt.Errorf("agent %d is not checked in: %w", agentID, storage.ErrConflict)
+	}
+	warehouses, err := s.GetWarehouses(ctx)
+	if err != nil {
+		return fmt.Errorf("load warehouses: %w", err)
+	}
+	now := time.Now()
+	stops, err := s.GetRoutes(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		return fmt.Errorf("load routes: %w", err)
+	}
+
+	hubs := make(map[int64]types.Location)
+	for _, w := range warehouses {
+		hubs[w.ID] = w.Location
+	}
+	rt := currentRoutes([]types.Agent{*agent}, warehouses, stops, now)[0]
+	from, clock := startOf(*agent, order, hubs), now
+	if n := len(rt.stops); n > 0 {
+		from = rt.stops[n-1].Location
+		clock = arrivals(rt.from, now, rt.stops, limits.PerKmTime)[n-1]
+	} else if rt.delivered {
+		from = rt.from
+	}
+
+	km := Distance(from.Lat, from.Lng, order.Lat, order.Lng)
+	st := plan(order, agentID, km, clock, limits.PerKmTime)
+	return s.ReassignOrder(ctx, orderID, agentID, st.km, st.km*limits.PerKmTime, st.eta, reason)
+}
@@ -0,0 +1,308 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+var (
+	ErrRunNotFound = errors.New("allocation run not found")
+	ErrRunFinished = errors.New("allocation run has already finished")
+	ErrQueueFull   = errors.New("too many allocation runs waiting, try again later")
+)
+
+// Statuses of an allocation run. Canceling means cancel was requested while the run was
+// assigning orders; it becomes canceled once the run has stopped.
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunCanceling = "canceling"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+	RunCanceled  = "canceled"
+)
+
+type run struct {
+	types.AllocationRun
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (r *run) finished() bool {
+	return r.Status == RunSucceeded || r.Status == RunFailed || r.Status == RunCanceled
+}
+
+// Runner runs allocations submitted over the API on a fixed pool of workers and keeps
+// their progress in memory. Runs are visible only to the instance that accepted them.
+type Runner struct {
+	store  storage.Storage
+	limits config.Delivery
+	cfg    config.Allocation
+	log    *slog.Logger
+	queue  chan *run
+
+	mu       sync.Mutex
+	runs     map[string]*run
+	finished []string // IDs of finished runs, oldest first
+	ctx      context.Context
+
+	wg sync.WaitGroup
+}
+
+func NewRunner(store storage.Storage, limits config.Delivery, cfg config.Allocation) *Runner {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = lockTTL
+	}
+	if cfg.KeepFinished <= 0 {
+		cfg.KeepFinished = 100
+	}
+
+	return &Runner{
+		store:  store,
+		limits: limits,
+		cfg:    cfg,
+		log:    slog.Default().With(slog.String("component", "allocation_runner")),
+		queue:  make(chan *run, cfg.QueueSize),
+		runs:   make(map[string]*run),
+		ctx:    context.Background(),
+	}
+}
+
+// Start runs the workers until ctx is cancelled. Runs in progress at that point are
+// interrupted and runs still queued are canceled.
+func (r *Runner) Start(ctx context.Context) {
+	r.mu.Lock()
+	r.ctx = ctx
+	r.mu.Unlock()
+
+	for i := 0; i < r.cfg.Workers; i++ {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.work(ctx)
+		}()
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		<-ctx.Done()
+		for {
+			select {
+			case q := <-r.queue:
+				r.finish(q, types.AllocationResult{}, ctx.Err())
+			default:
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until every worker has returned.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// Submit queues a new allocation run and returns it in the queued state.
+func (r *Runner) Submit() (types.AllocationRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.ctx.Err(); err != nil {
+		return types.AllocationRun{}, fmt.Errorf("allocation runner stopped: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	q := &run{
+		AllocationRun: types.AllocationRun{
+			ID:        newRunID(),
+			Status:    RunQueued,
+			Conflicts: []int64{},
+			AtRisk:    []int64{},
+			Unfit:     []int64{},
+			CreatedAt: time.Now().UTC(),
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	select {
+	case r.queue <- q:
+	default:
+		cancel()
+		return types.AllocationRun{}, ErrQueueFull
+	}
+	r.runs[q.ID] = q
+	return snapshot(q), nil
+}
+
+// Get returns the current state of a run.
+func (r *Runner) Get(id string) (types.AllocationRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q, ok := r.runs[id]
+	if !ok {
+		return types.AllocationRun{}, fmt.Errorf("%s: %w", id, ErrRunNotFound)
+	}
+	return snapshot(q), nil
+}
+
+// Cancel stops a run. A queued run is canceled at once; a running one stops before its
+// next order, keeping the assignments it already made.
+func (r *Runner) Cancel(id string) (types.AllocationRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q, ok := r.runs[id]
+	if !ok {
+		return types.AllocationRun{}, fmt.Errorf("%s: %w", id, ErrRunNotFound)
+	}
+	if q.finished() {
+		return snapshot(q), fmt.Errorf("%s: %w", id, ErrRunFinished)
+	}
+
+	q.cancel()
+	switch q.Status {
+	case RunQueued:
+		r.done(q, RunCanceled, context.Canceled)
+	case RunRunning:
+		q.Status = RunCanceling
+	}
+	return snapshot(q), nil
+}
+
+func (r *Runner) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case q := <-r.queue:
+			r.execute(q)
+		}
+	}
+}
+
+func (r *Runner) execute(q *run) {
+	r.mu.Lock()
+	canceled := q.finished()
+	r.mu.Unlock()
+	if canceled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(q.ctx, r.cfg.Timeout)
+	defer cancel()
+
+	log := r.log.With(slog.String("run_id", q.ID))
+	report := func(processed, total int, res types.AllocationResult, conflict int64) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if q.Status == RunQueued {
+			now := time.Now().UTC()
+			q.Status = RunRunning
+			q.StartedAt = &now
+			log.Info("allocation run started", slog.Int("orders", total))
+		}
+		q.Total = total
+		q.Processed = processed
+		q.Assigned = res.Assigned
+		q.Deferred = res.Deferred
+		q.AtRisk = append(q.AtRisk[:0], res.AtRisk...)
+		q.Unfit = append(q.Unfit[:0], res.Unfit...)
+		if conflict != 0 {
+			q.Conflicts = append(q.Conflicts, conflict)
+		}
+	}
+
+	// stays queued until a scheduled run or another instance lets go of the lock
+	res, err := allocateWhenFree(ctx, r.store, r.limits, 0, report)
+	r.finish(q, res, err)
+}
+
+// finish records the outcome of a run, or of a queued run dropped at shutdown.
+func (r *Runner) finish(q *run, res types.AllocationResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if q.finished() {
+		return
+	}
+
+	q.Assigned = res.Assigned
+	q.Deferred = res.Deferred
+	q.AtRisk = append(q.AtRisk[:0], res.AtRisk...)
+	q.Unfit = append(q.Unfit[:0], res.Unfit...)
+	switch {
+	case err == nil:
+		r.done(q, RunSucceeded, nil)
+	case errors.Is(err, context.Canceled):
+		r.done(q, RunCanceled, err)
+	default:
+		r.done(q, RunFailed, err)
+	}
+}
+
+// done moves q to a final status and forgets the oldest finished runs beyond
+// KeepFinished. The caller holds r.mu.
+func (r *Runner) done(q *run, status string, err error) {
+	now := time.Now().UTC()
+	q.Status = status
+	q.FinishedAt = &now
+	if err != nil {
+		q.Error = err.Error()
+	}
+	q.cancel()
+
+	attrs := []any{
+		slog.String("run_id", q.ID),
+		slog.String("status", status),
+		slog.Int("assigned", q.Assigned),
+		slog.Int("deferred", q.Deferred),
+		slog.Int("conflicts", len(q.Conflicts)),
+		slog.Int("at_risk", len(q.AtRisk)),
+		slog.Int("unfit", len(q.Unfit)),
+	}
+	if status == RunFailed {
+		r.log.Error("allocation run failed", append(attrs, slog.String("error", q.Error))...)
+	} else {
+		r.log.Info("allocation run finished", attrs...)
+	}
+
+	r.finished = append(r.finished, q.ID)
+	for len(r.finished) > r.cfg.KeepFinished {
+		delete(r.runs, r.finished[0])
+		r.finished = r.finished[1:]
+	}
+}
+
+func snapshot(q *run) types.AllocationRun {
+	s := q.AllocationRun
+	s.Conflicts = append([]int64{}, q.Conflicts...)
+	s.AtRisk = append([]int64{}, q.AtRisk...)
+	s.Unfit = append([]int64{}, q.Unfit...)
+	return s
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// SLA statuses reported by LateOrders.
+const (
+	// SLALate is an order still undelivered after its window closed.
+	SLALate = "late"
+	// SLAAtRisk is an order whose planned ETA falls after its window.
+	SLAAtRisk = "at_risk"
+)
+
+// LateOrders lists undelivered orders that missed their window by now or are predicted
+// to miss it, most overdue window first. Orders not yet assigned only show up once late.
+func LateOrders(ctx context.Context, s storage.Storage, now time.Time) ([]types.SLAOrder, error) {
+	windowed, err := s.GetWindowedOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load windowed orders: %w", err)
+	}
+
+	late := []types.SLAOrder{}
+	for _, o := range windowed {
+		switch {
+		case o.WindowEnd.Before(now):
+			o.Status = SLALate
+			o.MinutesLate = now.Sub(o.WindowEnd).Minutes()
+		case o.ETA != nil && o.ETA.After(o.WindowEnd):
+			o.Status = SLAAtRisk
+			o.MinutesLate = o.ETA.Sub(o.WindowEnd).Minutes()
+		default:
+			continue
+		}
+		late = append(late, o)
+	}
+	return late, nil
+}
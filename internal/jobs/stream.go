@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+)
+
+// Streamer allocates orders shortly after they are created by inserting them into the
+// agents' current routes. Orders are gathered for cfg.Debounce after the first one of a
+// batch, so that a bulk import is one insertion run rather than thousands. A nil or
+// disabled Streamer ignores everything it is given.
+type Streamer struct {
+	store  storage.Storage
+	limits config.Delivery
+	cfg    config.Streaming
+	log    *slog.Logger
+	in     chan []int64
+
+	wg sync.WaitGroup
+}
+
+func NewStreamer(store storage.Storage, limits config.Delivery, cfg config.Streaming) *Streamer {
+	if cfg.Debounce <= 0 {
+		cfg.Debounce = 2 * time.Second
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = 1000
+	}
+
+	return &Streamer{
+		store:  store,
+		limits: limits,
+		cfg:    cfg,
+		log:    slog.Default().With(slog.String("component", "allocation_stream")),
+		in:     make(chan []int64, 64),
+	}
+}
+
+// Enabled reports whether new orders are allocated as they come in.
+func (s *Streamer) Enabled() bool {
+	return s != nil && s.cfg.Enabled
+}
+
+// Enqueue hands newly created orders to the worker. It never blocks: when the worker is
+// too far behind the orders are left for the next allocation run.
+func (s *Streamer) Enqueue(orderIDs ...int64) {
+	if !s.Enabled() || len(orderIDs) == 0 {
+		return
+	}
+	select {
+	case s.in <- orderIDs:
+	default:
+		s.log.Warn("allocation stream is behind, orders left for the next run", slog.Int("orders", len(orderIDs)))
+	}
+}
+
+// Start runs the worker until ctx is cancelled. Orders still gathering then stay pending.
+func (s *Streamer) Start(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.work(ctx)
+	}()
+	s.log.Info("allocation streaming enabled", slog.Duration("debounce", s.cfg.Debounce), slog.Int("max_batch", s.cfg.MaxBatch))
+}
+
+// Wait blocks until the worker has stopped.
+func (s *Streamer) Wait() {
+	if s != nil {
+		s.wg.Wait()
+	}
+}
+
+func (s *Streamer) work(ctx context.Context) {
+	var pending []int64
+	var due <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			if len(pending) > 0 {
+				s.log.Info("allocation stream stopped, orders left for the next run", slog.Int("orders", len(pending)))
+			}
+			return
+		case ids := <-s.in:
+			if len(pending) == 0 {
+				due = time.After(s.cfg.Debounce)
+			}
+			pending = append(pending, ids...)
+			if len(pending) < s.cfg.MaxBatch {
+				continue
+			}
+		case <-due:
+		}
+
+		s.flush(ctx, pending)
+		pending, due = nil, nil
+	}
+}
+
+func (s *Streamer) flush(ctx context.Context, orderIDs []int64) {
+	ctx, cancel := context.WithTimeout(ctx, lockTTL)
+	defer cancel()
+
+	res, err := InsertOrders(ctx, s.store, s.limits, orderIDs)
+	if err != nil {
+		s.log.Error("streaming allocation failed", slog.Int("orders", len(orderIDs)), slog.String("error", err.Error()))
+		return
+	}
+	s.log.Info("streaming allocation finished",
+		slog.Int("orders", len(orderIDs)),
+		slog.Int("assigned", res.Assigned),
+		slog.Int("deferred", res.Deferred),
+		slog.Int("conflicts", len(res.Conflicts)),
+		slog.Int("at_risk", len(res.AtRisk)),
+		slog.Int("unfit", len(res.Unfit)),
+	)
+}
@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// streamed returns a store with a checked-in agent and n pending orders, and a started
+// streamer over it that stops with the test.
+func streamed(t *testing.T, cfg config.Streaming, n int) (storage.Storage, *Streamer, []int64) {
+	t.Helper()
+	ctx := context.Background()
+	s := newStore()
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: hub}))
+	must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98 + float64(i)/100, Lng: 77.60, WarehouseID: wh}))
+	}
+
+	cfg.Enabled = true
+	st := NewStreamer(s, testLimits, cfg)
+	ctx, cancel := context.WithCancel(ctx)
+	st.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		st.Wait()
+	})
+	return s, st, ids
+}
+
+// assigned waits up to a second for every order to be given an agent.
+func assigned(t *testing.T, s storage.Storage, ids ...int64) bool {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		done := true
+		for _, id := range ids {
+			done = done && agentOf(t, s, id) != 0
+		}
+		if done || time.Now().After(deadline) {
+			return done
+		}
+	}
+}
+
+func TestStreamerDisabled(t *testing.T) {
+	var nothing *Streamer
+	nothing.Enqueue(1)
+	nothing.Start(context.Background())
+	nothing.Wait()
+	if nothing.Enabled() {
+		t.Error("nil Streamer enabled")
+	}
+
+	st := NewStreamer(newStore(), testLimits, config.Streaming{})
+	st.Start(context.Background())
+	st.Enqueue(1, 2)
+	if st.Enabled() || len(st.in) != 0 {
+		t.Errorf("disabled Streamer took %d batches", len(st.in))
+	}
+	st.Wait()
+}
+
+func TestStreamerDebounce(t *testing.T) {
+	s, st, ids := streamed(t, config.Streaming{Debounce: 200 * time.Millisecond}, 3)
+
+	// orders coming in one by one are gathered into a single run
+	for _, id := range ids {
+		st.Enqueue(id)
+	}
+	st.Enqueue()
+	time.Sleep(50 * time.Millisecond)
+	for _, id := range ids {
+		if agentOf(t, s, id) != 0 {
+			t.Fatalf("order %d assigned before the debounce", id)
+		}
+	}
+	if !assigned(t, s, ids...) {
+		t.Error("orders not assigned after the debounce")
+	}
+}
+
+func TestStreamerMaxBatch(t *testing.T) {
+	s, st, ids := streamed(t, config.Streaming{Debounce: time.Hour, MaxBatch: 2}, 3)
+
+	// a full batch goes at once; the order after it waits out the debounce
+	st.Enqueue(ids[0], ids[1])
+	st.Enqueue(ids[2])
+	if !assigned(t, s, ids[0], ids[1]) {
+		t.Error("full batch not assigned")
+	}
+	if agentOf(t, s, ids[2]) != 0 {
+		t.Errorf("order %d assigned before its batch was full", ids[2])
+	}
+}
@@ -0,0 +1,194 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dms"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	allocationDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "allocation_run_duration_seconds",
+		Help:      "Duration of allocation runs.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	allocationRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "allocation_runs_total",
+		Help:      "Allocation runs by result.",
+	}, []string{"result"})
+
+	ordersAssigned = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "allocation_orders_assigned",
+		Help:      "Orders assigned per allocation run.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	ordersDeferred = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "allocation_orders_deferred",
+		Help:      "Orders left unassigned per allocation run.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	allocationConflicts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "allocation_conflicts_total",
+		Help:      "Orders an allocation run found already claimed by another run.",
+	})
+
+	jobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Scheduled job runs by job and result.",
+	}, []string{"job", "result"})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of scheduled job runs.",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"job"})
+
+	overdueEscalations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "overdue_escalations_total",
+		Help:      "Undelivered assignments escalated as overdue.",
+	})
+
+	notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Customer notifications handled by channel and outcome: sent, retry, failed or skipped.",
+	}, []string{"channel", "outcome"})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Storage query latency by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"op"})
+
+	registry = prometheus.NewRegistry()
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		allocationDuration,
+		allocationRuns,
+		ordersAssigned,
+		ordersDeferred,
+		allocationConflicts,
+		jobRuns,
+		jobDuration,
+		overdueEscalations,
+		notifications,
+		dbDuration,
+	)
+}
+
+// Register adds extra collectors, such as the warehouse gauges, to the registry served on /metrics.
+func Register(c prometheus.Collector) {
+	registry.MustRegister(c)
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records request count and latency labelled by the mux route template.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		status := strconv.Itoa(rec.status)
+		httpRequests.WithLabelValues(r.Method, route, status).Inc()
+		httpDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveAllocation records one allocation run.
+func ObserveAllocation(start time.Time, assigned, deferred, conflicts int, err error) {
+	allocationDuration.Observe(time.Since(start).Seconds())
+	allocationConflicts.Add(float64(conflicts))
+	if err != nil {
+		allocationRuns.WithLabelValues("error").Inc()
+		return
+	}
+	allocationRuns.WithLabelValues("ok").Inc()
+	ordersAssigned.Observe(float64(assigned))
+	ordersDeferred.Observe(float64(deferred))
+}
+
+// ObserveJob records one scheduled job run.
+func ObserveJob(name string, start time.Time, err error) {
+	jobDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	jobRuns.WithLabelValues(name, result).Inc()
+}
+
+// ObserveEscalations counts assignments escalated by the overdue job.
+func ObserveEscalations(n int) {
+	overdueEscalations.Add(float64(n))
+}
+
+// ObserveNotification counts one notification handled on channel.
+func ObserveNotification(channel, outcome string) {
+	notifications.WithLabelValues(channel, outcome).Inc()
+}
+
+// ObserveQuery records the latency of a storage operation. Use it as
+// defer metrics.ObserveQuery("op", time.Now()).
+func ObserveQuery(op string, start time.Time) {
+	dbDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,130 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/metrics"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// scrape returns the /metrics page.
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", rec.Code)
+	}
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func expectSeries(t *testing.T, page string, series ...string) {
+	t.Helper()
+	for _, s := range series {
+		if !strings.Contains(page, "\n"+s+"\n") {
+			t.Errorf("missing %s", s)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(metrics.Middleware)
+	r.HandleFunc("/api/test/orders/{order_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}).Methods(http.MethodPost)
+	r.HandleFunc("/api/test/agents", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+	for _, path := range []string{"/api/test/orders/7", "/api/test/orders/8"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/test/agents", nil))
+
+	// a request no route matches never reaches mux middleware, so wrap the router itself
+	metrics.Middleware(r).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/test/nowhere", nil))
+
+	page := scrape(t)
+	expectSeries(t, page,
+		`dms_http_requests_total{method="POST",route="/api/test/orders/{order_id}",status="409"} 2`,
+		`dms_http_requests_total{method="GET",route="/api/test/agents",status="200"} 1`,
+		`dms_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`dms_http_request_duration_seconds_count{method="POST",route="/api/test/orders/{order_id}",status="409"} 2`,
+	)
+	// order ids are not labels
+	if strings.Contains(page, "/api/test/orders/7") {
+		t.Error("request path used as a label")
+	}
+}
+
+func TestObserve(t *testing.T) {
+	start := time.Now()
+	metrics.ObserveAllocation(start, 3, 1, 2, nil)
+	metrics.ObserveAllocation(start, 0, 0, 0, errors.New("lock held"))
+	metrics.ObserveJob("test_job", start, nil)
+	metrics.ObserveJob("test_job", start, errors.New("timeout"))
+	metrics.ObserveEscalations(4)
+	metrics.ObserveNotification("test_sms", "sent")
+	metrics.ObserveQuery("test_query", start)
+
+	expectSeries(t, scrape(t),
+		`dms_allocation_runs_total{result="ok"} 1`,
+		`dms_allocation_runs_total{result="error"} 1`,
+		`dms_allocation_conflicts_total 2`,
+		`dms_allocation_orders_assigned_sum 3`,
+		`dms_allocation_orders_deferred_sum 1`,
+		`dms_allocation_run_duration_seconds_count 2`,
+		`dms_job_runs_total{job="test_job",result="ok"} 1`,
+		`dms_job_runs_total{job="test_job",result="error"} 1`,
+		`dms_job_duration_seconds_count{job="test_job"} 2`,
+		`dms_overdue_escalations_total 4`,
+		`dms_notifications_total{channel="test_sms",outcome="sent"} 1`,
+		`dms_db_query_duration_seconds_count{op="test_query"} 1`,
+	)
+}
+
+func TestWarehouseCollector(t *testing.T) {
+	var failing atomic.Bool
+	metrics.Register(metrics.WarehouseCollector{Load: func(ctx context.Context) ([]types.WarehouseLoad, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("warehouse load has no deadline")
+		}
+		if failing.Load() {
+			return nil, errors.New("connection refused")
+		}
+		return []types.WarehouseLoad{
+			{WarehouseID: 1, CheckedInAgents: 4, PendingOrders: 12},
+			{WarehouseID: 2, CheckedInAgents: 0, PendingOrders: 3},
+		}, nil
+	}})
+
+	expectSeries(t, scrape(t),
+		`dms_checked_in_agents{warehouse_id="1"} 4`,
+		`dms_checked_in_agents{warehouse_id="2"} 0`,
+		`dms_pending_orders{warehouse_id="1"} 12`,
+		`dms_pending_orders{warehouse_id="2"} 3`,
+	)
+
+	// a failed load drops the gauges but the rest of the page is still served
+	failing.Store(true)
+	page := scrape(t)
+	if strings.Contains(page, "dms_checked_in_agents{") || strings.Contains(page, "dms_pending_orders{") {
+		t.Error("warehouse gauges served after a failed load")
+	}
+	if !strings.Contains(page, "dms_http_requests_total") {
+		t.Error("other metrics dropped with the warehouse gauges")
+	}
+}
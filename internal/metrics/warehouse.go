@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+var (
+	checkedInAgentsDesc = prometheus.NewDesc(
+		namespace+"_checked_in_agents",
+		"Checked-in agents per warehouse.",
+		[]string{"warehouse_id"}, nil,
+	)
+	pendingOrdersDesc = prometheus.NewDesc(
+		namespace+"_pending_orders",
+		"Unassigned orders per warehouse.",
+		[]string{"warehouse_id"}, nil,
+	)
+)
+
+// collectTimeout bounds the storage query made on each scrape.
+const collectTimeout = 5 * time.Second
+
+// WarehouseCollector reads per-warehouse gauges from storage at scrape time.
+type WarehouseCollector struct {
+	Load func(ctx context.Context) ([]types.WarehouseLoad, error)
+}
+
+func (c WarehouseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- checkedInAgentsDesc
+	ch <- pendingOrdersDesc
+}
+
+func (c WarehouseCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	loads, err := c.Load(ctx)
+	if err != nil {
+		slog.Error("failed to collect warehouse metrics", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, l := range loads {
+		id := strconv.FormatInt(l.WarehouseID, 10)
+		ch <- prometheus.MustNewConstMetric(checkedInAgentsDesc, prometheus.GaugeValue, float64(l.CheckedInAgents), id)
+		ch <- prometheus.MustNewConstMetric(pendingOrdersDesc, prometheus.GaugeValue, float64(l.PendingOrders), id)
+	}
+}
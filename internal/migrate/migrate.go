@@ -0,0 +1,361 @@
+// Package migrate applies numbered, embedded SQL migrations and records them in a
+// schema_migrations table. Files are named NNNN_description.up.sql / NNNN_description.down.sql.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrLocked is returned when another process holds the migration lock past the wait timeout.
+var ErrLocked = errors.New("migrations are locked by another process")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes one known migration and whether it has been applied.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	log        *slog.Logger
+
+	// LockTimeout is how long to wait for another migrating process; StaleLock is the
+	// age after which a lock left by a crashed process is broken.
+	LockTimeout time.Duration
+	StaleLock   time.Duration
+}
+
+// Load reads every migration under dir in fsys.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// New returns a migrator for the migrations under dir in fsys.
+func New(db *sql.DB, fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := Load(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:          db,
+		migrations:  migrations,
+		log:         slog.Default().With(slog.String("component", "migrate")),
+		LockTimeout: 30 * time.Second,
+		StaleLock:   10 * time.Minute,
+	}, nil
+}
+
+// Latest returns the highest known migration version.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the highest applied migration version.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	if err := m.ensureTables(ctx); err != nil {
+		return 0, err
+	}
+
+	var v sql.NullInt64
+	if err := m.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&v); err != nil {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the given number of applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, mig, false); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// To migrates up or down until exactly the migrations <= version are applied.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; ok && mig.Version > version {
+				if err := m.apply(ctx, mig, false); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+				if err := m.apply(ctx, mig, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Baseline records every migration up to version as applied without running it. It is
+// used for databases whose schema was created before migrations were tracked.
+func (m *Migrator) Baseline(ctx context.Context, version int) error {
+	return m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok || mig.Version > version {
+				continue
+			}
+			if _, err := m.db.ExecContext(ctx, insertVersion(mig)); err != nil {
+				return err
+			}
+			m.log.Info("baselined migration", slog.Int("version", mig.Version), slog.String("name", mig.Name))
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration with its applied state.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Status
+	for _, mig := range m.migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			st.Applied = true
+			at := at
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// apply runs one migration and updates schema_migrations in the same transaction.
+func (m *Migrator) apply(ctx context.Context, mig Migration, up bool) error {
+	body, record, direction := mig.Up, insertVersion(mig), "up"
+	if !up {
+		if strings.TrimSpace(mig.Down) == "" {
+			return fmt.Errorf("migration %d_%s cannot be rolled back: no down file", mig.Version, mig.Name)
+		}
+		body, record, direction = mig.Down, fmt.Sprintf(`DELETE FROM schema_migrations WHERE version = %d`, mig.Version), "down"
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+	}
+	if _, err := tx.ExecContext(ctx, record); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	m.log.Info("applied migration", slog.Int("version", mig.Version), slog.String("name", mig.Name), slog.String("direction", direction))
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := m.ensureTables(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		applied[v] = at
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) ensureTables(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+		id INTEGER PRIMARY KEY,
+		locked_at BIGINT NOT NULL
+	)`)
+	return err
+}
+
+// withLock holds a row in schema_migrations_lock while fn runs so that concurrent
+// processes starting against the same database migrate one at a time.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if err := m.ensureTables(ctx); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(m.LockTimeout)
+	for {
+		_, err := m.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, %d)`, time.Now().Unix()))
+		if err == nil {
+			break
+		}
+
+		stale := time.Now().Add(-m.StaleLock).Unix()
+		if res, derr := m.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM schema_migrations_lock WHERE id = 1 AND locked_at < %d`, stale)); derr == nil {
+			if n, _ := res.RowsAffected(); n > 0 {
+				m.log.Warn("broke stale migration lock")
+				continue
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return ErrLocked
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+
+	// Release the lock even when ctx was cancelled mid-migration.
+	defer func() {
+		if _, err := m.db.ExecContext(context.WithoutCancel(ctx), `DELETE FROM schema_migrations_lock WHERE id = 1`); err != nil {
+			m.log.Error("failed to release migration lock", slog.String("error", err.Error()))
+		}
+	}()
+
+	return fn()
+}
+
+func insertVersion(mig Migration) string {
+	return fmt.Sprintf(`INSERT INTO schema_migrations (version, name) VALUES (%d, '%s')`,
+		mig.Version, strings.ReplaceAll(mig.Name, "'", "''"))
+}
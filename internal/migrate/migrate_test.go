@@ -0,0 +1,291 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// files are three migrations, each creating a table, listed out of order among files
+// that are not migrations.
+var files = fstest.MapFS{
+	"sql/0010_carts.up.sql":     {Data: []byte(`CREATE TABLE carts (id INTEGER PRIMARY KEY)`)},
+	"sql/0010_carts.down.sql":   {Data: []byte(`DROP TABLE carts`)},
+	"sql/0002_orders.up.sql":    {Data: []byte(`CREATE TABLE orders (id INTEGER PRIMARY KEY)`)},
+	"sql/0002_orders.down.sql":  {Data: []byte(`DROP TABLE orders`)},
+	"sql/0001_agents.up.sql":    {Data: []byte(`CREATE TABLE agents (id INTEGER PRIMARY KEY)`)},
+	"sql/0001_agents.down.sql":  {Data: []byte(`DROP TABLE agents`)},
+	"sql/README.md":             {Data: []byte(`not a migration`)},
+	"sql/0003_Draft.up.sql":     {Data: []byte(`not a migration either`)},
+	"sql/0004_old.up.sql/x.sql": {Data: []byte(`a directory`)},
+}
+
+// open returns a SQLite database in a temporary file.
+func open(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func migrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *Migrator {
+	t.Helper()
+	m, err := New(db, fsys, "sql")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return m
+}
+
+// tables lists the tables in db other than the migrator's own.
+func tables(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'schema_migrations%' ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(files, "sql")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var got []string
+	for _, m := range migrations {
+		got = append(got, m.Name)
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("migration %d has up %q and down %q", m.Version, m.Up, m.Down)
+		}
+	}
+	if want := []string{"agents", "orders", "carts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Load = %v, want %v in version order", got, want)
+	}
+
+	bad := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"conflicting names", fstest.MapFS{
+			"sql/0001_agents.up.sql":  {Data: []byte(`SELECT 1`)},
+			"sql/0001_drivers.up.sql": {Data: []byte(`SELECT 1`)},
+		}, "conflicting names"},
+		{"down without up", fstest.MapFS{
+			"sql/0001_agents.down.sql": {Data: []byte(`SELECT 1`)},
+		}, "has no up file"},
+	}
+	for _, tt := range bad {
+		if _, err := Load(tt.fsys, "sql"); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Load = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+	if _, err := Load(files, "missing"); err == nil {
+		t.Error("Load of a missing directory succeeded")
+	}
+}
+
+func TestUpDownTo(t *testing.T) {
+	ctx := context.Background()
+	db := open(t)
+	m := migrator(t, db, files)
+
+	if v, err := m.Version(ctx); err != nil || v != 0 || m.Latest() != 10 {
+		t.Fatalf("Version = %d, %v, Latest = %d; want 0 and 10", v, err, m.Latest())
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if got := tables(t, db); !reflect.DeepEqual(got, []string{"agents", "carts", "orders"}) {
+		t.Errorf("tables after Up = %v", got)
+	}
+	if v, _ := m.Version(ctx); v != 10 {
+		t.Errorf("Version after Up = %d, want 10", v)
+	}
+
+	steps := []struct {
+		name    string
+		migrate func() error
+		version int
+		tables  []string
+	}{
+		{"Up again", func() error { return m.Up(ctx) }, 10, []string{"agents", "carts", "orders"}},
+		{"Down 1", func() error { return m.Down(ctx, 1) }, 2, []string{"agents", "orders"}},
+		{"To 10", func() error { return m.To(ctx, 10) }, 10, []string{"agents", "carts", "orders"}},
+		{"To 1", func() error { return m.To(ctx, 1) }, 1, []string{"agents"}},
+		{"To 2", func() error { return m.To(ctx, 2) }, 2, []string{"agents", "orders"}},
+		{"Down past the first", func() error { return m.Down(ctx, 5) }, 0, []string{}},
+		{"To 0 again", func() error { return m.To(ctx, 0) }, 0, []string{}},
+		{"Up once more", func() error { return m.Up(ctx) }, 10, []string{"agents", "carts", "orders"}},
+	}
+	for _, st := range steps {
+		if err := st.migrate(); err != nil {
+			t.Fatalf("%s: %v", st.name, err)
+		}
+		v, err := m.Version(ctx)
+		if err != nil || v != st.version {
+			t.Errorf("%s: Version = %d, %v; want %d", st.name, v, err, st.version)
+		}
+		if got := tables(t, db); !reflect.DeepEqual(got, st.tables) {
+			t.Errorf("%s: tables = %v, want %v", st.name, got, st.tables)
+		}
+	}
+
+	if err := m.To(ctx, 3); err == nil || !strings.Contains(err.Error(), "unknown migration version 3") {
+		t.Errorf("To an unknown version: %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	m := migrator(t, open(t), files)
+	if err := m.To(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	want := []struct {
+		version int
+		name    string
+		applied bool
+	}{{1, "agents", true}, {2, "orders", true}, {10, "carts", false}}
+	if len(status) != len(want) {
+		t.Fatalf("Status = %+v, want %d migrations", status, len(want))
+	}
+	for i, w := range want {
+		st := status[i]
+		if st.Version != w.version || st.Name != w.name || st.Applied != w.applied || (st.AppliedAt != nil) != w.applied {
+			t.Errorf("status[%d] = %+v, want %+v", i, st, w)
+		}
+		if st.AppliedAt != nil && time.Since(*st.AppliedAt) > time.Minute {
+			t.Errorf("status[%d] applied at %v", i, st.AppliedAt)
+		}
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := open(t)
+	fsys := fstest.MapFS{
+		"sql/0001_agents.up.sql": {Data: []byte(`CREATE TABLE agents (id INTEGER PRIMARY KEY)`)},
+		"sql/0002_broken.up.sql": {Data: []byte(`CREATE TABLE orders (id INTEGER PRIMARY KEY); INSERT INTO nowhere VALUES (1)`)},
+	}
+	m := migrator(t, db, fsys)
+
+	err := m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "migration 2_broken up") {
+		t.Fatalf("Up = %v, want migration 2 to fail", err)
+	}
+	// the first stays applied; nothing of the second is left
+	if v, _ := m.Version(ctx); v != 1 {
+		t.Errorf("Version = %d, want 1", v)
+	}
+	if got := tables(t, db); !reflect.DeepEqual(got, []string{"agents"}) {
+		t.Errorf("tables = %v, want only agents", got)
+	}
+	// and without a down file it cannot be rolled back
+	if err := m.Down(ctx, 1); err == nil || !strings.Contains(err.Error(), "no down file") {
+		t.Errorf("Down without a down file: %v", err)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	ctx := context.Background()
+	db := open(t)
+	// a database made before migrations were tracked already has the first two tables
+	if _, err := db.Exec(`CREATE TABLE agents (id INTEGER PRIMARY KEY); CREATE TABLE orders (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	m := migrator(t, db, files)
+
+	if err := m.Baseline(ctx, 2); err != nil {
+		t.Fatalf("Baseline: %v", err)
+	}
+	if v, _ := m.Version(ctx); v != 2 {
+		t.Errorf("Version after Baseline = %d, want 2", v)
+	}
+	// running the first two again would fail on their existing tables
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up after Baseline: %v", err)
+	}
+	if got := tables(t, db); !reflect.DeepEqual(got, []string{"agents", "carts", "orders"}) {
+		t.Errorf("tables = %v", got)
+	}
+	// baselining again changes nothing
+	if err := m.Baseline(ctx, 10); err != nil {
+		t.Errorf("Baseline of applied migrations: %v", err)
+	}
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	db := open(t)
+	m := migrator(t, db, files)
+	m.LockTimeout = 300 * time.Millisecond
+	if _, err := m.Version(ctx); err != nil {
+		t.Fatal(err)
+	}
+	locked := func() bool {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations_lock`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n > 0
+	}
+
+	// another process is migrating
+	if _, err := db.Exec(`INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)`, time.Now().Unix()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(ctx); !errors.Is(err, ErrLocked) {
+		t.Errorf("Up while locked: %v, want ErrLocked", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	m.LockTimeout = time.Minute
+	if err := m.Up(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Up cancelled while waiting: %v", err)
+	}
+	if v, _ := m.Version(ctx); v != 0 || !locked() {
+		t.Fatalf("Version = %d, want nothing applied and the other lock kept", v)
+	}
+
+	// it crashed long ago; its lock is broken
+	if _, err := db.Exec(`UPDATE schema_migrations_lock SET locked_at = ?`, time.Now().Add(-time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	}
+	m.StaleLock = time.Minute
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up with a stale lock: %v", err)
+	}
+	if v, _ := m.Version(ctx); v != 10 {
+		t.Errorf("Version = %d, want 10", v)
+	}
+	if locked() {
+		t.Error("lock kept after migrating")
+	}
+}
@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/metrics"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// maxBackoff caps the wait between two attempts at a message.
+const maxBackoff = time.Hour
+
+// Dispatcher sends the notifications due in the outbox every cfg.PollInterval. Messages
+// are claimed before they are sent, so several instances can run one each; a message
+// whose sender dies is picked up again once its claim runs out.
+type Dispatcher struct {
+	store     storage.Storage
+	templates *Templates
+	cfg       config.Notifications
+	notifiers map[string]Notifier
+	log       *slog.Logger
+
+	wg sync.WaitGroup
+}
+
+func NewDispatcher(store storage.Storage, templates *Templates, cfg config.Notifications, notifiers ...Notifier) *Dispatcher {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 30 * time.Second
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = 15 * time.Second
+	}
+
+	d := &Dispatcher{
+		store:     store,
+		templates: templates,
+		cfg:       cfg,
+		notifiers: make(map[string]Notifier),
+		log:       slog.Default().With(slog.String("component", "notifications")),
+	}
+	for _, n := range notifiers {
+		d.notifiers[n.Channel()] = n
+	}
+	return d
+}
+
+// Start sends notifications until ctx is cancelled. Messages claimed but not sent by then
+// are sent once their claim runs out.
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			d.drain(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	channels := make([]string, 0, len(d.notifiers))
+	for ch := range d.notifiers {
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+	d.log.Info("notifications started", slog.Any("channels", channels), slog.Duration("poll_interval", d.cfg.PollInterval))
+}
+
+// Wait blocks until the dispatcher has stopped.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// drain sends batches until fewer than a full one are due.
+func (d *Dispatcher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		batch, err := d.store.ClaimNotifications(ctx, time.Now(), 2*d.cfg.SendTimeout, d.cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				d.log.Error("failed to claim notifications", slog.String("error", err.Error()))
+			}
+			return
+		}
+		for _, n := range batch {
+			d.send(ctx, n)
+		}
+		if len(batch) < d.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, n types.Notification) {
+	log := d.log.With(slog.Int64("notification_id", n.ID), slog.Int64("order_id", n.OrderID),
+		slog.String("event", n.Event), slog.String("channel", n.Channel))
+
+	notifier, ok := d.notifiers[n.Channel]
+	if !ok {
+		d.record(ctx, log, n, types.NotificationSkipped, "no "+n.Channel+" adapter configured", time.Time{})
+		return
+	}
+	m, err := d.templates.Render(n)
+	if err != nil {
+		d.record(ctx, log, n, types.NotificationFailed, err.Error(), time.Time{})
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, d.cfg.SendTimeout)
+	err = notifier.Send(sendCtx, m)
+	cancel()
+	switch {
+	case err == nil:
+		d.record(ctx, log, n, types.NotificationSent, "", time.Time{})
+	case ctx.Err() != nil:
+		// shutting down; the message is sent again once its claim runs out
+	case errors.Is(err, ErrRejected) || n.Attempts+1 >= d.cfg.MaxAttempts:
+		d.record(ctx, log, n, types.NotificationFailed, err.Error(), time.Time{})
+	default:
+		d.record(ctx, log, n, types.NotificationPending, err.Error(), time.Now().Add(d.backoff(n.Attempts)))
+	}
+}
+
+// backoff is the wait after the given number of earlier failed attempts and this one.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.RetryBackoff
+	for i := 0; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
+
+func (d *Dispatcher) record(ctx context.Context, log *slog.Logger, n types.Notification, status, lastError string, retryAt time.Time) {
+	outcome := status
+	if status == types.NotificationPending {
+		outcome = "retry"
+	}
+	metrics.ObserveNotification(n.Channel, outcome)
+
+	switch status {
+	case types.NotificationSent:
+		log.Info("notification sent")
+	case types.NotificationSkipped:
+		log.Debug("notification skipped", slog.String("reason", lastError))
+	case types.NotificationPending:
+		log.Warn("notification failed, will retry", slog.String("error", lastError), slog.Time("retry_at", retryAt))
+	default:
+		log.Error("notification failed", slog.String("error", lastError), slog.Int("attempts", n.Attempts+1))
+	}
+
+	if err := d.store.RecordNotificationAttempt(ctx, n.ID, status, lastError, retryAt); err != nil {
+		log.Error("failed to record notification attempt", slog.String("error", err.Error()))
+	}
+}
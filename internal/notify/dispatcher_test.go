@@ -0,0 +1,268 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// fake is a Notifier that fails with errs in turn, then succeeds.
+type fake struct {
+	channel string
+
+	mu   sync.Mutex
+	errs []error
+	sent []Message
+}
+
+func (f *fake) Channel() string { return f.channel }
+
+func (f *fake) Send(ctx context.Context, m Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+func (f *fake) messages() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.sent...)
+}
+
+// assigned returns a store with an order assigned to an agent for a customer reachable by
+// email and SMS, so two messages wait in the outbox.
+func assigned(t *testing.T) (storage.Storage, int64) {
+	t.Helper()
+	ctx := context.Background()
+	s := memory.New(&config.Config{})
+	wh, err := s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent, err := s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cust, err := s.CreateCustomer(ctx, types.Customer{Name: "Meera", Phone: "+919876543210", Email: "meera@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.CreateOrder(ctx, types.Order{Customer: "Meera", Lat: 12.98, Lng: 77.60, WarehouseID: wh, CustomerID: cust})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignOrderToAgent(ctx, id, agent, 1.5, 7.5, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	return s, id
+}
+
+// byChannel returns the order's notifications keyed by channel.
+func byChannel(t *testing.T, s storage.Storage, orderID int64) map[string]types.Notification {
+	t.Helper()
+	list, err := s.GetOrderNotifications(context.Background(), orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]types.Notification, len(list))
+	for _, n := range list {
+		out[n.Channel] = n
+	}
+	return out
+}
+
+func dispatcher(t *testing.T, s storage.Storage, cfg config.Notifications, notifiers ...Notifier) *Dispatcher {
+	t.Helper()
+	tmpl, err := LoadTemplates("", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDispatcher(s, tmpl, cfg, notifiers...)
+}
+
+func TestDispatcherSends(t *testing.T) {
+	s, id := assigned(t)
+	email, sms := &fake{channel: types.ChannelEmail}, &fake{channel: types.ChannelSMS}
+	d := dispatcher(t, s, config.Notifications{}, email, sms)
+
+	d.drain(context.Background())
+	if len(email.messages()) != 1 || len(sms.messages()) != 1 {
+		t.Fatalf("sent %d emails and %d texts, want one each", len(email.messages()), len(sms.messages()))
+	}
+	if m := email.messages()[0]; m.To != "meera@example.com" || m.Subject == "" {
+		t.Errorf("email = %+v", m)
+	}
+	if m := sms.messages()[0]; m.To != "+919876543210" || m.Subject != "" {
+		t.Errorf("text = %+v", m)
+	}
+	for ch, n := range byChannel(t, s, id) {
+		if n.Status != types.NotificationSent || n.Attempts != 1 || n.SentAt == nil {
+			t.Errorf("%s notification = %+v, want it sent", ch, n)
+		}
+	}
+
+	// nothing is sent twice
+	d.drain(context.Background())
+	if len(email.messages()) != 1 || len(sms.messages()) != 1 {
+		t.Errorf("sent %d emails and %d texts after a second drain, want one each", len(email.messages()), len(sms.messages()))
+	}
+}
+
+func TestDispatcherSkipsUnconfiguredChannel(t *testing.T) {
+	s, id := assigned(t)
+	sms := &fake{channel: types.ChannelSMS}
+	dispatcher(t, s, config.Notifications{}, sms).drain(context.Background())
+
+	got := byChannel(t, s, id)
+	if n := got[types.ChannelEmail]; n.Status != types.NotificationSkipped || n.Attempts != 0 || n.LastError == "" {
+		t.Errorf("email without an adapter = %+v, want it skipped", n)
+	}
+	if n := got[types.ChannelSMS]; n.Status != types.NotificationSent {
+		t.Errorf("text = %+v, want it sent", n)
+	}
+}
+
+func TestDispatcherLeavesClaimedMessages(t *testing.T) {
+	s, id := assigned(t)
+	email, sms := &fake{channel: types.ChannelEmail}, &fake{channel: types.ChannelSMS}
+	d := dispatcher(t, s, config.Notifications{SendTimeout: time.Minute}, email, sms)
+
+	// another instance claims the messages and dies before sending them
+	claimed, err := s.ClaimNotifications(context.Background(), time.Now(), time.Hour, 10)
+	if err != nil || len(claimed) != 2 {
+		t.Fatalf("ClaimNotifications = %d, %v; want 2", len(claimed), err)
+	}
+	d.drain(context.Background())
+	if len(email.messages())+len(sms.messages()) != 0 {
+		t.Fatal("dispatcher sent messages claimed elsewhere")
+	}
+	for ch, n := range byChannel(t, s, id) {
+		if n.Status != types.NotificationPending || n.NextAttemptAt.Before(time.Now().Add(59*time.Minute)) {
+			t.Errorf("%s notification = %+v, want it pending until the claim runs out", ch, n)
+		}
+	}
+
+	// the dispatcher's own claims last twice the send timeout
+	s, _ = assigned(t)
+	d = dispatcher(t, s, config.Notifications{SendTimeout: time.Minute}, email, sms)
+	d.store = claimOnly{s}
+	d.drain(context.Background())
+	again, err := s.ClaimNotifications(context.Background(), time.Now().Add(119*time.Second), time.Minute, 10)
+	if err != nil || len(again) != 0 {
+		t.Errorf("claimed %d messages within the lease, want none", len(again))
+	}
+	again, err = s.ClaimNotifications(context.Background(), time.Now().Add(121*time.Second), time.Minute, 10)
+	if err != nil || len(again) != 2 {
+		t.Errorf("claimed %d messages after the lease, want 2", len(again))
+	}
+}
+
+// claimOnly claims messages but never records sending them, as an instance that stops
+// mid-send.
+type claimOnly struct{ storage.Storage }
+
+func (c claimOnly) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]types.Notification, error) {
+	if _, err := c.Storage.ClaimNotifications(ctx, now, lease, limit); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	s, id := assigned(t)
+	down := errors.New("connection refused")
+	email := &fake{channel: types.ChannelEmail, errs: []error{down, down}}
+	const backoff = 200 * time.Millisecond
+	d := dispatcher(t, s, config.Notifications{RetryBackoff: backoff, MaxAttempts: 5}, email)
+
+	// each failure waits twice as long as the one before
+	for i, wait := range []time.Duration{backoff, 2 * backoff} {
+		before := time.Now()
+		d.drain(context.Background())
+		n := byChannel(t, s, id)[types.ChannelEmail]
+		if n.Status != types.NotificationPending || n.Attempts != i+1 || n.LastError != down.Error() {
+			t.Fatalf("after failure %d: %+v, want it pending", i+1, n)
+		}
+		if n.NextAttemptAt.Before(before.Add(wait)) || n.NextAttemptAt.After(time.Now().Add(wait)) {
+			t.Errorf("after failure %d: retry at %v, want %v after %v", i+1, n.NextAttemptAt, wait, before)
+		}
+
+		// not due yet
+		d.drain(context.Background())
+		if n := byChannel(t, s, id)[types.ChannelEmail]; n.Attempts != i+1 {
+			t.Fatalf("retried before the backoff: %+v", n)
+		}
+		time.Sleep(time.Until(n.NextAttemptAt))
+	}
+
+	d.drain(context.Background())
+	if n := byChannel(t, s, id)[types.ChannelEmail]; n.Status != types.NotificationSent || n.Attempts != 3 {
+		t.Errorf("after the retries: %+v, want it sent on the third attempt", n)
+	}
+	if len(email.messages()) != 1 {
+		t.Errorf("sent %d messages, want 1", len(email.messages()))
+	}
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	down := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+	}{
+		{"after max attempts", []error{down, down, down, down}, 3},
+		{"at once when rejected", []error{ErrRejected}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, id := assigned(t)
+			email := &fake{channel: types.ChannelEmail, errs: tt.errs}
+			d := dispatcher(t, s, config.Notifications{RetryBackoff: time.Millisecond, MaxAttempts: 3}, email)
+
+			deadline := time.Now().Add(5 * time.Second)
+			for byChannel(t, s, id)[types.ChannelEmail].Status == types.NotificationPending {
+				if time.Now().After(deadline) {
+					t.Fatal("message never given up")
+				}
+				time.Sleep(5 * time.Millisecond)
+				d.drain(context.Background())
+			}
+			if n := byChannel(t, s, id)[types.ChannelEmail]; n.Status != types.NotificationFailed || n.Attempts != tt.attempts {
+				t.Errorf("notification = %+v, want it failed after %d attempts", n, tt.attempts)
+			}
+			if len(email.messages()) != 0 {
+				t.Errorf("sent %d messages, want none", len(email.messages()))
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, nil, config.Notifications{RetryBackoff: 30 * time.Second})
+	for attempts, want := range map[int]time.Duration{
+		0:  30 * time.Second,
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		6:  32 * time.Minute,
+		7:  time.Hour,
+		50: time.Hour,
+	} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
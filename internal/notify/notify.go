@@ -0,0 +1,26 @@
+// Package notify sends customers messages about their orders. Storage writes them to an
+// outbox together with the status change they report; a Dispatcher renders each one from
+// its event's template and hands it to the Notifier for its channel, retrying failures.
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrRejected marks a send that will fail again however often it is retried, such as an
+// invalid recipient. Adapters wrap it; the message is then given up at once.
+var ErrRejected = errors.New("message rejected")
+
+// Message is a rendered notification. Subject is empty for channels without one.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier sends messages on one channel, types.ChannelEmail or types.ChannelSMS.
+type Notifier interface {
+	Channel() string
+	Send(ctx context.Context, m Message) error
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// SMSGateway sends text messages through an HTTP gateway that takes a JSON body
+// {"to", "from", "message"}, the shape most providers' send APIs accept or can be mapped to.
+type SMSGateway struct {
+	cfg    config.SMS
+	client *http.Client
+}
+
+func NewSMSGateway(cfg config.SMS) *SMSGateway {
+	return &SMSGateway{cfg: cfg, client: &http.Client{}}
+}
+
+func (g *SMSGateway) Channel() string { return types.ChannelSMS }
+
+// Send posts m to the gateway. A 4xx reply other than 408 and 429 is reported as
+// ErrRejected; other failures are worth retrying.
+func (g *SMSGateway) Send(ctx context.Context, m Message) error {
+	body, err := json.Marshal(map[string]string{"to": m.To, "from": g.cfg.Sender, "message": m.Body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.cfg.Token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	// the start of the reply usually says what was wrong
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("sms gateway replied %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	return err
+}
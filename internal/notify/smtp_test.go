@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+)
+
+// sink is an SMTP server on a local port that keeps what it is sent. It answers RCPT TO
+// for addresses in refuse with that reply instead of accepting them.
+type sink struct {
+	addr   *net.TCPAddr
+	refuse map[string]string
+	mail   chan string
+}
+
+func newSink(t *testing.T, refuse map[string]string) *sink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &sink{addr: ln.Addr().(*net.TCPAddr), refuse: refuse, mail: make(chan string, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *sink) serve(conn net.Conn) {
+	defer conn.Close()
+	c := textproto.NewConn(conn)
+	c.PrintfLine("220 sink ready")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			c.PrintfLine("250-sink")
+			c.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			c.PrintfLine("250 ok")
+		case "RCPT":
+			to := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if reply, ok := s.refuse[to]; ok {
+				c.PrintfLine("%s", reply)
+				continue
+			}
+			c.PrintfLine("250 ok")
+		case "DATA":
+			c.PrintfLine("354 go ahead")
+			b, err := io.ReadAll(c.DotReader())
+			if err != nil {
+				return
+			}
+			s.mail <- string(b)
+			c.PrintfLine("250 queued")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *sink) client(from string) *SMTP {
+	return NewSMTP(config.SMTP{Host: "127.0.0.1", Port: s.addr.Port, From: from})
+}
+
+func TestSMTPSend(t *testing.T) {
+	s := newSink(t, nil)
+	m := Message{To: "Meera <meera@example.com>", Subject: "Your order #7 is on its way", Body: "Hi Meera,\n\nIt is on its way."}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.client("Deliveries <deliveries@shop.example>").Send(ctx, m); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	var got string
+	select {
+	case got = <-s.mail:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+	head, body, ok := strings.Cut(got, "\n\n")
+	if !ok {
+		t.Fatalf("mail has no blank line after the headers: %q", got)
+	}
+	for _, want := range []string{
+		`From: "Deliveries" <deliveries@shop.example>`,
+		`To: "Meera" <meera@example.com>`,
+		"Subject: Your order #7 is on its way",
+		`Content-Type: text/plain; charset="utf-8"`,
+	} {
+		if !strings.Contains(head, want+"\n") {
+			t.Errorf("headers missing %q:\n%s", want, head)
+		}
+	}
+	if !strings.Contains(head, "Message-ID: <") || !strings.Contains(head, "@shop.example>") {
+		t.Errorf("headers without a Message-ID on the sender's domain:\n%s", head)
+	}
+	if body != "Hi Meera,\n\nIt is on its way.\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSMTPErrors(t *testing.T) {
+	s := newSink(t, map[string]string{
+		"gone@example.com": "550 no such user",
+		"full@example.com": "452 mailbox full",
+	})
+	tests := []struct {
+		to       string
+		rejected bool
+	}{
+		{"not an address", true},
+		{"gone@example.com", true},
+		{"full@example.com", false},
+	}
+	for _, tt := range tests {
+		err := s.client("deliveries@shop.example").Send(context.Background(), Message{To: tt.to, Body: "hi"})
+		if err == nil {
+			t.Errorf("Send to %s succeeded", tt.to)
+			continue
+		}
+		if got := errors.Is(err, ErrRejected); got != tt.rejected {
+			t.Errorf("Send to %s: %v, rejected = %v, want %v", tt.to, err, got, tt.rejected)
+		}
+	}
+
+	// nothing listening is worth retrying
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	err = NewSMTP(config.SMTP{Host: "127.0.0.1", Port: port, From: "deliveries@shop.example"}).
+		Send(context.Background(), Message{To: "meera@example.com", Body: "hi"})
+	if err == nil || errors.Is(err, ErrRejected) {
+		t.Errorf("Send with the server down: %v, want a retryable error", err)
+	}
+}
+
+func TestSMSGateway(t *testing.T) {
+	tests := []struct {
+		status   int
+		rejected bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusBadRequest, true},
+		{http.StatusUnauthorized, true},
+		{http.StatusTooManyRequests, false},
+		{http.StatusRequestTimeout, false},
+		{http.StatusBadGateway, false},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				got = r.Header.Get("Authorization") + " " + string(b)
+				w.WriteHeader(tt.status)
+				io.WriteString(w, "reply")
+			}))
+			defer srv.Close()
+
+			g := NewSMSGateway(config.SMS{URL: srv.URL, Token: "secret", Sender: "SHOP"})
+			err := g.Send(context.Background(), Message{To: "+919876543210", Body: "Order #7 is on its way."})
+			if want := `Bearer secret {"from":"SHOP","message":"Order #7 is on its way.","to":"+919876543210"}`; got != want {
+				t.Errorf("request = %s, want %s", got, want)
+			}
+			if tt.status == http.StatusOK {
+				if err != nil {
+					t.Errorf("Send: %v", err)
+				}
+				return
+			}
+			if err == nil || errors.Is(err, ErrRejected) != tt.rejected || !strings.Contains(err.Error(), "reply") {
+				t.Errorf("Send: %v, want rejected = %v", err, tt.rejected)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// events are those with a template. Each defines "subject" and "email" for email and
+// "sms" for text messages.
+var events = []string{types.EventAssigned, types.EventDelivered, types.EventAttemptFailed, types.EventReturning, types.EventUnassigned}
+
+// Templates renders notifications, one template per event.
+type Templates struct {
+	byEvent map[string]*template.Template
+	loc     *time.Location
+}
+
+// data is what a template sees. ETA is empty when unknown.
+type data struct {
+	OrderID  int64
+	Customer string
+	Agent    string
+	ETA      string
+	OTP      string
+}
+
+// LoadTemplates parses the built-in templates, replacing those for which dir, when set,
+// has an <event>.tmpl file. ETAs are written in loc.
+func LoadTemplates(dir string, loc *time.Location) (*Templates, error) {
+	t := &Templates{byEvent: make(map[string]*template.Template), loc: loc}
+	for _, event := range events {
+		name := event + ".tmpl"
+		src, err := fs.ReadFile(builtin, "templates/"+name)
+		if err != nil {
+			return nil, err
+		}
+		if dir != "" {
+			custom, err := os.ReadFile(filepath.Join(dir, name))
+			switch {
+			case err == nil:
+				src = custom
+			case !errors.Is(err, fs.ErrNotExist):
+				return nil, err
+			}
+		}
+
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(string(src))
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range []string{"subject", "email", "sms"} {
+			if tmpl.Lookup(part) == nil {
+				return nil, fmt.Errorf("template %s does not define %q", name, part)
+			}
+		}
+		t.byEvent[event] = tmpl
+	}
+	return t, nil
+}
+
+// Render writes the message for n on its channel.
+func (t *Templates) Render(n types.Notification) (Message, error) {
+	tmpl, ok := t.byEvent[n.Event]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for event %q", n.Event)
+	}
+
+	d := data{OrderID: n.OrderID, Customer: n.CustomerName, Agent: n.AgentName, OTP: n.OTP}
+	if n.ETA != nil {
+		d.ETA = n.ETA.In(t.loc).Format("15:04 on Mon 2 Jan")
+	}
+
+	m := Message{To: n.Recipient}
+	var err error
+	switch n.Channel {
+	case types.ChannelEmail:
+		if m.Subject, err = execute(tmpl, "subject", d); err != nil {
+			return m, err
+		}
+		// a subject is a single header line
+		m.Subject = strings.Join(strings.Fields(m.Subject), " ")
+		m.Body, err = execute(tmpl, "email", d)
+	case types.ChannelSMS:
+		m.Body, err = execute(tmpl, "sms", d)
+	default:
+		err = fmt.Errorf("unknown channel %q", n.Channel)
+	}
+	return m, err
+}
+
+func execute(tmpl *template.Template, name string, d data) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, name, d); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+func TestRender(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := LoadTemplates("", kolkata)
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	eta := time.Date(2024, 5, 1, 9, 15, 0, 0, time.UTC)
+	n := types.Notification{OrderID: 7, CustomerName: "Meera", AgentName: "Ravi", Recipient: "meera@example.com"}
+
+	tests := []struct {
+		name        string
+		event       string
+		channel     string
+		eta         *time.Time
+		otp         string
+		subject     string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "assigned email", event: types.EventAssigned, channel: types.ChannelEmail, eta: &eta, otp: "4821",
+			subject:  "Your order #7 is out for delivery",
+			contains: []string{"Hi Meera,", "with Ravi", "around 14:45 on Wed 1 May", "Ravi when you receive it: 4821"},
+		},
+		{
+			name: "assigned text", event: types.EventAssigned, channel: types.ChannelSMS, eta: &eta, otp: "4821",
+			contains: []string{"Order #7 is out for delivery with Ravi, arriving around 14:45 on Wed 1 May. Delivery code: 4821"},
+		},
+		{
+			name: "assigned without eta or code", event: types.EventAssigned, channel: types.ChannelSMS,
+			contains:    []string{"Order #7 is out for delivery with Ravi."},
+			notContains: []string{"arriving", "code"},
+		},
+		{
+			name: "attempt failed email", event: types.EventAttemptFailed, channel: types.ChannelEmail,
+			subject:  "We could not deliver your order #7",
+			contains: []string{"Ravi could not deliver your order #7 today"},
+		},
+		{
+			name: "delivered text", event: types.EventDelivered, channel: types.ChannelSMS,
+			contains: []string{"Order #7 has been delivered by Ravi."},
+		},
+		{
+			name: "returning email", event: types.EventReturning, channel: types.ChannelEmail,
+			subject:  "Your order #7 is being returned",
+			contains: []string{"Hi Meera,", "returned to our warehouse"},
+		},
+		{
+			name: "unassigned text", event: types.EventUnassigned, channel: types.ChannelSMS,
+			contains: []string{"Ravi can no longer deliver order #7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := n
+			n.Event, n.Channel, n.ETA, n.OTP = tt.event, tt.channel, tt.eta, tt.otp
+			m, err := tmpl.Render(n)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if m.To != n.Recipient {
+				t.Errorf("To = %q, want %q", m.To, n.Recipient)
+			}
+			if tt.channel == types.ChannelSMS && m.Subject != "" {
+				t.Errorf("text has subject %q", m.Subject)
+			}
+			if tt.subject != "" && m.Subject != tt.subject {
+				t.Errorf("Subject = %q, want %q", m.Subject, tt.subject)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(m.Body, want) {
+					t.Errorf("body %q does not contain %q", m.Body, want)
+				}
+			}
+			for _, unwanted := range tt.notContains {
+				if strings.Contains(m.Body, unwanted) {
+					t.Errorf("body %q contains %q", m.Body, unwanted)
+				}
+			}
+		})
+	}
+
+	for _, bad := range []types.Notification{
+		{Event: "cancelled", Channel: types.ChannelSMS},
+		{Event: types.EventAssigned, Channel: "pigeon"},
+	} {
+		if _, err := tmpl.Render(bad); err == nil {
+			t.Errorf("Render(%s on %s) succeeded", bad.Event, bad.Channel)
+		}
+	}
+}
+
+func TestCustomTemplates(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a template in the directory replaces the built-in one; subjects are folded onto one line
+	write("delivered.tmpl", `{{define "subject"}}Delivered:
+  order {{.OrderID}}{{end}}{{define "email"}}Thanks {{.Customer}}{{end}}{{define "sms"}}Delivered #{{.OrderID}}{{end}}`)
+	tmpl, err := LoadTemplates(dir, time.UTC)
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	m, err := tmpl.Render(types.Notification{OrderID: 7, CustomerName: "Meera", Event: types.EventDelivered, Channel: types.ChannelEmail})
+	if err != nil || m.Subject != "Delivered: order 7" || m.Body != "Thanks Meera" {
+		t.Errorf("Render = %+v, %v", m, err)
+	}
+	// the others stay built in
+	if m, err := tmpl.Render(types.Notification{OrderID: 7, AgentName: "Ravi", Event: types.EventAssigned, Channel: types.ChannelSMS}); err != nil || !strings.Contains(m.Body, "out for delivery") {
+		t.Errorf("Render = %+v, %v", m, err)
+	}
+
+	// a template must define every part
+	write("returning.tmpl", `{{define "subject"}}Returning{{end}}{{define "email"}}Returning{{end}}`)
+	if _, err := LoadTemplates(dir, time.UTC); err == nil || !strings.Contains(err.Error(), `"sms"`) {
+		t.Errorf("LoadTemplates without sms: %v, want an error", err)
+	}
+	write("returning.tmpl", `{{define "subject"}}{{.Nope}}{{end}}{{define "email"}}x{{end}}{{define "sms"}}x{{end}}`)
+	tmpl, err = LoadTemplates(dir, time.UTC)
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	if _, err := tmpl.Render(types.Notification{Event: types.EventReturning, Channel: types.ChannelEmail}); err == nil {
+		t.Error("Render of an unknown field succeeded")
+	}
+	write("returning.tmpl", `{{define "subject"}}{{end`)
+	if _, err := LoadTemplates(dir, time.UTC); err == nil {
+		t.Error("LoadTemplates of a broken template succeeded")
+	}
+}
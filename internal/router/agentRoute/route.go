@@ -2,13 +2,23 @@ package agentRoute
 
 import (
 	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
 	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/agent"
+	"github.com/sharmaprinceji/delivery-management-system/internal/schedular"
 	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
 )
 
-func RegisterAgentRoutes(router *mux.Router, storage storage.Storage) {
-	router.HandleFunc("/api/warehouse", agent.CreateWareHouse(storage)).Methods("POST")
+func RegisterAgentRoutes(router *mux.Router, storage storage.Storage, sched *schedular.Scheduler, limits config.Delivery) {
+	router.HandleFunc("/api/warehouse", agent.CreateWareHouse(storage, sched)).Methods("POST")
+	router.HandleFunc("/api/warehouses", agent.ListWarehouses(storage)).Methods("GET")
+	router.HandleFunc("/api/warehouses/{warehouse_id}/zones", agent.CreateServiceZones(storage)).Methods("POST")
+	router.HandleFunc("/api/warehouses/{warehouse_id}/zones", agent.ListServiceZones(storage)).Methods("GET")
+	router.HandleFunc("/api/zones/check", agent.CheckServiceZones(storage)).Methods("GET")
+	router.HandleFunc("/api/zones/{zone_id}", agent.DeleteServiceZone(storage)).Methods("DELETE")
+	router.HandleFunc("/api/warehouses/{warehouse_id}/clusters", agent.GetWarehouseClusters(storage, limits)).Methods("GET")
+	router.HandleFunc("/api/warehouse/{warehouse_id}/calendar", agent.SetWarehouseCalendar(storage, sched)).Methods("PUT")
 	router.HandleFunc("/api/agent/checkin", agent.CheckedInAgents(storage)).Methods("POST")
 	router.HandleFunc("/api/agent/{agent_id}", agent.GetAgentDetails(storage)).Methods("GET")
+	router.HandleFunc("/api/agent/{agent_id}/release", agent.ReleaseAgentOrders(storage)).Methods("POST")
 	router.HandleFunc("/api/assignments", agent.GetAssignments(storage)).Methods("GET")
 }
@@ -0,0 +1,16 @@
+package customerRoute
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/geo"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/customer"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+)
+
+func RegisterCustomerRoutes(router *mux.Router, storage storage.Storage, geocoder geo.Geocoder) {
+	router.HandleFunc("/api/customers", customer.CreateCustomer(storage, geocoder)).Methods("POST")
+	router.HandleFunc("/api/customers/{customer_id}", customer.GetCustomer(storage)).Methods("GET")
+	router.HandleFunc("/api/customers/{customer_id}/addresses", customer.AddCustomerAddress(storage, geocoder)).Methods("POST")
+	router.HandleFunc("/api/customers/{customer_id}/orders", customer.GetCustomerOrders(storage)).Methods("GET")
+	router.HandleFunc("/api/customers/{customer_id}/notifications", customer.SetNotifications(storage)).Methods("PUT")
+}
@@ -0,0 +1,14 @@
+package healthRoute
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/health"
+	"github.com/sharmaprinceji/delivery-management-system/internal/schedular"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+)
+
+func RegisterHealthRoutes(router *mux.Router, storage storage.Storage, sched *schedular.Scheduler) {
+	router.HandleFunc("/healthz", health.Liveness()).Methods("GET")
+	router.HandleFunc("/readyz", health.Readiness(storage, sched)).Methods("GET")
+	router.HandleFunc("/version", health.Version(storage)).Methods("GET")
+}
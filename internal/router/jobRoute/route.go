@@ -0,0 +1,12 @@
+package jobRoute
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/job"
+	"github.com/sharmaprinceji/delivery-management-system/internal/schedular"
+)
+
+func RegisterJobRoutes(router *mux.Router, sched *schedular.Scheduler) {
+	router.HandleFunc("/api/jobs", job.ListJobs(sched)).Methods("GET")
+	router.HandleFunc("/api/jobs/{name}/run", job.RunJob(sched)).Methods("POST")
+}
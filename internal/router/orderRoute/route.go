@@ -2,14 +2,33 @@ package orderroute
 
 import (
 	"github.com/gorilla/mux"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/geo"
 	"github.com/sharmaprinceji/delivery-management-system/internal/http/handlers/order"
+	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
 	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
 )
 
-func RegisterOrderRoutes(router *mux.Router, storage storage.Storage) {
-	router.HandleFunc("/api/order", order.CreateOrder(storage)).Methods("POST")
-	router.HandleFunc("/api/orders/bulk", order.CreateBulkOrders(storage)).Methods("POST")
-	router.HandleFunc("/api/allocate", order.ManualAllocation(storage)).Methods("GET")
+func RegisterOrderRoutes(router *mux.Router, storage storage.Storage, runner *jobs.Runner, stream *jobs.Streamer, zones config.Zones, geocoder geo.Geocoder, blobs storage.BlobStore, pod config.POD, attempts config.Attempts, limits config.Delivery) {
+	router.HandleFunc("/api/order", order.CreateOrder(storage, stream, zones, geocoder)).Methods("POST")
+	router.HandleFunc("/api/orders/bulk", order.CreateBulkOrders(storage, stream, zones, geocoder)).Methods("POST")
+	router.HandleFunc("/api/orders/late", order.GetLateOrders(storage)).Methods("GET")
+	router.HandleFunc("/api/orders/returns", order.GetReturningOrders(storage)).Methods("GET")
+	router.HandleFunc("/api/orders/reviews", order.ListAddressReviews(storage)).Methods("GET")
+	router.HandleFunc("/api/orders/reviews/{review_id}/resolve", order.ResolveAddressReview(storage, stream, zones)).Methods("POST")
+	router.HandleFunc("/api/orders/reviews/{review_id}", order.DeleteAddressReview(storage)).Methods("DELETE")
+	router.HandleFunc("/api/orders/{order_id}/fail", order.FailDelivery(storage, attempts)).Methods("POST")
+	router.HandleFunc("/api/orders/{order_id}/attempts", order.GetDeliveryAttempts(storage)).Methods("GET")
+	router.HandleFunc("/api/orders/{order_id}/return", order.CompleteReturn(storage)).Methods("POST")
+	router.HandleFunc("/api/orders/{order_id}/notifications", order.GetOrderNotifications(storage)).Methods("GET")
+	router.HandleFunc("/api/orders/{order_id}/pod", order.SubmitProof(storage, blobs, pod)).Methods("POST")
+	router.HandleFunc("/api/orders/{order_id}/pod", order.GetProof(storage)).Methods("GET")
+	router.HandleFunc("/api/orders/{order_id}/pod/{artefact}", order.GetProofArtefact(storage, blobs)).Methods("GET")
+	router.HandleFunc("/api/orders/{order_id}/unassign", order.UnassignOrder(storage)).Methods("POST")
+	router.HandleFunc("/api/orders/{order_id}/reassign", order.ReassignOrder(storage, limits)).Methods("POST")
+	router.HandleFunc("/api/allocate", order.StartAllocation(runner)).Methods("POST")
+	router.HandleFunc("/api/allocate/{job_id}", order.GetAllocation(runner)).Methods("GET")
+	router.HandleFunc("/api/allocate/{job_id}/cancel", order.CancelAllocation(runner)).Methods("POST")
 	router.HandleFunc("/api/agent-summary", order.GetAgentSummary(storage)).Methods("GET")
 	router.HandleFunc("/api/system-summary", order.GetSystemSummary(storage)).Methods("GET")
 }
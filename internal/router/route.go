@@ -1,30 +1,45 @@
 package router
 
 import (
-	"log"
+	"context"
+	"log/slog"
 
 	"github.com/gorilla/mux"
 	"github.com/sharmaprinceji/delivery-management-system/db"
 	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
 	"github.com/sharmaprinceji/delivery-management-system/internal/schedular"
 	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/disk"
 )
 
-func SetupRouter() (*mux.Router, storage.Storage) {
+// SetupRouter opens the storage and the proof of delivery files, and starts the scheduler,
+// which runs until ctx is cancelled.
+func SetupRouter(ctx context.Context) (*mux.Router, storage.Storage, storage.BlobStore, *schedular.Scheduler) {
 	router := mux.NewRouter()
 	cfg := config.MustLoad()
 
 	st, err := db.Mydb(cfg)
 	if err != nil {
-		log.Fatalf("failed to init DB: %v", err)
+		logger.Fatal("failed to init DB", slog.String("error", err.Error()))
 	}
-	log.Println("DB connection on..", cfg.HTTPServer.Addr)
+	slog.Info("DB connection on..", slog.String("storage_path", cfg.StoragePath))
 
-	if err := st.InitSchema(); err != nil {
-		log.Fatalf("schema error: %v", err)
+	if err := st.InitSchema(ctx); err != nil {
+		logger.Fatal("schema error", slog.String("error", err.Error()))
 	}
 
-	schedular.SchedularJob(st)
+	// proof of delivery photos and signatures
+	podFiles, err := disk.New(cfg.POD.Dir)
+	if err != nil {
+		logger.Fatal("failed to open proof of delivery store", slog.String("error", err.Error()))
+	}
+
+	sched, err := schedular.Setup(ctx, cfg, st, podFiles)
+	if err != nil {
+		logger.Fatal("invalid job configuration", slog.String("error", err.Error()))
+	}
+	sched.Start(ctx)
 
-	return router, st
+	return router, st, podFiles, sched
 }
@@ -0,0 +1,201 @@
+package schedular
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// Names of the built-in jobs, as used by the config and the /api/jobs endpoints. Every
+// warehouse has its own allocation job, named by WarehouseJobName, except that with
+// jobs.allocation.schedule set the warehouses open all day share the JobAllocation job.
+const (
+	JobAllocation        = "allocation"
+	JobOverdueEscalation = "overdue_escalation"
+	JobDailyReport       = "daily_report"
+	JobRetentionCleanup  = "retention_cleanup"
+)
+
+// defaultSchedules apply when a job has no schedule in the config.
+var defaultSchedules = map[string]string{
+	JobOverdueEscalation: "*/15 * * * *",
+	JobDailyReport:       "5 0 * * *",
+	JobRetentionCleanup:  "30 3 * * *",
+}
+
+// WarehouseJobName names the allocation job of a warehouse.
+func WarehouseJobName(warehouseID int64) string {
+	return fmt.Sprintf("%s:%d", JobAllocation, warehouseID)
+}
+
+// Setup returns a scheduler with the built-in jobs registered from cfg, including an
+// allocation job for every warehouse in s. Retention cleanup deletes proof files from blobs.
+func Setup(ctx context.Context, cfg *config.Config, s storage.Storage, blobs storage.BlobStore) (*Scheduler, error) {
+	sch := New(s)
+	jc := cfg.Jobs
+
+	// warehouses open all day have no opening time to run at; with a schedule of its own
+	// the plain allocation job allocates them instead
+	shared := func(w types.Warehouse) bool {
+		return jc.Allocation.Schedule != "" && w.AllDay()
+	}
+
+	sch.warehouseJob = func(w types.Warehouse) (Job, bool) {
+		return Job{
+			Name:     WarehouseJobName(w.ID),
+			Schedule: dailyAt(w.WithDefaults().OpensAt),
+			Location: w.Zone(),
+			Jitter:   jc.Allocation.Jitter,
+			Timeout:  jc.Allocation.Timeout,
+			Disabled: jc.Allocation.Disabled,
+			Run: func(ctx context.Context) (string, error) {
+				if w.IsHoliday(time.Now()) {
+					return "skipped: holiday", nil
+				}
+				res, err := jobs.AllocateWarehouse(ctx, s, cfg.Variables.Delivery, w.ID)
+				if err != nil {
+					return "", err
+				}
+				return outcome(res), nil
+			},
+		}, !shared(w)
+	}
+
+	bodies := []struct {
+		name string
+		cfg  config.Job
+		run  func(loc *time.Location) Func
+	}{
+		{JobAllocation, jc.Allocation, func(*time.Location) Func {
+			return func(ctx context.Context) (string, error) {
+				warehouses, err := s.GetWarehouses(ctx)
+				if err != nil {
+					return "", fmt.Errorf("load warehouses: %w", err)
+				}
+				var total types.AllocationResult
+				n := 0
+				for _, w := range warehouses {
+					if !shared(w) || w.IsHoliday(time.Now()) {
+						continue
+					}
+					res, err := jobs.AllocateWarehouse(ctx, s, cfg.Variables.Delivery, w.ID)
+					if err != nil {
+						return "", fmt.Errorf("warehouse %d: %w", w.ID, err)
+					}
+					n++
+					total.Assigned += res.Assigned
+					total.Deferred += res.Deferred
+					total.Conflicts = append(total.Conflicts, res.Conflicts...)
+					total.AtRisk = append(total.AtRisk, res.AtRisk...)
+					total.Unfit = append(total.Unfit, res.Unfit...)
+				}
+				return fmt.Sprintf("%d warehouses: %s", n, outcome(total)), nil
+			}
+		}},
+		{JobOverdueEscalation, jc.OverdueEscalation, func(*time.Location) Func {
+			return func(ctx context.Context) (string, error) {
+				n, err := jobs.EscalateOverdue(ctx, s, jc.OverdueAfter)
+				return fmt.Sprintf("%d escalated", n), err
+			}
+		}},
+		{JobDailyReport, jc.DailyReport, func(loc *time.Location) Func {
+			return func(ctx context.Context) (string, error) {
+				r, err := jobs.DailyReport(ctx, s, loc)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s: %d assigned, %d delivered, %d agents, %.1f planned km, %.1f actual km",
+					r.From.Format("2006-01-02"), r.OrdersAssigned, r.OrdersDelivered, r.ActiveAgents, r.PlannedKm, r.ActualKm), nil
+			}
+		}},
+		{JobRetentionCleanup, jc.RetentionCleanup, func(*time.Location) Func {
+			return func(ctx context.Context) (string, error) {
+				n, err := jobs.PurgeDelivered(ctx, s, blobs, jc.RetainFor)
+				return fmt.Sprintf("%d purged", n), err
+			}
+		}},
+	}
+
+	for _, b := range bodies {
+		loc := time.Local
+		if b.cfg.Timezone != "" {
+			var err error
+			if loc, err = time.LoadLocation(b.cfg.Timezone); err != nil {
+				return nil, fmt.Errorf("job %s: %w", b.name, err)
+			}
+		}
+
+		schedule := b.cfg.Schedule
+		if schedule == "" {
+			schedule = defaultSchedules[b.name]
+		}
+		if schedule == "" {
+			// only the plain allocation job has no default
+			if b.cfg.Timezone != "" {
+				sch.log.Warn("job has a timezone but no schedule and is not run", slog.String("job", b.name))
+			}
+			continue
+		}
+
+		err := sch.Register(Job{
+			Name:     b.name,
+			Schedule: schedule,
+			Location: loc,
+			Jitter:   b.cfg.Jitter,
+			Timeout:  b.cfg.Timeout,
+			Disabled: b.cfg.Disabled,
+			Run:      b.run(loc),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	warehouses, err := s.GetWarehouses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load warehouses: %w", err)
+	}
+	for _, w := range warehouses {
+		if err := sch.ScheduleWarehouse(w); err != nil {
+			return nil, err
+		}
+	}
+
+	return sch, nil
+}
+
+// ScheduleWarehouse (re)schedules the allocation job of w at its local opening time, or
+// removes it when w is left to the plain allocation job. Call it whenever a warehouse is
+// created or its calendar changes.
+func (s *Scheduler) ScheduleWarehouse(w types.Warehouse) error {
+	if s.warehouseJob == nil {
+		return nil
+	}
+	job, own := s.warehouseJob(w)
+	s.Unregister(job.Name)
+	if !own {
+		return nil
+	}
+	return s.Register(job)
+}
+
+// outcome sums up an allocation for the job's last result.
+func outcome(res types.AllocationResult) string {
+	return fmt.Sprintf("%d assigned, %d deferred, %d conflicts, %d at risk, %d unfit",
+		res.Assigned, res.Deferred, len(res.Conflicts), len(res.AtRisk), len(res.Unfit))
+}
+
+// dailyAt turns an HH:MM time of day into a cron expression firing at it every day.
+func dailyAt(hm string) string {
+	t, err := time.Parse("15:04", hm)
+	if err != nil {
+		return "0 0 * * *"
+	}
+	return fmt.Sprintf("%d %d * * *", t.Minute(), t.Hour())
+}
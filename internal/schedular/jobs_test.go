@@ -0,0 +1,167 @@
+package schedular
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/disk"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// setup runs Setup over a store holding warehouses and starts the scheduler, stopped with the test.
+func setup(t *testing.T, jc config.Jobs, warehouses ...types.Warehouse) (*Scheduler, storage.Storage, []int64) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	store := memory.New(&config.Config{})
+	var ids []int64
+	for _, w := range warehouses {
+		id, err := store.CreateWarehouse(ctx, w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	blobs, err := disk.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sch, err := Setup(ctx, &config.Config{Jobs: jc}, store, blobs)
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	sch.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		sch.Wait()
+	})
+	return sch, store, ids
+}
+
+// hub is a warehouse with the given calendar.
+func hub(name string, cal types.WarehouseCalendar) types.Warehouse {
+	return types.Warehouse{Name: name, Location: types.Location{Lat: 12.97, Lng: 77.59}, WarehouseCalendar: cal}
+}
+
+// today is the current date in the zone of cal, as holidays are given.
+func today(cal types.WarehouseCalendar) string {
+	return time.Now().In(cal.Zone()).Format("2006-01-02")
+}
+
+func TestSetupRegistersJobs(t *testing.T) {
+	kolkata := types.WarehouseCalendar{Timezone: "Asia/Kolkata", OpensAt: "08:30", ClosesAt: "20:00"}
+	sch, _, ids := setup(t, config.Jobs{
+		DailyReport: config.Job{Timezone: "Asia/Kolkata"},
+	}, hub("Bangalore", kolkata), hub("Anywhere", types.WarehouseCalendar{}))
+
+	tests := []struct {
+		name, schedule, zone string
+	}{
+		{JobOverdueEscalation, "*/15 * * * *", time.Local.String()},
+		{JobDailyReport, "5 0 * * *", "Asia/Kolkata"},
+		{JobRetentionCleanup, "30 3 * * *", time.Local.String()},
+		// each warehouse allocates at its own opening time, in its own zone
+		{WarehouseJobName(ids[0]), "30 8 * * *", "Asia/Kolkata"},
+		{WarehouseJobName(ids[1]), "0 0 * * *", "UTC"},
+	}
+	for _, tt := range tests {
+		e, ok := sch.jobs[tt.name]
+		if !ok {
+			t.Errorf("job %s not registered", tt.name)
+			continue
+		}
+		if e.Schedule != tt.schedule || e.Location.String() != tt.zone {
+			t.Errorf("job %s runs at %q in %s, want %q in %s", tt.name, e.Schedule, e.Location, tt.schedule, tt.zone)
+		}
+	}
+	// without a schedule of its own there is no plain allocation job
+	if _, ok := sch.jobs[JobAllocation]; ok {
+		t.Errorf("job %s registered without a schedule", JobAllocation)
+	}
+}
+
+func TestSetupSharedAllocationJob(t *testing.T) {
+	allDay := types.WarehouseCalendar{}
+	closed := types.WarehouseCalendar{Timezone: "Asia/Kolkata"}
+	closed.Holidays = []string{today(closed)}
+	hours := types.WarehouseCalendar{Timezone: "Europe/London", OpensAt: "09:00", ClosesAt: "17:00"}
+	sch, _, ids := setup(t, config.Jobs{Allocation: config.Job{Schedule: "*/10 * * * *"}},
+		hub("Open", allDay), hub("Closed", closed), hub("Hours", hours))
+
+	// the warehouses open all day are left to the plain allocation job
+	for _, id := range ids[:2] {
+		if _, ok := sch.jobs[WarehouseJobName(id)]; ok {
+			t.Errorf("warehouse %d open all day has a job of its own", id)
+		}
+	}
+	if e, ok := sch.jobs[WarehouseJobName(ids[2])]; !ok || e.Schedule != "0 9 * * *" {
+		t.Errorf("warehouse with hours: job = %v, want one at 09:00", e)
+	}
+
+	// which allocates the one that is not on holiday
+	if err := sch.Trigger(JobAllocation); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	waitFor(t, "the allocation run", func() bool { return stateOf(t, sch, JobAllocation).LastStatus != "" })
+	if st := stateOf(t, sch, JobAllocation); st.LastStatus != StatusOK || !strings.HasPrefix(st.LastResult, "1 warehouses:") {
+		t.Errorf("allocation run = %s %q %s, want one warehouse allocated", st.LastStatus, st.LastResult, st.LastError)
+	}
+
+	// given hours, a warehouse gets its own job and leaves the shared one
+	open := hub("Open", types.WarehouseCalendar{OpensAt: "07:00", ClosesAt: "22:00"})
+	open.ID = ids[0]
+	if err := sch.ScheduleWarehouse(open); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := sch.jobs[WarehouseJobName(ids[0])]; !ok || e.Schedule != "0 7 * * *" {
+		t.Errorf("after the calendar change: job = %v, want one at 07:00", e)
+	}
+	// and back again
+	open.WarehouseCalendar = allDay
+	if err := sch.ScheduleWarehouse(open); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sch.jobs[WarehouseJobName(ids[0])]; ok {
+		t.Error("warehouse open all day again still has a job of its own")
+	}
+}
+
+func TestWarehouseJobSkipsHoliday(t *testing.T) {
+	cal := types.WarehouseCalendar{Timezone: "America/New_York", OpensAt: "08:00", ClosesAt: "18:00"}
+	cal.Holidays = []string{today(cal)}
+	sch, _, ids := setup(t, config.Jobs{}, hub("Closed", cal), hub("Open", types.WarehouseCalendar{}))
+
+	closed, open := WarehouseJobName(ids[0]), WarehouseJobName(ids[1])
+	for _, name := range []string{closed, open} {
+		if err := sch.Trigger(name); err != nil {
+			t.Fatalf("Trigger %s: %v", name, err)
+		}
+	}
+	waitFor(t, "both runs", func() bool {
+		return stateOf(t, sch, closed).LastStatus != "" && stateOf(t, sch, open).LastStatus != ""
+	})
+	if st := stateOf(t, sch, closed); st.LastResult != "skipped: holiday" {
+		t.Errorf("on a holiday: %s %q, want it skipped", st.LastStatus, st.LastResult)
+	}
+	if st := stateOf(t, sch, open); st.LastStatus != StatusOK || !strings.Contains(st.LastResult, "assigned") {
+		t.Errorf("on a working day: %s %q %s, want an allocation", st.LastStatus, st.LastResult, st.LastError)
+	}
+}
+
+func TestDailyAt(t *testing.T) {
+	for hm, want := range map[string]string{
+		"00:00": "0 0 * * *",
+		"08:30": "30 8 * * *",
+		"23:59": "59 23 * * *",
+		"8am":   "0 0 * * *",
+	} {
+		if got := dailyAt(hm); got != want {
+			t.Errorf("dailyAt(%q) = %q, want %q", hm, got, want)
+		}
+	}
+}
@@ -1,33 +1,320 @@
+// Package schedular runs named jobs on cron schedules. Every job runs in its own
+// timezone with optional jitter, never overlaps itself (in this process or, through a
+// storage lock, in any other) and has its last and next run persisted in storage.
 package schedular
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	mathrand "math/rand"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
+	"github.com/robfig/cron/v3"
+	"github.com/sharmaprinceji/delivery-management-system/internal/metrics"
 	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
 )
 
-func SchedularJob(s storage.Storage) {
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// Run statuses stored with each job run.
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Func is the body of a job. The returned summary is stored as the run's result.
+type Func func(ctx context.Context) (string, error)
+
+type Job struct {
+	Name     string
+	Schedule string
+	Location *time.Location
+	// Jitter delays each scheduled run by a random duration in [0, Jitter).
+	Jitter time.Duration
+	// Timeout bounds one run; it is also how long a crashed run's lock is honoured. A live
+	// run renews its lock until it returns.
+	Timeout time.Duration
+	// Disabled jobs are listed and can be triggered but are never scheduled.
+	Disabled bool
+	Run      Func
+}
+
+type entry struct {
+	Job
+	schedule cron.Schedule
+	busy     atomic.Bool
+	// removed is closed by Unregister to end the job's loop.
+	removed chan struct{}
+}
+
+type Scheduler struct {
+	store storage.Storage
+	log   *slog.Logger
+	owner string
+
+	mu    sync.Mutex
+	jobs  map[string]*entry
+	names []string
+	ctx   context.Context
+
+	running atomic.Bool
+	loops   sync.WaitGroup
+	wg      sync.WaitGroup
+
+	// warehouseJob builds a warehouse's allocation job and reports whether the warehouse
+	// has one of its own; set by Setup.
+	warehouseJob func(types.Warehouse) (Job, bool)
+}
+
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+func New(store storage.Storage) *Scheduler {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+
+	return &Scheduler{
+		store: store,
+		log:   slog.Default().With(slog.String("component", "scheduler")),
+		owner: fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b)),
+		jobs:  make(map[string]*entry),
+		ctx:   context.Background(),
+	}
+}
+
+// Register adds a job. Jobs registered after Start are scheduled at once.
+func (s *Scheduler) Register(job Job) error {
+	schedule, err := parser.Parse(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: invalid schedule %q: %w", job.Name, job.Schedule, err)
+	}
+	if job.Location == nil {
+		job.Location = time.Local
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = 30 * time.Minute
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("job %s registered twice", job.Name)
+	}
+	e := &entry{Job: job, schedule: schedule, removed: make(chan struct{})}
+	s.jobs[job.Name] = e
+	s.names = append(s.names, job.Name)
+	if s.running.Load() {
+		s.startLoop(e)
+	}
+	return nil
+}
+
+// Unregister removes a job and stops scheduling it. A run in progress is not interrupted.
+func (s *Scheduler) Unregister(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.jobs[name]
+	if !ok {
+		return
+	}
+	close(e.removed)
+	delete(s.jobs, name)
+	s.names = slices.DeleteFunc(s.names, func(n string) bool { return n == name })
+}
+
+// Start schedules every enabled job until ctx is cancelled. Runs in progress at that
+// point are interrupted through their context.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.running.Store(true)
+	for _, name := range s.names {
+		s.startLoop(s.jobs[name])
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		<-ctx.Done()
+		s.loops.Wait()
+		s.running.Store(false)
+		s.log.Info("scheduler stopped")
+	}()
+}
+
+// startLoop schedules e unless it is disabled. The caller holds s.mu.
+func (s *Scheduler) startLoop(e *entry) {
+	if e.Disabled {
+		s.log.Info("job disabled", slog.String("job", e.Name))
+		return
+	}
+	ctx := s.ctx
+	s.loops.Add(1)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.loops.Done()
+		s.loop(ctx, e)
+	}()
+}
+
+// Running reports whether the scheduler has been started and not yet stopped.
+func (s *Scheduler) Running() bool {
+	return s.running.Load()
+}
+
+// Wait blocks until the job loops and any triggered runs have returned.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// Trigger starts a run of the named job in the background.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%s: %w", name, ErrUnknownJob)
+	}
+	if !e.busy.CompareAndSwap(false, true) {
+		return fmt.Errorf("%s: %w", name, ErrJobRunning)
+	}
+
+	s.wg.Add(1)
 	go func() {
-		for {
-			now := time.Now()
-			next := time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, now.Location())
-			if now.After(next) {
-				next = next.Add(24 * time.Hour)
-			}
-			duration := next.Sub(now)
-
-			log.Printf("Allocation job scheduled at: %v", next)
-
-			time.Sleep(duration)
-
-			log.Println("Running auto allocation job...")
-			if err := jobs.AllocateOrders(s); err != nil {
-				log.Printf("Auto allocation error: %v", err)
-			} else {
-				log.Println(" Auto allocation completed.")
-			}
+		defer s.wg.Done()
+		defer e.busy.Store(false)
+		s.execute(ctx, e, "manual")
+	}()
+	return nil
+}
+
+// States lists every registered job merged with its persisted run history.
+func (s *Scheduler) States(ctx context.Context) ([]types.JobState, error) {
+	persisted, err := s.store.GetJobStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]types.JobState, len(persisted))
+	for _, st := range persisted {
+		byName[st.Name] = st
+	}
+
+	s.mu.Lock()
+	entries := make([]*entry, 0, len(s.names))
+	for _, name := range s.names {
+		entries = append(entries, s.jobs[name])
+	}
+	s.mu.Unlock()
+
+	states := make([]types.JobState, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name
+		st := byName[name]
+		st.Name = name
+		st.Schedule = e.Schedule
+		st.Timezone = e.Location.String()
+		st.Enabled = !e.Disabled
+		st.Running = e.busy.Load()
+		if e.Disabled {
+			st.NextRunAt = nil
+		}
+		states = append(states, st)
+	}
+	return states, nil
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	log := s.log.With(slog.String("job", e.Name))
+	for {
+		next := e.schedule.Next(time.Now().In(e.Location))
+		if e.Jitter > 0 {
+			next = next.Add(time.Duration(mathrand.Int63n(int64(e.Jitter))))
+		}
+
+		if err := s.store.ScheduleJob(ctx, e.Name, next); err != nil && ctx.Err() == nil {
+			log.Error("failed to persist next run", slog.String("error", err.Error()))
+		}
+		log.Info("job scheduled", slog.Time("next_run", next))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-e.removed:
+			timer.Stop()
+			log.Info("job unregistered")
+			return
+		case <-timer.C:
+		}
+
+		if !e.busy.CompareAndSwap(false, true) {
+			log.Warn("job skipped, previous run still in progress")
+			continue
+		}
+		s.execute(ctx, e, "schedule")
+		e.busy.Store(false)
+	}
+}
+
+// execute runs e once unless another instance holds its lock. The caller has marked e busy.
+func (s *Scheduler) execute(ctx context.Context, e *entry, trigger string) {
+	log := s.log.With(slog.String("job", e.Name), slog.String("trigger", trigger))
+
+	lock := "job:" + e.Name
+	ok, err := s.store.AcquireLock(ctx, lock, s.owner, e.Timeout)
+	if err != nil {
+		log.Error("failed to take job lock", slog.String("error", err.Error()))
+		return
+	}
+	if !ok {
+		log.Info("job skipped, running in another instance")
+		return
+	}
+	stop := storage.KeepLock(ctx, s.store, lock, s.owner, e.Timeout)
+	defer func() {
+		stop()
+		if err := s.store.ReleaseLock(context.WithoutCancel(ctx), lock, s.owner); err != nil {
+			log.Error("failed to release job lock", slog.String("error", err.Error()))
 		}
 	}()
+
+	runCtx, cancel := context.WithTimeout(ctx, e.Timeout)
+	defer cancel()
+
+	log.Info("job started")
+	start := time.Now()
+	result, err := e.Run(runCtx)
+	metrics.ObserveJob(e.Name, start, err)
+
+	record := types.JobRun{StartedAt: start, Duration: time.Since(start), Status: StatusOK, Result: result}
+	if err != nil {
+		record.Status = StatusError
+		record.Error = err.Error()
+		log.Error("job failed", slog.String("error", err.Error()), slog.Duration("duration", record.Duration))
+	} else {
+		log.Info("job finished", slog.String("result", result), slog.Duration("duration", record.Duration))
+	}
+
+	if err := s.store.RecordJobRun(context.WithoutCancel(ctx), e.Name, record); err != nil {
+		log.Error("failed to record job run", slog.String("error", err.Error()))
+	}
 }
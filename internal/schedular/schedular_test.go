@@ -0,0 +1,236 @@
+package schedular
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// never is a schedule that does not come round during a test.
+const never = "0 0 1 1 *"
+
+// started returns a running scheduler over an in-memory store, stopped with the test.
+func started(t *testing.T) (*Scheduler, storage.Storage) {
+	t.Helper()
+	store := memory.New(&config.Config{})
+	sch := New(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	sch.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		sch.Wait()
+	})
+	return sch, store
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// stateOf returns the listed state of the named job.
+func stateOf(t *testing.T, sch *Scheduler, name string) types.JobState {
+	t.Helper()
+	states, err := sch.States(context.Background())
+	if err != nil {
+		t.Fatalf("States: %v", err)
+	}
+	for _, st := range states {
+		if st.Name == name {
+			return st
+		}
+	}
+	t.Fatalf("job %s not listed in %+v", name, states)
+	return types.JobState{}
+}
+
+func TestRegister(t *testing.T) {
+	sch := New(memory.New(&config.Config{}))
+	noop := func(context.Context) (string, error) { return "", nil }
+
+	if err := sch.Register(Job{Name: "bad", Schedule: "every day", Run: noop}); err == nil {
+		t.Error("Register accepted an invalid schedule")
+	}
+	if err := sch.Register(Job{Name: "daily", Schedule: "30 3 * * *", Run: noop}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := sch.Register(Job{Name: "daily", Schedule: "30 3 * * *", Run: noop}); err == nil {
+		t.Error("Register accepted a job twice")
+	}
+
+	// defaults apply to what was left out
+	e := sch.jobs["daily"]
+	if e.Location != time.Local || e.Timeout != 30*time.Minute {
+		t.Errorf("job = %+v, want the local zone and a 30 minute timeout", e.Job)
+	}
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, loc)
+	if next := e.schedule.Next(from); !next.Equal(time.Date(2030, 1, 1, 3, 30, 0, 0, loc)) {
+		t.Errorf("next run after %v = %v, want 03:30 the same day", from, next)
+	}
+}
+
+func TestTriggerRecordsRuns(t *testing.T) {
+	sch, _ := started(t)
+	fail := errors.New("warehouse unreachable")
+	var runs atomic.Int32
+	err := sch.Register(Job{Name: "report", Schedule: never, Run: func(context.Context) (string, error) {
+		if runs.Add(1) == 2 {
+			return "", fail
+		}
+		return "3 sent", nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sch.Trigger("missing"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Trigger of an unknown job: got %v, want ErrUnknownJob", err)
+	}
+
+	if err := sch.Trigger("report"); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	waitFor(t, "the first run to be recorded", func() bool { return stateOf(t, sch, "report").LastStatus != "" })
+	st := stateOf(t, sch, "report")
+	if st.LastStatus != StatusOK || st.LastResult != "3 sent" || st.LastRunAt == nil || st.LastError != "" {
+		t.Errorf("after a successful run: %+v", st)
+	}
+	if st.NextRunAt == nil || st.NextRunAt.Year() <= time.Now().Year()-1 {
+		t.Errorf("next run = %v, want the persisted schedule", st.NextRunAt)
+	}
+
+	waitFor(t, "the job to be free", func() bool { return !stateOf(t, sch, "report").Running })
+	if err := sch.Trigger("report"); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	waitFor(t, "the failed run to be recorded", func() bool { return stateOf(t, sch, "report").LastStatus == StatusError })
+	if st := stateOf(t, sch, "report"); st.LastError != fail.Error() {
+		t.Errorf("after a failed run: %+v", st)
+	}
+}
+
+func TestOverlappingTicksSkipped(t *testing.T) {
+	sch, _ := started(t)
+	release := make(chan struct{})
+	var runs, running, overlapped atomic.Int32
+	err := sch.Register(Job{Name: "slow", Schedule: "@every 1s", Run: func(ctx context.Context) (string, error) {
+		runs.Add(1)
+		if running.Add(1) > 1 {
+			overlapped.Store(1)
+		}
+		defer running.Add(-1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return "done", nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "the first tick", func() bool { return runs.Load() == 1 })
+	if err := sch.Trigger("slow"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("Trigger while running: got %v, want ErrJobRunning", err)
+	}
+	// two more ticks come and go while the first run holds on
+	time.Sleep(2200 * time.Millisecond)
+	if n := runs.Load(); n != 1 {
+		t.Errorf("%d runs while the first was in progress, want 1", n)
+	}
+
+	close(release)
+	waitFor(t, "the run to be recorded", func() bool { return stateOf(t, sch, "slow").LastStatus == StatusOK })
+	waitFor(t, "the next tick", func() bool { return runs.Load() >= 2 })
+	if overlapped.Load() != 0 {
+		t.Error("runs overlapped")
+	}
+}
+
+func TestRunLockedElsewhereSkipped(t *testing.T) {
+	sch, store := started(t)
+	var runs atomic.Int32
+	err := sch.Register(Job{Name: "cleanup", Schedule: never, Timeout: time.Minute, Run: func(context.Context) (string, error) {
+		runs.Add(1)
+		return "", nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := store.AcquireLock(context.Background(), "job:cleanup", "other-instance", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("AcquireLock = %v, %v", ok, err)
+	}
+	if err := sch.Trigger("cleanup"); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	waitFor(t, "the trigger to finish", func() bool { return !stateOf(t, sch, "cleanup").Running })
+	if runs.Load() != 0 || stateOf(t, sch, "cleanup").LastStatus != "" {
+		t.Errorf("job ran while another instance held its lock")
+	}
+
+	if err := store.ReleaseLock(context.Background(), "job:cleanup", "other-instance"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sch.Trigger("cleanup"); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	waitFor(t, "the run", func() bool { return stateOf(t, sch, "cleanup").LastStatus == StatusOK })
+}
+
+func TestJitterAndUnregister(t *testing.T) {
+	sch, _ := started(t)
+	noop := func(context.Context) (string, error) { return "", nil }
+	err := sch.Register(Job{Name: "jittery", Schedule: never, Location: time.UTC, Jitter: time.Hour, Run: noop})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "the next run to be persisted", func() bool { return stateOf(t, sch, "jittery").NextRunAt != nil })
+	next := *stateOf(t, sch, "jittery").NextRunAt
+	due := sch.jobs["jittery"].schedule.Next(time.Now().UTC())
+	if next.Before(due) || !next.Before(due.Add(time.Hour)) {
+		t.Errorf("next run = %v, want within an hour after %v", next, due)
+	}
+
+	sch.Unregister("jittery")
+	if err := sch.Trigger("jittery"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Trigger after Unregister: got %v, want ErrUnknownJob", err)
+	}
+}
+
+func TestDisabledJobNotScheduled(t *testing.T) {
+	sch, _ := started(t)
+	noop := func(context.Context) (string, error) { return "ran", nil }
+	if err := sch.Register(Job{Name: "off", Schedule: "@every 1s", Disabled: true, Run: noop}); err != nil {
+		t.Fatal(err)
+	}
+	if st := stateOf(t, sch, "off"); st.Enabled || st.NextRunAt != nil {
+		t.Errorf("disabled job = %+v, want no next run", st)
+	}
+
+	// it can still be triggered by hand
+	if err := sch.Trigger("off"); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	waitFor(t, "the manual run", func() bool { return stateOf(t, sch, "off").LastResult == "ran" })
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// BlobStore keeps files, such as proof of delivery photos, by key. Keys are slash
+// separated relative paths, e.g. "7/12-photo.jpg".
+type BlobStore interface {
+	// Put stores r under key, replacing any file there, and returns its size.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns ErrNotFound for an unknown key.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file under key; an unknown key is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,10 @@
+package storage
+
+import "errors"
+
+// Sentinel errors returned by Storage implementations. Wrap them with context using %w;
+// handlers map them to HTTP statuses via response.FromError.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+)
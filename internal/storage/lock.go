@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// KeepLock renews a lock owner holds every third of ttl until the returned stop is called,
+// so that a holder running longer than ttl is not taken for a crashed one. A failed
+// renewal is logged and tried again; a lost lock is logged and no longer renewed.
+func KeepLock(ctx context.Context, s Storage, name, owner string, ttl time.Duration) (stop func()) {
+	if ttl <= 0 {
+		return func() {}
+	}
+	log := slog.Default().With(slog.String("lock", name))
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			// the holder may still be finishing after ctx is cancelled
+			ok, err := s.RenewLock(context.WithoutCancel(ctx), name, owner)
+			if err != nil {
+				log.Warn("failed to renew lock", slog.String("error", err.Error()))
+				continue
+			}
+			if !ok {
+				log.Warn("lock lost to another owner")
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
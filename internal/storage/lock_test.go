@@ -0,0 +1,34 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+)
+
+func TestKeepLock(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New(&config.Config{})
+	const ttl = 60 * time.Millisecond
+
+	if ok, err := s.AcquireLock(ctx, "job", "a", ttl); err != nil || !ok {
+		t.Fatalf("AcquireLock = %v, %v", ok, err)
+	}
+	stop := storage.KeepLock(ctx, s, "job", "a", ttl)
+
+	// well past the ttl, the renewed lock is still held
+	time.Sleep(3 * ttl)
+	if ok, err := s.AcquireLock(ctx, "job", "b", ttl); err != nil || ok {
+		t.Errorf("acquire of a renewed lock = %v, %v; want false, nil", ok, err)
+	}
+
+	stop()
+	time.Sleep(2 * ttl)
+	if ok, err := s.AcquireLock(ctx, "job", "b", ttl); err != nil || !ok {
+		t.Errorf("acquire once renewals stopped = %v, %v; want true, nil", ok, err)
+	}
+}
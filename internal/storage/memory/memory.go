@@ -0,0 +1,1647 @@
+// Package memory is a thread-safe, in-process storage.Storage with the same semantics as
+// the SQL backends. It is meant for tests and simulations; nothing is persisted.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+type Memory struct {
+	mu       sync.RWMutex
+	delivery config.Delivery
+	now      func() time.Time
+
+	users       []types.User
+	warehouses  []types.Warehouse
+	agents      []types.Agent
+	orders      []types.Order
+	assignments []types.Assignment
+	zones       []types.ServiceZone
+	reviews     []types.AddressReview
+	customers   []types.Customer // without addresses, see addresses
+	addresses   []types.CustomerAddress
+	proofs      []types.DeliveryProof
+	attempts    []types.DeliveryAttempt
+	outbox      []types.Notification
+
+	// ids keep counting after retention cleanup removes rows
+	nextOrderID        int64
+	nextAssignmentID   int64
+	nextZoneID         int64
+	nextReviewID       int64
+	nextCustomerID     int64
+	nextAddressID      int64
+	nextAttemptID      int64
+	nextNotificationID int64
+
+	locks     map[string]lock
+	jobStates map[string]types.JobState
+	escalated map[int64]bool
+}
+
+type lock struct {
+	owner    string
+	lockedAt time.Time
+}
+
+func New(cfg *config.Config) *Memory {
+	return &Memory{
+		delivery:  cfg.Variables.Delivery,
+		now:       func() time.Time { return time.Now().UTC() },
+		locks:     make(map[string]lock),
+		jobStates: make(map[string]types.JobState),
+		escalated: make(map[int64]bool),
+	}
+}
+
+func (m *Memory) InitSchema(ctx context.Context) error { return ctx.Err() }
+
+func (m *Memory) Ping(ctx context.Context) error { return ctx.Err() }
+
+// SchemaVersion always reports an up-to-date schema; there is nothing to migrate.
+func (m *Memory) SchemaVersion(ctx context.Context) (int, int, error) { return 0, 0, ctx.Err() }
+
+func (m *Memory) Save(ctx context.Context, data any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch v := data.(type) {
+	case types.User:
+		for _, u := range m.users {
+			if u.Email == v.Email {
+				return fmt.Errorf("user with email %s: %w", v.Email, storage.ErrConflict)
+			}
+		}
+		v.ID = int64(len(m.users) + 1)
+		m.users = append(m.users, v)
+		return nil
+	default:
+		return fmt.Errorf("unsupported type in Save: %T", v)
+	}
+}
+
+func (m *Memory) GetCheckedInAgents(ctx context.Context) ([]types.Agent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var agents []types.Agent
+	for _, a := range m.agents {
+		if a.CheckedIn {
+			agents = append(agents, a)
+		}
+	}
+	return agents, nil
+}
+
+func (m *Memory) GetUnassignedOrders(ctx context.Context) ([]types.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var orders []types.Order
+	for _, o := range m.orders {
+		if !o.Assigned {
+			orders = append(orders, copyOrder(o))
+		}
+	}
+	return orders, nil
+}
+
+func (m *Memory) AssignOrderToAgent(ctx context.Context, orderID int64, agentID int64, plannedKm, plannedMinutes float64, eta time.Time) error {
+	return m.InsertOrderIntoRoute(ctx, orderID, agentID, 0, plannedKm, plannedMinutes, eta)
+}
+
+func (m *Memory) InsertOrderIntoRoute(ctx context.Context, orderID, agentID, beforeOrderID int64, plannedKm, plannedMinutes float64, eta time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seq := m.nextRouteSeq(agentID)
+	if beforeOrderID != 0 {
+		before := m.openAssignment(beforeOrderID)
+		if before == nil || before.AgentID != agentID {
+			return fmt.Errorf("order %d is no longer on agent %d's route: %w", beforeOrderID, agentID, storage.ErrConflict)
+		}
+		seq = before.RouteSeq
+	}
+
+	o := m.order(orderID)
+	if o == nil {
+		return fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+	if o.Assigned {
+		return fmt.Errorf("order %d already assigned: %w", orderID, storage.ErrConflict)
+	}
+	otp, err := storage.NewOTP()
+	if err != nil {
+		return err
+	}
+	o.Assigned = true
+	id := agentID
+	o.AgentID = &id
+
+	if beforeOrderID != 0 {
+		for i := range m.assignments {
+			if a := &m.assignments[i]; a.AgentID == agentID && a.RouteSeq >= seq {
+				a.RouteSeq++
+			}
+		}
+	}
+
+	m.nextAssignmentID++
+	a := types.Assignment{
+		ID:             m.nextAssignmentID,
+		AgentID:        agentID,
+		OrderID:        orderID,
+		WarehouseID:    o.WarehouseID,
+		AssignedAt:     m.now(),
+		PlannedKm:      plannedKm,
+		PlannedMinutes: plannedMinutes,
+		RouteSeq:       seq,
+		OTP:            otp,
+	}
+	if !eta.IsZero() {
+		eta = eta.UTC()
+		a.ETA = &eta
+	}
+	m.assignments = append(m.assignments, a)
+	m.enqueueNotifications(orderID, types.EventAssigned)
+	return nil
+}
+
+func (m *Memory) CompleteDelivery(ctx context.Context, orderID int64, actualKm, actualMinutes float64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.openAssignment(orderID)
+	if a == nil {
+		return fmt.Errorf("no open assignment for order %d: %w", orderID, storage.ErrNotFound)
+	}
+
+	at := m.now()
+	a.ActualKm = &actualKm
+	a.ActualMinutes = &actualMinutes
+	a.DeliveredAt = &at
+	m.verifyAddress(orderID)
+	m.enqueueNotifications(orderID, types.EventDelivered)
+	return nil
+}
+
+// verifyAddress marks the saved address an order went to, if any, verified: a delivery
+// proves its coordinates. Callers hold the lock.
+func (m *Memory) verifyAddress(orderID int64) {
+	if o := m.order(orderID); o != nil && o.AddressID != 0 {
+		if addr := m.address(o.AddressID); addr != nil {
+			addr.Verified = true
+		}
+	}
+}
+
+func (m *Memory) GetOpenAssignment(ctx context.Context, orderID int64) (types.Assignment, error) {
+	if err := ctx.Err(); err != nil {
+		return types.Assignment{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a := m.openAssignment(orderID)
+	if a == nil {
+		return types.Assignment{}, fmt.Errorf("no open assignment for order %d: %w", orderID, storage.ErrNotFound)
+	}
+	return *a, nil
+}
+
+func (m *Memory) RecordOTPFailure(ctx context.Context, assignmentID int64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.assignments {
+		if a := &m.assignments[i]; a.ID == assignmentID {
+			a.OTPFailures++
+			return a.OTPFailures, nil
+		}
+	}
+	return 0, fmt.Errorf("assignment %d: %w", assignmentID, storage.ErrNotFound)
+}
+
+func (m *Memory) CompleteDeliveryWithProof(ctx context.Context, p types.DeliveryProof, actualKm, actualMinutes float64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.openAssignment(p.OrderID)
+	if a == nil || a.ID != p.AssignmentID {
+		return fmt.Errorf("assignment %d of order %d is no longer open: %w", p.AssignmentID, p.OrderID, storage.ErrNotFound)
+	}
+
+	at := m.now()
+	a.ActualKm = &actualKm
+	a.ActualMinutes = &actualMinutes
+	a.DeliveredAt = &at
+	m.verifyAddress(p.OrderID)
+
+	p.DeliveredAt = at
+	m.proofs = append(m.proofs, copyProof(p))
+	m.enqueueNotifications(p.OrderID, types.EventDelivered)
+	return nil
+}
+
+func (m *Memory) GetDeliveryProof(ctx context.Context, orderID int64) (types.DeliveryProof, error) {
+	if err := ctx.Err(); err != nil {
+		return types.DeliveryProof{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, p := range m.proofs {
+		if p.OrderID == orderID {
+			return copyProof(p), nil
+		}
+	}
+	return types.DeliveryProof{}, fmt.Errorf("no proof of delivery for order %d: %w", orderID, storage.ErrNotFound)
+}
+
+func (m *Memory) UnassignOrder(ctx context.Context, orderID int64, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.openAssignment(orderID)
+	if a == nil {
+		return fmt.Errorf("no open assignment for order %d: %w", orderID, storage.ErrNotFound)
+	}
+	m.close(a, reason)
+	return nil
+}
+
+func (m *Memory) ReassignOrder(ctx context.Context, orderID, agentID int64, plannedKm, plannedMinutes float64, eta time.Time, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if agent := m.agent(agentID); agent == nil || !agent.CheckedIn {
+		return fmt.Errorf("agent %d is not checked in: %w", agentID, storage.ErrConflict)
+	}
+	a := m.openAssignment(orderID)
+	if a == nil {
+		return fmt.Errorf("no open assignment for order %d: %w", orderID, storage.ErrNotFound)
+	}
+	if a.AgentID == agentID {
+		return fmt.Errorf("order %d is already assigned to agent %d: %w", orderID, agentID, storage.ErrConflict)
+	}
+
+	// the new agent gets a new code; the old one may have been shared with the old agent
+	otp, err := storage.NewOTP()
+	if err != nil {
+		return err
+	}
+	m.close(a, reason)
+	o := m.order(orderID)
+	o.Assigned = true
+	id := agentID
+	o.AgentID = &id
+
+	m.nextAssignmentID++
+	next := types.Assignment{
+		ID:             m.nextAssignmentID,
+		AgentID:        agentID,
+		OrderID:        orderID,
+		WarehouseID:    a.WarehouseID,
+		AssignedAt:     m.now(),
+		PlannedKm:      plannedKm,
+		PlannedMinutes: plannedMinutes,
+		RouteSeq:       m.nextRouteSeq(agentID),
+		OTP:            otp,
+	}
+	if !eta.IsZero() {
+		eta = eta.UTC()
+		next.ETA = &eta
+	}
+	m.assignments = append(m.assignments, next)
+	// the customer needs the new agent's name and code
+	m.enqueueNotifications(orderID, types.EventAssigned)
+	return nil
+}
+
+func (m *Memory) FailDelivery(ctx context.Context, orderID int64, a types.DeliveryAttempt, maxAttempts, priorityBump int) (types.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return types.Order{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	open := m.openAssignment(orderID)
+	if open == nil {
+		return types.Order{}, fmt.Errorf("no open assignment for order %d: %w", orderID, storage.ErrNotFound)
+	}
+	m.close(open, "delivery failed: "+a.Reason)
+
+	o := m.order(orderID)
+	o.FailedAttempts++
+	now := m.now()
+	m.nextAttemptID++
+	m.attempts = append(m.attempts, types.DeliveryAttempt{
+		ID:           m.nextAttemptID,
+		OrderID:      orderID,
+		AssignmentID: open.ID,
+		AgentID:      open.AgentID,
+		Attempt:      o.FailedAttempts,
+		Reason:       a.Reason,
+		Note:         a.Note,
+		AttemptedAt:  now,
+	})
+
+	event := types.EventAttemptFailed
+	if o.FailedAttempts >= maxAttempts {
+		// the agent takes it back, so it stays theirs and out of allocation
+		event = types.EventReturning
+		agentID := open.AgentID
+		o.Assigned = true
+		o.AgentID = &agentID
+		o.ReturnStartedAt = &now
+	} else {
+		o.Priority += priorityBump
+	}
+	m.enqueueNotifications(orderID, event)
+	return copyOrder(*o), nil
+}
+
+func (m *Memory) GetDeliveryAttempts(ctx context.Context, orderID int64) ([]types.DeliveryAttempt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.order(orderID) == nil {
+		return nil, fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+	attempts := []types.DeliveryAttempt{}
+	for _, a := range m.attempts {
+		if a.OrderID == orderID {
+			attempts = append(attempts, a)
+		}
+	}
+	return attempts, nil
+}
+
+func (m *Memory) GetReturningOrders(ctx context.Context, warehouseID int64) ([]types.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	orders := []types.Order{}
+	for _, o := range m.orders {
+		if o.ReturnStartedAt != nil && o.ReturnedAt == nil && (warehouseID == 0 || o.WarehouseID == warehouseID) {
+			orders = append(orders, copyOrder(o))
+		}
+	}
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].ReturnStartedAt.Before(*orders[j].ReturnStartedAt) })
+	return orders, nil
+}
+
+func (m *Memory) CompleteReturn(ctx context.Context, orderID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o := m.order(orderID)
+	if o == nil {
+		return fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+	if o.ReturnStartedAt == nil || o.ReturnedAt != nil {
+		return fmt.Errorf("order %d is not being returned: %w", orderID, storage.ErrConflict)
+	}
+	now := m.now()
+	o.ReturnedAt = &now
+	return nil
+}
+
+func (m *Memory) ReleaseAgentOrders(ctx context.Context, agentID int64, reason string, checkOut bool) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agent := m.agent(agentID)
+	if agent == nil {
+		return nil, fmt.Errorf("agent %d: %w", agentID, storage.ErrNotFound)
+	}
+
+	released := []int64{}
+	for i := range m.assignments {
+		if a := &m.assignments[i]; a.AgentID == agentID && a.Open() {
+			m.close(a, reason)
+			released = append(released, a.OrderID)
+		}
+	}
+	sort.Slice(released, func(i, j int) bool { return released[i] < released[j] })
+	for _, id := range released {
+		m.enqueueNotifications(id, types.EventUnassigned)
+	}
+	if checkOut {
+		agent.CheckedIn = false
+	}
+	return released, nil
+}
+
+// nextRouteSeq returns the position after the last stop of the agent's route. Callers hold the lock.
+func (m *Memory) nextRouteSeq(agentID int64) int {
+	seq := 0
+	for _, a := range m.assignments {
+		if a.AgentID == agentID && a.RouteSeq > seq {
+			seq = a.RouteSeq
+		}
+	}
+	return seq + 1
+}
+
+// openAssignment returns the latest open assignment of an order, or nil. Callers hold the lock.
+func (m *Memory) openAssignment(orderID int64) *types.Assignment {
+	var open *types.Assignment
+	for i := range m.assignments {
+		a := &m.assignments[i]
+		if a.OrderID != orderID || !a.Open() {
+			continue
+		}
+		if open == nil || !a.AssignedAt.Before(open.AssignedAt) {
+			open = a
+		}
+	}
+	return open
+}
+
+// close takes an open assignment back from its agent and makes the order pending again.
+// Callers hold the lock.
+func (m *Memory) close(a *types.Assignment, reason string) {
+	at := m.now()
+	a.UnassignedAt = &at
+	a.UnassignReason = reason
+	if o := m.order(a.OrderID); o != nil {
+		o.Assigned = false
+		o.AgentID = nil
+	}
+}
+
+func (m *Memory) GetAgentDetails(ctx context.Context, agentID int64) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	agent := m.agent(agentID)
+	if agent == nil {
+		return nil, fmt.Errorf("agent %d: %w", agentID, storage.ErrNotFound)
+	}
+
+	warehouseName := ""
+	if w := m.warehouse(agent.WarehouseID); w != nil {
+		warehouseName = w.Name
+	}
+
+	summary := m.summaries()[agentID]
+	summary.AgentID = agentID
+	m.fillSummary(&summary)
+
+	return map[string]interface{}{
+		"agent_id":         agentID,
+		"agent_name":       agent.Name,
+		"warehouse_id":     agent.WarehouseID,
+		"warehouse_name":   warehouseName,
+		"total_orders":     summary.TotalOrders,
+		"delivered_orders": summary.DeliveredOrders,
+		"total_km":         summary.TotalKm,
+		"total_minutes":    summary.TotalMinutes,
+		"planned_km":       summary.PlannedKm,
+		"planned_minutes":  summary.PlannedMinutes,
+		"actual_km":        summary.ActualKm,
+		"actual_minutes":   summary.ActualMinutes,
+		"profit":           summary.Profit,
+	}, nil
+}
+
+func (m *Memory) GetPaginatedAssignments(ctx context.Context, limit, offset int) ([]types.Assignment, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sorted := make([]types.Assignment, len(m.assignments))
+	copy(sorted, m.assignments)
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].AssignedAt.Equal(sorted[j].AssignedAt) {
+			return sorted[i].AssignedAt.After(sorted[j].AssignedAt)
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+
+	return paginate(sorted, limit, offset), len(sorted), nil
+}
+
+func (m *Memory) CreateWarehouse(ctx context.Context, w types.Warehouse) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.ID = int64(len(m.warehouses) + 1)
+	w.WarehouseCalendar = w.WarehouseCalendar.WithDefaults()
+	m.warehouses = append(m.warehouses, w)
+	return w.ID, nil
+}
+
+func (m *Memory) SetWarehouseCalendar(ctx context.Context, warehouseID int64, cal types.WarehouseCalendar) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := m.warehouse(warehouseID)
+	if w == nil {
+		return fmt.Errorf("warehouse %d: %w", warehouseID, storage.ErrNotFound)
+	}
+	w.WarehouseCalendar = cal.WithDefaults()
+	return nil
+}
+
+func (m *Memory) GetWarehouses(ctx context.Context) ([]types.Warehouse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	warehouses := make([]types.Warehouse, len(m.warehouses))
+	for i, w := range m.warehouses {
+		w.Holidays = append([]string{}, w.Holidays...)
+		warehouses[i] = w
+	}
+	return warehouses, nil
+}
+
+func (m *Memory) GetWarehouseLoad(ctx context.Context) ([]types.WarehouseLoad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var loads []types.WarehouseLoad
+	for _, w := range m.warehouses {
+		l := types.WarehouseLoad{WarehouseID: w.ID}
+		for _, a := range m.agents {
+			if a.WarehouseID == w.ID && a.CheckedIn {
+				l.CheckedInAgents++
+			}
+		}
+		for _, o := range m.orders {
+			if o.WarehouseID == w.ID && !o.Assigned {
+				l.PendingOrders++
+			}
+		}
+		loads = append(loads, l)
+	}
+	return loads, nil
+}
+
+func (m *Memory) CreateServiceZones(ctx context.Context, warehouseID int64, zones []types.ServiceZone) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.warehouse(warehouseID) == nil {
+		return nil, fmt.Errorf("warehouse %d: %w", warehouseID, storage.ErrNotFound)
+	}
+
+	ids := make([]int64, 0, len(zones))
+	for _, z := range zones {
+		m.nextZoneID++
+		z.ID = m.nextZoneID
+		z.WarehouseID = warehouseID
+		z.CreatedAt = m.now()
+		z.Polygon = copyPolygon(z.Polygon)
+		m.zones = append(m.zones, z)
+		ids = append(ids, z.ID)
+	}
+	return ids, nil
+}
+
+func (m *Memory) GetServiceZones(ctx context.Context, warehouseID int64) ([]types.ServiceZone, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var zones []types.ServiceZone
+	for _, z := range m.zones {
+		if warehouseID == 0 || z.WarehouseID == warehouseID {
+			z.Polygon = copyPolygon(z.Polygon)
+			zones = append(zones, z)
+		}
+	}
+	return zones, nil
+}
+
+func (m *Memory) DeleteServiceZone(ctx context.Context, zoneID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.zones, func(z types.ServiceZone) bool { return z.ID == zoneID })
+	if i < 0 {
+		return fmt.Errorf("service zone %d: %w", zoneID, storage.ErrNotFound)
+	}
+	m.zones = slices.Delete(m.zones, i, i+1)
+	return nil
+}
+
+func (m *Memory) CreateAddressReviews(ctx context.Context, reviews []types.AddressReview) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]int64, 0, len(reviews))
+	for _, rv := range reviews {
+		m.nextReviewID++
+		rv.ID = m.nextReviewID
+		rv.CreatedAt = m.now()
+		m.reviews = append(m.reviews, copyReview(rv))
+		ids = append(ids, rv.ID)
+	}
+	return ids, nil
+}
+
+func (m *Memory) GetAddressReviews(ctx context.Context) ([]types.AddressReview, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var reviews []types.AddressReview
+	for _, rv := range m.reviews {
+		reviews = append(reviews, copyReview(rv))
+	}
+	return reviews, nil
+}
+
+func (m *Memory) GetAddressReview(ctx context.Context, reviewID int64) (types.AddressReview, error) {
+	if err := ctx.Err(); err != nil {
+		return types.AddressReview{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i := slices.IndexFunc(m.reviews, func(rv types.AddressReview) bool { return rv.ID == reviewID })
+	if i < 0 {
+		return types.AddressReview{}, fmt.Errorf("address review %d: %w", reviewID, storage.ErrNotFound)
+	}
+	return copyReview(m.reviews[i]), nil
+}
+
+func (m *Memory) ResolveAddressReview(ctx context.Context, reviewID int64, o types.Order) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.reviews, func(rv types.AddressReview) bool { return rv.ID == reviewID })
+	if i < 0 {
+		return 0, fmt.Errorf("address review %d: %w", reviewID, storage.ErrNotFound)
+	}
+	m.reviews = slices.Delete(m.reviews, i, i+1)
+	return m.insertOrder(o), nil
+}
+
+func (m *Memory) DeleteAddressReview(ctx context.Context, reviewID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.reviews, func(rv types.AddressReview) bool { return rv.ID == reviewID })
+	if i < 0 {
+		return fmt.Errorf("address review %d: %w", reviewID, storage.ErrNotFound)
+	}
+	m.reviews = slices.Delete(m.reviews, i, i+1)
+	return nil
+}
+
+// copyReview detaches the address and window pointers of a queued request.
+func copyReview(rv types.AddressReview) types.AddressReview {
+	if a := rv.Request.Address; a != nil {
+		c := *a
+		rv.Request.Address = &c
+	}
+	rv.Request.WindowStart = utcPtr(rv.Request.WindowStart)
+	rv.Request.WindowEnd = utcPtr(rv.Request.WindowEnd)
+	return rv
+}
+
+// copyPolygon detaches a polygon's rings from the caller's.
+func copyPolygon(p types.Polygon) types.Polygon {
+	out := make(types.Polygon, len(p))
+	for i, ring := range p {
+		out[i] = slices.Clone(ring)
+	}
+	return out
+}
+
+func (m *Memory) CreateCustomer(ctx context.Context, c types.Customer) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, other := range m.customers {
+		if other.Phone == c.Phone {
+			return 0, fmt.Errorf("phone %s is customer %d: %w", c.Phone, other.ID, storage.ErrConflict)
+		}
+	}
+
+	m.nextCustomerID++
+	c.ID = m.nextCustomerID
+	c.CreatedAt = m.now()
+	for _, a := range c.Addresses {
+		m.insertAddress(c.ID, a)
+	}
+	c.Addresses = nil
+	m.customers = append(m.customers, c)
+	return c.ID, nil
+}
+
+func (m *Memory) insertAddress(customerID int64, a types.CustomerAddress) int64 {
+	m.nextAddressID++
+	a.ID = m.nextAddressID
+	a.CustomerID = customerID
+	a.Verified = false
+	a.CreatedAt = m.now()
+	m.addresses = append(m.addresses, a)
+	return a.ID
+}
+
+func (m *Memory) GetCustomer(ctx context.Context, customerID int64) (types.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return types.Customer{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c := m.customer(customerID)
+	if c == nil {
+		return types.Customer{}, fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+	out := *c
+	out.Addresses = []types.CustomerAddress{}
+	for _, a := range m.addresses {
+		if a.CustomerID == customerID {
+			out.Addresses = append(out.Addresses, a)
+		}
+	}
+	return out, nil
+}
+
+func (m *Memory) AddCustomerAddress(ctx context.Context, customerID int64, a types.CustomerAddress) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.customer(customerID) == nil {
+		return 0, fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+	return m.insertAddress(customerID, a), nil
+}
+
+func (m *Memory) SetNotificationsOptOut(ctx context.Context, customerID int64, optOut bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.customer(customerID)
+	if c == nil {
+		return fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+	c.NotificationsOptOut = optOut
+	return nil
+}
+
+func (m *Memory) GetCustomerAddress(ctx context.Context, addressID int64) (types.CustomerAddress, error) {
+	if err := ctx.Err(); err != nil {
+		return types.CustomerAddress{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a := m.address(addressID)
+	if a == nil {
+		return types.CustomerAddress{}, fmt.Errorf("address %d: %w", addressID, storage.ErrNotFound)
+	}
+	return *a, nil
+}
+
+func (m *Memory) GetCustomerOrders(ctx context.Context, customerID int64, limit, offset int) ([]types.CustomerOrder, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.customer(customerID) == nil {
+		return nil, 0, fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+
+	var all []types.CustomerOrder
+	for i := len(m.orders) - 1; i >= 0; i-- {
+		o := m.orders[i]
+		if o.CustomerID != customerID {
+			continue
+		}
+		var deliveredAt *time.Time
+		for _, a := range m.assignments {
+			if a.OrderID == o.ID && a.DeliveredAt != nil {
+				deliveredAt = utcPtr(a.DeliveredAt)
+			}
+		}
+		all = append(all, types.NewCustomerOrder(copyOrder(o), deliveredAt))
+	}
+	return append([]types.CustomerOrder{}, paginate(all, limit, offset)...), len(all), nil
+}
+
+func (m *Memory) CheckInAgents(ctx context.Context, a types.Agent) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := int64(len(m.agents) + 1)
+	m.agents = append(m.agents, types.Agent{ID: id, Name: a.Name, WarehouseID: a.WarehouseID, CheckedIn: true, Capacity: a.Capacity})
+	return id, nil
+}
+
+func (m *Memory) GetOrder(ctx context.Context, orderID int64) (types.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return types.Order{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	o := m.order(orderID)
+	if o == nil {
+		return types.Order{}, fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+	return copyOrder(*o), nil
+}
+
+func (m *Memory) CreateOrder(ctx context.Context, o types.Order) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.insertOrder(o), nil
+}
+
+func (m *Memory) CreateBulkOrders(ctx context.Context, orders []types.Order) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]int64, 0, len(orders))
+	for _, o := range orders {
+		ids = append(ids, m.insertOrder(o))
+	}
+	return ids, nil
+}
+
+func (m *Memory) GetRoutes(ctx context.Context, since time.Time) ([]types.RouteStop, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stops []types.RouteStop
+	for _, a := range m.assignments {
+		if a.UnassignedAt != nil || (a.DeliveredAt != nil && a.AssignedAt.Before(since)) {
+			continue
+		}
+		o := m.order(a.OrderID)
+		if o == nil {
+			continue
+		}
+		stops = append(stops, types.RouteStop{
+			AgentID:        a.AgentID,
+			OrderID:        a.OrderID,
+			RouteSeq:       a.RouteSeq,
+			Location:       types.Location{Lat: o.Lat, Lng: o.Lng},
+			WindowStart:    o.WindowStart,
+			WindowEnd:      o.WindowEnd,
+			PlannedKm:      a.PlannedKm,
+			PlannedMinutes: a.PlannedMinutes,
+			AssignedAt:     a.AssignedAt,
+			DeliveredAt:    a.DeliveredAt,
+			Load:           o.Load,
+		})
+	}
+	sort.SliceStable(stops, func(i, j int) bool {
+		if stops[i].AgentID != stops[j].AgentID {
+			return stops[i].AgentID < stops[j].AgentID
+		}
+		return stops[i].RouteSeq < stops[j].RouteSeq
+	})
+	return stops, nil
+}
+
+func (m *Memory) GetWindowedOrders(ctx context.Context) ([]types.SLAOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var orders []types.SLAOrder
+	for _, o := range m.orders {
+		if o.WindowEnd == nil || o.ReturnStartedAt != nil {
+			continue
+		}
+		sla := types.SLAOrder{
+			OrderID:     o.ID,
+			WarehouseID: o.WarehouseID,
+			Customer:    o.Customer,
+			Priority:    o.Priority,
+			WindowStart: o.WindowStart,
+			WindowEnd:   *o.WindowEnd,
+		}
+		delivered := false
+		for _, a := range m.assignments {
+			if a.OrderID != o.ID || a.UnassignedAt != nil {
+				continue
+			}
+			if a.DeliveredAt != nil {
+				delivered = true
+				break
+			}
+			agentID := a.AgentID
+			sla.AgentID = &agentID
+			sla.ETA = a.ETA
+		}
+		if !delivered {
+			orders = append(orders, sla)
+		}
+	}
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].WindowEnd.Before(orders[j].WindowEnd) })
+	return orders, nil
+}
+
+func (m *Memory) GetAgentSummaryPaginated(ctx context.Context, page int, limit int) (types.PaginatedAgentSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return types.PaginatedAgentSummary{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.agentSummaryPage(page, limit), nil
+}
+
+func (m *Memory) GetSystemSummaryPaginated(ctx context.Context, page, limit int) (types.SystemSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return types.SystemSummary{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var summary types.SystemSummary
+	summary.TotalOrders = len(m.orders)
+	for _, o := range m.orders {
+		summary.FailedAttempts += o.FailedAttempts
+		switch {
+		case o.ReturnedAt != nil:
+			summary.ReturnedOrders++
+		case o.ReturnStartedAt != nil:
+			summary.ReturningOrders++
+		case o.Assigned:
+			summary.AssignedOrders++
+		case o.FailedAttempts > 0:
+			summary.RequeuedOrders++
+		}
+	}
+	summary.DeferredOrders = summary.TotalOrders - summary.AssignedOrders - summary.ReturningOrders - summary.ReturnedOrders
+	summary.AgentUtilization = m.agentSummaryPage(page, limit)
+	return summary, nil
+}
+
+func (m *Memory) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if l, ok := m.locks[name]; ok && now.Sub(l.lockedAt) <= ttl {
+		return false, nil
+	}
+	m.locks[name] = lock{owner: owner, lockedAt: now}
+	return true, nil
+}
+
+func (m *Memory) RenewLock(ctx context.Context, name, owner string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.locks[name]
+	if !ok || l.owner != owner {
+		return false, nil
+	}
+	l.lockedAt = m.now()
+	m.locks[name] = l
+	return true, nil
+}
+
+func (m *Memory) ReleaseLock(ctx context.Context, name, owner string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.locks[name].owner == owner {
+		delete(m.locks, name)
+	}
+	return nil
+}
+
+func (m *Memory) GetJobStates(ctx context.Context) ([]types.JobState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	states := make([]types.JobState, 0, len(m.jobStates))
+	for _, st := range m.jobStates {
+		states = append(states, st)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states, nil
+}
+
+func (m *Memory) ScheduleJob(ctx context.Context, name string, next time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.jobStates[name]
+	st.Name = name
+	next = next.UTC()
+	st.NextRunAt = &next
+	m.jobStates[name] = st
+	return nil
+}
+
+func (m *Memory) RecordJobRun(ctx context.Context, name string, run types.JobRun) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.jobStates[name]
+	st.Name = name
+	started := run.StartedAt.UTC()
+	st.LastRunAt = &started
+	st.LastDurationMs = run.Duration.Milliseconds()
+	st.LastStatus = run.Status
+	st.LastError = run.Error
+	st.LastResult = run.Result
+	m.jobStates[name] = st
+	return nil
+}
+
+func (m *Memory) EscalateOverdueAssignments(ctx context.Context, before time.Time) ([]types.Assignment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var overdue []types.Assignment
+	for _, a := range m.assignments {
+		if !a.Open() || m.escalated[a.ID] || !a.AssignedAt.Before(before) {
+			continue
+		}
+		m.escalated[a.ID] = true
+		overdue = append(overdue, a)
+	}
+	sort.SliceStable(overdue, func(i, j int) bool { return overdue[i].AssignedAt.Before(overdue[j].AssignedAt) })
+	return overdue, nil
+}
+
+func (m *Memory) GetDailyReport(ctx context.Context, from, to time.Time) (types.DailyReport, error) {
+	if err := ctx.Err(); err != nil {
+		return types.DailyReport{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	report := types.DailyReport{From: from, To: to}
+	agents := make(map[int64]bool)
+	for _, a := range m.assignments {
+		if !a.AssignedAt.Before(from) && a.AssignedAt.Before(to) {
+			report.OrdersAssigned++
+			report.PlannedKm += a.PlannedKm
+			agents[a.AgentID] = true
+		}
+		if a.DeliveredAt != nil && !a.DeliveredAt.Before(from) && a.DeliveredAt.Before(to) {
+			report.OrdersDelivered++
+			if a.ActualKm != nil {
+				report.ActualKm += *a.ActualKm
+			}
+		}
+	}
+	report.ActiveAgents = len(agents)
+	return report, nil
+}
+
+func (m *Memory) PurgeDeliveredBefore(ctx context.Context, before time.Time) (int, []string, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := make(map[int64]bool)
+	for _, a := range m.assignments {
+		if a.DeliveredAt != nil && a.DeliveredAt.Before(before) {
+			purged[a.OrderID] = true
+		}
+	}
+
+	// earlier assignments of the purged orders that were taken back go with them
+	removed := 0
+	kept := m.assignments[:0]
+	for _, a := range m.assignments {
+		if purged[a.OrderID] {
+			if a.UnassignedAt == nil {
+				removed++
+			}
+			continue
+		}
+		kept = append(kept, a)
+	}
+	m.assignments = kept
+
+	orders := m.orders[:0]
+	for _, o := range m.orders {
+		if !purged[o.ID] {
+			orders = append(orders, o)
+		}
+	}
+	m.orders = orders
+
+	keys := []string{}
+	proofs := m.proofs[:0]
+	for _, p := range m.proofs {
+		if !purged[p.OrderID] {
+			proofs = append(proofs, p)
+			continue
+		}
+		for _, f := range []*types.ProofArtefact{p.Photo, p.Signature} {
+			if f != nil && f.Key != "" {
+				keys = append(keys, f.Key)
+			}
+		}
+	}
+	m.proofs = proofs
+
+	attempts := m.attempts[:0]
+	for _, a := range m.attempts {
+		if !purged[a.OrderID] {
+			attempts = append(attempts, a)
+		}
+	}
+	m.attempts = attempts
+
+	outbox := m.outbox[:0]
+	for _, n := range m.outbox {
+		if !purged[n.OrderID] {
+			outbox = append(outbox, n)
+		}
+	}
+	m.outbox = outbox
+	return removed, keys, nil
+}
+
+// enqueueNotifications puts the messages about event in the outbox like the SQL backends.
+// Callers hold the lock.
+func (m *Memory) enqueueNotifications(orderID int64, event string) {
+	o := m.order(orderID)
+	if o == nil || o.CustomerID == 0 {
+		return
+	}
+	c := m.customer(o.CustomerID)
+	if c == nil || c.NotificationsOptOut {
+		return
+	}
+
+	var agentName, otp string
+	var eta *time.Time
+	for i := len(m.assignments) - 1; i >= 0; i-- {
+		if a := m.assignments[i]; a.OrderID == orderID {
+			if agent := m.agent(a.AgentID); agent != nil {
+				agentName = agent.Name
+			}
+			eta, otp = utcPtr(a.ETA), a.OTP
+			break
+		}
+	}
+	if event != types.EventAssigned {
+		otp = ""
+	}
+
+	now := m.now()
+	for _, to := range []struct{ channel, recipient string }{{types.ChannelEmail, c.Email}, {types.ChannelSMS, c.Phone}} {
+		if to.recipient == "" {
+			continue
+		}
+		m.nextNotificationID++
+		m.outbox = append(m.outbox, types.Notification{
+			ID:            m.nextNotificationID,
+			OrderID:       orderID,
+			CustomerID:    c.ID,
+			Event:         event,
+			Channel:       to.channel,
+			Recipient:     to.recipient,
+			CustomerName:  c.Name,
+			AgentName:     agentName,
+			ETA:           eta,
+			OTP:           otp,
+			Status:        types.NotificationPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+}
+
+func (m *Memory) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]types.Notification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*types.Notification
+	for i := range m.outbox {
+		if n := &m.outbox[i]; n.Status == types.NotificationPending && !n.NextAttemptAt.After(now) {
+			due = append(due, n)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+
+	claimed := []types.Notification{}
+	until := now.Add(lease).UTC()
+	for _, n := range due {
+		if len(claimed) == limit {
+			break
+		}
+		n.NextAttemptAt = until
+		claimed = append(claimed, copyNotification(*n))
+	}
+	return claimed, nil
+}
+
+func (m *Memory) RecordNotificationAttempt(ctx context.Context, notificationID int64, status, lastError string, retryAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.outbox {
+		n := &m.outbox[i]
+		if n.ID != notificationID {
+			continue
+		}
+		now := m.now()
+		n.Status, n.LastError, n.NextAttemptAt = status, lastError, now
+		switch status {
+		case types.NotificationSent:
+			n.SentAt = &now
+		case types.NotificationPending:
+			n.NextAttemptAt = retryAt.UTC()
+		}
+		if status != types.NotificationSkipped {
+			n.Attempts++
+		}
+		return nil
+	}
+	return fmt.Errorf("notification %d: %w", notificationID, storage.ErrNotFound)
+}
+
+func (m *Memory) GetOrderNotifications(ctx context.Context, orderID int64) ([]types.Notification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.order(orderID) == nil {
+		return nil, fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+	notifications := []types.Notification{}
+	for _, n := range m.outbox {
+		if n.OrderID == orderID {
+			notifications = append(notifications, copyNotification(n))
+		}
+	}
+	return notifications, nil
+}
+
+func (m *Memory) agentSummaryPage(page, limit int) types.PaginatedAgentSummary {
+	byAgent := m.summaries()
+
+	ids := make([]int64, 0, len(byAgent))
+	for id := range byAgent {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var summaries []types.AgentSummary
+	for _, id := range paginate(ids, limit, (page-1)*limit) {
+		s := byAgent[id]
+		m.fillSummary(&s)
+		summaries = append(summaries, s)
+	}
+
+	return types.PaginatedAgentSummary{
+		CurrentPage: page,
+		TotalPages:  int(math.Ceil(float64(len(ids)) / float64(limit))),
+		Data:        summaries,
+	}
+}
+
+// summaries aggregates the assignments per agent. Callers hold the lock.
+func (m *Memory) summaries() map[int64]types.AgentSummary {
+	// the legs driven to failed attempts count towards km and minutes, not orders
+	attempted := make(map[int64]bool)
+	for _, at := range m.attempts {
+		attempted[at.AssignmentID] = true
+	}
+	out := make(map[int64]types.AgentSummary)
+	for _, a := range m.assignments {
+		if a.UnassignedAt != nil && !attempted[a.ID] {
+			continue
+		}
+		s := out[a.AgentID]
+		s.AgentID = a.AgentID
+		if a.UnassignedAt == nil {
+			s.TotalOrders++
+		}
+		s.PlannedKm += a.PlannedKm
+		s.PlannedMinutes += a.PlannedMinutes
+		if a.ActualKm != nil {
+			s.ActualKm += *a.ActualKm
+		}
+		if a.ActualMinutes != nil {
+			s.ActualMinutes += *a.ActualMinutes
+		}
+		if a.DeliveredAt != nil {
+			s.DeliveredOrders++
+		}
+		out[a.AgentID] = s
+	}
+	return out
+}
+
+// fillSummary mirrors the SQL backends: totals are the planned figures, profit follows the tiers.
+func (m *Memory) fillSummary(summary *types.AgentSummary) {
+	summary.TotalKm = summary.PlannedKm
+	summary.TotalMinutes = summary.PlannedMinutes
+	summary.Profit = m.delivery.Profit(summary.TotalOrders)
+}
+
+func (m *Memory) insertOrder(o types.Order) int64 {
+	m.nextOrderID++
+	o.ID = m.nextOrderID
+	o.AgentID = nil
+	o.WindowStart = utcPtr(o.WindowStart)
+	o.WindowEnd = utcPtr(o.WindowEnd)
+	m.orders = append(m.orders, o)
+	return o.ID
+}
+
+func (m *Memory) order(id int64) *types.Order {
+	for i := range m.orders {
+		if m.orders[i].ID == id {
+			return &m.orders[i]
+		}
+	}
+	return nil
+}
+
+func (m *Memory) agent(id int64) *types.Agent {
+	for i := range m.agents {
+		if m.agents[i].ID == id {
+			return &m.agents[i]
+		}
+	}
+	return nil
+}
+
+func (m *Memory) address(id int64) *types.CustomerAddress {
+	for i := range m.addresses {
+		if m.addresses[i].ID == id {
+			return &m.addresses[i]
+		}
+	}
+	return nil
+}
+
+func (m *Memory) customer(id int64) *types.Customer {
+	for i := range m.customers {
+		if m.customers[i].ID == id {
+			return &m.customers[i]
+		}
+	}
+	return nil
+}
+
+func (m *Memory) warehouse(id int64) *types.Warehouse {
+	for i := range m.warehouses {
+		if m.warehouses[i].ID == id {
+			return &m.warehouses[i]
+		}
+	}
+	return nil
+}
+
+// utcPtr returns a copy of t in UTC, as the SQL backends store timestamps.
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// copyProof detaches the artefacts from the stored proof.
+func copyProof(p types.DeliveryProof) types.DeliveryProof {
+	if p.Photo != nil {
+		photo := *p.Photo
+		p.Photo = &photo
+	}
+	if p.Signature != nil {
+		signature := *p.Signature
+		p.Signature = &signature
+	}
+	return p
+}
+
+// copyOrder detaches the AgentID pointer from the stored order.
+func copyOrder(o types.Order) types.Order {
+	if o.AgentID != nil {
+		id := *o.AgentID
+		o.AgentID = &id
+	}
+	return o
+}
+
+func copyNotification(n types.Notification) types.Notification {
+	n.ETA = utcPtr(n.ETA)
+	if n.SentAt != nil {
+		at := *n.SentAt
+		n.SentAt = &at
+	}
+	return n
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset < 0 || offset >= len(items) || limit <= 0 {
+		return nil
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// OTPDigits is the length of a delivery OTP.
+const OTPDigits = 4
+
+// NewOTP returns a random code for the customer to hand the agent at delivery. Backends
+// issue one with every assignment they open.
+func NewOTP() (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(OTPDigits), nil)
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", fmt.Errorf("generate otp: %w", err)
+	}
+	return fmt.Sprintf("%0*d", OTPDigits, n.Int64()), nil
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS assignments;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS agents;
+DROP TABLE IF EXISTS warehouses;
+DROP TABLE IF EXISTS users;
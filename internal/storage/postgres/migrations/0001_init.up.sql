@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS users (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT,
+	age INTEGER,
+	email TEXT UNIQUE,
+	city TEXT
+);
+
+CREATE TABLE IF NOT EXISTS warehouses (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	lat DOUBLE PRECISION NOT NULL,
+	lng DOUBLE PRECISION NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS agents (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+	checked_in BOOLEAN NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+	id BIGSERIAL PRIMARY KEY,
+	customer TEXT NOT NULL,
+	lat DOUBLE PRECISION NOT NULL,
+	lng DOUBLE PRECISION NOT NULL,
+	warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+	assigned BOOLEAN NOT NULL DEFAULT FALSE,
+	agent_id BIGINT REFERENCES agents(id)
+);
+
+CREATE TABLE IF NOT EXISTS assignments (
+	id BIGSERIAL PRIMARY KEY,
+	agent_id BIGINT NOT NULL,
+	order_id BIGINT NOT NULL,
+	assigned_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
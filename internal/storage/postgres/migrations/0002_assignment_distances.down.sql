@@ -0,0 +1,5 @@
+ALTER TABLE assignments DROP COLUMN delivered_at;
+ALTER TABLE assignments DROP COLUMN actual_minutes;
+ALTER TABLE assignments DROP COLUMN actual_km;
+ALTER TABLE assignments DROP COLUMN planned_minutes;
+ALTER TABLE assignments DROP COLUMN planned_km;
@@ -0,0 +1,5 @@
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS planned_km DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS planned_minutes DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS actual_km DOUBLE PRECISION;
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS actual_minutes DOUBLE PRECISION;
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMPTZ;
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_assignments_delivered_at;
+
+ALTER TABLE assignments DROP COLUMN IF EXISTS escalated_at;
+
+CREATE TABLE IF NOT EXISTS allocation_lock (
+	id INTEGER PRIMARY KEY,
+	owner TEXT NOT NULL,
+	locked_at BIGINT NOT NULL
+);
+
+DROP TABLE IF EXISTS locks;
+DROP TABLE IF EXISTS scheduled_jobs;
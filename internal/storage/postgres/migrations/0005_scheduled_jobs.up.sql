@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+	name TEXT PRIMARY KEY,
+	next_run_at TIMESTAMPTZ,
+	last_run_at TIMESTAMPTZ,
+	last_duration_ms BIGINT NOT NULL DEFAULT 0,
+	last_status TEXT NOT NULL DEFAULT '',
+	last_error TEXT NOT NULL DEFAULT '',
+	last_result TEXT NOT NULL DEFAULT ''
+);
+
+-- named locks replace the single-purpose allocation lock
+CREATE TABLE IF NOT EXISTS locks (
+	name TEXT PRIMARY KEY,
+	owner TEXT NOT NULL,
+	locked_at BIGINT NOT NULL
+);
+
+DROP TABLE IF EXISTS allocation_lock;
+
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_assignments_delivered_at ON assignments(delivered_at);
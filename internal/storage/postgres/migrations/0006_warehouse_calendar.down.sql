@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS warehouse_holidays;
+
+ALTER TABLE warehouses DROP COLUMN IF EXISTS closes_at;
+ALTER TABLE warehouses DROP COLUMN IF EXISTS opens_at;
+ALTER TABLE warehouses DROP COLUMN IF EXISTS timezone;
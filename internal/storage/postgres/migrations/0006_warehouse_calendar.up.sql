@@ -0,0 +1,11 @@
+-- warehouses keep local time: existing rows start out in UTC and open all day
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS opens_at TEXT NOT NULL DEFAULT '00:00';
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS closes_at TEXT NOT NULL DEFAULT '23:59';
+
+-- day is a local YYYY-MM-DD date in the warehouse's timezone
+CREATE TABLE IF NOT EXISTS warehouse_holidays (
+	warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+	day TEXT NOT NULL,
+	PRIMARY KEY (warehouse_id, day)
+);
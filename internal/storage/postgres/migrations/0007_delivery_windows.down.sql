@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_orders_window_end;
+
+ALTER TABLE assignments DROP COLUMN IF EXISTS eta;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS window_end;
+ALTER TABLE orders DROP COLUMN IF EXISTS window_start;
+ALTER TABLE orders DROP COLUMN IF EXISTS priority;
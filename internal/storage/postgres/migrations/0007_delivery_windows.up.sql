@@ -0,0 +1,9 @@
+-- optional promised delivery window and an urgency ranking for the allocator
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS window_start TIMESTAMPTZ;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS window_end TIMESTAMPTZ;
+
+-- planned arrival at the order, used to predict SLA breaches
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS eta TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_orders_window_end ON orders(window_end);
@@ -0,0 +1,8 @@
+ALTER TABLE agents DROP COLUMN IF EXISTS max_parcels;
+ALTER TABLE agents DROP COLUMN IF EXISTS max_volume_l;
+ALTER TABLE agents DROP COLUMN IF EXISTS max_weight_kg;
+ALTER TABLE agents DROP COLUMN IF EXISTS vehicle;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS parcels;
+ALTER TABLE orders DROP COLUMN IF EXISTS volume_l;
+ALTER TABLE orders DROP COLUMN IF EXISTS weight_kg;
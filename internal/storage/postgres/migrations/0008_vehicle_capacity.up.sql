@@ -0,0 +1,10 @@
+-- what each order puts on a vehicle; existing orders count as one weightless parcel
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS weight_kg DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS volume_l DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS parcels INTEGER NOT NULL DEFAULT 1;
+
+-- what each agent's vehicle carries, 0 for no limit
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS vehicle TEXT NOT NULL DEFAULT '';
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS max_weight_kg DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS max_volume_l DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS max_parcels INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_assignments_agent_route;
+
+ALTER TABLE assignments DROP COLUMN IF EXISTS route_seq;
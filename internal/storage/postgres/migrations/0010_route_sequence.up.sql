@@ -0,0 +1,6 @@
+-- position of an assignment in its agent's route, so that orders can be inserted mid-route;
+-- existing routes keep the order they were assigned in
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS route_seq INTEGER NOT NULL DEFAULT 0;
+UPDATE assignments SET route_seq = id;
+
+CREATE INDEX IF NOT EXISTS idx_assignments_agent_route ON assignments(agent_id, route_seq);
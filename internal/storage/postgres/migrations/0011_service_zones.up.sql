@@ -0,0 +1,14 @@
+-- areas a warehouse delivers to; polygon holds the GeoJSON coordinates of one polygon,
+-- outer ring first, as [lng, lat] positions
+CREATE TABLE IF NOT EXISTS service_zones (
+	id BIGSERIAL PRIMARY KEY,
+	warehouse_id BIGINT NOT NULL REFERENCES warehouses(id),
+	name TEXT NOT NULL DEFAULT '',
+	polygon TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_zones_warehouse_id ON service_zones(warehouse_id);
+
+-- orders taken although no zone of their warehouse contains them
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS out_of_zone BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,30 @@
+-- people orders are delivered to, with the addresses they order to again and again
+CREATE TABLE IF NOT EXISTS customers (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	phone TEXT NOT NULL UNIQUE,
+	email TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- verified is set once an order has been delivered to the address
+CREATE TABLE IF NOT EXISTS customer_addresses (
+	id BIGSERIAL PRIMARY KEY,
+	customer_id BIGINT NOT NULL REFERENCES customers(id),
+	label TEXT NOT NULL DEFAULT '',
+	line TEXT NOT NULL DEFAULT '',
+	city TEXT NOT NULL DEFAULT '',
+	pincode TEXT NOT NULL DEFAULT '',
+	lat DOUBLE PRECISION NOT NULL,
+	lng DOUBLE PRECISION NOT NULL,
+	verified BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_addresses_customer_id ON customer_addresses(customer_id);
+
+-- orders placed for a saved customer and address; NULL for orders given inline
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id BIGINT REFERENCES customers(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS address_id BIGINT REFERENCES customer_addresses(id);
+
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id);
@@ -0,0 +1,25 @@
+-- the code the customer hands the agent at delivery, issued with each assignment; wrong
+-- tries are counted so it cannot be guessed
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS otp TEXT NOT NULL DEFAULT '';
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS otp_failures INTEGER NOT NULL DEFAULT 0;
+
+-- what the agent captured at the door; photo and signature files live in the blob store
+-- under their keys, empty when not taken
+CREATE TABLE IF NOT EXISTS delivery_proofs (
+	order_id BIGINT PRIMARY KEY REFERENCES orders(id),
+	assignment_id BIGINT NOT NULL,
+	agent_id BIGINT NOT NULL,
+	lat DOUBLE PRECISION NOT NULL,
+	lng DOUBLE PRECISION NOT NULL,
+	accuracy_m DOUBLE PRECISION NOT NULL DEFAULT 0,
+	distance_m DOUBLE PRECISION NOT NULL DEFAULT 0,
+	otp_verified BOOLEAN NOT NULL DEFAULT FALSE,
+	note TEXT NOT NULL DEFAULT '',
+	photo_key TEXT NOT NULL DEFAULT '',
+	photo_type TEXT NOT NULL DEFAULT '',
+	photo_size BIGINT NOT NULL DEFAULT 0,
+	signature_key TEXT NOT NULL DEFAULT '',
+	signature_type TEXT NOT NULL DEFAULT '',
+	signature_size BIGINT NOT NULL DEFAULT 0,
+	delivered_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
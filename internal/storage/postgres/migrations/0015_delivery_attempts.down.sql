@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_delivery_attempts_order_id;
+DROP TABLE IF EXISTS delivery_attempts;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS returned_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS return_started_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS failed_attempts;
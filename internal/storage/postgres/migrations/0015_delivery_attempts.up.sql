@@ -0,0 +1,19 @@
+-- orders that could not be delivered are tried again until too many attempts failed, then
+-- taken back to their warehouse: return_started_at is set when the agent turns back and
+-- returned_at once the warehouse has the order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS return_started_at TIMESTAMPTZ;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS returned_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+	id BIGSERIAL PRIMARY KEY,
+	order_id BIGINT NOT NULL REFERENCES orders(id),
+	assignment_id BIGINT NOT NULL,
+	agent_id BIGINT NOT NULL,
+	attempt INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	note TEXT NOT NULL DEFAULT '',
+	attempted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_attempts_order_id ON delivery_attempts(order_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_notifications_due;
+DROP INDEX IF EXISTS idx_notifications_order_id;
+DROP TABLE IF EXISTS notifications;
+
+ALTER TABLE customers DROP COLUMN IF EXISTS notifications_opt_out;
@@ -0,0 +1,35 @@
+// Package postgres stores data in PostgreSQL through the pure-Go pgx driver. Select it
+// with `driver: postgres` and a `database_url` in the config.
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/sqlstore"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type Postgres struct {
+	*sqlstore.Store
+}
+
+func New(cfg *config.Config) (*Postgres, error) {
+	if cfg.DatabaseURL == "" {
+		return nil, fmt.Errorf("database_url is required for the postgres driver")
+	}
+
+	db, err := sql.Open("pgx", cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Postgres{
+		Store: sqlstore.New(db, sqlstore.Postgres, cfg, migrationFiles, "migrations"),
+	}, nil
+}
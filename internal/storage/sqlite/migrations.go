@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"log/slog"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// legacySchemaVersion is the last migration contained in databases created by the
+// InitSchema that predates tracked migrations: the tables of 0001 and the assignment
+// columns of 0002, which it added to existing databases on startup.
+const legacySchemaVersion = 2
+
+// InitSchema applies all pending migrations on startup, baselining legacy databases first.
+func (s *Sqlite) InitSchema(ctx context.Context) error {
+	m, err := s.Migrator()
+	if err != nil {
+		return fmt.Errorf("schema error: %w", err)
+	}
+
+	if err := s.BaselineLegacy(ctx, m); err != nil {
+		return fmt.Errorf("schema error: %w", err)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		return fmt.Errorf("schema error: %w", err)
+	}
+	return nil
+}
+
+// BaselineLegacy marks the migrations already present in databases created by the old
+// CREATE TABLE IF NOT EXISTS setup so they are not applied twice. Such databases are
+// recognised by their tables, as the old setup recorded no version: those created
+// before assignments had planned_km are baselined at 0001, the rest at 0002.
+func (s *Sqlite) BaselineLegacy(ctx context.Context, m *migrate.Migrator) error {
+	current, err := m.Version(ctx)
+	if err != nil || current > 0 {
+		return err
+	}
+
+	var tables int
+	err = s.Db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('orders', 'assignments')`).Scan(&tables)
+	if err != nil || tables < 2 {
+		return err
+	}
+
+	var distances int
+	err = s.Db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pragma_table_info('assignments') WHERE name = 'planned_km'`).Scan(&distances)
+	if err != nil {
+		return err
+	}
+	version := 1
+	if distances > 0 {
+		version = legacySchemaVersion
+	}
+
+	s.Log().Info("baselining legacy schema", slog.Int("version", version))
+	return m.Baseline(ctx, version)
+}
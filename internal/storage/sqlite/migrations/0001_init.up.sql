@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS Users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT,
+	age INTEGER,
+	email TEXT UNIQUE,
+	city Text
+);
+
+CREATE TABLE IF NOT EXISTS warehouses (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	lat REAL NOT NULL,
+	lng REAL NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS agents (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	warehouse_id INTEGER NOT NULL,
+	checked_in BOOLEAN NOT NULL,
+	FOREIGN KEY (warehouse_id) REFERENCES warehouses(id)
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	customer TEXT NOT NULL,
+	lat REAL NOT NULL,
+	lng REAL NOT NULL,
+	warehouse_id INTEGER NOT NULL,
+	assigned BOOLEAN DEFAULT 0,
+	agent_id INTEGER,
+	FOREIGN KEY (warehouse_id) REFERENCES warehouses(id),
+	FOREIGN KEY (agent_id) REFERENCES agents(id)
+);
+
+CREATE TABLE IF NOT EXISTS assignments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	agent_id INTEGER NOT NULL,
+	order_id INTEGER NOT NULL,
+	assigned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+ALTER TABLE assignments ADD COLUMN planned_km REAL NOT NULL DEFAULT 0;
+ALTER TABLE assignments ADD COLUMN planned_minutes REAL NOT NULL DEFAULT 0;
+ALTER TABLE assignments ADD COLUMN actual_km REAL;
+ALTER TABLE assignments ADD COLUMN actual_minutes REAL;
+ALTER TABLE assignments ADD COLUMN delivered_at TIMESTAMP;
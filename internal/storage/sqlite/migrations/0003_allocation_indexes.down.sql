@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_assignments_assigned_at;
+DROP INDEX IF EXISTS idx_orders_agent_id;
+DROP INDEX IF EXISTS idx_orders_assigned;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_orders_assigned ON orders(assigned);
+CREATE INDEX IF NOT EXISTS idx_orders_agent_id ON orders(agent_id);
+CREATE INDEX IF NOT EXISTS idx_assignments_assigned_at ON assignments(assigned_at);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS allocation_lock (
+	id INTEGER PRIMARY KEY,
+	owner TEXT NOT NULL,
+	locked_at BIGINT NOT NULL
+);
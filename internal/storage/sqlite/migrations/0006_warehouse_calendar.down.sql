@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS warehouse_holidays;
+
+ALTER TABLE warehouses DROP COLUMN closes_at;
+ALTER TABLE warehouses DROP COLUMN opens_at;
+ALTER TABLE warehouses DROP COLUMN timezone;
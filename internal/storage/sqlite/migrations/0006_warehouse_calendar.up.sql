@@ -0,0 +1,12 @@
+-- warehouses keep local time: existing rows start out in UTC and open all day
+ALTER TABLE warehouses ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE warehouses ADD COLUMN opens_at TEXT NOT NULL DEFAULT '00:00';
+ALTER TABLE warehouses ADD COLUMN closes_at TEXT NOT NULL DEFAULT '23:59';
+
+-- day is a local YYYY-MM-DD date in the warehouse's timezone
+CREATE TABLE IF NOT EXISTS warehouse_holidays (
+	warehouse_id INTEGER NOT NULL,
+	day TEXT NOT NULL,
+	PRIMARY KEY (warehouse_id, day),
+	FOREIGN KEY (warehouse_id) REFERENCES warehouses(id)
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_orders_window_end;
+
+ALTER TABLE assignments DROP COLUMN eta;
+
+ALTER TABLE orders DROP COLUMN window_end;
+ALTER TABLE orders DROP COLUMN window_start;
+ALTER TABLE orders DROP COLUMN priority;
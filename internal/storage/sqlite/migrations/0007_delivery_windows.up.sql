@@ -0,0 +1,9 @@
+-- optional promised delivery window and an urgency ranking for the allocator
+ALTER TABLE orders ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN window_start TIMESTAMP;
+ALTER TABLE orders ADD COLUMN window_end TIMESTAMP;
+
+-- planned arrival at the order, used to predict SLA breaches
+ALTER TABLE assignments ADD COLUMN eta TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_orders_window_end ON orders(window_end);
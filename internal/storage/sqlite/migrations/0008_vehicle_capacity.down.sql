@@ -0,0 +1,8 @@
+ALTER TABLE agents DROP COLUMN max_parcels;
+ALTER TABLE agents DROP COLUMN max_volume_l;
+ALTER TABLE agents DROP COLUMN max_weight_kg;
+ALTER TABLE agents DROP COLUMN vehicle;
+
+ALTER TABLE orders DROP COLUMN parcels;
+ALTER TABLE orders DROP COLUMN volume_l;
+ALTER TABLE orders DROP COLUMN weight_kg;
@@ -0,0 +1,10 @@
+-- what each order puts on a vehicle; existing orders count as one weightless parcel
+ALTER TABLE orders ADD COLUMN weight_kg REAL NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN volume_l REAL NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN parcels INTEGER NOT NULL DEFAULT 1;
+
+-- what each agent's vehicle carries, 0 for no limit
+ALTER TABLE agents ADD COLUMN vehicle TEXT NOT NULL DEFAULT '';
+ALTER TABLE agents ADD COLUMN max_weight_kg REAL NOT NULL DEFAULT 0;
+ALTER TABLE agents ADD COLUMN max_volume_l REAL NOT NULL DEFAULT 0;
+ALTER TABLE agents ADD COLUMN max_parcels INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_assignments_order_id;
+
+-- the history rows would read as open assignments once the columns are gone
+DELETE FROM assignments WHERE unassigned_at IS NOT NULL;
+
+ALTER TABLE assignments DROP COLUMN unassign_reason;
+ALTER TABLE assignments DROP COLUMN unassigned_at;
@@ -0,0 +1,5 @@
+-- assignments taken back from an agent are closed rather than deleted
+ALTER TABLE assignments ADD COLUMN unassigned_at TIMESTAMP;
+ALTER TABLE assignments ADD COLUMN unassign_reason TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_assignments_order_id ON assignments(order_id);
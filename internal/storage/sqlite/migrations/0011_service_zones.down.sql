@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN out_of_zone;
+
+DROP INDEX IF EXISTS idx_service_zones_warehouse_id;
+DROP TABLE IF EXISTS service_zones;
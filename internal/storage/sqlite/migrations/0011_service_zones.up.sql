@@ -0,0 +1,15 @@
+-- areas a warehouse delivers to; polygon holds the GeoJSON coordinates of one polygon,
+-- outer ring first, as [lng, lat] positions
+CREATE TABLE IF NOT EXISTS service_zones (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	warehouse_id INTEGER NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	polygon TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (warehouse_id) REFERENCES warehouses(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_zones_warehouse_id ON service_zones(warehouse_id);
+
+-- orders taken although no zone of their warehouse contains them
+ALTER TABLE orders ADD COLUMN out_of_zone BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,8 @@
+-- orders whose address could not be geocoded, waiting for someone to give coordinates;
+-- request holds the order request as JSON
+CREATE TABLE IF NOT EXISTS address_reviews (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	request TEXT NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_orders_customer_id;
+ALTER TABLE orders DROP COLUMN address_id;
+ALTER TABLE orders DROP COLUMN customer_id;
+
+DROP INDEX IF EXISTS idx_customer_addresses_customer_id;
+DROP TABLE IF EXISTS customer_addresses;
+DROP TABLE IF EXISTS customers;
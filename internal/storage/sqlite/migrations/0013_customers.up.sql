@@ -0,0 +1,31 @@
+-- people orders are delivered to, with the addresses they order to again and again
+CREATE TABLE IF NOT EXISTS customers (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	phone TEXT NOT NULL UNIQUE,
+	email TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- verified is set once an order has been delivered to the address
+CREATE TABLE IF NOT EXISTS customer_addresses (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	customer_id INTEGER NOT NULL,
+	label TEXT NOT NULL DEFAULT '',
+	line TEXT NOT NULL DEFAULT '',
+	city TEXT NOT NULL DEFAULT '',
+	pincode TEXT NOT NULL DEFAULT '',
+	lat REAL NOT NULL,
+	lng REAL NOT NULL,
+	verified BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (customer_id) REFERENCES customers(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_addresses_customer_id ON customer_addresses(customer_id);
+
+-- orders placed for a saved customer and address; NULL for orders given inline
+ALTER TABLE orders ADD COLUMN customer_id INTEGER;
+ALTER TABLE orders ADD COLUMN address_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id);
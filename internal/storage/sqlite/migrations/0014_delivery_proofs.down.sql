@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS delivery_proofs;
+
+ALTER TABLE assignments DROP COLUMN otp_failures;
+ALTER TABLE assignments DROP COLUMN otp;
@@ -0,0 +1,25 @@
+-- the code the customer hands the agent at delivery, issued with each assignment; wrong
+-- tries are counted so it cannot be guessed
+ALTER TABLE assignments ADD COLUMN otp TEXT NOT NULL DEFAULT '';
+ALTER TABLE assignments ADD COLUMN otp_failures INTEGER NOT NULL DEFAULT 0;
+
+-- what the agent captured at the door; photo and signature files live in the blob store
+-- under their keys, empty when not taken
+CREATE TABLE IF NOT EXISTS delivery_proofs (
+	order_id INTEGER PRIMARY KEY,
+	assignment_id INTEGER NOT NULL,
+	agent_id INTEGER NOT NULL,
+	lat REAL NOT NULL,
+	lng REAL NOT NULL,
+	accuracy_m REAL NOT NULL DEFAULT 0,
+	distance_m REAL NOT NULL DEFAULT 0,
+	otp_verified BOOLEAN NOT NULL DEFAULT FALSE,
+	note TEXT NOT NULL DEFAULT '',
+	photo_key TEXT NOT NULL DEFAULT '',
+	photo_type TEXT NOT NULL DEFAULT '',
+	photo_size INTEGER NOT NULL DEFAULT 0,
+	signature_key TEXT NOT NULL DEFAULT '',
+	signature_type TEXT NOT NULL DEFAULT '',
+	signature_size INTEGER NOT NULL DEFAULT 0,
+	delivered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
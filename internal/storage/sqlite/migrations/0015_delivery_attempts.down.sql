@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_delivery_attempts_order_id;
+DROP TABLE IF EXISTS delivery_attempts;
+
+ALTER TABLE orders DROP COLUMN returned_at;
+ALTER TABLE orders DROP COLUMN return_started_at;
+ALTER TABLE orders DROP COLUMN failed_attempts;
@@ -0,0 +1,19 @@
+-- orders that could not be delivered are tried again until too many attempts failed, then
+-- taken back to their warehouse: return_started_at is set when the agent turns back and
+-- returned_at once the warehouse has the order
+ALTER TABLE orders ADD COLUMN failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN return_started_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN returned_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	order_id INTEGER NOT NULL,
+	assignment_id INTEGER NOT NULL,
+	agent_id INTEGER NOT NULL,
+	attempt INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	note TEXT NOT NULL DEFAULT '',
+	attempted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_attempts_order_id ON delivery_attempts(order_id);
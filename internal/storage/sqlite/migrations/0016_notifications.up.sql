@@ -0,0 +1,26 @@
+-- the outbox of messages to customers about their orders. A row is written with the status
+-- change it reports and sent in the background, with retries; what the templates need is
+-- copied in so that the message does not change when the order does
+ALTER TABLE customers ADD COLUMN notifications_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS notifications (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	order_id INTEGER NOT NULL,
+	customer_id INTEGER NOT NULL,
+	event TEXT NOT NULL,
+	channel TEXT NOT NULL,
+	recipient TEXT NOT NULL,
+	customer_name TEXT NOT NULL DEFAULT '',
+	agent_name TEXT NOT NULL DEFAULT '',
+	eta TIMESTAMP,
+	otp TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	sent_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_order_id ON notifications(order_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications(status, next_attempt_at);
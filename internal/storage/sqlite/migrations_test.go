@@ -0,0 +1,148 @@
+package sqlite_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/sqlite"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/storagetest"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// open returns an empty database file, not yet migrated.
+func open(t *testing.T) *sqlite.Sqlite {
+	t.Helper()
+	cfg := storagetest.Config()
+	cfg.StoragePath = filepath.Join(t.TempDir(), "delivery.db")
+	s, err := sqlite.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Db.Close() })
+	return s
+}
+
+// tableCount counts the tables other than the migrator's own.
+func tableCount(t *testing.T, s *sqlite.Sqlite) int {
+	t.Helper()
+	var n int
+	err := s.Db.QueryRow(`SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'schema_migrations%' AND name != 'sqlite_sequence'`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMigrationsUpDownUp(t *testing.T) {
+	ctx := context.Background()
+	s := open(t)
+	m, err := s.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if v, err := m.Version(ctx); err != nil || v != m.Latest() {
+		t.Fatalf("Version = %d, %v; want %d", v, err, m.Latest())
+	}
+	tables := tableCount(t, s)
+
+	// every down undoes its up
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To 0: %v", err)
+	}
+	if n := tableCount(t, s); n != 0 {
+		t.Errorf("%d tables left after rolling everything back", n)
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range status {
+		if st.Applied {
+			t.Errorf("migration %d_%s still applied", st.Version, st.Name)
+		}
+	}
+
+	if err := s.InitSchema(ctx); err != nil {
+		t.Fatalf("InitSchema after rolling back: %v", err)
+	}
+	if n := tableCount(t, s); n != tables {
+		t.Errorf("%d tables after migrating up again, want %d", n, tables)
+	}
+	if _, err := s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}}); err != nil {
+		t.Errorf("CreateWarehouse on the migrated schema: %v", err)
+	}
+}
+
+func TestBaselineLegacy(t *testing.T) {
+	// databases made before migrations were tracked hold the schema of 0001, and those
+	// started since assignments had distances that of 0002 too
+	for _, legacy := range []struct {
+		files   []string
+		version int
+	}{
+		{[]string{"0001_init.up.sql"}, 1},
+		{[]string{"0001_init.up.sql", "0002_assignment_distances.up.sql"}, 2},
+	} {
+		ctx := context.Background()
+		s := open(t)
+		for _, f := range legacy.files {
+			body, err := os.ReadFile(filepath.Join("migrations", f))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Db.Exec(string(body)); err != nil {
+				t.Fatalf("%s: %v", f, err)
+			}
+		}
+		if _, err := s.Db.Exec(`INSERT INTO warehouses (name, lat, lng) VALUES ('Legacy hub', 12.97, 77.59)`); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := s.Migrator()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.BaselineLegacy(ctx, m); err != nil {
+			t.Fatalf("BaselineLegacy: %v", err)
+		}
+		if v, _ := m.Version(ctx); v != legacy.version {
+			t.Errorf("legacy %d: baselined at %d", legacy.version, v)
+		}
+
+		if err := s.InitSchema(ctx); err != nil {
+			t.Fatalf("InitSchema of a version %d legacy database: %v", legacy.version, err)
+		}
+		if v, _ := m.Version(ctx); v != m.Latest() {
+			t.Errorf("legacy %d: Version = %d, want %d", legacy.version, v, m.Latest())
+		}
+
+		// the data is kept and the rest of the schema added
+		warehouses, err := s.GetWarehouses(ctx)
+		if err != nil || len(warehouses) != 1 || warehouses[0].Name != "Legacy hub" {
+			t.Fatalf("legacy %d: warehouses = %+v, %v", legacy.version, warehouses, err)
+		}
+		if _, err := s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: warehouses[0].ID}); err != nil {
+			t.Errorf("legacy %d: CheckInAgents: %v", legacy.version, err)
+		}
+	}
+
+	// a new database is not baselined
+	s := open(t)
+	m, err := s.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BaselineLegacy(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Version(context.Background()); v != 0 {
+		t.Errorf("empty database baselined at %d", v)
+	}
+}
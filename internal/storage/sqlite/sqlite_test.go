@@ -0,0 +1,11 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, storagetest.SQLite)
+}
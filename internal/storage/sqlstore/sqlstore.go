@@ -0,0 +1,2193 @@
+// Package sqlstore implements storage.Storage on database/sql with SQL that runs on
+// both SQLite and PostgreSQL. Queries are written with ? placeholders and rebound per dialect.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/metrics"
+	"github.com/sharmaprinceji/delivery-management-system/internal/migrate"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+type Dialect int
+
+const (
+	SQLite Dialect = iota
+	Postgres
+)
+
+type Store struct {
+	Db       *sql.DB
+	Dialect  Dialect
+	delivery config.Delivery
+	log      *slog.Logger
+
+	migrations    fs.FS
+	migrationsDir string
+}
+
+// New wraps an open database. migrations holds the dialect's NNNN_name.up/down.sql files under dir.
+func New(db *sql.DB, dialect Dialect, cfg *config.Config, migrations fs.FS, dir string) *Store {
+	return &Store{
+		Db:            db,
+		Dialect:       dialect,
+		delivery:      cfg.Variables.Delivery,
+		log:           slog.Default().With(slog.String("component", "storage")),
+		migrations:    migrations,
+		migrationsDir: dir,
+	}
+}
+
+// Log returns the storage logger.
+func (s *Store) Log() *slog.Logger {
+	return s.log
+}
+
+// q rewrites ? placeholders into the dialect's form.
+func (s *Store) q(query string) string {
+	if s.Dialect != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Migrator returns the migrator for this database's embedded migrations.
+func (s *Store) Migrator() (*migrate.Migrator, error) {
+	return migrate.New(s.Db, s.migrations, s.migrationsDir)
+}
+
+// InitSchema applies all pending migrations on startup.
+func (s *Store) InitSchema(ctx context.Context) error {
+	m, err := s.Migrator()
+	if err != nil {
+		return fmt.Errorf("schema error: %w", err)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		return fmt.Errorf("schema error: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion reports the applied migration version next to the latest embedded one.
+func (s *Store) SchemaVersion(ctx context.Context) (int, int, error) {
+	m, err := s.Migrator()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	current, err := m.Version(ctx)
+	return current, m.Latest(), err
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return s.Db.PingContext(ctx)
+}
+
+func (s *Store) Save(ctx context.Context, data any) error {
+	defer metrics.ObserveQuery("save", time.Now())
+
+	switch v := data.(type) {
+	case types.User:
+		_, err := s.Db.ExecContext(ctx, s.q(`INSERT INTO Users (name, age, email, city) VALUES (?, ?, ?, ?)`),
+			v.Name, v.Age, v.Email, v.City)
+		return err
+	default:
+		return fmt.Errorf("unsupported type in Save: %T", v)
+	}
+}
+
+func (s *Store) GetCheckedInAgents(ctx context.Context) ([]types.Agent, error) {
+	defer metrics.ObserveQuery("get_checked_in_agents", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, `
+		SELECT id, name, warehouse_id, checked_in, vehicle, max_weight_kg, max_volume_l, max_parcels
+		FROM agents WHERE checked_in = TRUE ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []types.Agent
+	for rows.Next() {
+		var a types.Agent
+		if err := rows.Scan(&a.ID, &a.Name, &a.WarehouseID, &a.CheckedIn,
+			&a.Vehicle, &a.MaxWeightKg, &a.MaxVolumeL, &a.MaxParcels); err != nil {
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return agents, nil
+}
+
+func (s *Store) GetUnassignedOrders(ctx context.Context) ([]types.Order, error) {
+	defer metrics.ObserveQuery("get_unassigned_orders", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders WHERE assigned = FALSE ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []types.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// orderColumns are the columns scanOrder reads, for a query on orders.
+const orderColumns = `id, customer, lat, lng, warehouse_id, assigned, agent_id, priority, window_start, window_end,
+	weight_kg, volume_l, parcels, out_of_zone, customer_id, address_id, failed_attempts, return_started_at, returned_at`
+
+// scanOrder reads orderColumns, followed by any extra destinations.
+func scanOrder(row interface{ Scan(...any) error }, extra ...any) (types.Order, error) {
+	var o types.Order
+	var agentID, customerID, addressID sql.NullInt64
+	var windowStart, windowEnd, returnStartedAt, returnedAt sql.NullTime
+
+	dest := append([]any{&o.ID, &o.Customer, &o.Lat, &o.Lng, &o.WarehouseID, &o.Assigned, &agentID,
+		&o.Priority, &windowStart, &windowEnd, &o.WeightKg, &o.VolumeL, &o.Parcels, &o.OutOfZone,
+		&customerID, &addressID, &o.FailedAttempts, &returnStartedAt, &returnedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return o, err
+	}
+	o.WindowStart = timePtr(windowStart)
+	o.WindowEnd = timePtr(windowEnd)
+	o.ReturnStartedAt = timePtr(returnStartedAt)
+	o.ReturnedAt = timePtr(returnedAt)
+	if agentID.Valid {
+		o.AgentID = &agentID.Int64
+	}
+	o.CustomerID, o.AddressID = customerID.Int64, addressID.Int64
+	return o, nil
+}
+
+func (s *Store) AssignOrderToAgent(ctx context.Context, orderID int64, agentID int64, plannedKm, plannedMinutes float64, eta time.Time) error {
+	defer metrics.ObserveQuery("assign_order_to_agent", time.Now())
+
+	return s.assign(ctx, orderID, agentID, 0, plannedKm, plannedMinutes, eta)
+}
+
+func (s *Store) InsertOrderIntoRoute(ctx context.Context, orderID, agentID, beforeOrderID int64, plannedKm, plannedMinutes float64, eta time.Time) error {
+	defer metrics.ObserveQuery("insert_order_into_route", time.Now())
+
+	return s.assign(ctx, orderID, agentID, beforeOrderID, plannedKm, plannedMinutes, eta)
+}
+
+// assign claims an order for an agent and adds it to the agent's route before the open stop
+// for beforeOrderID, or at the end when that is zero.
+func (s *Store) assign(ctx context.Context, orderID, agentID, beforeOrderID int64, plannedKm, plannedMinutes float64, eta time.Time) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// claim the order only if no concurrent run got there first
+	res, err := tx.ExecContext(ctx, s.q(`UPDATE orders SET assigned = TRUE, agent_id = ? WHERE id = ? AND assigned = FALSE`), agentID, orderID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n == 0 {
+		var exists int
+		err := tx.QueryRowContext(ctx, s.q(`SELECT COUNT(*) FROM orders WHERE id = ?`), orderID).Scan(&exists)
+		tx.Rollback()
+		switch {
+		case err != nil:
+			return err
+		case exists == 0:
+			return fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+		default:
+			return fmt.Errorf("order %d already assigned: %w", orderID, storage.ErrConflict)
+		}
+	}
+
+	var seq int
+	if beforeOrderID == 0 {
+		seq, err = s.nextRouteSeq(ctx, tx, agentID)
+	} else {
+		seq, err = s.makeRoom(ctx, tx, agentID, beforeOrderID)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var etaArg any
+	if !eta.IsZero() {
+		etaArg = eta.UTC()
+	}
+	otp, err := storage.NewOTP()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.q(`
+		INSERT INTO assignments (agent_id, order_id, planned_km, planned_minutes, eta, route_seq, otp)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`), agentID, orderID, plannedKm, plannedMinutes, etaArg, seq, otp)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := s.enqueueNotifications(ctx, tx, orderID, types.EventAssigned); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// nextRouteSeq returns the position after the last stop of the agent's route.
+func (s *Store) nextRouteSeq(ctx context.Context, tx *sql.Tx, agentID int64) (int, error) {
+	var seq int
+	err := tx.QueryRowContext(ctx, s.q(`SELECT COALESCE(MAX(route_seq), 0) + 1 FROM assignments WHERE agent_id = ?`), agentID).Scan(&seq)
+	return seq, err
+}
+
+// makeRoom shifts the agent's stops from the open one for beforeOrderID on back by one and
+// returns the position it freed.
+func (s *Store) makeRoom(ctx context.Context, tx *sql.Tx, agentID, beforeOrderID int64) (int, error) {
+	var seq int
+	err := tx.QueryRowContext(ctx, s.q(`SELECT route_seq FROM assignments WHERE agent_id = ? AND order_id = ? AND `+openAssignment),
+		agentID, beforeOrderID).Scan(&seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("order %d is no longer on agent %d's route: %w", beforeOrderID, agentID, storage.ErrConflict)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, s.q(`UPDATE assignments SET route_seq = route_seq + 1 WHERE agent_id = ? AND route_seq >= ?`), agentID, seq)
+	return seq, err
+}
+
+// CompleteDelivery records the actual distance and time on the open assignment of an order.
+// It returns storage.ErrNotFound when the order has no undelivered assignment.
+func (s *Store) CompleteDelivery(ctx context.Context, orderID int64, actualKm, actualMinutes float64) error {
+	defer metrics.ObserveQuery("complete_delivery", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, s.q(`
+		UPDATE assignments
+		SET actual_km = ?, actual_minutes = ?, delivered_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM assignments
+			WHERE order_id = ? AND `+openAssignment+`
+			ORDER BY assigned_at DESC, id DESC
+			LIMIT 1
+		)
+	`), actualKm, actualMinutes, orderID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no open assignment for order %d: %w", orderID, storage.ErrNotFound)
+	}
+
+	if err := s.verifyAddress(ctx, tx, orderID); err != nil {
+		return err
+	}
+	if err := s.enqueueNotifications(ctx, tx, orderID, types.EventDelivered); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// verifyAddress marks the saved address an order went to, if any, verified: a delivery
+// proves its coordinates.
+func (s *Store) verifyAddress(ctx context.Context, tx *sql.Tx, orderID int64) error {
+	_, err := tx.ExecContext(ctx, s.q(`
+		UPDATE customer_addresses SET verified = TRUE
+		WHERE id = (SELECT address_id FROM orders WHERE id = ?)
+	`), orderID)
+	return err
+}
+
+func (s *Store) GetOpenAssignment(ctx context.Context, orderID int64) (types.Assignment, error) {
+	defer metrics.ObserveQuery("get_open_assignment", time.Now())
+
+	a, err := scanAssignment(s.Db.QueryRowContext(ctx, s.q(`
+		SELECT `+assignmentColumns+`
+		FROM assignments
+		WHERE order_id = ? AND `+openAssignment+`
+		ORDER BY assigned_at DESC, id DESC
+		LIMIT 1
+	`), orderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, fmt.Errorf("no open assignment for order %d: %w", orderID, storage.ErrNotFound)
+	}
+	return a, err
+}
+
+func (s *Store) RecordOTPFailure(ctx context.Context, assignmentID int64) (int, error) {
+	defer metrics.ObserveQuery("record_otp_failure", time.Now())
+
+	var failures int
+	err := s.Db.QueryRowContext(ctx, s.q(`
+		UPDATE assignments SET otp_failures = otp_failures + 1 WHERE id = ? RETURNING otp_failures
+	`), assignmentID).Scan(&failures)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("assignment %d: %w", assignmentID, storage.ErrNotFound)
+	}
+	return failures, err
+}
+
+func (s *Store) CompleteDeliveryWithProof(ctx context.Context, p types.DeliveryProof, actualKm, actualMinutes float64) error {
+	defer metrics.ObserveQuery("complete_delivery_with_proof", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// whole seconds, like CURRENT_TIMESTAMP in CompleteDelivery
+	at := time.Now().UTC().Truncate(time.Second)
+	res, err := tx.ExecContext(ctx, s.q(`
+		UPDATE assignments
+		SET actual_km = ?, actual_minutes = ?, delivered_at = ?
+		WHERE id = ? AND order_id = ? AND `+openAssignment),
+		actualKm, actualMinutes, at, p.AssignmentID, p.OrderID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("assignment %d of order %d is no longer open: %w", p.AssignmentID, p.OrderID, storage.ErrNotFound)
+	}
+
+	photo, signature := p.Photo, p.Signature
+	if photo == nil {
+		photo = &types.ProofArtefact{}
+	}
+	if signature == nil {
+		signature = &types.ProofArtefact{}
+	}
+	_, err = tx.ExecContext(ctx, s.q(`
+		INSERT INTO delivery_proofs (order_id, assignment_id, agent_id, lat, lng, accuracy_m, distance_m, otp_verified, note,
+			photo_key, photo_type, photo_size, signature_key, signature_type, signature_size, delivered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`), p.OrderID, p.AssignmentID, p.AgentID, p.Location.Lat, p.Location.Lng, p.AccuracyM, p.DistanceM, p.OTPVerified, p.Note,
+		photo.Key, photo.ContentType, photo.Size, signature.Key, signature.ContentType, signature.Size, at)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifyAddress(ctx, tx, p.OrderID); err != nil {
+		return err
+	}
+	if err := s.enqueueNotifications(ctx, tx, p.OrderID, types.EventDelivered); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) GetDeliveryProof(ctx context.Context, orderID int64) (types.DeliveryProof, error) {
+	defer metrics.ObserveQuery("get_delivery_proof", time.Now())
+
+	var p types.DeliveryProof
+	var photo, signature types.ProofArtefact
+	err := s.Db.QueryRowContext(ctx, s.q(`
+		SELECT order_id, assignment_id, agent_id, lat, lng, accuracy_m, distance_m, otp_verified, note,
+			photo_key, photo_type, photo_size, signature_key, signature_type, signature_size, delivered_at
+		FROM delivery_proofs
+		WHERE order_id = ?
+	`), orderID).Scan(&p.OrderID, &p.AssignmentID, &p.AgentID, &p.Location.Lat, &p.Location.Lng, &p.AccuracyM, &p.DistanceM,
+		&p.OTPVerified, &p.Note, &photo.Key, &photo.ContentType, &photo.Size, &signature.Key, &signature.ContentType,
+		&signature.Size, &p.DeliveredAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, fmt.Errorf("no proof of delivery for order %d: %w", orderID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return p, err
+	}
+	p.DeliveredAt = p.DeliveredAt.UTC()
+	if photo.Key != "" {
+		p.Photo = &photo
+	}
+	if signature.Key != "" {
+		p.Signature = &signature
+	}
+	return p, nil
+}
+
+func (s *Store) UnassignOrder(ctx context.Context, orderID int64, reason string) error {
+	defer metrics.ObserveQuery("unassign_order", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.closeAssignment(ctx, tx, orderID, reason); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.q(`UPDATE orders SET assigned = FALSE, agent_id = NULL WHERE id = ?`), orderID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) FailDelivery(ctx context.Context, orderID int64, a types.DeliveryAttempt, maxAttempts, priorityBump int) (types.Order, error) {
+	defer metrics.ObserveQuery("fail_delivery", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.Order{}, err
+	}
+	defer tx.Rollback()
+
+	open, err := s.closeAssignment(ctx, tx, orderID, "delivery failed: "+a.Reason)
+	if err != nil {
+		return types.Order{}, err
+	}
+
+	var failed int
+	err = tx.QueryRowContext(ctx, s.q(`UPDATE orders SET failed_attempts = failed_attempts + 1 WHERE id = ? RETURNING failed_attempts`),
+		orderID).Scan(&failed)
+	if err != nil {
+		return types.Order{}, err
+	}
+	_, err = tx.ExecContext(ctx, s.q(`
+		INSERT INTO delivery_attempts (order_id, assignment_id, agent_id, attempt, reason, note, attempted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`), orderID, open.ID, open.AgentID, failed, a.Reason, a.Note, time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		return types.Order{}, err
+	}
+
+	event := types.EventAttemptFailed
+	if failed >= maxAttempts {
+		// the agent takes it back, so it stays theirs and out of allocation
+		event = types.EventReturning
+		_, err = tx.ExecContext(ctx, s.q(`UPDATE orders SET return_started_at = ? WHERE id = ?`),
+			time.Now().UTC().Truncate(time.Second), orderID)
+	} else {
+		_, err = tx.ExecContext(ctx, s.q(`UPDATE orders SET assigned = FALSE, agent_id = NULL, priority = priority + ? WHERE id = ?`),
+			priorityBump, orderID)
+	}
+	if err != nil {
+		return types.Order{}, err
+	}
+	if err := s.enqueueNotifications(ctx, tx, orderID, event); err != nil {
+		return types.Order{}, err
+	}
+
+	o, err := scanOrder(tx.QueryRowContext(ctx, s.q(`SELECT `+orderColumns+` FROM orders WHERE id = ?`), orderID))
+	if err != nil {
+		return types.Order{}, err
+	}
+	return o, tx.Commit()
+}
+
+func (s *Store) GetDeliveryAttempts(ctx context.Context, orderID int64) ([]types.DeliveryAttempt, error) {
+	defer metrics.ObserveQuery("get_delivery_attempts", time.Now())
+
+	var exists bool
+	if err := s.Db.QueryRowContext(ctx, s.q(`SELECT EXISTS (SELECT 1 FROM orders WHERE id = ?)`), orderID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+
+	rows, err := s.Db.QueryContext(ctx, s.q(`
+		SELECT id, order_id, assignment_id, agent_id, attempt, reason, note, attempted_at
+		FROM delivery_attempts
+		WHERE order_id = ?
+		ORDER BY attempt, id
+	`), orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []types.DeliveryAttempt{}
+	for rows.Next() {
+		var a types.DeliveryAttempt
+		if err := rows.Scan(&a.ID, &a.OrderID, &a.AssignmentID, &a.AgentID, &a.Attempt, &a.Reason, &a.Note, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+		a.AttemptedAt = a.AttemptedAt.UTC()
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+func (s *Store) GetReturningOrders(ctx context.Context, warehouseID int64) ([]types.Order, error) {
+	defer metrics.ObserveQuery("get_returning_orders", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, s.q(`
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE return_started_at IS NOT NULL AND returned_at IS NULL AND (? = 0 OR warehouse_id = ?)
+		ORDER BY return_started_at, id
+	`), warehouseID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []types.Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (s *Store) CompleteReturn(ctx context.Context, orderID int64) error {
+	defer metrics.ObserveQuery("complete_return", time.Now())
+
+	res, err := s.Db.ExecContext(ctx, s.q(`
+		UPDATE orders SET returned_at = ?
+		WHERE id = ? AND return_started_at IS NOT NULL AND returned_at IS NULL
+	`), time.Now().UTC().Truncate(time.Second), orderID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 1 {
+		return nil
+	}
+
+	var exists bool
+	if err := s.Db.QueryRowContext(ctx, s.q(`SELECT EXISTS (SELECT 1 FROM orders WHERE id = ?)`), orderID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+	return fmt.Errorf("order %d is not being returned: %w", orderID, storage.ErrConflict)
+}
+
+func (s *Store) ReassignOrder(ctx context.Context, orderID, agentID int64, plannedKm, plannedMinutes float64, eta time.Time, reason string) error {
+	defer metrics.ObserveQuery("reassign_order", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var checkedIn bool
+	err = tx.QueryRowContext(ctx, s.q(`SELECT checked_in FROM agents WHERE id = ?`), agentID).Scan(&checkedIn)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if !checkedIn {
+		return fmt.Errorf("agent %d is not checked in: %w", agentID, storage.ErrConflict)
+	}
+
+	prev, err := s.closeAssignment(ctx, tx, orderID, reason)
+	if err != nil {
+		return err
+	}
+	if prev.AgentID == agentID {
+		return fmt.Errorf("order %d is already assigned to agent %d: %w", orderID, agentID, storage.ErrConflict)
+	}
+
+	seq, err := s.nextRouteSeq(ctx, tx, agentID)
+	if err != nil {
+		return err
+	}
+	// the new agent gets a new code; the old one may have been shared with the old agent
+	otp, err := storage.NewOTP()
+	if err != nil {
+		return err
+	}
+	var etaArg any
+	if !eta.IsZero() {
+		etaArg = eta.UTC()
+	}
+	_, err = tx.ExecContext(ctx, s.q(`INSERT INTO assignments (agent_id, order_id, planned_km, planned_minutes, eta, route_seq, otp) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		agentID, orderID, plannedKm, plannedMinutes, etaArg, seq, otp)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.q(`UPDATE orders SET agent_id = ? WHERE id = ?`), agentID, orderID); err != nil {
+		return err
+	}
+	// the customer needs the new agent's name and code
+	if err := s.enqueueNotifications(ctx, tx, orderID, types.EventAssigned); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) ReleaseAgentOrders(ctx context.Context, agentID int64, reason string, checkOut bool) ([]int64, error) {
+	defer metrics.ObserveQuery("release_agent_orders", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRowContext(ctx, s.q(`SELECT COUNT(*) FROM agents WHERE id = ?`), agentID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("agent %d: %w", agentID, storage.ErrNotFound)
+	}
+
+	rows, err := tx.QueryContext(ctx, s.q(`SELECT order_id FROM assignments WHERE agent_id = ? AND `+openAssignment+` ORDER BY order_id`), agentID)
+	if err != nil {
+		return nil, err
+	}
+	released := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		released = append(released, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, s.q(`UPDATE assignments SET unassigned_at = ?, unassign_reason = ? WHERE agent_id = ? AND `+openAssignment),
+		now, reason, agentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range released {
+		if _, err := tx.ExecContext(ctx, s.q(`UPDATE orders SET assigned = FALSE, agent_id = NULL WHERE id = ?`), id); err != nil {
+			return nil, err
+		}
+		// the customer was told this agent and code; another agent will bring it
+		if err := s.enqueueNotifications(ctx, tx, id, types.EventUnassigned); err != nil {
+			return nil, err
+		}
+	}
+	if checkOut {
+		if _, err := tx.ExecContext(ctx, s.q(`UPDATE agents SET checked_in = FALSE WHERE id = ?`), agentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return released, tx.Commit()
+}
+
+// closeAssignment marks the open assignment of an order as taken back and returns it. It
+// returns storage.ErrNotFound when the order has none, also when a concurrent writer closed
+// it first.
+func (s *Store) closeAssignment(ctx context.Context, tx *sql.Tx, orderID int64, reason string) (types.Assignment, error) {
+	rows, err := tx.QueryContext(ctx, s.q(`
+		SELECT `+assignmentColumns+`
+		FROM assignments
+		WHERE order_id = ? AND `+openAssignment+`
+		ORDER BY assigned_at DESC, id DESC
+		LIMIT 1
+	`), orderID)
+	if err != nil {
+		return types.Assignment{}, err
+	}
+	var open *types.Assignment
+	if rows.Next() {
+		a, err := scanAssignment(rows)
+		if err != nil {
+			rows.Close()
+			return types.Assignment{}, err
+		}
+		open = &a
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return types.Assignment{}, err
+	}
+	if open == nil {
+		return types.Assignment{}, fmt.Errorf("no open assignment for order %d: %w", orderID, storage.ErrNotFound)
+	}
+
+	res, err := tx.ExecContext(ctx, s.q(`UPDATE assignments SET unassigned_at = ?, unassign_reason = ? WHERE id = ? AND `+openAssignment),
+		time.Now().UTC(), reason, open.ID)
+	if err != nil {
+		return types.Assignment{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return types.Assignment{}, err
+	} else if n == 0 {
+		return types.Assignment{}, fmt.Errorf("no open assignment for order %d: %w", orderID, storage.ErrNotFound)
+	}
+	return *open, nil
+}
+
+func (s *Store) GetAgentDetails(ctx context.Context, agentID int64) (map[string]interface{}, error) {
+	defer metrics.ObserveQuery("get_agent_details", time.Now())
+
+	query := `
+		SELECT
+			a.id AS agent_id,
+			a.name AS agent_name,
+			a.warehouse_id,
+			COALESCE(w.name, '') AS warehouse_name,
+			COUNT(CASE WHEN s.unassigned_at IS NULL THEN s.id END) AS total_orders,
+			COUNT(s.delivered_at) AS delivered_orders,
+			COALESCE(SUM(s.planned_km), 0) AS planned_km,
+			COALESCE(SUM(s.planned_minutes), 0) AS planned_minutes,
+			COALESCE(SUM(s.actual_km), 0) AS actual_km,
+			COALESCE(SUM(s.actual_minutes), 0) AS actual_minutes
+		FROM agents a
+		LEFT JOIN warehouses w ON a.warehouse_id = w.id
+		LEFT JOIN assignments s ON s.agent_id = a.id AND (s.unassigned_at IS NULL OR EXISTS (
+			SELECT 1 FROM delivery_attempts d WHERE d.assignment_id = s.id))
+		WHERE a.id = ?
+		GROUP BY a.id, a.name, a.warehouse_id, w.name;
+	`
+
+	row := s.Db.QueryRowContext(ctx, s.q(query), agentID)
+
+	var result = make(map[string]interface{})
+	var name, warehouseName string
+	var warehouseID int64
+	var summary types.AgentSummary
+
+	err := row.Scan(&agentID, &name, &warehouseID, &warehouseName, &summary.TotalOrders, &summary.DeliveredOrders,
+		&summary.PlannedKm, &summary.PlannedMinutes, &summary.ActualKm, &summary.ActualMinutes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("agent %d: %w", agentID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.fillSummary(&summary)
+
+	result["agent_id"] = agentID
+	result["agent_name"] = name
+	result["warehouse_id"] = warehouseID
+	result["warehouse_name"] = warehouseName
+	result["total_orders"] = summary.TotalOrders
+	result["delivered_orders"] = summary.DeliveredOrders
+	result["total_km"] = summary.TotalKm
+	result["total_minutes"] = summary.TotalMinutes
+	result["planned_km"] = summary.PlannedKm
+	result["planned_minutes"] = summary.PlannedMinutes
+	result["actual_km"] = summary.ActualKm
+	result["actual_minutes"] = summary.ActualMinutes
+	result["profit"] = summary.Profit
+
+	return result, nil
+}
+
+// fillSummary derives the compatibility totals and the profit tier from the stored figures.
+func (s *Store) fillSummary(summary *types.AgentSummary) {
+	summary.TotalKm = summary.PlannedKm
+	summary.TotalMinutes = summary.PlannedMinutes
+	summary.Profit = s.delivery.Profit(summary.TotalOrders)
+}
+
+func (s *Store) GetAllAssignments(ctx context.Context) ([]types.Assignment, error) {
+	defer metrics.ObserveQuery("get_all_assignments", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, "SELECT " + assignmentColumns + " FROM assignments ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []types.Assignment
+	for rows.Next() {
+		a, err := scanAssignment(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, a)
+	}
+	return results, rows.Err()
+}
+
+const assignmentColumns = `id, agent_id, order_id,
+	COALESCE((SELECT warehouse_id FROM orders WHERE orders.id = assignments.order_id), 0),
+	assigned_at, planned_km, planned_minutes, actual_km, actual_minutes, delivered_at, eta,
+	unassigned_at, unassign_reason, route_seq, otp, otp_failures`
+
+// openAssignment matches assignments that are neither delivered nor taken back.
+const openAssignment = `delivered_at IS NULL AND unassigned_at IS NULL`
+
+// drivenAssignment matches the assignments whose leg the agent drove: those not taken back
+// and those closed by a failed attempt at the door.
+const drivenAssignment = `(unassigned_at IS NULL OR EXISTS (
+	SELECT 1 FROM delivery_attempts d WHERE d.assignment_id = assignments.id))`
+
+func scanAssignment(rows interface{ Scan(...any) error }) (types.Assignment, error) {
+	var a types.Assignment
+	var actualKm, actualMinutes sql.NullFloat64
+	var deliveredAt, eta, unassignedAt sql.NullTime
+
+	err := rows.Scan(&a.ID, &a.AgentID, &a.OrderID, &a.WarehouseID, &a.AssignedAt, &a.PlannedKm, &a.PlannedMinutes,
+		&actualKm, &actualMinutes, &deliveredAt, &eta, &unassignedAt, &a.UnassignReason, &a.RouteSeq, &a.OTP, &a.OTPFailures)
+	if err != nil {
+		return a, err
+	}
+	a.ETA = timePtr(eta)
+	a.UnassignedAt = timePtr(unassignedAt)
+
+	if actualKm.Valid {
+		a.ActualKm = &actualKm.Float64
+	}
+	if actualMinutes.Valid {
+		a.ActualMinutes = &actualMinutes.Float64
+	}
+	if deliveredAt.Valid {
+		a.DeliveredAt = &deliveredAt.Time
+	}
+	return a, nil
+}
+
+// timePtr returns nil for a NULL timestamp.
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// nullTime passes an optional timestamp as a query argument, in UTC.
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// nullID passes an optional reference as a query argument, NULL when zero.
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func (s *Store) GetPaginatedAssignments(ctx context.Context, limit, offset int) ([]types.Assignment, int, error) {
+	defer metrics.ObserveQuery("get_paginated_assignments", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, s.q(`
+		SELECT `+assignmentColumns+`
+		FROM assignments
+		ORDER BY assigned_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`), limit, offset)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var result []types.Assignment
+	for rows.Next() {
+		a, err := scanAssignment(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	// Get total count
+	var total int
+	err = s.Db.QueryRowContext(ctx, `SELECT COUNT(*) FROM assignments`).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// insertID runs an INSERT ... RETURNING id statement, which both dialects support.
+func (s *Store) insertID(ctx context.Context, query string, args ...any) (int64, error) {
+	var id int64
+	if err := s.Db.QueryRowContext(ctx, s.q(query+" RETURNING id"), args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s *Store) CreateWarehouse(ctx context.Context, w types.Warehouse) (int64, error) {
+	defer metrics.ObserveQuery("create_warehouse", time.Now())
+
+	cal := w.WarehouseCalendar.WithDefaults()
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, s.q(`
+		INSERT INTO warehouses (name, lat, lng, timezone, opens_at, closes_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), w.Name, w.Location.Lat, w.Location.Lng, cal.Timezone, cal.OpensAt, cal.ClosesAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.insertHolidays(ctx, tx, id, cal.Holidays); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (s *Store) SetWarehouseCalendar(ctx context.Context, warehouseID int64, cal types.WarehouseCalendar) error {
+	defer metrics.ObserveQuery("set_warehouse_calendar", time.Now())
+
+	cal = cal.WithDefaults()
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, s.q(`
+		UPDATE warehouses SET timezone = ?, opens_at = ?, closes_at = ? WHERE id = ?
+	`), cal.Timezone, cal.OpensAt, cal.ClosesAt, warehouseID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("warehouse %d: %w", warehouseID, storage.ErrNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, s.q(`DELETE FROM warehouse_holidays WHERE warehouse_id = ?`), warehouseID); err != nil {
+		return err
+	}
+	if err := s.insertHolidays(ctx, tx, warehouseID, cal.Holidays); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) insertHolidays(ctx context.Context, tx *sql.Tx, warehouseID int64, days []string) error {
+	for _, day := range days {
+		_, err := tx.ExecContext(ctx, s.q(`INSERT INTO warehouse_holidays (warehouse_id, day) VALUES (?, ?)`), warehouseID, day)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetWarehouses(ctx context.Context) ([]types.Warehouse, error) {
+	defer metrics.ObserveQuery("get_warehouses", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, "SELECT id, name, lat, lng, timezone, opens_at, closes_at FROM warehouses ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var warehouses []types.Warehouse
+	index := make(map[int64]int)
+	for rows.Next() {
+		var w types.Warehouse
+		if err := rows.Scan(&w.ID, &w.Name, &w.Location.Lat, &w.Location.Lng, &w.Timezone, &w.OpensAt, &w.ClosesAt); err != nil {
+			return nil, err
+		}
+		w.Holidays = []string{}
+		index[w.ID] = len(warehouses)
+		warehouses = append(warehouses, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	holidays, err := s.Db.QueryContext(ctx, "SELECT warehouse_id, day FROM warehouse_holidays ORDER BY warehouse_id, day")
+	if err != nil {
+		return nil, err
+	}
+	defer holidays.Close()
+
+	for holidays.Next() {
+		var id int64
+		var day string
+		if err := holidays.Scan(&id, &day); err != nil {
+			return nil, err
+		}
+		if i, ok := index[id]; ok {
+			warehouses[i].Holidays = append(warehouses[i].Holidays, day)
+		}
+	}
+
+	return warehouses, holidays.Err()
+}
+
+func (s *Store) GetWarehouseLoad(ctx context.Context) ([]types.WarehouseLoad, error) {
+	defer metrics.ObserveQuery("get_warehouse_load", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, `
+		SELECT w.id,
+			(SELECT COUNT(*) FROM agents a WHERE a.warehouse_id = w.id AND a.checked_in = TRUE),
+			(SELECT COUNT(*) FROM orders o WHERE o.warehouse_id = w.id AND o.assigned = FALSE)
+		FROM warehouses w
+		ORDER BY w.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var loads []types.WarehouseLoad
+	for rows.Next() {
+		var l types.WarehouseLoad
+		if err := rows.Scan(&l.WarehouseID, &l.CheckedInAgents, &l.PendingOrders); err != nil {
+			return nil, err
+		}
+		loads = append(loads, l)
+	}
+
+	return loads, rows.Err()
+}
+
+func (s *Store) CreateServiceZones(ctx context.Context, warehouseID int64, zones []types.ServiceZone) ([]int64, error) {
+	defer metrics.ObserveQuery("create_service_zones", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, s.q(`SELECT EXISTS (SELECT 1 FROM warehouses WHERE id = ?)`), warehouseID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("warehouse %d: %w", warehouseID, storage.ErrNotFound)
+	}
+
+	ids := make([]int64, 0, len(zones))
+	for _, z := range zones {
+		polygon, err := json.Marshal(z.Polygon)
+		if err != nil {
+			return nil, err
+		}
+		var id int64
+		err = tx.QueryRowContext(ctx, s.q(`
+			INSERT INTO service_zones (warehouse_id, name, polygon) VALUES (?, ?, ?) RETURNING id
+		`), warehouseID, z.Name, string(polygon)).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, tx.Commit()
+}
+
+func (s *Store) GetServiceZones(ctx context.Context, warehouseID int64) ([]types.ServiceZone, error) {
+	defer metrics.ObserveQuery("get_service_zones", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, s.q(`
+		SELECT id, warehouse_id, name, polygon, created_at FROM service_zones
+		WHERE ? = 0 OR warehouse_id = ?
+		ORDER BY id
+	`), warehouseID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var zones []types.ServiceZone
+	for rows.Next() {
+		var z types.ServiceZone
+		var polygon string
+		if err := rows.Scan(&z.ID, &z.WarehouseID, &z.Name, &polygon, &z.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(polygon), &z.Polygon); err != nil {
+			return nil, fmt.Errorf("service zone %d: %w", z.ID, err)
+		}
+		zones = append(zones, z)
+	}
+
+	return zones, rows.Err()
+}
+
+func (s *Store) DeleteServiceZone(ctx context.Context, zoneID int64) error {
+	defer metrics.ObserveQuery("delete_service_zone", time.Now())
+
+	res, err := s.Db.ExecContext(ctx, s.q(`DELETE FROM service_zones WHERE id = ?`), zoneID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("service zone %d: %w", zoneID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) CreateAddressReviews(ctx context.Context, reviews []types.AddressReview) ([]int64, error) {
+	defer metrics.ObserveQuery("create_address_reviews", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, 0, len(reviews))
+	for _, rv := range reviews {
+		req, err := json.Marshal(rv.Request)
+		if err != nil {
+			return nil, err
+		}
+		var id int64
+		err = tx.QueryRowContext(ctx, s.q(`
+			INSERT INTO address_reviews (request, reason) VALUES (?, ?) RETURNING id
+		`), string(req), rv.Reason).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, tx.Commit()
+}
+
+func (s *Store) GetAddressReviews(ctx context.Context) ([]types.AddressReview, error) {
+	defer metrics.ObserveQuery("get_address_reviews", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, `SELECT id, request, reason, created_at FROM address_reviews ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []types.AddressReview
+	for rows.Next() {
+		rv, err := scanAddressReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, rv)
+	}
+
+	return reviews, rows.Err()
+}
+
+func (s *Store) GetAddressReview(ctx context.Context, reviewID int64) (types.AddressReview, error) {
+	defer metrics.ObserveQuery("get_address_review", time.Now())
+
+	row := s.Db.QueryRowContext(ctx, s.q(`SELECT id, request, reason, created_at FROM address_reviews WHERE id = ?`), reviewID)
+	rv, err := scanAddressReview(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.AddressReview{}, fmt.Errorf("address review %d: %w", reviewID, storage.ErrNotFound)
+	}
+	return rv, err
+}
+
+func scanAddressReview(row interface{ Scan(...any) error }) (types.AddressReview, error) {
+	var rv types.AddressReview
+	var req string
+	if err := row.Scan(&rv.ID, &req, &rv.Reason, &rv.CreatedAt); err != nil {
+		return rv, err
+	}
+	if err := json.Unmarshal([]byte(req), &rv.Request); err != nil {
+		return rv, fmt.Errorf("address review %d: %w", rv.ID, err)
+	}
+	return rv, nil
+}
+
+func (s *Store) ResolveAddressReview(ctx context.Context, reviewID int64, o types.Order) (int64, error) {
+	defer metrics.ObserveQuery("resolve_address_review", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, s.q(`DELETE FROM address_reviews WHERE id = ?`), reviewID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("address review %d: %w", reviewID, storage.ErrNotFound)
+	}
+
+	var id int64
+	if err := tx.QueryRowContext(ctx, s.q(insertOrder), orderArgs(o)...).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (s *Store) DeleteAddressReview(ctx context.Context, reviewID int64) error {
+	defer metrics.ObserveQuery("delete_address_review", time.Now())
+
+	res, err := s.Db.ExecContext(ctx, s.q(`DELETE FROM address_reviews WHERE id = ?`), reviewID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("address review %d: %w", reviewID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) CreateCustomer(ctx context.Context, c types.Customer) (int64, error) {
+	defer metrics.ObserveQuery("create_customer", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var existing int64
+	err = tx.QueryRowContext(ctx, s.q(`SELECT id FROM customers WHERE phone = ?`), c.Phone).Scan(&existing)
+	if err == nil {
+		return 0, fmt.Errorf("phone %s is customer %d: %w", c.Phone, existing, storage.ErrConflict)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, s.q(`
+		INSERT INTO customers (name, phone, email, notifications_opt_out) VALUES (?, ?, ?, ?) RETURNING id
+	`), c.Name, c.Phone, c.Email, c.NotificationsOptOut).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range c.Addresses {
+		if _, err := s.insertAddress(ctx, tx, id, a); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+func (s *Store) insertAddress(ctx context.Context, tx *sql.Tx, customerID int64, a types.CustomerAddress) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, s.q(`
+		INSERT INTO customer_addresses (customer_id, label, line, city, pincode, lat, lng)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), customerID, a.Label, a.Line, a.City, a.Pincode, a.Location.Lat, a.Location.Lng).Scan(&id)
+	return id, err
+}
+
+func (s *Store) GetCustomer(ctx context.Context, customerID int64) (types.Customer, error) {
+	defer metrics.ObserveQuery("get_customer", time.Now())
+
+	var c types.Customer
+	err := s.Db.QueryRowContext(ctx, s.q(`
+		SELECT id, name, phone, email, notifications_opt_out, created_at FROM customers WHERE id = ?
+	`), customerID).Scan(&c.ID, &c.Name, &c.Phone, &c.Email, &c.NotificationsOptOut, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return c, err
+	}
+
+	rows, err := s.Db.QueryContext(ctx, s.q(`SELECT `+addressColumns+` FROM customer_addresses WHERE customer_id = ? ORDER BY id`), customerID)
+	if err != nil {
+		return c, err
+	}
+	defer rows.Close()
+
+	c.Addresses = []types.CustomerAddress{}
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return c, err
+		}
+		c.Addresses = append(c.Addresses, a)
+	}
+
+	return c, rows.Err()
+}
+
+func (s *Store) SetNotificationsOptOut(ctx context.Context, customerID int64, optOut bool) error {
+	defer metrics.ObserveQuery("set_notifications_opt_out", time.Now())
+
+	res, err := s.Db.ExecContext(ctx, s.q(`UPDATE customers SET notifications_opt_out = ? WHERE id = ?`), optOut, customerID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) AddCustomerAddress(ctx context.Context, customerID int64, a types.CustomerAddress) (int64, error) {
+	defer metrics.ObserveQuery("add_customer_address", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, s.q(`SELECT EXISTS (SELECT 1 FROM customers WHERE id = ?)`), customerID).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+
+	id, err := s.insertAddress(ctx, tx, customerID, a)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (s *Store) GetCustomerAddress(ctx context.Context, addressID int64) (types.CustomerAddress, error) {
+	defer metrics.ObserveQuery("get_customer_address", time.Now())
+
+	row := s.Db.QueryRowContext(ctx, s.q(`SELECT `+addressColumns+` FROM customer_addresses WHERE id = ?`), addressID)
+	a, err := scanAddress(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, fmt.Errorf("address %d: %w", addressID, storage.ErrNotFound)
+	}
+	return a, err
+}
+
+const addressColumns = `id, customer_id, label, line, city, pincode, lat, lng, verified, created_at`
+
+func scanAddress(row interface{ Scan(...any) error }) (types.CustomerAddress, error) {
+	var a types.CustomerAddress
+	err := row.Scan(&a.ID, &a.CustomerID, &a.Label, &a.Line, &a.City, &a.Pincode, &a.Location.Lat, &a.Location.Lng,
+		&a.Verified, &a.CreatedAt)
+	return a, err
+}
+
+func (s *Store) GetCustomerOrders(ctx context.Context, customerID int64, limit, offset int) ([]types.CustomerOrder, int, error) {
+	defer metrics.ObserveQuery("get_customer_orders", time.Now())
+
+	var total int
+	var exists bool
+	err := s.Db.QueryRowContext(ctx, s.q(`
+		SELECT EXISTS (SELECT 1 FROM customers WHERE id = ?), (SELECT COUNT(*) FROM orders WHERE customer_id = ?)
+	`), customerID, customerID).Scan(&exists, &total)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !exists {
+		return nil, 0, fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+
+	rows, err := s.Db.QueryContext(ctx, s.q(`
+		SELECT `+orderColumns+`, d.delivered_at
+		FROM orders
+		LEFT JOIN (SELECT order_id, delivered_at FROM assignments WHERE delivered_at IS NOT NULL) d ON d.order_id = orders.id
+		WHERE customer_id = ?
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`), customerID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orders := []types.CustomerOrder{}
+	for rows.Next() {
+		var deliveredAt sql.NullTime
+		o, err := scanOrder(rows, &deliveredAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, types.NewCustomerOrder(o, timePtr(deliveredAt)))
+	}
+
+	return orders, total, rows.Err()
+}
+
+func (s *Store) CheckInAgents(ctx context.Context, a types.Agent) (int64, error) {
+	defer metrics.ObserveQuery("check_in_agents", time.Now())
+
+	return s.insertID(ctx, `
+		INSERT INTO agents (name, warehouse_id, checked_in, vehicle, max_weight_kg, max_volume_l, max_parcels)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, a.Name, a.WarehouseID, true, a.Vehicle, a.MaxWeightKg, a.MaxVolumeL, a.MaxParcels)
+}
+
+// insertOrder stores an order given orderArgs and returns its ID.
+const insertOrder = `
+	INSERT INTO orders (customer, lat, lng, warehouse_id, assigned, priority, window_start, window_end,
+		weight_kg, volume_l, parcels, out_of_zone, customer_id, address_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING id
+`
+
+func orderArgs(o types.Order) []any {
+	return []any{o.Customer, o.Lat, o.Lng, o.WarehouseID, o.Assigned, o.Priority, nullTime(o.WindowStart), nullTime(o.WindowEnd),
+		o.WeightKg, o.VolumeL, o.Parcels, o.OutOfZone, nullID(o.CustomerID), nullID(o.AddressID)}
+}
+
+func (s *Store) GetOrder(ctx context.Context, orderID int64) (types.Order, error) {
+	defer metrics.ObserveQuery("get_order", time.Now())
+
+	o, err := scanOrder(s.Db.QueryRowContext(ctx, s.q(`SELECT `+orderColumns+` FROM orders WHERE id = ?`), orderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return o, fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+	return o, err
+}
+
+func (s *Store) CreateOrder(ctx context.Context, o types.Order) (int64, error) {
+	defer metrics.ObserveQuery("create_order", time.Now())
+
+	var id int64
+	if err := s.Db.QueryRowContext(ctx, s.q(insertOrder), orderArgs(o)...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s *Store) CreateBulkOrders(ctx context.Context, orders []types.Order) ([]int64, error) {
+	defer metrics.ObserveQuery("create_bulk_orders", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, s.q(insertOrder))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	ids := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		var id int64
+		err := stmt.QueryRowContext(ctx, orderArgs(order)...).Scan(&id)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (s *Store) GetRoutes(ctx context.Context, since time.Time) ([]types.RouteStop, error) {
+	defer metrics.ObserveQuery("get_routes", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, s.q(`
+		SELECT a.agent_id, a.order_id, a.route_seq, o.lat, o.lng, o.window_start, o.window_end,
+			a.planned_km, a.planned_minutes, a.assigned_at, a.delivered_at, o.weight_kg, o.volume_l, o.parcels
+		FROM assignments a
+		JOIN orders o ON o.id = a.order_id
+		WHERE a.unassigned_at IS NULL AND (a.delivered_at IS NULL OR a.assigned_at >= ?)
+		ORDER BY a.agent_id, a.route_seq, a.id
+	`), since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stops []types.RouteStop
+	for rows.Next() {
+		var st types.RouteStop
+		var windowStart, windowEnd, deliveredAt sql.NullTime
+		err := rows.Scan(&st.AgentID, &st.OrderID, &st.RouteSeq, &st.Location.Lat, &st.Location.Lng, &windowStart, &windowEnd,
+			&st.PlannedKm, &st.PlannedMinutes, &st.AssignedAt, &deliveredAt, &st.WeightKg, &st.VolumeL, &st.Parcels)
+		if err != nil {
+			return nil, err
+		}
+		st.WindowStart = timePtr(windowStart)
+		st.WindowEnd = timePtr(windowEnd)
+		st.DeliveredAt = timePtr(deliveredAt)
+		stops = append(stops, st)
+	}
+	return stops, rows.Err()
+}
+
+// agentSummaryQuery counts the orders an agent holds or delivered; the km and minutes
+// include the legs driven to failed attempts too.
+const agentSummaryQuery = `
+	SELECT agent_id,
+		COUNT(CASE WHEN unassigned_at IS NULL THEN 1 END) AS total_orders,
+		COUNT(delivered_at) AS delivered_orders,
+		COALESCE(SUM(planned_km), 0) AS planned_km,
+		COALESCE(SUM(planned_minutes), 0) AS planned_minutes,
+		COALESCE(SUM(actual_km), 0) AS actual_km,
+		COALESCE(SUM(actual_minutes), 0) AS actual_minutes
+	FROM assignments
+	WHERE ` + drivenAssignment + `
+	GROUP BY agent_id
+	ORDER BY agent_id
+`
+
+func (s *Store) GetWindowedOrders(ctx context.Context) ([]types.SLAOrder, error) {
+	defer metrics.ObserveQuery("get_windowed_orders", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, `
+		SELECT o.id, o.warehouse_id, o.customer, o.priority, o.window_start, o.window_end, a.agent_id, a.eta
+		FROM orders o
+		LEFT JOIN assignments a ON a.order_id = o.id AND a.delivered_at IS NULL AND a.unassigned_at IS NULL
+		WHERE o.window_end IS NOT NULL AND o.return_started_at IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM assignments d WHERE d.order_id = o.id AND d.delivered_at IS NOT NULL)
+		ORDER BY o.window_end, o.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []types.SLAOrder
+	for rows.Next() {
+		var o types.SLAOrder
+		var windowStart, eta sql.NullTime
+		var agentID sql.NullInt64
+		if err := rows.Scan(&o.OrderID, &o.WarehouseID, &o.Customer, &o.Priority, &windowStart, &o.WindowEnd, &agentID, &eta); err != nil {
+			return nil, err
+		}
+		o.WindowStart = timePtr(windowStart)
+		o.ETA = timePtr(eta)
+		if agentID.Valid {
+			o.AgentID = &agentID.Int64
+		}
+		orders = append(orders, o)
+	}
+
+	return orders, rows.Err()
+}
+
+func (s *Store) scanAgentSummaries(rows *sql.Rows) ([]types.AgentSummary, error) {
+	var summaries []types.AgentSummary
+	for rows.Next() {
+		var summary types.AgentSummary
+		err := rows.Scan(&summary.AgentID, &summary.TotalOrders, &summary.DeliveredOrders,
+			&summary.PlannedKm, &summary.PlannedMinutes, &summary.ActualKm, &summary.ActualMinutes)
+		if err != nil {
+			return nil, err
+		}
+		s.fillSummary(&summary)
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+func (s *Store) GetAgentSummary(ctx context.Context) ([]types.AgentSummary, error) {
+	defer metrics.ObserveQuery("get_agent_summary", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, agentSummaryQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.scanAgentSummaries(rows)
+}
+
+func (s *Store) GetAgentSummaryPaginated(ctx context.Context, page int, limit int) (types.PaginatedAgentSummary, error) {
+	defer metrics.ObserveQuery("get_agent_summary_paginated", time.Now())
+
+	offset := (page - 1) * limit
+
+	// 1. Get total agent count
+	var totalCount int
+	err := s.Db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT agent_id) FROM assignments WHERE "+drivenAssignment).Scan(&totalCount)
+	if err != nil {
+		return types.PaginatedAgentSummary{}, err
+	}
+
+	// 2. Get actual data with pagination
+	rows, err := s.Db.QueryContext(ctx, s.q(agentSummaryQuery+" LIMIT ? OFFSET ?"), limit, offset)
+	if err != nil {
+		return types.PaginatedAgentSummary{}, err
+	}
+	defer rows.Close()
+
+	summaries, err := s.scanAgentSummaries(rows)
+	if err != nil {
+		return types.PaginatedAgentSummary{}, err
+	}
+
+	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
+
+	return types.PaginatedAgentSummary{
+		CurrentPage: page,
+		TotalPages:  totalPages,
+		Data:        summaries,
+	}, nil
+}
+
+func (s *Store) GetSystemSummaryPaginated(ctx context.Context, page, limit int) (types.SystemSummary, error) {
+	defer metrics.ObserveQuery("get_system_summary_paginated", time.Now())
+
+	var summary types.SystemSummary
+
+	err := s.Db.QueryRowContext(ctx, "SELECT COUNT(*) FROM orders").Scan(&summary.TotalOrders)
+	if err != nil {
+		return summary, err
+	}
+
+	err = s.Db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(CASE WHEN assigned = TRUE AND return_started_at IS NULL THEN 1 END),
+			COUNT(CASE WHEN assigned = FALSE AND failed_attempts > 0 THEN 1 END),
+			COUNT(CASE WHEN return_started_at IS NOT NULL AND returned_at IS NULL THEN 1 END),
+			COUNT(returned_at),
+			COALESCE(SUM(failed_attempts), 0)
+		FROM orders
+	`).Scan(&summary.AssignedOrders, &summary.RequeuedOrders, &summary.ReturningOrders, &summary.ReturnedOrders, &summary.FailedAttempts)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.DeferredOrders = summary.TotalOrders - summary.AssignedOrders - summary.ReturningOrders - summary.ReturnedOrders
+
+	// Fetch paginated utilization
+	util, err := s.GetAgentSummaryPaginated(ctx, page, limit)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.AgentUtilization = util
+	return summary, nil
+}
+
+// AcquireLock inserts the lock row for name, or takes it over when it has outlived ttl, in
+// one statement so that two callers breaking the same stale lock cannot both win.
+func (s *Store) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	defer metrics.ObserveQuery("acquire_lock", time.Now())
+
+	now := time.Now()
+	res, err := s.Db.ExecContext(ctx, s.q(`
+		INSERT INTO locks (name, owner, locked_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET owner = excluded.owner, locked_at = excluded.locked_at
+		WHERE locks.locked_at < ?
+	`), name, owner, now.Unix(), now.Add(-ttl).Unix())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (s *Store) RenewLock(ctx context.Context, name, owner string) (bool, error) {
+	defer metrics.ObserveQuery("renew_lock", time.Now())
+
+	res, err := s.Db.ExecContext(ctx, s.q(`UPDATE locks SET locked_at = ? WHERE name = ? AND owner = ?`),
+		time.Now().Unix(), name, owner)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (s *Store) ReleaseLock(ctx context.Context, name, owner string) error {
+	defer metrics.ObserveQuery("release_lock", time.Now())
+
+	_, err := s.Db.ExecContext(ctx, s.q(`DELETE FROM locks WHERE name = ? AND owner = ?`), name, owner)
+	return err
+}
+
+func (s *Store) GetJobStates(ctx context.Context) ([]types.JobState, error) {
+	defer metrics.ObserveQuery("get_job_states", time.Now())
+
+	rows, err := s.Db.QueryContext(ctx, `
+		SELECT name, next_run_at, last_run_at, last_duration_ms, last_status, last_error, last_result
+		FROM scheduled_jobs
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []types.JobState
+	for rows.Next() {
+		var st types.JobState
+		var next, last sql.NullTime
+		if err := rows.Scan(&st.Name, &next, &last, &st.LastDurationMs, &st.LastStatus, &st.LastError, &st.LastResult); err != nil {
+			return nil, err
+		}
+		if next.Valid {
+			st.NextRunAt = &next.Time
+		}
+		if last.Valid {
+			st.LastRunAt = &last.Time
+		}
+		states = append(states, st)
+	}
+	return states, rows.Err()
+}
+
+func (s *Store) ScheduleJob(ctx context.Context, name string, next time.Time) error {
+	defer metrics.ObserveQuery("schedule_job", time.Now())
+
+	_, err := s.Db.ExecContext(ctx, s.q(`
+		INSERT INTO scheduled_jobs (name, next_run_at)
+		VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET next_run_at = excluded.next_run_at
+	`), name, next.UTC())
+	return err
+}
+
+func (s *Store) RecordJobRun(ctx context.Context, name string, run types.JobRun) error {
+	defer metrics.ObserveQuery("record_job_run", time.Now())
+
+	_, err := s.Db.ExecContext(ctx, s.q(`
+		INSERT INTO scheduled_jobs (name, last_run_at, last_duration_ms, last_status, last_error, last_result)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			last_run_at = excluded.last_run_at,
+			last_duration_ms = excluded.last_duration_ms,
+			last_status = excluded.last_status,
+			last_error = excluded.last_error,
+			last_result = excluded.last_result
+	`), name, run.StartedAt.UTC(), run.Duration.Milliseconds(), run.Status, run.Error, run.Result)
+	return err
+}
+
+func (s *Store) EscalateOverdueAssignments(ctx context.Context, before time.Time) ([]types.Assignment, error) {
+	defer metrics.ObserveQuery("escalate_overdue_assignments", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, s.q(`
+		SELECT `+assignmentColumns+`
+		FROM assignments
+		WHERE `+openAssignment+` AND escalated_at IS NULL AND assigned_at < ?
+		ORDER BY assigned_at, id
+	`), before.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	var overdue []types.Assignment
+	for rows.Next() {
+		a, err := scanAssignment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		overdue = append(overdue, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	for _, a := range overdue {
+		if _, err := tx.ExecContext(ctx, s.q(`UPDATE assignments SET escalated_at = ? WHERE id = ?`), now, a.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return overdue, tx.Commit()
+}
+
+func (s *Store) GetDailyReport(ctx context.Context, from, to time.Time) (types.DailyReport, error) {
+	defer metrics.ObserveQuery("get_daily_report", time.Now())
+
+	report := types.DailyReport{From: from, To: to}
+	from, to = from.UTC(), to.UTC()
+
+	err := s.Db.QueryRowContext(ctx, s.q(`
+		SELECT COUNT(*), COUNT(DISTINCT agent_id), COALESCE(SUM(planned_km), 0)
+		FROM assignments
+		WHERE assigned_at >= ? AND assigned_at < ?
+	`), from, to).Scan(&report.OrdersAssigned, &report.ActiveAgents, &report.PlannedKm)
+	if err != nil {
+		return report, err
+	}
+
+	err = s.Db.QueryRowContext(ctx, s.q(`
+		SELECT COUNT(*), COALESCE(SUM(actual_km), 0)
+		FROM assignments
+		WHERE delivered_at >= ? AND delivered_at < ?
+	`), from, to).Scan(&report.OrdersDelivered, &report.ActualKm)
+	return report, err
+}
+
+func (s *Store) PurgeDeliveredBefore(ctx context.Context, before time.Time) (int, []string, error) {
+	defer metrics.ObserveQuery("purge_delivered_before", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	cutoff := before.UTC()
+	rows, err := tx.QueryContext(ctx, s.q(`
+		SELECT photo_key, signature_key FROM delivery_proofs
+		WHERE order_id IN (SELECT order_id FROM assignments WHERE delivered_at IS NOT NULL AND delivered_at < ?)
+		ORDER BY order_id
+	`), cutoff)
+	if err != nil {
+		return 0, nil, err
+	}
+	keys := []string{}
+	for rows.Next() {
+		var photo, signature string
+		if err := rows.Scan(&photo, &signature); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		for _, key := range []string{photo, signature} {
+			if key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	for _, table := range []string{"delivery_proofs", "delivery_attempts", "notifications"} {
+		_, err = tx.ExecContext(ctx, s.q(`
+			DELETE FROM `+table+`
+			WHERE order_id IN (SELECT order_id FROM assignments WHERE delivered_at IS NOT NULL AND delivered_at < ?)
+		`), cutoff)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	// earlier assignments of the purged orders that were taken back go with them
+	_, err = tx.ExecContext(ctx, s.q(`
+		DELETE FROM assignments
+		WHERE unassigned_at IS NOT NULL
+		  AND order_id IN (SELECT order_id FROM assignments WHERE delivered_at IS NOT NULL AND delivered_at < ?)
+	`), cutoff)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, s.q(`
+		DELETE FROM orders
+		WHERE id IN (SELECT order_id FROM assignments WHERE delivered_at IS NOT NULL AND delivered_at < ?)
+	`), cutoff)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res, err := tx.ExecContext(ctx, s.q(`DELETE FROM assignments WHERE delivered_at IS NOT NULL AND delivered_at < ?`), cutoff)
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return int(n), keys, nil
+}
+
+// enqueueNotifications puts the messages about event in the outbox for the order's customer,
+// one per channel they have a contact for, with the agent and ETA of the order's latest
+// assignment and, when it was just assigned, its OTP.
+func (s *Store) enqueueNotifications(ctx context.Context, tx *sql.Tx, orderID int64, event string) error {
+	var customerID int64
+	var name, phone, email string
+	var optOut bool
+	err := tx.QueryRowContext(ctx, s.q(`
+		SELECT c.id, c.name, c.phone, c.email, c.notifications_opt_out
+		FROM orders o JOIN customers c ON c.id = o.customer_id
+		WHERE o.id = ?
+	`), orderID).Scan(&customerID, &name, &phone, &email, &optOut)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && optOut) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var agentName, otp string
+	var eta sql.NullTime
+	err = tx.QueryRowContext(ctx, s.q(`
+		SELECT ag.name, a.eta, a.otp
+		FROM assignments a JOIN agents ag ON ag.id = a.agent_id
+		WHERE a.order_id = ?
+		ORDER BY a.assigned_at DESC, a.id DESC
+		LIMIT 1
+	`), orderID).Scan(&agentName, &eta, &otp)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if event != types.EventAssigned {
+		otp = ""
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, to := range []struct{ channel, recipient string }{{types.ChannelEmail, email}, {types.ChannelSMS, phone}} {
+		if to.recipient == "" {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, s.q(`
+			INSERT INTO notifications (order_id, customer_id, event, channel, recipient, customer_name, agent_name, eta, otp,
+				status, next_attempt_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`), orderID, customerID, event, to.channel, to.recipient, name, agentName, nullTime(timePtr(eta)), otp,
+			types.NotificationPending, now, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const notificationColumns = `id, order_id, customer_id, event, channel, recipient, customer_name, agent_name, eta, otp,
+	status, attempts, last_error, next_attempt_at, created_at, sent_at`
+
+func scanNotification(rows interface{ Scan(...any) error }) (types.Notification, error) {
+	var n types.Notification
+	var eta, sentAt sql.NullTime
+	err := rows.Scan(&n.ID, &n.OrderID, &n.CustomerID, &n.Event, &n.Channel, &n.Recipient, &n.CustomerName, &n.AgentName,
+		&eta, &n.OTP, &n.Status, &n.Attempts, &n.LastError, &n.NextAttemptAt, &n.CreatedAt, &sentAt)
+	if err != nil {
+		return n, err
+	}
+	n.ETA = timePtr(eta)
+	if n.ETA != nil {
+		*n.ETA = n.ETA.UTC()
+	}
+	n.SentAt = timePtr(sentAt)
+	if n.SentAt != nil {
+		*n.SentAt = n.SentAt.UTC()
+	}
+	n.NextAttemptAt, n.CreatedAt = n.NextAttemptAt.UTC(), n.CreatedAt.UTC()
+	return n, nil
+}
+
+// ClaimNotifications pushes each due notification's next attempt past the lease, only if
+// it is still due: a concurrent claim that got there first leaves it out.
+func (s *Store) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]types.Notification, error) {
+	defer metrics.ObserveQuery("claim_notifications", time.Now())
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now = now.UTC()
+	rows, err := tx.QueryContext(ctx, s.q(`
+		SELECT `+notificationColumns+`
+		FROM notifications
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`), types.NotificationPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	var due []types.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	until := now.Add(lease).Truncate(time.Second)
+	claimed := []types.Notification{}
+	for _, n := range due {
+		res, err := tx.ExecContext(ctx, s.q(`
+			UPDATE notifications SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?
+		`), until, n.ID, types.NotificationPending, now)
+		if err != nil {
+			return nil, err
+		}
+		if k, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if k == 1 {
+			n.NextAttemptAt = until
+			claimed = append(claimed, n)
+		}
+	}
+	return claimed, tx.Commit()
+}
+
+func (s *Store) RecordNotificationAttempt(ctx context.Context, notificationID int64, status, lastError string, retryAt time.Time) error {
+	defer metrics.ObserveQuery("record_notification_attempt", time.Now())
+
+	now := time.Now().UTC().Truncate(time.Second)
+	attempt, next := 1, now
+	var sentAt any
+	switch status {
+	case types.NotificationSent:
+		sentAt = now
+	case types.NotificationSkipped:
+		attempt = 0
+	case types.NotificationPending:
+		next = retryAt.UTC().Truncate(time.Second)
+	}
+
+	res, err := s.Db.ExecContext(ctx, s.q(`
+		UPDATE notifications
+		SET status = ?, attempts = attempts + ?, last_error = ?, next_attempt_at = ?, sent_at = ?
+		WHERE id = ?
+	`), status, attempt, lastError, next, sentAt, notificationID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("notification %d: %w", notificationID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) GetOrderNotifications(ctx context.Context, orderID int64) ([]types.Notification, error) {
+	defer metrics.ObserveQuery("get_order_notifications", time.Now())
+
+	var exists bool
+	if err := s.Db.QueryRowContext(ctx, s.q(`SELECT EXISTS (SELECT 1 FROM orders WHERE id = ?)`), orderID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+
+	rows, err := s.Db.QueryContext(ctx, s.q(`SELECT `+notificationColumns+` FROM notifications WHERE order_id = ? ORDER BY id`), orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []types.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
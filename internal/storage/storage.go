@@ -1,24 +1,164 @@
 package storage
 
+import (
+	"context"
+	"time"
 
- import "github.com/sharmaprinceji/delivery-management-system/internal/types"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
 
 //interface setup....
+// Every method honours ctx cancellation and deadlines.
 type Storage interface {
-	Save(data any) error
-	GetCheckedInAgents() ([]types.Agent, error)
-	GetUnassignedOrders() ([]types.Order, error)
-	AssignOrderToAgent(orderID int64, agentID int64) error
-	GetAgentDetails(agentID int64) (map[string]interface{}, error)
+	Save(ctx context.Context, data any) error
+	GetCheckedInAgents(ctx context.Context) ([]types.Agent, error)
+	GetUnassignedOrders(ctx context.Context) ([]types.Order, error)
+	// AssignOrderToAgent claims an unassigned order, planned to be reached at eta (zero when
+	// unknown). It returns ErrConflict when the order was already claimed and ErrNotFound
+	// when it does not exist.
+	AssignOrderToAgent(ctx context.Context, orderID int64, agentID int64, plannedKm, plannedMinutes float64, eta time.Time) error
+	// InsertOrderIntoRoute claims an unassigned order like AssignOrderToAgent, but places it on
+	// the agent's route right before the open stop for beforeOrderID, or at the end when that
+	// is zero. It returns ErrConflict when the order was already claimed or beforeOrderID is no
+	// longer an open stop of the agent.
+	InsertOrderIntoRoute(ctx context.Context, orderID, agentID, beforeOrderID int64, plannedKm, plannedMinutes float64, eta time.Time) error
+	// GetRoutes lists the stops of every agent that are still open or were assigned since the
+	// given time, leaving out those taken back, by agent in route order.
+	GetRoutes(ctx context.Context, since time.Time) ([]types.RouteStop, error)
+	// CompleteDelivery closes the order's open assignment as delivered and marks the saved
+	// address it went to, if any, verified.
+	CompleteDelivery(ctx context.Context, orderID int64, actualKm, actualMinutes float64) error
+	// FailDelivery closes the order's open assignment as a failed attempt and records the
+	// attempt with its reason. The order is made pending again with its priority raised by
+	// priorityBump or, once maxAttempts attempts have failed, stays assigned to the agent and
+	// its return to the warehouse starts. It returns the order as updated, and ErrNotFound
+	// when there is no open assignment.
+	FailDelivery(ctx context.Context, orderID int64, a types.DeliveryAttempt, maxAttempts, priorityBump int) (types.Order, error)
+	// GetDeliveryAttempts lists an order's failed attempts, oldest first. It returns
+	// ErrNotFound for an unknown order.
+	GetDeliveryAttempts(ctx context.Context, orderID int64) ([]types.DeliveryAttempt, error)
+	// GetReturningOrders lists the orders on their way back to a warehouse, or to any when
+	// warehouseID is zero, longest on the way first.
+	GetReturningOrders(ctx context.Context, warehouseID int64) ([]types.Order, error)
+	// CompleteReturn records that a returning order is back at its warehouse. It returns
+	// ErrNotFound for an unknown order and ErrConflict for one that is not being returned.
+	CompleteReturn(ctx context.Context, orderID int64) error
+	// GetOpenAssignment returns the order's undelivered assignment with its OTP. It returns
+	// ErrNotFound when there is none.
+	GetOpenAssignment(ctx context.Context, orderID int64) (types.Assignment, error)
+	// RecordOTPFailure counts a wrong OTP against an assignment and returns the count.
+	RecordOTPFailure(ctx context.Context, assignmentID int64) (int, error)
+	// CompleteDeliveryWithProof closes p's assignment as delivered like CompleteDelivery and
+	// keeps p with it, delivered now. It returns ErrNotFound when the assignment is no longer open.
+	CompleteDeliveryWithProof(ctx context.Context, p types.DeliveryProof, actualKm, actualMinutes float64) error
+	// GetDeliveryProof returns ErrNotFound for an order delivered without one.
+	GetDeliveryProof(ctx context.Context, orderID int64) (types.DeliveryProof, error)
+	// UnassignOrder closes the order's open assignment with reason, keeping it as history,
+	// and makes the order pending again. It returns ErrNotFound when there is no open assignment.
+	UnassignOrder(ctx context.Context, orderID int64, reason string) error
+	// ReassignOrder closes the order's open assignment with reason and opens one for agentID
+	// at the end of its route, planned with the given leg and ETA. It returns ErrNotFound when
+	// there is no open assignment and ErrConflict when agentID is not checked in or already
+	// has the order.
+	ReassignOrder(ctx context.Context, orderID, agentID int64, plannedKm, plannedMinutes float64, eta time.Time, reason string) error
+	// ReleaseAgentOrders unassigns every open order of an agent, checking the agent out too
+	// when checkOut is set, and tells each order's customer. It returns the released order
+	// IDs, and ErrNotFound for an unknown agent.
+	ReleaseAgentOrders(ctx context.Context, agentID int64, reason string, checkOut bool) ([]int64, error)
+	GetAgentDetails(ctx context.Context, agentID int64) (map[string]interface{}, error)
 	// GetAllAssignments() ([]types.Assignment, error)
-	GetPaginatedAssignments(limit, offset int) ([]types.Assignment, int, error)
-
-	InitSchema() error
-	CreateWarehouse(name string, location types.Location) (int64, error)
-	CheckInAgents(name string, warehouseID int64) (int64, error)
-	CreateOrder(o types.Order) (int64, error)
-	CreateBulkOrders(orders []types.Order) (int, error)
-	GetAgentSummaryPaginated(page int, limit int) (types.PaginatedAgentSummary, error)
-    GetSystemSummaryPaginated(page, limit int) (types.SystemSummary, error)
-}
+	GetPaginatedAssignments(ctx context.Context, limit, offset int) ([]types.Assignment, int, error)
+
+	InitSchema(ctx context.Context) error
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (current int, expected int, err error)
+	// CreateWarehouse stores w with empty calendar fields set to their defaults.
+	CreateWarehouse(ctx context.Context, w types.Warehouse) (int64, error)
+	GetWarehouses(ctx context.Context) ([]types.Warehouse, error)
+	// SetWarehouseCalendar replaces a warehouse's timezone, hours and holidays. It returns
+	// ErrNotFound for an unknown warehouse.
+	SetWarehouseCalendar(ctx context.Context, warehouseID int64, cal types.WarehouseCalendar) error
+	GetWarehouseLoad(ctx context.Context) ([]types.WarehouseLoad, error)
+	// CreateServiceZones stores zones for a warehouse, all or none, and returns their IDs in
+	// input order. It returns ErrNotFound for an unknown warehouse.
+	CreateServiceZones(ctx context.Context, warehouseID int64, zones []types.ServiceZone) ([]int64, error)
+	// GetServiceZones lists a warehouse's zones, or every zone when warehouseID is zero,
+	// oldest first.
+	GetServiceZones(ctx context.Context, warehouseID int64) ([]types.ServiceZone, error)
+	// DeleteServiceZone returns ErrNotFound for an unknown zone.
+	DeleteServiceZone(ctx context.Context, zoneID int64) error
+	// CreateAddressReviews queues orders whose address could not be geocoded, all or none,
+	// and returns their IDs in input order.
+	CreateAddressReviews(ctx context.Context, reviews []types.AddressReview) ([]int64, error)
+	// GetAddressReviews lists the queued orders, oldest first.
+	GetAddressReviews(ctx context.Context) ([]types.AddressReview, error)
+	GetAddressReview(ctx context.Context, reviewID int64) (types.AddressReview, error)
+	// ResolveAddressReview creates the order and takes its review off the queue together,
+	// returning the order ID. It returns ErrNotFound when the review is no longer queued.
+	ResolveAddressReview(ctx context.Context, reviewID int64, o types.Order) (int64, error)
+	// DeleteAddressReview discards a queued order. It returns ErrNotFound for an unknown review.
+	DeleteAddressReview(ctx context.Context, reviewID int64) error
+	// CreateCustomer stores a customer with their addresses. It returns ErrConflict when the
+	// phone number belongs to another customer.
+	CreateCustomer(ctx context.Context, c types.Customer) (int64, error)
+	// GetCustomer returns a customer with their addresses, oldest first.
+	GetCustomer(ctx context.Context, customerID int64) (types.Customer, error)
+	// AddCustomerAddress returns ErrNotFound for an unknown customer.
+	AddCustomerAddress(ctx context.Context, customerID int64, a types.CustomerAddress) (int64, error)
+	GetCustomerAddress(ctx context.Context, addressID int64) (types.CustomerAddress, error)
+	// SetNotificationsOptOut turns a customer's notifications off or back on. It returns
+	// ErrNotFound for an unknown customer.
+	SetNotificationsOptOut(ctx context.Context, customerID int64, optOut bool) error
+	// GetCustomerOrders lists a page of a customer's orders, newest first, with the total.
+	// It returns ErrNotFound for an unknown customer.
+	GetCustomerOrders(ctx context.Context, customerID int64, limit, offset int) ([]types.CustomerOrder, int, error)
+	CheckInAgents(ctx context.Context, a types.Agent) (int64, error)
+	// GetOrder returns ErrNotFound for an unknown order.
+	GetOrder(ctx context.Context, orderID int64) (types.Order, error)
+	CreateOrder(ctx context.Context, o types.Order) (int64, error)
+	// CreateBulkOrders stores all orders or none and returns their IDs in input order.
+	CreateBulkOrders(ctx context.Context, orders []types.Order) ([]int64, error)
+	// GetWindowedOrders lists undelivered orders that have a window end and are not going
+	// back to their warehouse, with the agent and ETA of their open assignment if any.
+	// Status and MinutesLate are left empty.
+	GetWindowedOrders(ctx context.Context) ([]types.SLAOrder, error)
+	GetAgentSummaryPaginated(ctx context.Context, page int, limit int) (types.PaginatedAgentSummary, error)
+	GetSystemSummaryPaginated(ctx context.Context, page, limit int) (types.SystemSummary, error)
+
+	// AcquireLock takes the named lock for owner, breaking a lock older than ttl. It
+	// reports false when another owner holds it. Locks are shared by every process.
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// RenewLock restarts the ttl of a lock owner holds. It reports false when the lock was
+	// broken or taken by another owner meanwhile.
+	RenewLock(ctx context.Context, name, owner string) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+
+	GetJobStates(ctx context.Context) ([]types.JobState, error)
+	ScheduleJob(ctx context.Context, name string, next time.Time) error
+	RecordJobRun(ctx context.Context, name string, run types.JobRun) error
 
+	// EscalateOverdueAssignments marks undelivered assignments made before the cutoff
+	// and returns them. Each assignment is escalated once.
+	EscalateOverdueAssignments(ctx context.Context, before time.Time) ([]types.Assignment, error)
+	GetDailyReport(ctx context.Context, from, to time.Time) (types.DailyReport, error)
+
+	// Assigning, delivering and failing to deliver an order put notifications for its
+	// customer in the outbox together with the change, one per channel they can be reached
+	// on, unless the order has no customer or they opted out.
+
+	// ClaimNotifications returns up to limit pending notifications due by now, oldest first,
+	// and holds them for lease: no other claim returns them until it has passed.
+	ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]types.Notification, error)
+	// RecordNotificationAttempt sets a claimed notification's status, sent, failed or skipped,
+	// or leaves it pending until retryAt, with the error if any. Sending counts as an attempt
+	// whatever the outcome; skipping does not.
+	RecordNotificationAttempt(ctx context.Context, notificationID int64, status, lastError string, retryAt time.Time) error
+	// GetOrderNotifications lists an order's notifications, oldest first. It returns
+	// ErrNotFound for an unknown order.
+	GetOrderNotifications(ctx context.Context, orderID int64) ([]types.Notification, error)
+	// PurgeDeliveredBefore deletes assignments delivered before the cutoff together with
+	// their orders, delivery proofs, failed attempts and notifications, and returns how many
+	// assignments were removed and the blob keys of the purged proofs' files, which are for
+	// the caller to delete.
+	PurgeDeliveredBefore(ctx context.Context, before time.Time) (int, []string, error)
+}
@@ -0,0 +1,1673 @@
+// Package storagetest is a conformance suite that every storage.Storage implementation
+// must pass. Each backend calls Run from an external test package with a factory
+// returning an empty store, since this package imports the backends:
+//
+//	package sqlite_test
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, storagetest.SQLite)
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sharmaprinceji/delivery-management-system/internal/config"
+	"github.com/sharmaprinceji/delivery-management-system/internal/jobs"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/memory"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/postgres"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage/sqlite"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+// PostgresDSNEnv names the variable holding the DSN of a disposable Postgres database.
+const PostgresDSNEnv = "DMS_TEST_POSTGRES_DSN"
+
+// Factory returns a new, empty store whose schema has been initialised.
+type Factory func(t *testing.T) storage.Storage
+
+// Config returns a config carrying the default delivery rules.
+func Config() *config.Config {
+	return &config.Config{
+		Env: "test",
+		Variables: config.Variables{Delivery: config.Delivery{
+			MaxDailyDistance: 100,
+			MaxDailyTime:     600,
+			PerKmTime:        5,
+			MinEarnings:      500,
+			BaseRate:         20,
+			Tier1Orders:      25,
+			Tier2Orders:      50,
+			Tier1Rate:        35,
+			Tier2Rate:        42,
+		}},
+	}
+}
+
+// Memory returns an empty in-memory store.
+func Memory(t *testing.T) storage.Storage {
+	return memory.New(Config())
+}
+
+// SQLite opens a fresh database file in a temporary directory.
+func SQLite(t *testing.T) storage.Storage {
+	t.Helper()
+
+	cfg := Config()
+	cfg.StoragePath = filepath.Join(t.TempDir(), "delivery.db")
+
+	st, err := sqlite.New(cfg)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { st.Db.Close() })
+
+	if err := st.InitSchema(context.Background()); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	return st
+}
+
+// Postgres connects to the database named by DMS_TEST_POSTGRES_DSN, resetting it to an
+// empty schema. The test is skipped when the variable is unset.
+func Postgres(t *testing.T) storage.Storage {
+	t.Helper()
+
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set; skipping postgres backend", PostgresDSNEnv)
+	}
+
+	cfg := Config()
+	cfg.Driver = "postgres"
+	cfg.DatabaseURL = dsn
+
+	st, err := postgres.New(cfg)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { st.Db.Close() })
+
+	if _, err := st.Db.Exec(`DROP SCHEMA public CASCADE; CREATE SCHEMA public`); err != nil {
+		t.Fatalf("reset postgres schema: %v", err)
+	}
+	if err := st.InitSchema(context.Background()); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	return st
+}
+
+// Run executes the conformance suite against stores produced by newStore. It is meant
+// to run with go test -race: ConcurrentClaims and ConcurrentAllocation race writers
+// against each other and only then catch unsynchronised access.
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storage)
+	}{
+		{"Schema", testSchema},
+		{"Warehouses", testWarehouses},
+		{"WarehouseCalendar", testWarehouseCalendar},
+		{"ServiceZones", testServiceZones},
+		{"AddressReviews", testAddressReviews},
+		{"Customers", testCustomers},
+		{"Agents", testAgents},
+		{"VehicleCapacity", testVehicleCapacity},
+		{"Orders", testOrders},
+		{"Assignments", testAssignments},
+		{"CompleteDelivery", testCompleteDelivery},
+		{"DeliveryProofs", testDeliveryProofs},
+		{"FailedDeliveries", testFailedDeliveries},
+		{"Notifications", testNotifications},
+		{"DeliveryWindows", testDeliveryWindows},
+		{"Unassignment", testUnassignment},
+		{"RouteInsertion", testRouteInsertion},
+		{"AgentDetails", testAgentDetails},
+		{"Summaries", testSummaries},
+		{"WarehouseLoad", testWarehouseLoad},
+		{"Canceled", testCanceled},
+		{"ConcurrentClaims", testConcurrentClaims},
+		{"Locks", testLocks},
+		{"JobStates", testJobStates},
+		{"OverdueAndRetention", testOverdueAndRetention},
+		{"ConcurrentAllocation", testConcurrentAllocation},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore(t))
+		})
+	}
+}
+
+// seed creates one warehouse, two checked-in agents and three unassigned orders.
+func seed(t *testing.T, s storage.Storage) (warehouseID int64, agents []int64, orders []int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	warehouseID = must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 12.97, Lng: 77.59}}))
+	for _, name := range []string{"Ravi", "Asha"} {
+		agents = append(agents, must(t)(s.CheckInAgents(ctx, types.Agent{Name: name, WarehouseID: warehouseID})))
+	}
+
+	orders = append(orders, must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 12.98, Lng: 77.60, WarehouseID: warehouseID})))
+	ids, err := s.CreateBulkOrders(ctx, []types.Order{
+		{Customer: "B", Lat: 12.99, Lng: 77.61, WarehouseID: warehouseID},
+		{Customer: "C", Lat: 13.00, Lng: 77.62, WarehouseID: warehouseID},
+	})
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("CreateBulkOrders = %v, %v; want 2 IDs", ids, err)
+	}
+
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	for i, o := range pending[1:] {
+		if o.ID != ids[i] {
+			t.Fatalf("bulk order %d has ID %d, CreateBulkOrders returned %d", i, o.ID, ids[i])
+		}
+	}
+	orders = append(orders, ids...)
+	return warehouseID, agents, orders
+}
+
+func must(t *testing.T) func(int64, error) int64 {
+	return func(id int64, err error) int64 {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= 0 {
+			t.Fatalf("got id %d, want > 0", id)
+		}
+		return id
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func testSchema(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	if err := s.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	current, expected, err := s.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if current != expected {
+		t.Errorf("schema at version %d, want %d", current, expected)
+	}
+
+	// applying again must be a no-op
+	if err := s.InitSchema(ctx); err != nil {
+		t.Errorf("second InitSchema: %v", err)
+	}
+}
+
+func testWarehouses(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	first := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "North", Location: types.Location{Lat: 12.5, Lng: 77.5}}))
+	second := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "South", Location: types.Location{Lat: -33.9, Lng: 18.4}}))
+
+	got, err := s.GetWarehouses(ctx)
+	if err != nil {
+		t.Fatalf("GetWarehouses: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d warehouses, want 2", len(got))
+	}
+	if got[0].ID != first || got[0].Name != "North" || !near(got[0].Location.Lat, 12.5) {
+		t.Errorf("first warehouse = %+v", got[0])
+	}
+	if got[1].ID != second || !near(got[1].Location.Lng, 18.4) {
+		t.Errorf("second warehouse = %+v", got[1])
+	}
+}
+
+func testWarehouseCalendar(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	cal := types.WarehouseCalendar{
+		Timezone: "Asia/Kolkata",
+		OpensAt:  "08:30",
+		ClosesAt: "20:00",
+		Holidays: []string{"2024-12-25", "2024-01-26", "2024-12-25"},
+	}
+	local := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Local", Location: types.Location{Lat: 1, Lng: 1}, WarehouseCalendar: cal}))
+	plain := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Plain", Location: types.Location{Lat: 2, Lng: 2}}))
+
+	byID := func() map[int64]types.Warehouse {
+		got, err := s.GetWarehouses(ctx)
+		if err != nil {
+			t.Fatalf("GetWarehouses: %v", err)
+		}
+		m := make(map[int64]types.Warehouse)
+		for _, w := range got {
+			m[w.ID] = w
+		}
+		return m
+	}
+
+	got := byID()
+	want := cal.WithDefaults()
+	if c := got[local].WarehouseCalendar; c.Timezone != want.Timezone || c.OpensAt != "08:30" || c.ClosesAt != "20:00" ||
+		!slices.Equal(c.Holidays, []string{"2024-01-26", "2024-12-25"}) {
+		t.Errorf("calendar = %+v, want %+v", c, want)
+	}
+	if c := got[plain].WarehouseCalendar; c.Timezone != types.DefaultTimezone || c.OpensAt != types.DefaultOpensAt ||
+		c.ClosesAt != types.DefaultClosesAt || len(c.Holidays) != 0 {
+		t.Errorf("default calendar = %+v", c)
+	}
+
+	update := types.WarehouseCalendar{Timezone: "America/New_York", OpensAt: "06:00", Holidays: []string{"2024-07-04"}}
+	if err := s.SetWarehouseCalendar(ctx, local, update); err != nil {
+		t.Fatalf("SetWarehouseCalendar: %v", err)
+	}
+	if c := byID()[local].WarehouseCalendar; c.Timezone != "America/New_York" || c.OpensAt != "06:00" ||
+		c.ClosesAt != types.DefaultClosesAt || !slices.Equal(c.Holidays, []string{"2024-07-04"}) {
+		t.Errorf("updated calendar = %+v", c)
+	}
+
+	if err := s.SetWarehouseCalendar(ctx, 9999, update); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("SetWarehouseCalendar(unknown) = %v, want ErrNotFound", err)
+	}
+}
+
+func testServiceZones(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh, _, _ := seed(t, s)
+	other := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "East", Location: types.Location{Lat: 12.95, Lng: 77.70}}))
+
+	square := types.Polygon{{{77.5, 12.9}, {77.7, 12.9}, {77.7, 13.1}, {77.5, 13.1}, {77.5, 12.9}}}
+	withHole := types.Polygon{
+		{{77.6, 12.9}, {77.8, 12.9}, {77.8, 13.0}, {77.6, 13.0}, {77.6, 12.9}},
+		{{77.65, 12.92}, {77.75, 12.92}, {77.75, 12.98}, {77.65, 12.98}, {77.65, 12.92}},
+	}
+
+	if _, err := s.CreateServiceZones(ctx, 9999, []types.ServiceZone{{Name: "x", Polygon: square}}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("zones for an unknown warehouse: got %v, want ErrNotFound", err)
+	}
+	ids, err := s.CreateServiceZones(ctx, wh, []types.ServiceZone{{Name: "central", Polygon: square}})
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("CreateServiceZones = %v, %v", ids, err)
+	}
+	more, err := s.CreateServiceZones(ctx, other, []types.ServiceZone{{Name: "east", Polygon: withHole}, {Name: "east 2", Polygon: square}})
+	if err != nil || len(more) != 2 || more[0] <= ids[0] {
+		t.Fatalf("CreateServiceZones = %v, %v", more, err)
+	}
+
+	zones, err := s.GetServiceZones(ctx, other)
+	if err != nil {
+		t.Fatalf("GetServiceZones: %v", err)
+	}
+	if len(zones) != 2 || zones[0].ID != more[0] || zones[0].Name != "east" || zones[0].WarehouseID != other || zones[0].CreatedAt.IsZero() {
+		t.Fatalf("zones of warehouse %d = %+v", other, zones)
+	}
+	if len(zones[0].Polygon) != 2 || !slices.Equal(zones[0].Polygon[1], withHole[1]) {
+		t.Errorf("polygon = %v, want %v", zones[0].Polygon, withHole)
+	}
+	if zones[0].Contains(12.95, 77.70) || !zones[0].Contains(12.95, 77.62) || zones[0].Contains(13.05, 77.62) {
+		t.Errorf("hole or outer ring not respected")
+	}
+
+	if err := s.DeleteServiceZone(ctx, more[1]); err != nil {
+		t.Fatalf("DeleteServiceZone: %v", err)
+	}
+	if err := s.DeleteServiceZone(ctx, more[1]); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("deleting twice: got %v, want ErrNotFound", err)
+	}
+	all, err := s.GetServiceZones(ctx, 0)
+	if err != nil {
+		t.Fatalf("GetServiceZones: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != ids[0] || all[1].ID != more[0] {
+		t.Errorf("all zones = %+v", all)
+	}
+
+	id := must(t)(s.CreateOrder(ctx, types.Order{Customer: "far", Lat: 14, Lng: 78, WarehouseID: wh, OutOfZone: true}))
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	for _, o := range pending {
+		if o.OutOfZone != (o.ID == id) {
+			t.Errorf("order %d out_of_zone = %v", o.ID, o.OutOfZone)
+		}
+	}
+}
+
+func testAddressReviews(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh, _, _ := seed(t, s)
+
+	end := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("IST", 19800))
+	ids, err := s.CreateAddressReviews(ctx, []types.AddressReview{
+		{Request: types.OrderRequest{Customer: "Asha", Address: &types.Address{Line: "somewhere", City: "Atlantis"}, WindowEnd: &end}, Reason: "address not found"},
+		{Request: types.OrderRequest{Customer: "Bala", Address: &types.Address{Pincode: "999999"}, Priority: 3}, Reason: "address not found"},
+	})
+	if err != nil || len(ids) != 2 || ids[1] <= ids[0] {
+		t.Fatalf("CreateAddressReviews = %v, %v", ids, err)
+	}
+
+	reviews, err := s.GetAddressReviews(ctx)
+	if err != nil {
+		t.Fatalf("GetAddressReviews: %v", err)
+	}
+	if len(reviews) != 2 || reviews[0].ID != ids[0] || reviews[0].Reason != "address not found" || reviews[0].CreatedAt.IsZero() {
+		t.Fatalf("reviews = %+v", reviews)
+	}
+	got := reviews[0].Request
+	if got.Customer != "Asha" || got.Address == nil || got.Address.City != "Atlantis" || got.WindowEnd == nil || !got.WindowEnd.Equal(end) {
+		t.Errorf("request = %+v, address %+v", got, got.Address)
+	}
+
+	rv, err := s.GetAddressReview(ctx, ids[1])
+	if err != nil || rv.Request.Priority != 3 || rv.Request.Address.Pincode != "999999" {
+		t.Fatalf("GetAddressReview = %+v, %v", rv, err)
+	}
+	if _, err := s.GetAddressReview(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("unknown review: got %v, want ErrNotFound", err)
+	}
+
+	orderID, err := s.ResolveAddressReview(ctx, ids[1], types.Order{Customer: "Bala", Lat: 12.93, Lng: 77.62, WarehouseID: wh, Priority: 3})
+	if err != nil {
+		t.Fatalf("ResolveAddressReview: %v", err)
+	}
+	if _, err := s.ResolveAddressReview(ctx, ids[1], types.Order{Customer: "Bala", Lat: 12.93, Lng: 77.62, WarehouseID: wh}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("resolving twice: got %v, want ErrNotFound", err)
+	}
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	if !slices.ContainsFunc(pending, func(o types.Order) bool { return o.ID == orderID && o.Customer == "Bala" && o.Priority == 3 }) {
+		t.Errorf("resolved order %d not pending: %+v", orderID, pending)
+	}
+
+	if err := s.DeleteAddressReview(ctx, ids[0]); err != nil {
+		t.Fatalf("DeleteAddressReview: %v", err)
+	}
+	if err := s.DeleteAddressReview(ctx, ids[0]); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("deleting twice: got %v, want ErrNotFound", err)
+	}
+	if reviews, err := s.GetAddressReviews(ctx); err != nil || len(reviews) != 0 {
+		t.Errorf("reviews left = %+v, %v", reviews, err)
+	}
+}
+
+func testCustomers(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh, agents, _ := seed(t, s)
+
+	home := types.CustomerAddress{Label: "home", Address: types.Address{Line: "4th Block", City: "Bengaluru", Pincode: "560034"},
+		Location: types.Location{Lat: 12.9352, Lng: 77.6245}}
+	id, err := s.CreateCustomer(ctx, types.Customer{Name: "Asha", Phone: "+919800000001", Addresses: []types.CustomerAddress{home}})
+	if err != nil {
+		t.Fatalf("CreateCustomer: %v", err)
+	}
+	if _, err := s.CreateCustomer(ctx, types.Customer{Name: "Other", Phone: "+919800000001"}); !errors.Is(err, storage.ErrConflict) {
+		t.Errorf("same phone twice: got %v, want ErrConflict", err)
+	}
+	if _, err := s.AddCustomerAddress(ctx, 9999, home); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("address for an unknown customer: got %v, want ErrNotFound", err)
+	}
+	work := must(t)(s.AddCustomerAddress(ctx, id, types.CustomerAddress{Label: "work", Address: types.Address{City: "Bengaluru"},
+		Location: types.Location{Lat: 12.97, Lng: 77.64}}))
+
+	c, err := s.GetCustomer(ctx, id)
+	if err != nil {
+		t.Fatalf("GetCustomer: %v", err)
+	}
+	if c.Name != "Asha" || c.Phone != "+919800000001" || c.CreatedAt.IsZero() || len(c.Addresses) != 2 {
+		t.Fatalf("customer = %+v", c)
+	}
+	first := c.Addresses[0]
+	if first.CustomerID != id || first.Label != "home" || first.Pincode != "560034" || !near(first.Location.Lat, 12.9352) || first.Verified {
+		t.Errorf("first address = %+v", first)
+	}
+	if c.Addresses[1].ID != work {
+		t.Errorf("second address = %+v, want id %d", c.Addresses[1], work)
+	}
+	if _, err := s.GetCustomer(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("unknown customer: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetCustomerAddress(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("unknown address: got %v, want ErrNotFound", err)
+	}
+
+	ids, err := s.CreateBulkOrders(ctx, []types.Order{
+		{Customer: "Asha", Lat: 12.9352, Lng: 77.6245, WarehouseID: wh, CustomerID: id, AddressID: first.ID},
+		{Customer: "Asha", Lat: 12.97, Lng: 77.64, WarehouseID: wh, CustomerID: id, AddressID: work},
+		{Customer: "Asha", Lat: 12.96, Lng: 77.63, WarehouseID: wh, CustomerID: id},
+	})
+	if err != nil {
+		t.Fatalf("CreateBulkOrders: %v", err)
+	}
+	if err := s.AssignOrderToAgent(ctx, ids[0], agents[0], 1, 5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	if err := s.CompleteDelivery(ctx, ids[0], 1, 5); err != nil {
+		t.Fatalf("CompleteDelivery: %v", err)
+	}
+	if err := s.AssignOrderToAgent(ctx, ids[1], agents[0], 1, 5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+
+	verified, err := s.GetCustomerAddress(ctx, first.ID)
+	if err != nil || !verified.Verified {
+		t.Errorf("address delivered to = %+v, %v; want verified", verified, err)
+	}
+	if a, err := s.GetCustomerAddress(ctx, work); err != nil || a.Verified {
+		t.Errorf("address not delivered to = %+v, %v; want unverified", a, err)
+	}
+
+	history, total, err := s.GetCustomerOrders(ctx, id, 2, 0)
+	if err != nil {
+		t.Fatalf("GetCustomerOrders: %v", err)
+	}
+	if total != 3 || len(history) != 2 || history[0].ID != ids[2] || history[1].ID != ids[1] {
+		t.Fatalf("history = %+v, total %d", history, total)
+	}
+	if history[0].Status != types.OrderPending || history[1].Status != types.OrderAssigned || history[1].AddressID != work {
+		t.Errorf("statuses = %s, %s", history[0].Status, history[1].Status)
+	}
+	rest, _, err := s.GetCustomerOrders(ctx, id, 2, 2)
+	if err != nil || len(rest) != 1 || rest[0].Status != types.OrderDelivered || rest[0].DeliveredAt == nil || rest[0].CustomerID != id {
+		t.Errorf("last page = %+v, %v", rest, err)
+	}
+	if _, _, err := s.GetCustomerOrders(ctx, 9999, 10, 0); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("orders of an unknown customer: got %v, want ErrNotFound", err)
+	}
+}
+
+func testAgents(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 1, Lng: 1}}))
+	id := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+
+	agents, err := s.GetCheckedInAgents(ctx)
+	if err != nil {
+		t.Fatalf("GetCheckedInAgents: %v", err)
+	}
+	if len(agents) != 1 || agents[0].ID != id || agents[0].WarehouseID != wh || !agents[0].CheckedIn {
+		t.Errorf("checked-in agents = %+v", agents)
+	}
+}
+
+func testVehicleCapacity(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 1, Lng: 1}}))
+	bike := types.Capacity{Vehicle: "bike", MaxWeightKg: 15, MaxVolumeL: 40.5, MaxParcels: 10}
+	rider := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh, Capacity: bike}))
+	walker := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Asha", WarehouseID: wh}))
+
+	agents, err := s.GetCheckedInAgents(ctx)
+	if err != nil {
+		t.Fatalf("GetCheckedInAgents: %v", err)
+	}
+	if len(agents) != 2 || agents[0].ID != rider || agents[0].Capacity != bike ||
+		agents[1].ID != walker || agents[1].Capacity != (types.Capacity{}) {
+		t.Errorf("agents = %+v", agents)
+	}
+
+	parcel := types.Load{WeightKg: 2.5, VolumeL: 6, Parcels: 3}
+	must(t)(s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 1, Lng: 1, WarehouseID: wh, Load: parcel}))
+	if _, err := s.CreateBulkOrders(ctx, []types.Order{{Customer: "B", Lat: 1, Lng: 1, WarehouseID: wh, Load: types.Load{Parcels: 1}}}); err != nil {
+		t.Fatalf("CreateBulkOrders: %v", err)
+	}
+
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	if len(pending) != 2 || pending[0].Load != parcel || pending[1].Load != (types.Load{Parcels: 1}) {
+		t.Errorf("order loads = %+v", pending)
+	}
+}
+
+func testOrders(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh, _, orders := seed(t, s)
+
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	if len(pending) != 3 {
+		t.Fatalf("got %d unassigned orders, want 3", len(pending))
+	}
+	for i, o := range pending {
+		if o.ID != orders[i] || o.WarehouseID != wh || o.Assigned || o.AgentID != nil {
+			t.Errorf("order %d = %+v", i, o)
+		}
+	}
+	if pending[0].Customer != "A" || !near(pending[0].Lat, 12.98) {
+		t.Errorf("first order = %+v", pending[0])
+	}
+}
+
+func testAssignments(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh, agents, orders := seed(t, s)
+
+	if err := s.AssignOrderToAgent(ctx, orders[0], agents[0], 1.5, 7.5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	if err := s.AssignOrderToAgent(ctx, orders[1], agents[1], 2, 10, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != orders[2] {
+		t.Errorf("unassigned after assigning = %+v", pending)
+	}
+
+	page, total, err := s.GetPaginatedAssignments(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("GetPaginatedAssignments: %v", err)
+	}
+	if total != 2 || len(page) != 1 {
+		t.Fatalf("got %d items of %d, want 1 of 2", len(page), total)
+	}
+
+	all, _, err := s.GetPaginatedAssignments(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("GetPaginatedAssignments: %v", err)
+	}
+	for _, a := range all {
+		if a.OrderID == orders[0] && (a.AgentID != agents[0] || !near(a.PlannedKm, 1.5) || !near(a.PlannedMinutes, 7.5)) {
+			t.Errorf("assignment = %+v", a)
+		}
+		if a.ActualKm != nil || a.DeliveredAt != nil || a.AssignedAt.IsZero() || a.WarehouseID != wh {
+			t.Errorf("new assignment = %+v", a)
+		}
+	}
+}
+
+func testCompleteDelivery(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	_, agents, orders := seed(t, s)
+
+	if err := s.CompleteDelivery(ctx, orders[0], 1, 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("completing an unassigned order: got %v, want ErrNotFound", err)
+	}
+
+	if err := s.AssignOrderToAgent(ctx, orders[0], agents[0], 1.5, 7.5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	if err := s.CompleteDelivery(ctx, orders[0], 1.8, 9); err != nil {
+		t.Fatalf("CompleteDelivery: %v", err)
+	}
+	if err := s.CompleteDelivery(ctx, orders[0], 1.8, 9); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("completing twice: got %v, want ErrNotFound", err)
+	}
+
+	all, _, err := s.GetPaginatedAssignments(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("GetPaginatedAssignments: %v", err)
+	}
+	if len(all) != 1 || all[0].ActualKm == nil || !near(*all[0].ActualKm, 1.8) || all[0].DeliveredAt == nil {
+		t.Errorf("delivered assignment = %+v", all)
+	}
+}
+
+func testDeliveryProofs(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	_, agents, orders := seed(t, s)
+
+	o, err := s.GetOrder(ctx, orders[0])
+	if err != nil || o.ID != orders[0] || o.Assigned {
+		t.Fatalf("GetOrder = %+v, %v", o, err)
+	}
+	if _, err := s.GetOrder(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("unknown order: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetOpenAssignment(ctx, orders[0]); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("open assignment of an unassigned order: got %v, want ErrNotFound", err)
+	}
+
+	if err := s.AssignOrderToAgent(ctx, orders[0], agents[0], 1, 5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	first, err := s.GetOpenAssignment(ctx, orders[0])
+	if err != nil {
+		t.Fatalf("GetOpenAssignment: %v", err)
+	}
+	if first.AgentID != agents[0] || len(first.OTP) != storage.OTPDigits || first.OTPFailures != 0 {
+		t.Errorf("open assignment = %+v", first)
+	}
+	if n, err := s.RecordOTPFailure(ctx, first.ID); err != nil || n != 1 {
+		t.Errorf("RecordOTPFailure = %d, %v; want 1", n, err)
+	}
+	if n, err := s.RecordOTPFailure(ctx, first.ID); err != nil || n != 2 {
+		t.Errorf("RecordOTPFailure = %d, %v; want 2", n, err)
+	}
+
+	// a reassignment issues a new code and starts counting again
+	if err := s.ReassignOrder(ctx, orders[0], agents[1], 2, 10, time.Time{}, "swap"); err != nil {
+		t.Fatalf("ReassignOrder: %v", err)
+	}
+	second, err := s.GetOpenAssignment(ctx, orders[0])
+	if err != nil {
+		t.Fatalf("GetOpenAssignment: %v", err)
+	}
+	if second.ID == first.ID || second.AgentID != agents[1] || len(second.OTP) != storage.OTPDigits || second.OTPFailures != 0 {
+		t.Errorf("reassigned = %+v", second)
+	}
+
+	proof := types.DeliveryProof{
+		OrderID:      orders[0],
+		AssignmentID: first.ID,
+		AgentID:      agents[0],
+		Location:     types.Location{Lat: 12.9701, Lng: 77.5901},
+	}
+	if err := s.CompleteDeliveryWithProof(ctx, proof, 1, 5); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("proof for a closed assignment: got %v, want ErrNotFound", err)
+	}
+	proof.AssignmentID, proof.AgentID = second.ID, agents[1]
+	proof.AccuracyM, proof.DistanceM, proof.OTPVerified, proof.Note = 8, 14, true, "handed to the guard"
+	proof.Photo = &types.ProofArtefact{Key: "1/1-photo.jpg", ContentType: "image/jpeg", Size: 2048}
+	if err := s.CompleteDeliveryWithProof(ctx, proof, 1.2, 6); err != nil {
+		t.Fatalf("CompleteDeliveryWithProof: %v", err)
+	}
+	if err := s.CompleteDeliveryWithProof(ctx, proof, 1.2, 6); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("delivering twice: got %v, want ErrNotFound", err)
+	}
+
+	got, err := s.GetDeliveryProof(ctx, orders[0])
+	if err != nil {
+		t.Fatalf("GetDeliveryProof: %v", err)
+	}
+	if got.AssignmentID != second.ID || got.AgentID != agents[1] || !near(got.Location.Lat, 12.9701) || !near(got.DistanceM, 14) ||
+		!got.OTPVerified || got.Note != "handed to the guard" || got.DeliveredAt.IsZero() || got.Signature != nil {
+		t.Errorf("proof = %+v", got)
+	}
+	if got.Photo == nil || got.Photo.Key != "1/1-photo.jpg" || got.Photo.ContentType != "image/jpeg" || got.Photo.Size != 2048 {
+		t.Errorf("photo = %+v", got.Photo)
+	}
+	if _, err := s.GetDeliveryProof(ctx, orders[1]); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("proof of an undelivered order: got %v, want ErrNotFound", err)
+	}
+
+	all, _, err := s.GetPaginatedAssignments(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("GetPaginatedAssignments: %v", err)
+	}
+	for _, a := range all {
+		if a.ID == second.ID && (a.DeliveredAt == nil || a.ActualKm == nil || !near(*a.ActualKm, 1.2)) {
+			t.Errorf("delivered assignment = %+v", a)
+		}
+	}
+
+	// retention takes the proof with the order, handing back its files to delete
+	_, keys, err := s.PurgeDeliveredBefore(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeliveredBefore: %v", err)
+	}
+	if !slices.Equal(keys, []string{"1/1-photo.jpg"}) {
+		t.Errorf("purged files = %v, want the photo", keys)
+	}
+	if _, err := s.GetDeliveryProof(ctx, orders[0]); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("proof after purge: got %v, want ErrNotFound", err)
+	}
+}
+
+func testFailedDeliveries(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh, agents, orders := seed(t, s)
+	const maxAttempts, bump = 2, 10
+
+	if _, err := s.FailDelivery(ctx, orders[0], types.DeliveryAttempt{Reason: types.FailOther}, maxAttempts, bump); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("failing an unassigned order: got %v, want ErrNotFound", err)
+	}
+
+	// the first failure sends the order back to allocation, ahead of the others
+	if err := s.AssignOrderToAgent(ctx, orders[0], agents[0], 1, 5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	first, err := s.GetOpenAssignment(ctx, orders[0])
+	if err != nil {
+		t.Fatalf("GetOpenAssignment: %v", err)
+	}
+	o, err := s.FailDelivery(ctx, orders[0], types.DeliveryAttempt{Reason: types.FailCustomerUnavailable}, maxAttempts, bump)
+	if err != nil {
+		t.Fatalf("FailDelivery: %v", err)
+	}
+	if o.FailedAttempts != 1 || o.Assigned || o.AgentID != nil || o.Priority != bump || o.ReturnStartedAt != nil {
+		t.Errorf("requeued order = %+v", o)
+	}
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil || len(pending) != 3 {
+		t.Fatalf("GetUnassignedOrders = %d orders, %v; want 3", len(pending), err)
+	}
+
+	// the last one hands it to its agent to take back
+	if err := s.AssignOrderToAgent(ctx, orders[0], agents[1], 1, 5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	second, err := s.GetOpenAssignment(ctx, orders[0])
+	if err != nil {
+		t.Fatalf("GetOpenAssignment: %v", err)
+	}
+	o, err = s.FailDelivery(ctx, orders[0], types.DeliveryAttempt{Reason: types.FailOther, Note: "gate locked"}, maxAttempts, bump)
+	if err != nil {
+		t.Fatalf("FailDelivery: %v", err)
+	}
+	if o.FailedAttempts != 2 || !o.Assigned || o.AgentID == nil || *o.AgentID != agents[1] || o.Priority != bump || o.ReturnStartedAt == nil || o.ReturnedAt != nil {
+		t.Errorf("returning order = %+v", o)
+	}
+	if _, err := s.GetOpenAssignment(ctx, orders[0]); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("open assignment of a returning order: got %v, want ErrNotFound", err)
+	}
+	if pending, _ := s.GetUnassignedOrders(ctx); len(pending) != 2 {
+		t.Errorf("GetUnassignedOrders = %d orders, want 2", len(pending))
+	}
+
+	attempts, err := s.GetDeliveryAttempts(ctx, orders[0])
+	if err != nil || len(attempts) != 2 {
+		t.Fatalf("GetDeliveryAttempts = %+v, %v; want 2", attempts, err)
+	}
+	if a := attempts[0]; a.Attempt != 1 || a.AssignmentID != first.ID || a.AgentID != agents[0] || a.Reason != types.FailCustomerUnavailable || a.AttemptedAt.IsZero() {
+		t.Errorf("first attempt = %+v", a)
+	}
+	if a := attempts[1]; a.Attempt != 2 || a.AssignmentID != second.ID || a.AgentID != agents[1] || a.Reason != types.FailOther || a.Note != "gate locked" {
+		t.Errorf("second attempt = %+v", a)
+	}
+	// the agents drove to the door, so the legs count towards their km and minutes but not
+	// towards their orders
+	page, err := s.GetAgentSummaryPaginated(ctx, 1, 10)
+	if err != nil || page.TotalPages != 1 || len(page.Data) != 2 {
+		t.Fatalf("GetAgentSummaryPaginated = %+v, %v; want both agents on one page", page, err)
+	}
+	for i, sum := range page.Data {
+		if sum.AgentID != agents[i] || sum.TotalOrders != 0 || !near(sum.TotalKm, 1) || !near(sum.TotalMinutes, 5) {
+			t.Errorf("summary of agent %d = %+v, want 1 km and 5 minutes without orders", agents[i], sum)
+		}
+	}
+	details, err := s.GetAgentDetails(ctx, agents[0])
+	if err != nil {
+		t.Fatalf("GetAgentDetails: %v", err)
+	}
+	if toFloat(details["total_orders"]) != 0 || !near(toFloat(details["total_km"]), 1) || !near(toFloat(details["total_minutes"]), 5) {
+		t.Errorf("details after a failed attempt = %v", details)
+	}
+
+	if attempts, err := s.GetDeliveryAttempts(ctx, orders[1]); err != nil || attempts == nil || len(attempts) != 0 {
+		t.Errorf("attempts of an order that never failed = %v, %v; want empty", attempts, err)
+	}
+	if _, err := s.GetDeliveryAttempts(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("attempts of an unknown order: got %v, want ErrNotFound", err)
+	}
+
+	// a returning order no longer counts against its delivery window
+	end := time.Now().Add(-time.Hour)
+	windowed := must(t)(s.CreateOrder(ctx, types.Order{Customer: "W", Lat: 12.98, Lng: 77.60, WarehouseID: wh, WindowEnd: &end}))
+	if err := s.AssignOrderToAgent(ctx, windowed, agents[0], 1, 5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	if _, err := s.FailDelivery(ctx, windowed, types.DeliveryAttempt{Reason: types.FailAddressNotFound}, 1, bump); err != nil {
+		t.Fatalf("FailDelivery: %v", err)
+	}
+	sla, err := s.GetWindowedOrders(ctx)
+	if err != nil || len(sla) != 0 {
+		t.Errorf("GetWindowedOrders = %+v, %v; want none", sla, err)
+	}
+
+	returning, err := s.GetReturningOrders(ctx, 0)
+	if err != nil || len(returning) != 2 || returning[0].ID != orders[0] || returning[1].ID != windowed {
+		t.Errorf("GetReturningOrders = %+v, %v; want orders %d, %d", returning, err, orders[0], windowed)
+	}
+	if returning, err := s.GetReturningOrders(ctx, wh+100); err != nil || len(returning) != 0 {
+		t.Errorf("GetReturningOrders of another warehouse = %+v, %v; want none", returning, err)
+	}
+
+	if err := s.AssignOrderToAgent(ctx, orders[1], agents[0], 1, 5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	if _, err := s.FailDelivery(ctx, orders[1], types.DeliveryAttempt{Reason: types.FailRefused}, maxAttempts, bump); err != nil {
+		t.Fatalf("FailDelivery: %v", err)
+	}
+
+	if err := s.CompleteReturn(ctx, orders[0]); err != nil {
+		t.Fatalf("CompleteReturn: %v", err)
+	}
+	if err := s.CompleteReturn(ctx, orders[0]); !errors.Is(err, storage.ErrConflict) {
+		t.Errorf("returning twice: got %v, want ErrConflict", err)
+	}
+	if err := s.CompleteReturn(ctx, orders[2]); !errors.Is(err, storage.ErrConflict) {
+		t.Errorf("returning an order not on its way back: got %v, want ErrConflict", err)
+	}
+	if err := s.CompleteReturn(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("returning an unknown order: got %v, want ErrNotFound", err)
+	}
+	if o, err := s.GetOrder(ctx, orders[0]); err != nil || o.ReturnedAt == nil {
+		t.Errorf("returned order = %+v, %v", o, err)
+	}
+	if returning, _ := s.GetReturningOrders(ctx, wh); len(returning) != 1 || returning[0].ID != windowed {
+		t.Errorf("GetReturningOrders after a return = %+v, want order %d", returning, windowed)
+	}
+
+	sys, err := s.GetSystemSummaryPaginated(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("GetSystemSummaryPaginated: %v", err)
+	}
+	if sys.TotalOrders != 4 || sys.AssignedOrders != 0 || sys.DeferredOrders != 2 || sys.FailedAttempts != 4 ||
+		sys.RequeuedOrders != 1 || sys.ReturningOrders != 1 || sys.ReturnedOrders != 1 {
+		t.Errorf("system summary = %+v", sys)
+	}
+
+	// retention takes the attempts of a delivered order with it
+	if err := s.AssignOrderToAgent(ctx, orders[1], agents[1], 1, 5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	if err := s.CompleteDelivery(ctx, orders[1], 1, 5); err != nil {
+		t.Fatalf("CompleteDelivery: %v", err)
+	}
+	if _, _, err := s.PurgeDeliveredBefore(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PurgeDeliveredBefore: %v", err)
+	}
+	if _, err := s.GetDeliveryAttempts(ctx, orders[1]); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("attempts after purge: got %v, want ErrNotFound", err)
+	}
+	if attempts, err := s.GetDeliveryAttempts(ctx, orders[0]); err != nil || len(attempts) != 2 {
+		t.Errorf("attempts of a returned order after purge = %d, %v; want 2", len(attempts), err)
+	}
+}
+
+func testNotifications(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh, agents, orders := seed(t, s)
+
+	asha := must(t)(s.CreateCustomer(ctx, types.Customer{Name: "Asha Rao", Phone: "+919876543210", Email: "asha@example.com"}))
+	ravi := must(t)(s.CreateCustomer(ctx, types.Customer{Name: "Ravi K", Phone: "+919876500000", NotificationsOptOut: true}))
+	if c, err := s.GetCustomer(ctx, ravi); err != nil || !c.NotificationsOptOut {
+		t.Errorf("opted out customer = %+v, %v", c, err)
+	}
+	forAsha := must(t)(s.CreateOrder(ctx, types.Order{Customer: "Asha Rao", Lat: 12.98, Lng: 77.60, WarehouseID: wh, CustomerID: asha}))
+	forRavi := must(t)(s.CreateOrder(ctx, types.Order{Customer: "Ravi K", Lat: 12.98, Lng: 77.60, WarehouseID: wh, CustomerID: ravi}))
+
+	eta := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	for _, id := range []int64{forAsha, forRavi, orders[0]} {
+		if err := s.AssignOrderToAgent(ctx, id, agents[0], 1, 5, eta); err != nil {
+			t.Fatalf("AssignOrderToAgent: %v", err)
+		}
+	}
+
+	// one per channel the customer has, with the agent, ETA and code
+	open, err := s.GetOpenAssignment(ctx, forAsha)
+	if err != nil {
+		t.Fatalf("GetOpenAssignment: %v", err)
+	}
+	got, err := s.GetOrderNotifications(ctx, forAsha)
+	if err != nil || len(got) != 2 {
+		t.Fatalf("GetOrderNotifications = %+v, %v; want 2", got, err)
+	}
+	for i, want := range []struct{ channel, recipient string }{{types.ChannelEmail, "asha@example.com"}, {types.ChannelSMS, "+919876543210"}} {
+		n := got[i]
+		if n.Channel != want.channel || n.Recipient != want.recipient || n.Event != types.EventAssigned || n.CustomerID != asha ||
+			n.CustomerName != "Asha Rao" || n.AgentName != "Ravi" || n.ETA == nil || !n.ETA.Equal(eta) || n.OTP != open.OTP ||
+			n.Status != types.NotificationPending || n.Attempts != 0 || n.SentAt != nil {
+			t.Errorf("notification %d = %+v", i, n)
+		}
+	}
+	for _, id := range []int64{forRavi, orders[0]} {
+		if got, err := s.GetOrderNotifications(ctx, id); err != nil || len(got) != 0 {
+			t.Errorf("notifications of order %d = %+v, %v; want none", id, got, err)
+		}
+	}
+	if _, err := s.GetOrderNotifications(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("notifications of an unknown order: got %v, want ErrNotFound", err)
+	}
+
+	if err := s.SetNotificationsOptOut(ctx, 9999, false); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("opting out an unknown customer: got %v, want ErrNotFound", err)
+	}
+	if err := s.SetNotificationsOptOut(ctx, ravi, false); err != nil {
+		t.Fatalf("SetNotificationsOptOut: %v", err)
+	}
+	if err := s.ReassignOrder(ctx, forRavi, agents[1], 3, 15, time.Now().Add(time.Hour), "closer"); err != nil {
+		t.Fatalf("ReassignOrder: %v", err)
+	}
+	got, err = s.GetOrderNotifications(ctx, forRavi)
+	if err != nil || len(got) != 1 || got[0].Channel != types.ChannelSMS || got[0].AgentName != "Asha" || got[0].OTP == "" ||
+		got[0].ETA == nil {
+		t.Errorf("notifications after reassigning = %+v, %v; want one sms from Asha with an ETA", got, err)
+	}
+
+	if err := s.CompleteDelivery(ctx, forAsha, 1, 5); err != nil {
+		t.Fatalf("CompleteDelivery: %v", err)
+	}
+	got, _ = s.GetOrderNotifications(ctx, forAsha)
+	if len(got) != 4 || got[2].Event != types.EventDelivered || got[2].AgentName != "Ravi" || got[2].OTP != "" {
+		t.Errorf("notifications after delivery = %+v", got)
+	}
+
+	// claims hold messages for the lease
+	now := time.Now().Add(time.Second)
+	first, err := s.ClaimNotifications(ctx, now, time.Minute, 2)
+	if err != nil || len(first) != 2 || first[0].ID != got[0].ID || first[1].ID != got[1].ID {
+		t.Fatalf("ClaimNotifications = %+v, %v; want the first two", first, err)
+	}
+	if rest, err := s.ClaimNotifications(ctx, now, time.Minute, 10); err != nil || len(rest) != 3 {
+		t.Errorf("ClaimNotifications = %d, %v; want the other 3", len(rest), err)
+	}
+	if none, err := s.ClaimNotifications(ctx, now, time.Minute, 10); err != nil || len(none) != 0 {
+		t.Errorf("ClaimNotifications while held = %d, %v; want none", len(none), err)
+	}
+
+	ids := []int64{got[0].ID, got[1].ID, got[2].ID, got[3].ID}
+	if err := s.RecordNotificationAttempt(ctx, ids[0], types.NotificationSent, "", time.Time{}); err != nil {
+		t.Fatalf("RecordNotificationAttempt: %v", err)
+	}
+	if err := s.RecordNotificationAttempt(ctx, ids[1], types.NotificationPending, "timeout", now.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordNotificationAttempt: %v", err)
+	}
+	if err := s.RecordNotificationAttempt(ctx, ids[2], types.NotificationSkipped, "no email adapter configured", time.Time{}); err != nil {
+		t.Fatalf("RecordNotificationAttempt: %v", err)
+	}
+	if err := s.RecordNotificationAttempt(ctx, ids[3], types.NotificationFailed, "rejected", time.Time{}); err != nil {
+		t.Fatalf("RecordNotificationAttempt: %v", err)
+	}
+	if err := s.RecordNotificationAttempt(ctx, 9999, types.NotificationSent, "", time.Time{}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("recording an unknown notification: got %v, want ErrNotFound", err)
+	}
+
+	got, _ = s.GetOrderNotifications(ctx, forAsha)
+	if n := got[0]; n.Status != types.NotificationSent || n.Attempts != 1 || n.SentAt == nil || n.LastError != "" {
+		t.Errorf("sent = %+v", n)
+	}
+	if n := got[1]; n.Status != types.NotificationPending || n.Attempts != 1 || n.LastError != "timeout" || n.NextAttemptAt.Before(now.Add(59*time.Minute)) {
+		t.Errorf("to retry = %+v", n)
+	}
+	if n := got[2]; n.Status != types.NotificationSkipped || n.Attempts != 0 {
+		t.Errorf("skipped = %+v", n)
+	}
+	if n := got[3]; n.Status != types.NotificationFailed || n.Attempts != 1 || n.LastError != "rejected" {
+		t.Errorf("failed = %+v", n)
+	}
+
+	// once the lease is over only the unfinished ones that are due come back
+	later, err := s.ClaimNotifications(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	if err != nil || len(later) != 1 || later[0].OrderID != forRavi {
+		t.Errorf("ClaimNotifications after the lease = %+v, %v; want order %d's", later, err, forRavi)
+	}
+
+	// releasing the agent tells the customer, without the old agent's code
+	released, err := s.ReleaseAgentOrders(ctx, agents[1], "off sick", false)
+	if err != nil || !slices.Equal(released, []int64{forRavi}) {
+		t.Fatalf("ReleaseAgentOrders = %v, %v; want order %d", released, err, forRavi)
+	}
+	got, err = s.GetOrderNotifications(ctx, forRavi)
+	if err != nil || len(got) != 2 || got[1].Event != types.EventUnassigned || got[1].Channel != types.ChannelSMS ||
+		got[1].AgentName != "Asha" || got[1].OTP != "" {
+		t.Errorf("notifications after releasing = %+v, %v; want an unassigned sms without a code", got, err)
+	}
+
+	// retention takes the notifications with the order
+	if _, _, err := s.PurgeDeliveredBefore(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PurgeDeliveredBefore: %v", err)
+	}
+	if _, err := s.GetOrderNotifications(ctx, forAsha); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("notifications after purge: got %v, want ErrNotFound", err)
+	}
+}
+
+func testDeliveryWindows(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Hub", Location: types.Location{Lat: 1, Lng: 1}}))
+	agent := must(t)(s.CheckInAgents(ctx, types.Agent{Name: "Ravi", WarehouseID: wh}))
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	// given in another zone, read back in UTC
+	ist := time.FixedZone("IST", 5*3600+1800)
+	endIST := end.In(ist)
+	windowed := must(t)(s.CreateOrder(ctx, types.Order{Customer: "W", Lat: 1, Lng: 1, WarehouseID: wh,
+		Priority: 7, WindowStart: &start, WindowEnd: &endIST}))
+	delivered := must(t)(s.CreateOrder(ctx, types.Order{Customer: "D", Lat: 1, Lng: 1, WarehouseID: wh, WindowEnd: &end}))
+	if _, err := s.CreateBulkOrders(ctx, []types.Order{{Customer: "P", Lat: 1, Lng: 1, WarehouseID: wh, Priority: 3}}); err != nil {
+		t.Fatalf("CreateBulkOrders: %v", err)
+	}
+
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	if len(pending) != 3 {
+		t.Fatalf("got %d unassigned orders, want 3", len(pending))
+	}
+	if o := pending[0]; o.Priority != 7 || o.WindowStart == nil || !o.WindowStart.Equal(start) ||
+		o.WindowEnd == nil || !o.WindowEnd.Equal(end) {
+		t.Errorf("windowed order = %+v", o)
+	}
+	if o := pending[2]; o.Priority != 3 || o.WindowStart != nil || o.WindowEnd != nil {
+		t.Errorf("bulk order = %+v", o)
+	}
+
+	eta := end.Add(30 * time.Minute)
+	if err := s.AssignOrderToAgent(ctx, windowed, agent, 1, 5, eta); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	if err := s.AssignOrderToAgent(ctx, delivered, agent, 1, 5, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	if err := s.CompleteDelivery(ctx, delivered, 1, 5); err != nil {
+		t.Fatalf("CompleteDelivery: %v", err)
+	}
+
+	all, _, err := s.GetPaginatedAssignments(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("GetPaginatedAssignments: %v", err)
+	}
+	for _, a := range all {
+		if a.OrderID == windowed && (a.ETA == nil || !a.ETA.Equal(eta)) {
+			t.Errorf("assignment ETA = %v, want %v", a.ETA, eta)
+		}
+		if a.OrderID == delivered && a.ETA != nil {
+			t.Errorf("assignment without ETA = %v", a.ETA)
+		}
+	}
+
+	// only the undelivered order has both a window and no delivery
+	sla, err := s.GetWindowedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetWindowedOrders: %v", err)
+	}
+	if len(sla) != 1 {
+		t.Fatalf("windowed orders = %+v, want 1", sla)
+	}
+	if o := sla[0]; o.OrderID != windowed || o.WarehouseID != wh || o.Customer != "W" || o.Priority != 7 ||
+		!o.WindowEnd.Equal(end) || o.WindowStart == nil || o.AgentID == nil || *o.AgentID != agent ||
+		o.ETA == nil || !o.ETA.Equal(eta) {
+		t.Errorf("windowed order = %+v", o)
+	}
+}
+
+func testUnassignment(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	_, agents, orders := seed(t, s)
+	for i, agent := range []int64{agents[0], agents[0], agents[1]} {
+		if err := s.AssignOrderToAgent(ctx, orders[i], agent, 2, 10, time.Time{}); err != nil {
+			t.Fatalf("AssignOrderToAgent: %v", err)
+		}
+	}
+
+	if err := s.UnassignOrder(ctx, orders[0], "customer rescheduled"); err != nil {
+		t.Fatalf("UnassignOrder: %v", err)
+	}
+	if err := s.UnassignOrder(ctx, orders[0], "again"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("unassigning twice: got %v, want ErrNotFound", err)
+	}
+	if err := s.CompleteDelivery(ctx, orders[0], 1, 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("delivering an unassigned order: got %v, want ErrNotFound", err)
+	}
+
+	eta := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err := s.ReassignOrder(ctx, orders[1], agents[1], 3.5, 17.5, eta, "closer agent"); err != nil {
+		t.Fatalf("ReassignOrder: %v", err)
+	}
+	moved, err := s.GetOpenAssignment(ctx, orders[1])
+	if err != nil {
+		t.Fatalf("GetOpenAssignment: %v", err)
+	}
+	if moved.AgentID != agents[1] || moved.PlannedKm != 3.5 || moved.PlannedMinutes != 17.5 || moved.ETA == nil || !moved.ETA.Equal(eta) {
+		t.Errorf("reassigned = %+v, want agent %d with the new leg and ETA %v", moved, agents[1], eta)
+	}
+	if err := s.ReassignOrder(ctx, orders[1], agents[1], 3.5, 17.5, eta, "same agent"); !errors.Is(err, storage.ErrConflict) {
+		t.Errorf("reassigning to the same agent: got %v, want ErrConflict", err)
+	}
+	if err := s.ReassignOrder(ctx, orders[1], 9999, 3.5, 17.5, eta, "unknown agent"); !errors.Is(err, storage.ErrConflict) {
+		t.Errorf("reassigning to an unknown agent: got %v, want ErrConflict", err)
+	}
+	if err := s.ReassignOrder(ctx, orders[0], agents[1], 3.5, 17.5, eta, "pending"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("reassigning a pending order: got %v, want ErrNotFound", err)
+	}
+
+	// taken-back assignments no longer count towards the agent
+	details, err := s.GetAgentDetails(ctx, agents[0])
+	if err != nil {
+		t.Fatalf("GetAgentDetails: %v", err)
+	}
+	if toFloat(details["total_orders"]) != 0 {
+		t.Errorf("agent total_orders after taking both back = %v, want 0", details["total_orders"])
+	}
+
+	released, err := s.ReleaseAgentOrders(ctx, agents[1], "sick", true)
+	if err != nil {
+		t.Fatalf("ReleaseAgentOrders: %v", err)
+	}
+	if !slices.Equal(released, []int64{orders[1], orders[2]}) {
+		t.Errorf("released = %v, want %v", released, orders[1:])
+	}
+	if _, err := s.ReleaseAgentOrders(ctx, 9999, "", false); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("releasing an unknown agent: got %v, want ErrNotFound", err)
+	}
+
+	checkedIn, err := s.GetCheckedInAgents(ctx)
+	if err != nil {
+		t.Fatalf("GetCheckedInAgents: %v", err)
+	}
+	if len(checkedIn) != 1 || checkedIn[0].ID != agents[0] {
+		t.Errorf("checked-in agents after check-out = %+v", checkedIn)
+	}
+
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	if len(pending) != 3 {
+		t.Fatalf("got %d pending orders after release, want 3", len(pending))
+	}
+	if err := s.AssignOrderToAgent(ctx, orders[0], agents[0], 1, 5, time.Time{}); err != nil {
+		t.Errorf("assigning a released order again: %v", err)
+	}
+
+	history, total, err := s.GetPaginatedAssignments(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("GetPaginatedAssignments: %v", err)
+	}
+	if total != 5 {
+		t.Errorf("got %d assignments, want 5 with the history kept", total)
+	}
+	closed := 0
+	for _, a := range history {
+		if a.UnassignedAt == nil {
+			continue
+		}
+		closed++
+		if a.OrderID == orders[0] && a.UnassignReason != "customer rescheduled" {
+			t.Errorf("unassign reason = %q", a.UnassignReason)
+		}
+		if a.OrderID == orders[1] && a.AgentID == agents[1] && a.UnassignReason != "sick" {
+			t.Errorf("release reason = %q", a.UnassignReason)
+		}
+	}
+	if closed != 4 {
+		t.Errorf("got %d closed assignments, want 4", closed)
+	}
+}
+
+func testRouteInsertion(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh, agents, orders := seed(t, s)
+	extra := must(t)(s.CreateOrder(ctx, types.Order{Customer: "D", Lat: 12.975, Lng: 77.595, WarehouseID: wh}))
+
+	if err := s.AssignOrderToAgent(ctx, orders[0], agents[0], 2, 10, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	if err := s.AssignOrderToAgent(ctx, orders[1], agents[0], 3, 15, time.Time{}); err != nil {
+		t.Fatalf("AssignOrderToAgent: %v", err)
+	}
+	eta := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err := s.InsertOrderIntoRoute(ctx, orders[2], agents[0], orders[1], 0.5, 2.5, eta); err != nil {
+		t.Fatalf("InsertOrderIntoRoute: %v", err)
+	}
+	if err := s.InsertOrderIntoRoute(ctx, orders[2], agents[1], 0, 1, 5, eta); !errors.Is(err, storage.ErrConflict) {
+		t.Errorf("inserting a claimed order: got %v, want ErrConflict", err)
+	}
+
+	// orders[0] delivered, so it can no longer be inserted before
+	if err := s.CompleteDelivery(ctx, orders[0], 2, 10); err != nil {
+		t.Fatalf("CompleteDelivery: %v", err)
+	}
+	if err := s.InsertOrderIntoRoute(ctx, extra, agents[0], orders[0], 1, 5, eta); !errors.Is(err, storage.ErrConflict) {
+		t.Errorf("inserting before a delivered stop: got %v, want ErrConflict", err)
+	}
+	if err := s.InsertOrderIntoRoute(ctx, extra, agents[1], orders[1], 1, 5, eta); !errors.Is(err, storage.ErrConflict) {
+		t.Errorf("inserting before another agent's stop: got %v, want ErrConflict", err)
+	}
+	pending, err := s.GetUnassignedOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != extra {
+		t.Errorf("pending after refused insertions = %+v", pending)
+	}
+
+	stops, err := s.GetRoutes(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetRoutes: %v", err)
+	}
+	var got []int64
+	for _, st := range stops {
+		got = append(got, st.OrderID)
+		if st.AgentID != agents[0] {
+			t.Errorf("stop of agent %d, want %d", st.AgentID, agents[0])
+		}
+	}
+	if want := []int64{orders[0], orders[2], orders[1]}; !slices.Equal(got, want) {
+		t.Fatalf("route = %v, want %v", got, want)
+	}
+	if stops[0].DeliveredAt == nil || stops[1].DeliveredAt != nil {
+		t.Errorf("delivered flags = %v, %v", stops[0].DeliveredAt, stops[1].DeliveredAt)
+	}
+	if st := stops[1]; !near(st.PlannedKm, 0.5) || !near(st.PlannedMinutes, 2.5) || !near(st.Location.Lat, 13.00) {
+		t.Errorf("inserted stop = %+v", st)
+	}
+	if !(stops[0].RouteSeq < stops[1].RouteSeq && stops[1].RouteSeq < stops[2].RouteSeq) {
+		t.Errorf("route_seq = %d, %d, %d; want increasing", stops[0].RouteSeq, stops[1].RouteSeq, stops[2].RouteSeq)
+	}
+
+	// stops delivered before since are left out
+	stops, err = s.GetRoutes(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetRoutes: %v", err)
+	}
+	if len(stops) != 2 || stops[0].OrderID != orders[2] {
+		t.Errorf("open route = %+v", stops)
+	}
+
+	// inserting at the end appends to the route
+	if err := s.InsertOrderIntoRoute(ctx, extra, agents[0], 0, 1, 5, eta); err != nil {
+		t.Fatalf("InsertOrderIntoRoute at the end: %v", err)
+	}
+	all, _, err := s.GetPaginatedAssignments(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("GetPaginatedAssignments: %v", err)
+	}
+	seq := make(map[int64]int)
+	for _, a := range all {
+		seq[a.OrderID] = a.RouteSeq
+		if a.OrderID == orders[2] && (a.ETA == nil || !a.ETA.Equal(eta)) {
+			t.Errorf("inserted ETA = %v, want %v", a.ETA, eta)
+		}
+	}
+	if seq[extra] <= seq[orders[1]] {
+		t.Errorf("appended route_seq %d, last stop %d", seq[extra], seq[orders[1]])
+	}
+}
+
+func testAgentDetails(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	_, agents, orders := seed(t, s)
+
+	if _, err := s.GetAgentDetails(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("unknown agent: got %v, want ErrNotFound", err)
+	}
+
+	for _, o := range orders[:2] {
+		if err := s.AssignOrderToAgent(ctx, o, agents[0], 2, 10, time.Time{}); err != nil {
+			t.Fatalf("AssignOrderToAgent: %v", err)
+		}
+	}
+	if err := s.CompleteDelivery(ctx, orders[0], 3, 12); err != nil {
+		t.Fatalf("CompleteDelivery: %v", err)
+	}
+
+	d, err := s.GetAgentDetails(ctx, agents[0])
+	if err != nil {
+		t.Fatalf("GetAgentDetails: %v", err)
+	}
+	checks := map[string]float64{
+		"total_orders":     2,
+		"delivered_orders": 1,
+		"planned_km":       4,
+		"planned_minutes":  20,
+		"actual_km":        3,
+		"actual_minutes":   12,
+		"profit":           40,
+	}
+	for key, want := range checks {
+		if got := toFloat(d[key]); !near(got, want) {
+			t.Errorf("%s = %v, want %v", key, d[key], want)
+		}
+	}
+	if d["warehouse_name"] != "Hub" {
+		t.Errorf("warehouse_name = %v", d["warehouse_name"])
+	}
+
+	idle, err := s.GetAgentDetails(ctx, agents[1])
+	if err != nil {
+		t.Fatalf("GetAgentDetails for idle agent: %v", err)
+	}
+	if toFloat(idle["total_orders"]) != 0 {
+		t.Errorf("idle agent total_orders = %v", idle["total_orders"])
+	}
+}
+
+func testSummaries(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	_, agents, orders := seed(t, s)
+
+	if err := s.AssignOrderToAgent(ctx, orders[0], agents[0], 2, 10, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignOrderToAgent(ctx, orders[1], agents[1], 3, 15, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CompleteDelivery(ctx, orders[1], 2.5, 14); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := s.GetAgentSummaryPaginated(ctx, 2, 1)
+	if err != nil {
+		t.Fatalf("GetAgentSummaryPaginated: %v", err)
+	}
+	if page.CurrentPage != 2 || page.TotalPages != 2 || len(page.Data) != 1 {
+		t.Fatalf("page = %+v", page)
+	}
+	got := page.Data[0]
+	if got.AgentID != agents[1] || got.TotalOrders != 1 || got.DeliveredOrders != 1 ||
+		!near(got.PlannedKm, 3) || !near(got.TotalKm, 3) || !near(got.ActualKm, 2.5) ||
+		!near(got.ActualMinutes, 14) || !near(got.Profit, 20) {
+		t.Errorf("summary = %+v", got)
+	}
+
+	sys, err := s.GetSystemSummaryPaginated(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("GetSystemSummaryPaginated: %v", err)
+	}
+	if sys.TotalOrders != 3 || sys.AssignedOrders != 2 || sys.DeferredOrders != 1 || len(sys.AgentUtilization.Data) != 2 {
+		t.Errorf("system summary = %+v", sys)
+	}
+}
+
+func testWarehouseLoad(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	wh, agents, orders := seed(t, s)
+	empty := must(t)(s.CreateWarehouse(ctx, types.Warehouse{Name: "Empty", Location: types.Location{Lat: 2, Lng: 2}}))
+
+	if err := s.AssignOrderToAgent(ctx, orders[0], agents[0], 1, 5, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	loads, err := s.GetWarehouseLoad(ctx)
+	if err != nil {
+		t.Fatalf("GetWarehouseLoad: %v", err)
+	}
+	want := map[int64]types.WarehouseLoad{
+		wh:    {WarehouseID: wh, CheckedInAgents: 2, PendingOrders: 2},
+		empty: {WarehouseID: empty},
+	}
+	if len(loads) != len(want) {
+		t.Fatalf("loads = %+v", loads)
+	}
+	for _, l := range loads {
+		if l != want[l.WarehouseID] {
+			t.Errorf("load = %+v, want %+v", l, want[l.WarehouseID])
+		}
+	}
+}
+
+// testCanceled checks that a cancelled context aborts reads and writes.
+func testCanceled(t *testing.T, s storage.Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.GetWarehouses(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetWarehouses with cancelled context: got %v, want context.Canceled", err)
+	}
+	if _, err := s.CreateOrder(ctx, types.Order{Customer: "A", Lat: 1, Lng: 1}); !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateOrder with cancelled context: got %v, want context.Canceled", err)
+	}
+
+	pending, err := s.GetUnassignedOrders(context.Background())
+	if err != nil {
+		t.Fatalf("GetUnassignedOrders: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("cancelled CreateOrder stored %+v", pending)
+	}
+}
+
+// testConcurrentClaims races agents for the same order; exactly one claim may win.
+func testConcurrentClaims(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	_, agents, orders := seed(t, s)
+
+	if err := s.AssignOrderToAgent(ctx, 9999, agents[0], 1, 1, time.Time{}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("claiming an unknown order: got %v, want ErrNotFound", err)
+	}
+
+	const claimants = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, claimants)
+	for i := 0; i < claimants; i++ {
+		wg.Add(1)
+		go func(agentID int64) {
+			defer wg.Done()
+			errs <- s.AssignOrderToAgent(ctx, orders[0], agentID, 1, 5, time.Time{})
+		}(agents[i%len(agents)])
+	}
+	wg.Wait()
+	close(errs)
+
+	won := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, storage.ErrConflict):
+			t.Errorf("losing claim: got %v, want ErrConflict", err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d claims won, want 1", won)
+	}
+
+	all, total, err := s.GetPaginatedAssignments(ctx, 100, 0)
+	if err != nil {
+		t.Fatalf("GetPaginatedAssignments: %v", err)
+	}
+	if total != 1 || len(all) != 1 {
+		t.Errorf("got %d assignment rows, want 1: %+v", total, all)
+	}
+}
+
+func testLocks(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	ok, err := s.AcquireLock(ctx, "job", "a", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("first acquire = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := s.AcquireLock(ctx, "job", "b", time.Minute); err != nil || ok {
+		t.Errorf("acquire while held = %v, %v; want false, nil", ok, err)
+	}
+	if ok, err := s.AcquireLock(ctx, "other", "b", time.Minute); err != nil || !ok {
+		t.Errorf("acquire of a different lock = %v, %v; want true, nil", ok, err)
+	}
+
+	// releasing someone else's lock is a no-op
+	if err := s.ReleaseLock(ctx, "job", "b"); err != nil {
+		t.Fatalf("ReleaseLock: %v", err)
+	}
+	if ok, _ := s.AcquireLock(ctx, "job", "b", time.Minute); ok {
+		t.Errorf("lock was released by a non-owner")
+	}
+
+	if err := s.ReleaseLock(ctx, "job", "a"); err != nil {
+		t.Fatalf("ReleaseLock: %v", err)
+	}
+	if ok, err := s.AcquireLock(ctx, "job", "b", time.Minute); err != nil || !ok {
+		t.Errorf("acquire after release = %v, %v; want true, nil", ok, err)
+	}
+
+	if ok, err := s.RenewLock(ctx, "job", "b"); err != nil || !ok {
+		t.Errorf("renew by the owner = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := s.RenewLock(ctx, "job", "a"); err != nil || ok {
+		t.Errorf("renew by a former owner = %v, %v; want false, nil", ok, err)
+	}
+
+	// a negative ttl treats the held lock as stale, as after a crash
+	if ok, err := s.AcquireLock(ctx, "job", "c", -time.Second); err != nil || !ok {
+		t.Errorf("acquire over stale lock = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := s.RenewLock(ctx, "job", "b"); err != nil || ok {
+		t.Errorf("renew of a broken lock = %v, %v; want false, nil", ok, err)
+	}
+	if ok, err := s.RenewLock(ctx, "missing", "b"); err != nil || ok {
+		t.Errorf("renew of an unknown lock = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func testJobStates(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	next := time.Date(2030, 1, 2, 7, 0, 0, 0, time.UTC)
+	started := time.Date(2030, 1, 1, 7, 0, 0, 0, time.UTC)
+
+	if err := s.ScheduleJob(ctx, "report", next); err != nil {
+		t.Fatalf("ScheduleJob: %v", err)
+	}
+	if err := s.RecordJobRun(ctx, "allocation", types.JobRun{StartedAt: started, Duration: 1500 * time.Millisecond, Status: "ok", Result: "3 assigned"}); err != nil {
+		t.Fatalf("RecordJobRun: %v", err)
+	}
+	if err := s.ScheduleJob(ctx, "allocation", next); err != nil {
+		t.Fatalf("ScheduleJob: %v", err)
+	}
+
+	states, err := s.GetJobStates(ctx)
+	if err != nil {
+		t.Fatalf("GetJobStates: %v", err)
+	}
+	if len(states) != 2 || states[0].Name != "allocation" || states[1].Name != "report" {
+		t.Fatalf("states = %+v", states)
+	}
+
+	alloc := states[0]
+	if alloc.NextRunAt == nil || !alloc.NextRunAt.Equal(next) {
+		t.Errorf("next run = %v, want %v", alloc.NextRunAt, next)
+	}
+	if alloc.LastRunAt == nil || !alloc.LastRunAt.Equal(started) || alloc.LastDurationMs != 1500 ||
+		alloc.LastStatus != "ok" || alloc.LastResult != "3 assigned" {
+		t.Errorf("recorded run = %+v", alloc)
+	}
+	if states[1].LastRunAt != nil {
+		t.Errorf("report has not run yet: %+v", states[1])
+	}
+}
+
+func testOverdueAndRetention(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	_, agents, orders := seed(t, s)
+
+	for i, o := range orders[:2] {
+		if err := s.AssignOrderToAgent(ctx, o, agents[i], 2, 10, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CompleteDelivery(ctx, orders[0], 2.5, 12); err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(time.Minute)
+	overdue, err := s.EscalateOverdueAssignments(ctx, later)
+	if err != nil {
+		t.Fatalf("EscalateOverdueAssignments: %v", err)
+	}
+	if len(overdue) != 1 || overdue[0].OrderID != orders[1] {
+		t.Errorf("overdue = %+v, want the undelivered order %d", overdue, orders[1])
+	}
+	if again, _ := s.EscalateOverdueAssignments(ctx, later); len(again) != 0 {
+		t.Errorf("escalated twice: %+v", again)
+	}
+
+	report, err := s.GetDailyReport(ctx, time.Now().Add(-time.Hour), later)
+	if err != nil {
+		t.Fatalf("GetDailyReport: %v", err)
+	}
+	if report.OrdersAssigned != 2 || report.OrdersDelivered != 1 || report.ActiveAgents != 2 ||
+		!near(report.PlannedKm, 4) || !near(report.ActualKm, 2.5) {
+		t.Errorf("report = %+v", report)
+	}
+
+	n, keys, err := s.PurgeDeliveredBefore(ctx, later)
+	if err != nil {
+		t.Fatalf("PurgeDeliveredBefore: %v", err)
+	}
+	if n != 1 || len(keys) != 0 {
+		t.Errorf("purged %d assignments and files %v, want 1 and none", n, keys)
+	}
+	sys, err := s.GetSystemSummaryPaginated(ctx, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sys.TotalOrders != 2 || sys.AssignedOrders != 1 {
+		t.Errorf("after purge: %+v", sys)
+	}
+
+	// new rows must not reuse purged ids
+	id := must(t)(s.CreateOrder(ctx, types.Order{Customer: "D", Lat: 1, Lng: 1}))
+	if id == orders[0] {
+		t.Errorf("order id %d reused after purge", id)
+	}
+}
+
+// testConcurrentAllocation starts overlapping allocation runs. Every order must end up
+// with at most one assignment, and runs that found the lock taken must say so.
+func testConcurrentAllocation(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	_, _, orders := seed(t, s)
+	limits := Config().Variables.Delivery
+
+	const runs = 6
+	var wg sync.WaitGroup
+	results := make(chan types.AllocationResult, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := jobs.Allocate(ctx, s, limits)
+			if errors.Is(err, jobs.ErrAllocationInProgress) {
+				return
+			}
+			if err != nil {
+				t.Errorf("Allocate: %v", err)
+				return
+			}
+			results <- res
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	assigned := 0
+	for res := range results {
+		assigned += res.Assigned
+	}
+
+	all, total, err := s.GetPaginatedAssignments(ctx, 100, 0)
+	if err != nil {
+		t.Fatalf("GetPaginatedAssignments: %v", err)
+	}
+	if total != assigned || total != len(orders) {
+		t.Errorf("got %d assignment rows, runs reported %d, want %d", total, assigned, len(orders))
+	}
+	seen := make(map[int64]bool)
+	for _, a := range all {
+		if seen[a.OrderID] {
+			t.Errorf("order %d assigned twice", a.OrderID)
+		}
+		seen[a.OrderID] = true
+	}
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return math.NaN()
+	}
+}
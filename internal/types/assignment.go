@@ -0,0 +1,6 @@
+package types
+
+// Open reports whether the assignment is neither delivered nor taken back from its agent.
+func (a Assignment) Open() bool {
+	return a.DeliveredAt == nil && a.UnassignedAt == nil
+}
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"sort"
+	"time"
+)
+
+// Calendar defaults for warehouses created without one: UTC, open all day.
+const (
+	DefaultTimezone = "UTC"
+	DefaultOpensAt  = "00:00"
+	DefaultClosesAt = "23:59"
+)
+
+// WithDefaults fills empty fields with the defaults and sorts and dedupes the holidays.
+func (c WarehouseCalendar) WithDefaults() WarehouseCalendar {
+	if c.Timezone == "" {
+		c.Timezone = DefaultTimezone
+	}
+	if c.OpensAt == "" {
+		c.OpensAt = DefaultOpensAt
+	}
+	if c.ClosesAt == "" {
+		c.ClosesAt = DefaultClosesAt
+	}
+
+	days := make([]string, 0, len(c.Holidays))
+	seen := make(map[string]bool, len(c.Holidays))
+	for _, d := range c.Holidays {
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Strings(days)
+	c.Holidays = days
+	return c
+}
+
+// Zone returns the warehouse's timezone, UTC when it is empty or unknown.
+func (c WarehouseCalendar) Zone() *time.Location {
+	if c.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsHoliday reports whether t falls on one of the holidays, in the warehouse's timezone.
+func (c WarehouseCalendar) IsHoliday(t time.Time) bool {
+	day := t.In(c.Zone()).Format("2006-01-02")
+	for _, d := range c.Holidays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// AllDay reports whether the warehouse keeps no operating hours, being open 00:00-23:59.
+func (c WarehouseCalendar) AllDay() bool {
+	c = c.WithDefaults()
+	return c.OpensAt == DefaultOpensAt && c.ClosesAt == DefaultClosesAt
+}
+
+// IsOpen reports whether the warehouse is within its operating hours at t and t is not a holiday.
+func (c WarehouseCalendar) IsOpen(t time.Time) bool {
+	c = c.WithDefaults()
+	if c.IsHoliday(t) {
+		return false
+	}
+	hm := t.In(c.Zone()).Format("15:04")
+	return hm >= c.OpensAt && hm <= c.ClosesAt
+}
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithDefaults(t *testing.T) {
+	got := WarehouseCalendar{Holidays: []string{"2024-12-25", "2024-01-26", "2024-12-25"}}.WithDefaults()
+	want := WarehouseCalendar{Timezone: "UTC", OpensAt: "00:00", ClosesAt: "23:59", Holidays: []string{"2024-01-26", "2024-12-25"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WithDefaults = %+v, want %+v", got, want)
+	}
+
+	// what is set is kept
+	set := WarehouseCalendar{Timezone: "Asia/Kolkata", OpensAt: "08:00", ClosesAt: "20:00", Holidays: []string{}}
+	if got := set.WithDefaults(); !reflect.DeepEqual(got, set) {
+		t.Errorf("WithDefaults = %+v, want %+v", got, set)
+	}
+}
+
+func TestZone(t *testing.T) {
+	for tz, want := range map[string]string{
+		"":              "UTC",
+		"Asia/Kolkata":  "Asia/Kolkata",
+		"Mars/Olympus":  "UTC",
+		"Europe/London": "Europe/London",
+	} {
+		if got := (WarehouseCalendar{Timezone: tz}).Zone().String(); got != want {
+			t.Errorf("Zone(%q) = %s, want %s", tz, got, want)
+		}
+	}
+}
+
+func TestAllDay(t *testing.T) {
+	tests := []struct {
+		opens, closes string
+		want          bool
+	}{
+		{"", "", true},
+		{"00:00", "23:59", true},
+		{"00:00", "", true},
+		{"08:00", "", false},
+		{"", "20:00", false},
+		{"08:00", "20:00", false},
+	}
+	for _, tt := range tests {
+		c := WarehouseCalendar{Timezone: "Asia/Kolkata", OpensAt: tt.opens, ClosesAt: tt.closes}
+		if got := c.AllDay(); got != tt.want {
+			t.Errorf("AllDay(%q-%q) = %v, want %v", tt.opens, tt.closes, got, tt.want)
+		}
+	}
+}
+
+func TestIsHoliday(t *testing.T) {
+	holidays := []string{"2024-12-25"}
+	tests := []struct {
+		name string
+		tz   string
+		at   time.Time
+		want bool
+	}{
+		{"on the day in UTC", "", time.Date(2024, 12, 25, 12, 0, 0, 0, time.UTC), true},
+		{"the day before in UTC", "", time.Date(2024, 12, 24, 23, 59, 0, 0, time.UTC), false},
+		// 20:00 UTC on the 24th is already the 25th in Kolkata
+		{"ahead of UTC", "Asia/Kolkata", time.Date(2024, 12, 24, 20, 0, 0, 0, time.UTC), true},
+		{"ahead of UTC, day after", "Asia/Kolkata", time.Date(2024, 12, 25, 19, 0, 0, 0, time.UTC), false},
+		// 02:00 UTC on the 26th is still the 25th in New York
+		{"behind UTC", "America/New_York", time.Date(2024, 12, 26, 2, 0, 0, 0, time.UTC), true},
+		{"behind UTC, day before", "America/New_York", time.Date(2024, 12, 25, 3, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		c := WarehouseCalendar{Timezone: tt.tz, Holidays: holidays}
+		if got := c.IsHoliday(tt.at); got != tt.want {
+			t.Errorf("%s: IsHoliday(%v) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	kolkata := WarehouseCalendar{Timezone: "Asia/Kolkata", OpensAt: "08:00", ClosesAt: "20:00", Holidays: []string{"2024-08-15"}}
+	london := WarehouseCalendar{Timezone: "Europe/London", OpensAt: "09:00", ClosesAt: "17:30"}
+	allDay := WarehouseCalendar{Holidays: []string{"2024-01-01"}}
+
+	tests := []struct {
+		name string
+		cal  WarehouseCalendar
+		at   time.Time
+		want bool
+	}{
+		// Kolkata is UTC+05:30
+		{"kolkata before opening", kolkata, time.Date(2024, 8, 14, 2, 29, 0, 0, time.UTC), false},
+		{"kolkata at opening", kolkata, time.Date(2024, 8, 14, 2, 30, 0, 0, time.UTC), true},
+		{"kolkata in the closing minute", kolkata, time.Date(2024, 8, 14, 14, 30, 59, 0, time.UTC), true},
+		{"kolkata after closing", kolkata, time.Date(2024, 8, 14, 14, 31, 0, 0, time.UTC), false},
+		{"kolkata holiday in hours", kolkata, time.Date(2024, 8, 15, 6, 0, 0, 0, time.UTC), false},
+		// 20:00 UTC on the 14th is 01:30 on the holiday, closed either way
+		{"kolkata holiday by local date", kolkata, time.Date(2024, 8, 14, 20, 0, 0, 0, time.UTC), false},
+		{"kolkata given in another zone", kolkata, time.Date(2024, 8, 14, 4, 0, 0, 0, time.FixedZone("EDT", -4*3600)), true},
+		// London is UTC+1 in summer and UTC in winter
+		{"london summer opening", london, time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC), true},
+		{"london summer before opening", london, time.Date(2024, 7, 1, 7, 59, 0, 0, time.UTC), false},
+		{"london winter before opening", london, time.Date(2024, 1, 10, 8, 30, 0, 0, time.UTC), false},
+		{"london winter opening", london, time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC), true},
+		{"london after closing", london, time.Date(2024, 1, 10, 17, 31, 0, 0, time.UTC), false},
+		{"all day at midnight", allDay, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"all day at the last minute", allDay, time.Date(2024, 3, 1, 23, 59, 59, 0, time.UTC), true},
+		{"all day on a holiday", allDay, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		if got := tt.cal.IsOpen(tt.at); got != tt.want {
+			t.Errorf("%s: IsOpen(%v) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package types
+
+// WithDefaults counts an order given without a parcel count as one parcel.
+func (l Load) WithDefaults() Load {
+	if l.Parcels == 0 {
+		l.Parcels = 1
+	}
+	return l
+}
+
+// Add returns the combined load of l and o.
+func (l Load) Add(o Load) Load {
+	return Load{
+		WeightKg: l.WeightKg + o.WeightKg,
+		VolumeL:  l.VolumeL + o.VolumeL,
+		Parcels:  l.Parcels + o.Parcels,
+	}
+}
+
+// Fits reports whether the vehicle carries l without exceeding any of its limits.
+func (c Capacity) Fits(l Load) bool {
+	return (c.MaxWeightKg == 0 || l.WeightKg <= c.MaxWeightKg) &&
+		(c.MaxVolumeL == 0 || l.VolumeL <= c.MaxVolumeL) &&
+		(c.MaxParcels == 0 || l.Parcels <= c.MaxParcels)
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// NewCustomerOrder gives an order its status: delivered once deliveredAt is set, returning
+// or returned once it goes back to its warehouse, else assigned or pending.
+func NewCustomerOrder(o Order, deliveredAt *time.Time) CustomerOrder {
+	co := CustomerOrder{Order: o, Status: OrderPending, DeliveredAt: deliveredAt}
+	switch {
+	case deliveredAt != nil:
+		co.Status = OrderDelivered
+	case o.ReturnedAt != nil:
+		co.Status = OrderReturned
+	case o.ReturnStartedAt != nil:
+		co.Status = OrderReturning
+	case o.Assigned:
+		co.Status = OrderAssigned
+	}
+	return co
+}
+
+// Normalized returns the request with names trimmed, the phone number without spaces,
+// dashes, dots or brackets, and its addresses normalized.
+func (r CustomerRequest) Normalized() CustomerRequest {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Email = strings.TrimSpace(r.Email)
+	r.Phone = strings.Map(func(c rune) rune {
+		if strings.ContainsRune(" -.()", c) {
+			return -1
+		}
+		return c
+	}, r.Phone)
+	addrs := make([]CustomerAddressRequest, len(r.Addresses))
+	for i, a := range r.Addresses {
+		addrs[i] = a.Normalized()
+	}
+	r.Addresses = addrs
+	return r
+}
+
+// Normalized returns the request with its coordinates rounded and its address trimmed.
+func (r CustomerAddressRequest) Normalized() CustomerAddressRequest {
+	r.Label = strings.TrimSpace(r.Label)
+	r.Address = r.Address.Normalized()
+	r.Lat, r.Lng = RoundCoordinate(r.Lat), RoundCoordinate(r.Lng)
+	return r
+}
@@ -0,0 +1,44 @@
+package types
+
+import (
+	"math"
+	"strings"
+)
+
+// CoordinateDecimals is the precision coordinates are kept at; 6 decimals is about 0.1 m.
+const CoordinateDecimals = 6
+
+// RoundCoordinate rounds a latitude or longitude to CoordinateDecimals.
+func RoundCoordinate(v float64) float64 {
+	p := math.Pow10(CoordinateDecimals)
+	r := math.Round(v*p) / p
+	if r == 0 {
+		return 0 // rather than -0
+	}
+	return r
+}
+
+// Normalized returns l with both coordinates rounded.
+func (l Location) Normalized() Location {
+	return Location{Lat: RoundCoordinate(l.Lat), Lng: RoundCoordinate(l.Lng)}
+}
+
+// Normalized returns the request with its coordinates rounded and its address trimmed.
+func (r OrderRequest) Normalized() OrderRequest {
+	r.Lat, r.Lng = RoundCoordinate(r.Lat), RoundCoordinate(r.Lng)
+	if r.Address != nil {
+		a := r.Address.Normalized()
+		r.Address = &a
+	}
+	return r
+}
+
+// Normalized returns a with its line and city trimmed and spaces taken out of the pincode,
+// so "560 034" matches 560034.
+func (a Address) Normalized() Address {
+	return Address{
+		Line:    strings.TrimSpace(a.Line),
+		City:    strings.TrimSpace(a.City),
+		Pincode: strings.ReplaceAll(a.Pincode, " ", ""),
+	}
+}
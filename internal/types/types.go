@@ -13,8 +13,8 @@ type User struct {
 
 // Location used in Warehouse and geo fields
 type Location struct {
-	Lat float64 `json:"lat" validate:"required"`
-	Lng float64 `json:"lng" validate:"required"`
+	Lat float64 `json:"lat" validate:"latitude" example:"12.9716"`
+	Lng float64 `json:"lng" validate:"longitude" example:"77.5946"`
 }
 
 // Agent model
@@ -23,11 +23,29 @@ type Agent struct {
 	Name        string `json:"name" validate:"required"`
 	WarehouseID int64  `json:"warehouse_id" validate:"required"`
 	CheckedIn   bool   `json:"checked_in,omitempty"` 
+	Capacity
 }
 
 type AgentCheckInRequest struct {
 	Name        string `json:"name" validate:"required"`
 	WarehouseID int64  `json:"warehouse_id" validate:"required"`
+	Capacity
+}
+
+// Capacity model for what an agent's vehicle carries. A zero limit leaves that
+// dimension unconstrained.
+type Capacity struct {
+	Vehicle     string  `json:"vehicle,omitempty" example:"bike"`
+	MaxWeightKg float64 `json:"max_weight_kg,omitempty" validate:"gte=0" example:"15"`
+	MaxVolumeL  float64 `json:"max_volume_l,omitempty" validate:"gte=0" example:"40"`
+	MaxParcels  int     `json:"max_parcels,omitempty" validate:"gte=0" example:"10"`
+}
+
+// Load model for what an order puts on a vehicle.
+type Load struct {
+	WeightKg float64 `json:"weight_kg" validate:"gte=0" example:"2.5"`
+	VolumeL  float64 `json:"volume_l" validate:"gte=0" example:"6"`
+	Parcels  int     `json:"parcels" validate:"gte=0" example:"1"`
 }
 
 // Warehouse model
@@ -35,60 +53,374 @@ type Warehouse struct {
 	ID       int64    `json:"id"`
 	Name     string   `json:"name" validate:"required"`
 	Location Location `json:"location" validate:"required"` 
+	WarehouseCalendar
+}
+
+// WarehouseCalendar model for a warehouse's local time. Hours are HH:MM and holidays
+// YYYY-MM-DD, both in Timezone; the warehouse is open from OpensAt through the ClosesAt minute.
+type WarehouseCalendar struct {
+	Timezone string   `json:"timezone" validate:"omitempty,timezone" example:"Asia/Kolkata"`
+	OpensAt  string   `json:"opens_at" validate:"omitempty,datetime=15:04" example:"08:00"`
+	ClosesAt string   `json:"closes_at" validate:"omitempty,datetime=15:04" example:"20:00"`
+	Holidays []string `json:"holidays" validate:"omitempty,dive,datetime=2006-01-02" example:"2024-12-25"`
+}
+
+// WarehouseLoad model for per-warehouse agent and backlog counts
+type WarehouseLoad struct {
+	WarehouseID     int64 `json:"warehouse_id"`
+	CheckedInAgents int   `json:"checked_in_agents"`
+	PendingOrders   int   `json:"pending_orders"`
 }
 
 type WarehouseRequest struct {
 	Name     string   `json:"name" validate:"required"`
 	Location Location `json:"location" validate:"required"`
+	WarehouseCalendar
 }
 
 // Order model
 type Order struct {
 	ID          int64   `json:"id"`
 	Customer    string  `json:"customer" validate:"required"`
-	Lat         float64 `json:"lat" validate:"required"`
-	Lng         float64 `json:"lng" validate:"required"`
+	Lat         float64 `json:"lat" validate:"latitude"`
+	Lng         float64 `json:"lng" validate:"longitude"`
 	WarehouseID int64   `json:"warehouse_id" validate:"required"`
-	Assigned    bool    `json:"assigned"`              
-	AgentID     *int64  `json:"agent_id,omitempty"`    
+	Assigned    bool    `json:"assigned"`
+	AgentID     *int64  `json:"agent_id,omitempty"`
+	// Priority ranks orders with the same deadline, higher first.
+	Priority    int        `json:"priority"`
+	WindowStart *time.Time `json:"window_start,omitempty"`
+	WindowEnd   *time.Time `json:"window_end,omitempty"`
+	Load
+	// OutOfZone marks an order taken although no service zone of its warehouse contains it.
+	OutOfZone bool `json:"out_of_zone,omitempty"`
+	// CustomerID and AddressID are set for orders placed for a saved customer and address.
+	CustomerID int64 `json:"customer_id,omitempty"`
+	AddressID  int64 `json:"address_id,omitempty"`
+	// FailedAttempts counts the deliveries that failed. Once too many have, the order goes
+	// back to its warehouse: ReturnStartedAt is set, and ReturnedAt when it is back.
+	FailedAttempts  int        `json:"failed_attempts,omitempty"`
+	ReturnStartedAt *time.Time `json:"return_started_at,omitempty"`
+	ReturnedAt      *time.Time `json:"returned_at,omitempty"`
 }
 
 //OrderRequest model for taking request..
+// The delivery window is optional; either end may be given alone. Without warehouse_id the
+// order goes to the warehouse whose service zone contains it. An address may be given
+// instead of lat and lng; it is geocoded, or queued for review when it cannot be.
+// A saved customer_id stands in for customer, and a saved address_id for the location.
 type OrderRequest struct {
-	Customer    string  `json:"customer" validate:"required"`
-	Lat         float64 `json:"lat" validate:"required"`
-	Lng         float64 `json:"lng" validate:"required"`
-	WarehouseID int64   `json:"warehouse_id" validate:"required"`
+	Customer    string     `json:"customer" validate:"required_without_all=CustomerID AddressID"`
+	Lat         float64    `json:"lat" validate:"latitude" example:"12.9721"`
+	Lng         float64    `json:"lng" validate:"longitude" example:"77.5940"`
+	Address     *Address   `json:"address,omitempty"`
+	CustomerID  int64      `json:"customer_id,omitempty" validate:"gte=0"`
+	AddressID   int64      `json:"address_id,omitempty" validate:"gte=0"`
+	WarehouseID int64      `json:"warehouse_id,omitempty" validate:"gte=0"`
+	Priority    int        `json:"priority" validate:"gte=0,lte=9"`
+	WindowStart *time.Time `json:"window_start,omitempty" example:"2024-05-01T10:00:00+05:30"`
+	WindowEnd   *time.Time `json:"window_end,omitempty" example:"2024-05-01T12:00:00+05:30"`
+	// Parcels defaults to 1.
+	Load
 }
 
 
 //BulkOrderRequest model for taking more request at a time..
 type BulkOrderRequest struct {
-	Orders []OrderRequest `json:"orders"`
+	Orders []OrderRequest `json:"orders" validate:"required,min=1,dive"`
+}
+
+// Address model for where a merchant wants an order delivered. A pincode or a city is
+// needed; the line is searched for a known locality.
+type Address struct {
+	Line    string `json:"line" validate:"max=200" example:"80 Feet Road, Koramangala 4th Block"`
+	City    string `json:"city" validate:"required_without=Pincode,max=100" example:"Bengaluru"`
+	Pincode string `json:"pincode" validate:"omitempty,alphanum,max=10" example:"560034"`
+}
+
+// Customer model for someone orders are delivered to, with their saved addresses.
+// NotificationsOptOut stops the messages about their orders.
+type Customer struct {
+	ID                  int64             `json:"id"`
+	Name                string            `json:"name"`
+	Phone               string            `json:"phone"`
+	Email               string            `json:"email,omitempty"`
+	NotificationsOptOut bool              `json:"notifications_opt_out"`
+	Addresses           []CustomerAddress `json:"addresses"`
+	CreatedAt           time.Time         `json:"created_at"`
+}
+
+// CustomerAddress model for a saved delivery address. Verified is set once an order has
+// been delivered there, so its coordinates are known to be right.
+type CustomerAddress struct {
+	ID         int64  `json:"id"`
+	CustomerID int64  `json:"customer_id"`
+	Label      string `json:"label,omitempty" example:"home"`
+	Address
+	Location  Location  `json:"location"`
+	Verified  bool      `json:"verified"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CustomerRequest model for creating a customer. The phone number identifies them and may
+// be saved only once.
+type CustomerRequest struct {
+	Name      string                   `json:"name" validate:"required,max=100" example:"Asha Rao"`
+	Phone     string                   `json:"phone" validate:"required,min=7,max=20" example:"+919876543210"`
+	Email     string                   `json:"email,omitempty" validate:"omitempty,email"`
+	Addresses []CustomerAddressRequest `json:"addresses" validate:"max=20,dive"`
+	// NotificationsOptOut stops messages by email and SMS about the customer's orders.
+	NotificationsOptOut bool `json:"notifications_opt_out"`
+}
+
+// NotificationPreferences model for turning a customer's notifications off or back on.
+type NotificationPreferences struct {
+	OptOut *bool `json:"opt_out" validate:"required" example:"true"`
+}
+
+// CustomerAddressRequest model for saving an address. Without lat and lng it is geocoded.
+type CustomerAddressRequest struct {
+	Label string `json:"label" validate:"max=50" example:"home"`
+	Address
+	Lat float64 `json:"lat" validate:"latitude" example:"12.9352"`
+	Lng float64 `json:"lng" validate:"longitude" example:"77.6245"`
+}
+
+// CustomerOrder model for an order in a customer's history. Status is pending, assigned,
+// delivered, returning or returned.
+type CustomerOrder struct {
+	Order
+	Status      string     `json:"status" example:"delivered"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Customer order statuses.
+const (
+	OrderPending   = "pending"
+	OrderAssigned  = "assigned"
+	OrderDelivered = "delivered"
+	OrderReturning = "returning"
+	OrderReturned  = "returned"
+)
+
+// Notification events: the status changes a customer is told about.
+const (
+	EventAssigned      = "assigned"
+	EventDelivered     = "delivered"
+	EventAttemptFailed = "attempt_failed"
+	EventReturning     = "returning"
+	EventUnassigned    = "unassigned"
+)
+
+// Notification channels.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Notification statuses. Pending ones are sent, and retried after a failure, until they
+// are sent or failed; skipped ones had no adapter configured for their channel.
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+	NotificationSkipped = "skipped"
+)
+
+// Notification model for a message to a customer in the outbox, with what its template
+// needs as it was when the order changed: the agent, the ETA and, for an assignment, the OTP.
+type Notification struct {
+	ID            int64      `json:"id"`
+	OrderID       int64      `json:"order_id"`
+	CustomerID    int64      `json:"customer_id"`
+	Event         string     `json:"event" example:"assigned"`
+	Channel       string     `json:"channel" example:"sms"`
+	Recipient     string     `json:"recipient" example:"+919876543210"`
+	CustomerName  string     `json:"customer_name"`
+	AgentName     string     `json:"agent_name,omitempty"`
+	ETA           *time.Time `json:"eta,omitempty"`
+	OTP           string     `json:"-"`
+	Status        string     `json:"status" example:"sent"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// AddressReview model for an order whose address could not be geocoded. It waits until
+// someone gives its coordinates, which creates the order, or discards it.
+type AddressReview struct {
+	ID        int64        `json:"id"`
+	Request   OrderRequest `json:"request"`
+	Reason    string       `json:"reason" example:"address not found"`
+	CreatedAt time.Time    `json:"created_at"`
 }
 
 // Assignment model..
+// Planned figures are written by the allocator, actual figures when the delivery is completed.
 type Assignment struct {
-	ID         int64     `json:"id"`
-	AgentID    int64     `json:"agent_id"`
-	OrderID    int64     `json:"order_id"`
-	AssignedAt time.Time `json:"assigned_at"`
+	ID             int64      `json:"id"`
+	AgentID        int64      `json:"agent_id"`
+	OrderID        int64      `json:"order_id"`
+	WarehouseID    int64      `json:"warehouse_id"`
+	AssignedAt     time.Time  `json:"assigned_at"`
+	PlannedKm      float64    `json:"planned_km"`
+	PlannedMinutes float64    `json:"planned_minutes"`
+	ActualKm       *float64   `json:"actual_km,omitempty"`
+	ActualMinutes  *float64   `json:"actual_minutes,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	// ETA is when the allocator planned to reach the order.
+	ETA *time.Time `json:"eta,omitempty"`
+	// UnassignedAt is set when the order was taken back from the agent, for UnassignReason.
+	UnassignedAt   *time.Time `json:"unassigned_at,omitempty"`
+	UnassignReason string     `json:"unassign_reason,omitempty"`
+	// RouteSeq orders the agent's stops; it only grows along a route.
+	RouteSeq int `json:"route_seq"`
+	// OTP is the code the customer hands the agent at delivery, issued with the assignment;
+	// OTPFailures counts wrong tries. Neither is shown with the assignment.
+	OTP         string `json:"-"`
+	OTPFailures int    `json:"-"`
+}
+
+// RouteStop model for an order on an agent's route, with what the allocator needs to plan
+// around it.
+type RouteStop struct {
+	AgentID        int64      `json:"agent_id"`
+	OrderID        int64      `json:"order_id"`
+	RouteSeq       int        `json:"route_seq"`
+	Location       Location   `json:"location"`
+	WindowStart    *time.Time `json:"window_start,omitempty"`
+	WindowEnd      *time.Time `json:"window_end,omitempty"`
+	PlannedKm      float64    `json:"planned_km"`
+	PlannedMinutes float64    `json:"planned_minutes"`
+	AssignedAt     time.Time  `json:"assigned_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	Load
 }
 
 type AssignmentResponse struct {
-	ID         int64  `json:"id"`
-	AgentID    int64  `json:"agent_id"`
-	OrderID    int64  `json:"order_id"`
-	AssignedAt string `json:"assigned_at"` 
+	ID             int64    `json:"id"`
+	AgentID        int64    `json:"agent_id"`
+	OrderID        int64    `json:"order_id"`
+	WarehouseID    int64    `json:"warehouse_id"`
+	Timezone       string   `json:"timezone"`
+	AssignedAt     string   `json:"assigned_at"`
+	PlannedKm      float64  `json:"planned_km"`
+	PlannedMinutes float64  `json:"planned_minutes"`
+	ActualKm       *float64 `json:"actual_km,omitempty"`
+	ActualMinutes  *float64 `json:"actual_minutes,omitempty"`
+	DeliveredAt    string   `json:"delivered_at,omitempty"`
+	ETA            string   `json:"eta,omitempty"`
+	UnassignedAt   string   `json:"unassigned_at,omitempty"`
+	UnassignReason string   `json:"unassign_reason,omitempty"`
+	RouteSeq       int      `json:"route_seq"`
+}
+
+// UnassignRequest model for taking an order back from its agent. The reason is kept on the
+// closed assignment.
+type UnassignRequest struct {
+	Reason string `json:"reason" validate:"max=200" example:"customer rescheduled"`
+}
+
+// ReassignRequest model for moving an assigned order to another checked-in agent.
+type ReassignRequest struct {
+	AgentID int64  `json:"agent_id" validate:"required"`
+	Reason  string `json:"reason" validate:"max=200" example:"closer agent available"`
+}
+
+// ReleaseRequest model for handing back every open order of an agent, e.g. one who goes
+// home sick. CheckOut also stops the agent getting new orders.
+type ReleaseRequest struct {
+	Reason   string `json:"reason" validate:"max=200" example:"sick"`
+	CheckOut bool   `json:"check_out"`
+}
+
+// ReleaseResult model for the orders handed back by a release.
+type ReleaseResult struct {
+	AgentID    int64   `json:"agent_id"`
+	Released   []int64 `json:"released"`
+	CheckedOut bool    `json:"checked_out"`
+}
+
+// Reasons a delivery attempt failed.
+const (
+	FailCustomerUnavailable = "customer_unavailable"
+	FailAddressNotFound     = "address_not_found"
+	FailRefused             = "refused"
+	FailAccessRestricted    = "access_restricted"
+	FailOther               = "other"
+)
+
+// FailedAttemptRequest model for recording that an order could not be delivered. Reason
+// other needs a note.
+type FailedAttemptRequest struct {
+	Reason string `json:"reason" validate:"required,oneof=customer_unavailable address_not_found refused access_restricted other" example:"customer_unavailable"`
+	Note   string `json:"note" validate:"required_if=Reason other,max=500" example:"no answer at the door or on the phone"`
+}
+
+// DeliveryAttempt model for a failed delivery of an order; Attempt numbers them from 1.
+type DeliveryAttempt struct {
+	ID           int64     `json:"id"`
+	OrderID      int64     `json:"order_id"`
+	AssignmentID int64     `json:"assignment_id"`
+	AgentID      int64     `json:"agent_id"`
+	Attempt      int       `json:"attempt"`
+	Reason       string    `json:"reason" example:"customer_unavailable"`
+	Note         string    `json:"note,omitempty"`
+	AttemptedAt  time.Time `json:"attempted_at"`
+}
+
+// ProofRequest model for the form fields of a proof of delivery: the GPS fix taken at the
+// door, the customer's OTP and the distance and time actually travelled.
+type ProofRequest struct {
+	Lat           float64 `json:"lat" validate:"latitude"`
+	Lng           float64 `json:"lng" validate:"longitude"`
+	AccuracyM     float64 `json:"accuracy_m" validate:"gte=0"`
+	OTP           string  `json:"otp" validate:"omitempty,numeric,max=10"`
+	Note          string  `json:"note" validate:"max=500"`
+	ActualKm      float64 `json:"actual_km" validate:"gte=0"`
+	ActualMinutes float64 `json:"actual_minutes" validate:"gte=0"`
+}
+
+// DeliveryProof model for what was captured when an order was handed over, kept for disputes.
+// DistanceM is how far the GPS fix was from the order's location.
+type DeliveryProof struct {
+	OrderID      int64          `json:"order_id"`
+	AssignmentID int64          `json:"assignment_id"`
+	AgentID      int64          `json:"agent_id"`
+	Location     Location       `json:"location"`
+	AccuracyM    float64        `json:"accuracy_m,omitempty"`
+	DistanceM    float64        `json:"distance_m"`
+	OTPVerified  bool           `json:"otp_verified"`
+	Note         string         `json:"note,omitempty"`
+	Photo        *ProofArtefact `json:"photo,omitempty"`
+	Signature    *ProofArtefact `json:"signature,omitempty"`
+	DeliveredAt  time.Time      `json:"delivered_at"`
+}
+
+// ProofArtefact model for a photo or signature kept with a delivery proof. Key locates the
+// file in the blob store.
+type ProofArtefact struct {
+	Key         string `json:"-"`
+	ContentType string `json:"content_type" example:"image/jpeg"`
+	Size        int64  `json:"size" example:"48213"`
+	URL         string `json:"url" example:"/api/orders/7/pod/photo"`
 }
 
 // AgentSummary model for paginated agent summaries
+// TotalKm and TotalMinutes mirror the planned figures so existing clients keep working.
 type AgentSummary struct {
-	AgentID      int64   `json:"agent_id"`
-	TotalOrders  int     `json:"total_orders"`
-	TotalKm      float64 `json:"total_km"`
-	TotalMinutes float64 `json:"total_minutes"`
-	Profit       float64 `json:"profit"`
+	AgentID         int64   `json:"agent_id"`
+	TotalOrders     int     `json:"total_orders"`
+	DeliveredOrders int     `json:"delivered_orders"`
+	TotalKm         float64 `json:"total_km"`
+	TotalMinutes    float64 `json:"total_minutes"`
+	PlannedKm       float64 `json:"planned_km"`
+	PlannedMinutes  float64 `json:"planned_minutes"`
+	ActualKm        float64 `json:"actual_km"`
+	ActualMinutes   float64 `json:"actual_minutes"`
+	Profit          float64 `json:"profit"`
 }
 
 // PaginatedAgentSummary model for paginated agent summaries...
@@ -99,10 +431,141 @@ type PaginatedAgentSummary struct {
 }
 
 // SystemSummary model for overall system summary...
+// Orders being returned or returned to their warehouse are neither assigned nor deferred.
+// RequeuedOrders are the deferred orders waiting for another attempt after a failed one.
 type SystemSummary struct {
 	TotalOrders      int                   `json:"total_orders"`
 	AssignedOrders   int                   `json:"assigned_orders"`
 	DeferredOrders   int                   `json:"deferred_orders"`
+	FailedAttempts   int                   `json:"failed_attempts"`
+	RequeuedOrders   int                   `json:"requeued_orders"`
+	ReturningOrders  int                   `json:"returning_orders"`
+	ReturnedOrders   int                   `json:"returned_orders"`
 	AgentUtilization PaginatedAgentSummary `json:"agent_utilization"`
 }
 
+
+// Polygon is a GeoJSON polygon's coordinates: the outer ring, then any holes, each a
+// closed ring of [lng, lat] positions.
+type Polygon [][][2]float64
+
+// ServiceZone model for an area a warehouse delivers to.
+type ServiceZone struct {
+	ID          int64     `json:"id"`
+	WarehouseID int64     `json:"warehouse_id"`
+	Name        string    `json:"name"`
+	Polygon     Polygon   `json:"polygon,omitempty" swaggertype:"array,number"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ZoneCheck model for the service zones containing a point. WarehouseID is the warehouse
+// an order there without a warehouse_id would go to.
+type ZoneCheck struct {
+	Lat         float64       `json:"lat"`
+	Lng         float64       `json:"lng"`
+	Served      bool          `json:"served"`
+	WarehouseID int64         `json:"warehouse_id,omitempty"`
+	Zones       []ServiceZone `json:"zones"`
+}
+
+// OrderCluster model for a delivery zone: pending orders of one warehouse close enough
+// together for one agent to deliver in a day. EstimatedKm is the nearest-first route
+// from the warehouse through every order.
+type OrderCluster struct {
+	ID          int      `json:"id"`
+	Centroid    Location `json:"centroid"`
+	OrderIDs    []int64  `json:"order_ids"`
+	EstimatedKm float64  `json:"estimated_km"`
+	Load
+}
+
+// WarehouseClusters model for the zones of a warehouse's pending orders, most urgent first.
+type WarehouseClusters struct {
+	WarehouseID int64          `json:"warehouse_id"`
+	Location    Location       `json:"location"`
+	BudgetKm    float64        `json:"budget_km"`
+	Clusters    []OrderCluster `json:"clusters"`
+}
+
+// AllocationResult model for the outcome of one allocation run.
+// Conflicts lists orders another run claimed first; they are neither assigned nor deferred here.
+// AtRisk lists orders assigned with an ETA after their window end. Unfit lists orders
+// too heavy or too big for every checked-in agent's vehicle; they are not deferred.
+type AllocationResult struct {
+	Assigned  int     `json:"assigned"`
+	Deferred  int     `json:"deferred"`
+	Conflicts []int64 `json:"conflicts"`
+	AtRisk    []int64 `json:"at_risk"`
+	Unfit     []int64 `json:"unfit"`
+}
+
+// SLAOrder model for an undelivered order with a delivery window. Status is "late" once
+// the window has closed and "at_risk" while the planned ETA falls after it. Times are in
+// the timezone of the order's warehouse.
+type SLAOrder struct {
+	OrderID     int64      `json:"order_id"`
+	WarehouseID int64      `json:"warehouse_id"`
+	Timezone    string     `json:"timezone,omitempty" example:"Asia/Kolkata"`
+	Customer    string     `json:"customer"`
+	Priority    int        `json:"priority"`
+	WindowStart *time.Time `json:"window_start,omitempty"`
+	WindowEnd   time.Time  `json:"window_end"`
+	AgentID     *int64     `json:"agent_id,omitempty"`
+	ETA         *time.Time `json:"eta,omitempty"`
+	Status      string     `json:"status" example:"at_risk"`
+	MinutesLate float64    `json:"minutes_late"`
+}
+
+// AllocationRun model for an allocation submitted over the API. The counters grow while
+// the run is in progress; Conflicts lists every order another writer claimed first.
+type AllocationRun struct {
+	ID         string     `json:"id" example:"3f9c1a7e5b2d4c60"`
+	Status     string     `json:"status" example:"running"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Assigned   int        `json:"assigned"`
+	Deferred   int        `json:"deferred"`
+	Conflicts  []int64    `json:"conflicts"`
+	AtRisk     []int64    `json:"at_risk"`
+	Unfit      []int64    `json:"unfit"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobState model for a scheduled job. Schedule, Timezone and Running describe this process;
+// the run fields are persisted and shared by every instance.
+type JobState struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	Timezone       string     `json:"timezone"`
+	Enabled        bool       `json:"enabled"`
+	Running        bool       `json:"running"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastStatus     string     `json:"last_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastResult     string     `json:"last_result,omitempty"`
+}
+
+// JobRun model for the outcome of one job run.
+type JobRun struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	Status    string
+	Error     string
+	Result    string
+}
+
+// DailyReport model for activity between From and To.
+type DailyReport struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	OrdersAssigned  int       `json:"orders_assigned"`
+	OrdersDelivered int       `json:"orders_delivered"`
+	ActiveAgents    int       `json:"active_agents"`
+	PlannedKm       float64   `json:"planned_km"`
+	ActualKm        float64   `json:"actual_km"`
+}
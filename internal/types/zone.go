@@ -0,0 +1,62 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate checks p is a GeoJSON polygon: every ring has at least four positions, ends
+// where it starts and stays within valid coordinates.
+func (p Polygon) Validate() error {
+	if len(p) == 0 {
+		return errors.New("polygon has no rings")
+	}
+	for i, ring := range p {
+		if len(ring) < 4 {
+			return fmt.Errorf("ring %d has %d positions, want at least 4", i, len(ring))
+		}
+		if ring[0] != ring[len(ring)-1] {
+			return fmt.Errorf("ring %d does not end where it starts", i)
+		}
+		for _, pos := range ring {
+			if pos[0] < -180 || pos[0] > 180 || pos[1] < -90 || pos[1] > 90 {
+				return fmt.Errorf("ring %d has position [%g, %g] outside [lng, lat] bounds", i, pos[0], pos[1])
+			}
+		}
+	}
+	return nil
+}
+
+// Contains reports whether the point lies inside the outer ring and outside every hole.
+func (p Polygon) Contains(lat, lng float64) bool {
+	if len(p) == 0 || !inRing(p[0], lat, lng) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if inRing(hole, lat, lng) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRing casts a ray east from the point and counts the edges it crosses. Coordinates are
+// treated as planar, which holds for zones that do not span the antimeridian. Points on the
+// west and south edges are inside and those on the east and north edges outside, so a
+// point on the edge two zones share is in exactly one of them.
+func inRing(ring [][2]float64, lat, lng float64) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+	return in
+}
+
+// Contains reports whether the zone covers the point.
+func (z ServiceZone) Contains(lat, lng float64) bool {
+	return z.Polygon.Contains(lat, lng)
+}
@@ -1,57 +1,242 @@
 package response
 
-
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"unicode"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
 )
 
-type Response struct {
-	Status  string  `json:"status"`      
-	Error   string   `json:"error"`
-}
+const (
+	StatusOk    = "OK"
+	StatusError = "Error"
+)
 
-type ErrorResponse struct {
-	Status string `json:"status"`
-	Error  string `json:"error"`
-}
+// ProblemContentType is the RFC 7807 media type used for every error response.
+const ProblemContentType = "application/problem+json"
 
+// Stable error codes. Clients should branch on these, never on the message.
 const (
-	StatusOk="OK"
-	StatusError="Error"
+	CodeInvalidRequest       = "INVALID_REQUEST"
+	CodeInvalidID            = "INVALID_ID"
+	CodeValidationFailed     = "VALIDATION_FAILED"
+	CodeNotFound             = "NOT_FOUND"
+	CodeAgentNotFound        = "AGENT_NOT_FOUND"
+	CodeOrderNotFound        = "ORDER_NOT_FOUND"
+	CodeWarehouseNotFound    = "WAREHOUSE_NOT_FOUND"
+	CodeZoneNotFound         = "ZONE_NOT_FOUND"
+	CodeReviewNotFound       = "REVIEW_NOT_FOUND"
+	CodeCustomerNotFound     = "CUSTOMER_NOT_FOUND"
+	CodeProofNotFound        = "PROOF_NOT_FOUND"
+	CodeOTPMismatch          = "OTP_MISMATCH"
+	CodeOTPLocked            = "OTP_LOCKED"
+	CodeUploadTooLarge       = "UPLOAD_TOO_LARGE"
+	CodeUnsupportedMedia     = "UNSUPPORTED_MEDIA_TYPE"
+	CodeOutOfZone            = "OUT_OF_ZONE"
+	CodeInvalidGeoJSON       = "INVALID_GEOJSON"
+	CodeNoOpenAssignment     = "NO_OPEN_ASSIGNMENT"
+	CodeConflict             = "CONFLICT"
+	CodeAllocationFailed     = "ALLOCATION_FAILED"
+	CodeAllocationInProgress = "ALLOCATION_IN_PROGRESS"
+	CodeAllocationNotFound   = "ALLOCATION_NOT_FOUND"
+	CodeAllocationFinished   = "ALLOCATION_FINISHED"
+	CodeAllocationQueueFull  = "ALLOCATION_QUEUE_FULL"
+	CodeJobNotFound          = "JOB_NOT_FOUND"
+	CodeJobRunning           = "JOB_RUNNING"
+	CodeTimeout              = "TIMEOUT"
+	CodeCanceled             = "REQUEST_CANCELED"
+	CodeInternal             = "INTERNAL_ERROR"
 )
 
+// Problem is the RFC 7807 problem+json error envelope.
+type Problem struct {
+	Type      string       `json:"type" example:"urn:dms:error:VALIDATION_FAILED"`
+	Title     string       `json:"title" example:"Bad Request"`
+	Status    int          `json:"status" example:"400"`
+	Detail    string       `json:"detail,omitempty" example:"request body failed validation"`
+	Instance  string       `json:"instance,omitempty" example:"/api/order"`
+	Code      string       `json:"code" example:"VALIDATION_FAILED"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one failed validation rule.
+type FieldError struct {
+	Field   string `json:"field" example:"lat"`
+	Tag     string `json:"tag" example:"required"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message" example:"lat is required"`
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Code, p.Detail)
+}
+
 func WriteJSON(w http.ResponseWriter, status int, data interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
-	return  json.NewEncoder(w).Encode(data)
+	return json.NewEncoder(w).Encode(data)
+}
+
+// WriteProblem writes p as application/problem+json, filling the instance and request ID from r.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) error {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	p.RequestID = logger.RequestID(r.Context())
+
+	if p.Status >= http.StatusInternalServerError {
+		logger.FromContext(r.Context()).Error("request failed",
+			"code", p.Code, "status", p.Status, "detail", p.Detail)
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
 }
 
-func GeneralError(err error) ErrorResponse {
-	return ErrorResponse{
-		Status: StatusError,
-		Error: err.Error(),
+// NewProblem builds a problem for the given status and code.
+func NewProblem(status int, code, detail string) Problem {
+	return Problem{
+		Type:   "urn:dms:error:" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
 	}
 }
 
-func ValidationError(errs  validator.ValidationErrors) ErrorResponse {
-	var errMsgs []string
+// BadRequest reports a malformed request.
+func BadRequest(code string, err error) Problem {
+	return NewProblem(http.StatusBadRequest, code, err.Error())
+}
+
+// Internal reports an unexpected failure.
+func Internal(err error) Problem {
+	return NewProblem(http.StatusInternalServerError, CodeInternal, err.Error())
+}
+
+// FromError maps storage errors to HTTP problems in one place. notFoundCode is the
+// resource specific code used for storage.ErrNotFound, e.g. CodeOrderNotFound.
+func FromError(err error, notFoundCode string) Problem {
+	var p Problem
+	switch {
+	case errors.As(err, &p):
+		return p
+	case errors.Is(err, storage.ErrNotFound):
+		if notFoundCode == "" {
+			notFoundCode = CodeNotFound
+		}
+		return NewProblem(http.StatusNotFound, notFoundCode, err.Error())
+	case errors.Is(err, storage.ErrConflict):
+		return NewProblem(http.StatusConflict, CodeConflict, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewProblem(http.StatusGatewayTimeout, CodeTimeout, err.Error())
+	case errors.Is(err, context.Canceled):
+		return NewProblem(http.StatusServiceUnavailable, CodeCanceled, err.Error())
+	default:
+		return Internal(err)
+	}
+}
+
+func ValidationError(errs validator.ValidationErrors) Problem {
+	var fields []FieldError
 	for _, err := range errs {
+		fe := FieldError{
+			Field: fieldPath(err),
+			Tag:   err.ActualTag(),
+			Param: err.Param(),
+		}
 		switch err.ActualTag() {
 		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is required", err.Field()))
+			fe.Message = fmt.Sprintf("%s is required", fe.Field)
+		case "min", "gte":
+			fe.Message = fmt.Sprintf("%s must be at least %s", fe.Field, fe.Param)
+		case "max", "lte":
+			fe.Message = fmt.Sprintf("%s must be at most %s", fe.Field, fe.Param)
+		case "required_without", "required_without_all":
+			fe.Message = fmt.Sprintf("%s is required without %s", fe.Field, jsonNames(fe.Param))
+		case "required_if":
+			if f := strings.Fields(fe.Param); len(f) == 2 {
+				fe.Message = fmt.Sprintf("%s is required when %s is %s", fe.Field, jsonNames(f[0]), f[1])
+			} else {
+				fe.Message = fmt.Sprintf("%s is required", fe.Field)
+			}
+		case "oneof":
+			fe.Message = fmt.Sprintf("%s must be one of %s", fe.Field, strings.Join(strings.Fields(fe.Param), ", "))
+		case "alphanum":
+			fe.Message = fmt.Sprintf("%s must contain only letters and digits", fe.Field)
+		case "email":
+			fe.Message = fmt.Sprintf("%s must be a valid email address", fe.Field)
+		case "latitude":
+			fe.Message = fmt.Sprintf("%s must be a latitude between -90 and 90", fe.Field)
+		case "longitude":
+			fe.Message = fmt.Sprintf("%s must be a longitude between -180 and 180", fe.Field)
+		case "null_island":
+			fe.Message = fmt.Sprintf("%s: (0, 0) is not a delivery location, the coordinates are missing", fe.Field)
 		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s failed validation for tag\n", err.Field()))
+			fe.Message = fmt.Sprintf("%s failed the %q rule", fe.Field, fe.Tag)
 		}
+		fields = append(fields, fe)
 	}
+	return FieldErrors(fields...)
+}
+
+// FieldErrors reports rules checked outside the validator, such as those that need
+// stored data, in the same shape as ValidationError.
+func FieldErrors(errs ...FieldError) Problem {
+	p := NewProblem(http.StatusBadRequest, CodeValidationFailed, "request body failed validation")
 
-	return ErrorResponse{
-		Status: StatusError,
-		Error:  strings.Join(errMsgs, ", "	),
+	var msgs []string
+	for _, fe := range errs {
+		p.Errors = append(p.Errors, fe)
+		msgs = append(msgs, fe.Message)
 	}
-}
\ No newline at end of file
+
+	p.Detail = strings.Join(msgs, ", ")
+	return p
+}
+
+// jsonNames turns the Go field names a rule refers to, e.g. "CustomerID AddressID", into
+// the request's names: "customer_id or address_id".
+func jsonNames(param string) string {
+	names := strings.Fields(param)
+	for i, name := range names {
+		var b strings.Builder
+		for j, r := range name {
+			if j > 0 && unicode.IsUpper(r) && unicode.IsLower(rune(name[j-1])) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		}
+		names[i] = b.String()
+	}
+	return strings.Join(names, " or ")
+}
+
+// fieldPath drops the root and embedded struct names from the namespace, e.g.
+// "BulkOrderRequest.orders[2].lat" becomes "orders[2].lat" and
+// "WarehouseRequest.WarehouseCalendar.timezone" becomes "timezone".
+func fieldPath(err validator.FieldError) string {
+	parts := strings.Split(err.Namespace(), ".")
+	path := parts[:0]
+	for i, part := range parts {
+		// json names are lower case; a capitalised parent is a Go type name
+		if i < len(parts)-1 && part != "" && unicode.IsUpper(rune(part[0])) {
+			continue
+		}
+		path = append(path, part)
+	}
+	if len(path) == 0 {
+		return err.Field()
+	}
+	return strings.Join(path, ".")
+}
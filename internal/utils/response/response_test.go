@@ -0,0 +1,123 @@
+package response_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sharmaprinceji/delivery-management-system/internal/logger"
+	"github.com/sharmaprinceji/delivery-management-system/internal/storage"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/response"
+	"github.com/sharmaprinceji/delivery-management-system/internal/utils/validation"
+)
+
+func TestFromError(t *testing.T) {
+	locked := response.NewProblem(http.StatusLocked, response.CodeOTPLocked, "too many attempts")
+	tests := []struct {
+		name         string
+		err          error
+		notFoundCode string
+		status       int
+		code         string
+	}{
+		{"not found", fmt.Errorf("order 7: %w", storage.ErrNotFound), response.CodeOrderNotFound, http.StatusNotFound, response.CodeOrderNotFound},
+		{"not found without a code", storage.ErrNotFound, "", http.StatusNotFound, response.CodeNotFound},
+		{"conflict", fmt.Errorf("order 7 already assigned: %w", storage.ErrConflict), "", http.StatusConflict, response.CodeConflict},
+		{"deadline", fmt.Errorf("load orders: %w", context.DeadlineExceeded), "", http.StatusGatewayTimeout, response.CodeTimeout},
+		{"canceled", fmt.Errorf("load orders: %w", context.Canceled), "", http.StatusServiceUnavailable, response.CodeCanceled},
+		{"problem", locked, response.CodeOrderNotFound, http.StatusLocked, response.CodeOTPLocked},
+		{"wrapped problem", fmt.Errorf("verify: %w", locked), "", http.StatusLocked, response.CodeOTPLocked},
+		{"anything else", errors.New("disk full"), response.CodeOrderNotFound, http.StatusInternalServerError, response.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := response.FromError(tt.err, tt.notFoundCode)
+			if p.Status != tt.status || p.Code != tt.code || p.Type != "urn:dms:error:"+tt.code || p.Title != http.StatusText(tt.status) {
+				t.Errorf("FromError = %+v, want %d %s", p, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		problem  response.Problem
+		instance string
+	}{
+		{"instance from the request", response.FromError(storage.ErrNotFound, response.CodeAgentNotFound), "/api/agent/7"},
+		{"instance kept", response.Problem{Status: http.StatusConflict, Code: response.CodeConflict, Instance: "/api/orders/7"}, "/api/orders/7"},
+		{"server error", response.Internal(errors.New("disk full")), "/api/agent/7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/agent/7", nil)
+			req = req.WithContext(logger.WithRequestID(req.Context(), "req-42"))
+			rec := httptest.NewRecorder()
+			if err := response.WriteProblem(rec, req, tt.problem); err != nil {
+				t.Fatalf("WriteProblem: %v", err)
+			}
+
+			if rec.Code != tt.problem.Status || rec.Header().Get("Content-Type") != response.ProblemContentType {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Header().Get("Content-Type"), tt.problem.Status, response.ProblemContentType)
+			}
+			var got response.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Code != tt.problem.Code || got.Status != tt.problem.Status || got.Instance != tt.instance || got.RequestID != "req-42" {
+				t.Errorf("body = %+v", got)
+			}
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	type Stop struct {
+		Lat float64 `json:"lat" validate:"required,latitude"`
+	}
+	type Request struct {
+		Name       string `json:"name" validate:"required"`
+		Priority   int    `json:"priority" validate:"min=1,max=5"`
+		Vehicle    string `json:"vehicle" validate:"oneof=bike van"`
+		Email      string `json:"email" validate:"omitempty,email"`
+		CustomerID int64  `json:"customer_id" validate:"required_without=AddressID"`
+		AddressID  int64  `json:"address_id"`
+		Stops      []Stop `json:"stops" validate:"dive"`
+	}
+	err := validation.Struct(Request{Priority: 9, Vehicle: "lorry", Email: "asha", Stops: []Stop{{Lat: 12.9}, {Lat: 97}}})
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Struct = %v, want validation errors", err)
+	}
+
+	p := response.ValidationError(errs)
+	if p.Status != http.StatusBadRequest || p.Code != response.CodeValidationFailed {
+		t.Errorf("problem = %d %s", p.Status, p.Code)
+	}
+	want := []response.FieldError{
+		{Field: "name", Tag: "required", Message: "name is required"},
+		{Field: "priority", Tag: "max", Param: "5", Message: "priority must be at most 5"},
+		{Field: "vehicle", Tag: "oneof", Param: "bike van", Message: "vehicle must be one of bike, van"},
+		{Field: "email", Tag: "email", Message: "email must be a valid email address"},
+		{Field: "customer_id", Tag: "required_without", Param: "AddressID", Message: "customer_id is required without address_id"},
+		{Field: "stops[1].lat", Tag: "latitude", Message: "stops[1].lat must be a latitude between -90 and 90"},
+	}
+	if len(p.Errors) != len(want) {
+		t.Fatalf("errors = %+v, want %d", p.Errors, len(want))
+	}
+	for i, fe := range p.Errors {
+		if fe != want[i] {
+			t.Errorf("errors[%d] = %+v, want %+v", i, fe, want[i])
+		}
+	}
+	if p.Detail != "name is required, priority must be at most 5, vehicle must be one of bike, van, email must be a valid email address, "+
+		"customer_id is required without address_id, stops[1].lat must be a latitude between -90 and 90" {
+		t.Errorf("detail = %q", p.Detail)
+	}
+}
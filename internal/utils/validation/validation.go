@@ -0,0 +1,54 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sharmaprinceji/delivery-management-system/internal/types"
+)
+
+var validate = newValidator()
+
+// newValidator reports fields by their json names so error details match the request body.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	v.RegisterStructValidation(notNullIsland, types.Location{}, types.OrderRequest{}, types.ProofRequest{})
+	return v
+}
+
+// notNullIsland rejects (0, 0): it is where a location that was left out ends up, and no
+// delivery goes to the Gulf of Guinea. Either coordinate alone may be 0. An order with an
+// address may leave both out, to be geocoded, as may one for a saved address.
+func notNullIsland(sl validator.StructLevel) {
+	var lat, lng float64
+	switch v := sl.Current().Interface().(type) {
+	case types.Location:
+		lat, lng = v.Lat, v.Lng
+	case types.OrderRequest:
+		if v.Address != nil || v.AddressID != 0 {
+			return
+		}
+		lat, lng = v.Lat, v.Lng
+	case types.ProofRequest:
+		lat, lng = v.Lat, v.Lng
+	}
+	if lat == 0 && lng == 0 {
+		sl.ReportError(lat, "lat", "Lat", "null_island", "")
+	}
+}
+
+// Struct validates s against its `validate` tags.
+func Struct(s any) error {
+	return validate.Struct(s)
+}